@@ -39,6 +39,7 @@ const (
 	MimetypeDataWithValidator = "data/validator"
 	MimetypeTypedData         = "data/typed"
 	MimetypeClique            = "application/x-clique-header"
+	MimetypeHotStuff          = "application/x-hotstuff-consensus"
 	MimetypeTextPlain         = "text/plain"
 )
 
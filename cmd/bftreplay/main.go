@@ -0,0 +1,184 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// bftreplay replays the consensus message logs of a set of HotStuff nodes, to
+// reproduce the view changes and vote counts of a stalled network offline.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/core"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/consensus/bft/wal"
+	gcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	genesisFlag    = flag.String("genesis", "", "genesis json file with the validators of the network")
+	validatorsFlag = flag.String("validators", "", "comma separated validator addresses, instead of the genesis")
+	verboseFlag    = flag.Bool("verbose", false, "print every replayed message")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "[-genesis <file> | -validators <addr,...>] [-verbose] <wal dir>...")
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr, `
+Replays the consensus message logs written by the nodes of a HotStuff network
+with --hotstuff.wal, one log directory per node. The messages of all nodes are
+merged by time, ties are broken by the order of the directories and of the log,
+so replays of the same logs print the same output.`)
+	}
+}
+
+// logEntry is a message of the log of a node.
+type logEntry struct {
+	*wal.Entry
+	node int
+	seq  int
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	valSet, err := loadValidators()
+	if err != nil {
+		die(err)
+	}
+	var (
+		names     = flag.Args()
+		replayers = make([]*core.Replayer, len(names))
+		entries   []*logEntry
+	)
+	for i, dir := range names {
+		logged, err := wal.Read(dir)
+		if err != nil {
+			die(fmt.Errorf("failed to read %s: %v", dir, err))
+		}
+		for j, entry := range logged {
+			entries = append(entries, &logEntry{Entry: entry, node: i, seq: j})
+		}
+		replayers[i] = core.NewReplayer(func(uint64) bft.ValidatorSet { return valSet })
+		names[i] = filepath.Base(filepath.Clean(dir))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Time != entries[j].Time {
+			return entries[i].Time < entries[j].Time
+		}
+		if entries[i].node != entries[j].node {
+			return entries[i].node < entries[j].node
+		}
+		return entries[i].seq < entries[j].seq
+	})
+
+	fmt.Println("Timeline:")
+	for _, entry := range entries {
+		r := replayers[entry.node]
+		changes := len(r.ViewChanges())
+
+		msg, err := r.Apply(entry.Time, entry.Payload, entry.Dir == wal.Outbound)
+		if err != nil {
+			fmt.Printf("%s %-12s %-3s invalid message: %v\n", timestamp(entry.Time), names[entry.node], entry.Dir, err)
+			continue
+		}
+		if *verboseFlag {
+			fmt.Printf("%s %-12s %-3s %-14s view %v/%v from %x\n", timestamp(entry.Time), names[entry.node], entry.Dir, msg.Code, msg.View.Height, msg.View.Round, msg.Address[:4])
+		}
+		if all := r.ViewChanges(); len(all) > changes {
+			change := all[len(all)-1]
+			from := "-"
+			if change.From != nil {
+				from = fmt.Sprintf("%v/%v", change.From.Height, change.From.Round)
+			}
+			fmt.Printf("%s %-12s view %s -> %v/%v (%s)\n", timestamp(change.Time), names[entry.node], from, change.To.Height, change.To.Round, change.Code)
+		}
+	}
+	for i, r := range replayers {
+		fmt.Printf("\nNode %s (%x):\n", names[i], r.Self())
+		for _, tally := range r.Tallies() {
+			fmt.Printf("  view %v/%v, quorum %d\n", tally.View.Height, tally.View.Round, tally.Quorum())
+			for _, code := range tally.Codes() {
+				for _, digest := range tally.Digests(code) {
+					power := tally.Power(code, digest)
+					mark := ""
+					if power >= tally.Quorum() {
+						mark = " quorum"
+					}
+					fmt.Printf("    %-14s %x  senders %d, power %d%s\n", code, digest[:8], len(tally.Senders[code][digest]), power, mark)
+				}
+			}
+		}
+	}
+}
+
+// loadValidators returns the validator set of the flags, the messages of every
+// height are checked against it.
+func loadValidators() (bft.ValidatorSet, error) {
+	if *validatorsFlag != "" {
+		var addrs []common.Address
+		for _, addr := range strings.Split(*validatorsFlag, ",") {
+			if !common.IsHexAddress(addr) {
+				return nil, fmt.Errorf("invalid validator address %q", addr)
+			}
+			addrs = append(addrs, common.HexToAddress(addr))
+		}
+		return validator.NewSet(addrs, bft.RoundRobin), nil
+	}
+	if *genesisFlag == "" {
+		return nil, fmt.Errorf("either -genesis or -validators is required")
+	}
+	file, err := os.Open(*genesisFlag)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	genesis := new(gcore.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	extra, err := types.ExtractBftExtra(&types.Header{Extra: genesis.ExtraData})
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis extra data: %v", err)
+	}
+	if len(extra.Powers) > 0 {
+		return validator.NewWeightedSet(extra.Validators, extra.Powers, bft.RoundRobin)
+	}
+	return validator.NewSet(extra.Validators, bft.RoundRobin), nil
+}
+
+func timestamp(t uint64) string {
+	return time.Unix(0, int64(t)).UTC().Format("15:04:05.000000")
+}
+
+func die(err error) {
+	fmt.Fprintln(os.Stderr, "Fatal:", err)
+	os.Exit(1)
+}
@@ -129,6 +129,23 @@ var (
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
 		utils.MinerNewPayloadTimeout,
+		utils.HotStuffProtocolFlag,
+		utils.HotStuffRequestTimeoutFlag,
+		utils.HotStuffMaxTimeoutFlag,
+		utils.HotStuffBlockPeriodFlag,
+		utils.HotStuffLeaderPolicyFlag,
+		utils.HotStuffEpochFlag,
+		utils.HotStuffSkipEmptyFlag,
+		utils.HotStuffMaxIdleFlag,
+		utils.HotStuffBuildDeadlineFlag,
+		utils.HotStuffRefreshPayloadFlag,
+		utils.HotStuffWALFlag,
+		utils.HotStuffWALMaxSizeFlag,
+		utils.HotStuffWALMaxFilesFlag,
+		utils.HotStuffSignerFlag,
+		utils.HotStuffSignerAccountFlag,
+		utils.HotStuffAbsentEpochsFlag,
+		utils.HotStuffAbsentVotesFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -139,6 +156,7 @@ var (
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
 		utils.DeveloperGasLimitFlag,
+		utils.DeveloperBFTFlag,
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
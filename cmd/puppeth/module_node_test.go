@@ -0,0 +1,61 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that a node deployed with a validator node key reports the validator of
+// the genesis it seals with.
+func TestDeployedValidator(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	header := &types.Header{Extra: make([]byte, types.BftExtraVanity)}
+	if err := types.BftHeaderFillWithValidators(header, []common.Address{address}); err != nil {
+		t.Fatalf("failed to encode validators: %v", err)
+	}
+	genesis := &core.Genesis{ExtraData: header.Extra}
+
+	infos := &nodeInfos{nodeKey: hex.EncodeToString(crypto.FromECDSA(key)), gasLimit: 7.5}
+	if validator := infos.validator(); validator != address.Hex() {
+		t.Fatalf("validator mismatch: have %s, want %s", validator, address.Hex())
+	}
+	if !isValidator(genesis, common.HexToAddress(infos.validator())) {
+		t.Fatalf("deployed validator %s not in genesis", infos.validator())
+	}
+	if report := infos.Report()["Validator account"]; report != address.Hex() {
+		t.Fatalf("reported validator mismatch: have %s, want %s", report, address.Hex())
+	}
+	// Nodes without a valid node key don't validate
+	for _, nodeKey := range []string{"", "invalid"} {
+		infos := &nodeInfos{nodeKey: nodeKey, gasLimit: 7.5}
+		if validator := infos.validator(); validator != "" {
+			t.Errorf("node key %q: unexpected validator %s", nodeKey, validator)
+		}
+		if _, ok := infos.Report()["Validator account"]; ok {
+			t.Errorf("node key %q: validator account reported", nodeKey)
+		}
+	}
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console/prompt"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/peterh/liner"
 	"golang.org/x/term"
@@ -255,6 +257,19 @@ func (w *wizard) readAddress() *common.Address {
 	}
 }
 
+// readNodeKey reads a single line from stdin and converts it to a node key. The
+// input will not be echoed.
+func (w *wizard) readNodeKey() *ecdsa.PrivateKey {
+	for {
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(w.readPassword()), "0x"))
+		if err != nil {
+			log.Error("Invalid node key, please retry", "err", err)
+			continue
+		}
+		return key
+	}
+}
+
 // readDefaultAddress reads a single line from stdin, trimming if from spaces and
 // converts it to an Ethereum address. If an empty line is entered, the default
 // value is returned.
@@ -19,6 +19,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -29,7 +30,9 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -58,6 +61,7 @@ func (w *wizard) makeGenesis() {
 	fmt.Println("Which consensus engine to use? (default = clique)")
 	fmt.Println(" 1. Ethash - proof-of-work")
 	fmt.Println(" 2. Clique - proof-of-authority")
+	fmt.Println(" 3. HotStuff - byzantine fault tolerant proof-of-authority")
 
 	choice := w.read()
 	switch {
@@ -104,6 +108,79 @@ func (w *wizard) makeGenesis() {
 			copy(genesis.ExtraData[32+i*common.AddressLength:], signer[:])
 		}
 
+	case choice == "3":
+		// In the case of hotstuff, configure the protocol and the validators
+		hotstuff := new(params.HotStuffConfig)
+
+		fmt.Println()
+		fmt.Println("Which protocol variant to use? (default = basic)")
+		fmt.Println(" 1. Basic - three phase hotstuff, block period in seconds")
+		fmt.Println(" 2. Event driven - chained hotstuff, block period in milliseconds")
+
+		defaults := bft.DefaultBasicConfig
+		switch w.read() {
+		case "", "1":
+			hotstuff.Protocol = string(bft.BFT_PROTOCOL_BASIC)
+		case "2":
+			hotstuff.Protocol = string(bft.BFT_PROTOCOL_EVENT_DRIVEN)
+			defaults = bft.DefaultEventDrivenConfig
+		default:
+			log.Crit("Invalid hotstuff protocol choice")
+		}
+		fmt.Println()
+		fmt.Printf("How many milliseconds should a round wait before timing out? (default = %d)\n", defaults.RequestTimeout)
+		hotstuff.RequestTimeout = uint64(w.readDefaultInt(int(defaults.RequestTimeout)))
+
+		fmt.Println()
+		if defaults.Protocol == bft.BFT_PROTOCOL_EVENT_DRIVEN {
+			fmt.Printf("How many milliseconds should blocks take? (default = %d)\n", defaults.BlockPeriod)
+		} else {
+			fmt.Printf("How many seconds should blocks take? (default = %d)\n", defaults.BlockPeriod)
+		}
+		hotstuff.BlockPeriod = uint64(w.readDefaultInt(int(defaults.BlockPeriod)))
+
+		fmt.Println()
+		fmt.Println("How should the proposer of a round be selected? (default = roundrobin)")
+		fmt.Println(" 1. Round robin - rotate over the validators every round")
+		fmt.Println(" 2. Sticky - keep the proposer until a round fails")
+		fmt.Println(" 3. VRF - pick the proposer with a verifiable random function")
+
+		switch w.read() {
+		case "", "1":
+			hotstuff.LeaderPolicy = bft.RoundRobin.String()
+		case "2":
+			hotstuff.LeaderPolicy = bft.Sticky.String()
+		case "3":
+			hotstuff.LeaderPolicy = bft.VRF.String()
+		default:
+			log.Crit("Invalid proposer policy choice")
+		}
+		if defaults.Protocol == bft.BFT_PROTOCOL_BASIC {
+			fmt.Println()
+			fmt.Printf("How many blocks should an epoch take? (default = %d)\n", defaults.Epoch)
+			hotstuff.Epoch = uint64(w.readDefaultInt(int(defaults.Epoch)))
+		}
+		if _, err := bft.NewConfig(hotstuff); err != nil {
+			log.Crit("Invalid hotstuff configuration", "err", err)
+		}
+		// We also need the initial list of validators, they sign with their node keys
+		fmt.Println()
+		fmt.Println("Which node accounts are allowed to validate? (mandatory at least one)")
+
+		var validators []common.Address
+		for {
+			if address := w.readAddress(); address != nil {
+				validators = append(validators, *address)
+				continue
+			}
+			if len(validators) > 0 {
+				break
+			}
+		}
+		if err := makeHotStuffGenesis(genesis, hotstuff, validators); err != nil {
+			log.Crit("Failed to configure the hotstuff genesis", "err", err)
+		}
+
 	default:
 		log.Crit("Invalid consensus engine choice", "choice", choice)
 	}
@@ -140,6 +217,23 @@ func (w *wizard) makeGenesis() {
 	w.conf.flush()
 }
 
+// makeHotStuffGenesis configures the genesis to be sealed by the hotstuff engine,
+// with the validators allowed to seal the first epoch.
+func makeHotStuffGenesis(genesis *core.Genesis, config *params.HotStuffConfig, validators []common.Address) error {
+	if len(validators) == 0 {
+		return errors.New("no hotstuff validators")
+	}
+	header := &types.Header{Extra: make([]byte, types.BftExtraVanity)}
+	if err := types.BftHeaderFillWithValidators(header, validators); err != nil {
+		return err
+	}
+	genesis.Difficulty = big.NewInt(1)
+	genesis.Mixhash = types.BftDigest
+	genesis.ExtraData = header.Extra
+	genesis.Config.HotStuff = config
+	return nil
+}
+
 // importGenesis imports a Geth genesis spec into puppeth.
 func (w *wizard) importGenesis() {
 	// Request the genesis JSON spec URL from the user
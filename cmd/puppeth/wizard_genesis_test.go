@@ -0,0 +1,86 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a hotstuff genesis block is generated with the configured protocol
+// and the validators allowed to seal the chain.
+func TestMakeHotStuffGenesis(t *testing.T) {
+	var (
+		validators = []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}
+		hotstuff   = &params.HotStuffConfig{
+			Protocol:     string(bft.BFT_PROTOCOL_EVENT_DRIVEN),
+			LeaderPolicy: bft.VRF.String(),
+			BlockPeriod:  bft.DefaultEventDrivenConfig.BlockPeriod,
+		}
+		genesis = &core.Genesis{
+			GasLimit:   4700000,
+			Difficulty: big.NewInt(524288),
+			Alloc:      make(core.GenesisAlloc),
+			Config:     &params.ChainConfig{ChainID: big.NewInt(1337)},
+		}
+	)
+	if _, err := bft.NewConfig(hotstuff); err != nil {
+		t.Fatalf("invalid hotstuff config: %v", err)
+	}
+	if err := makeHotStuffGenesis(genesis, hotstuff, validators); err != nil {
+		t.Fatalf("failed to make genesis: %v", err)
+	}
+	if genesis.Config.HotStuff != hotstuff {
+		t.Fatal("hotstuff config not set")
+	}
+	block := genesis.ToBlock(rawdb.NewMemoryDatabase())
+	if block.MixDigest() != types.BftDigest {
+		t.Fatalf("mix digest mismatch: have %x, want %x", block.MixDigest(), types.BftDigest)
+	}
+	if block.Difficulty().Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("difficulty mismatch: have %v, want 1", block.Difficulty())
+	}
+	extra, err := types.ExtractBftExtra(block.Header())
+	if err != nil {
+		t.Fatalf("failed to extract genesis validators: %v", err)
+	}
+	if len(extra.Validators) != len(validators) {
+		t.Fatalf("validator count mismatch: have %d, want %d", len(extra.Validators), len(validators))
+	}
+	for i, validator := range validators {
+		if extra.Validators[i] != validator {
+			t.Errorf("validator %d mismatch: have %s, want %s", i, extra.Validators[i], validator)
+		}
+		if !isValidator(genesis, validator) {
+			t.Errorf("validator %s not found in genesis", validator)
+		}
+	}
+	if isValidator(genesis, common.HexToAddress("0x03")) {
+		t.Fatal("unknown account reported as validator")
+	}
+	// A genesis without validators can't be sealed
+	if err := makeHotStuffGenesis(genesis, hotstuff, nil); err == nil {
+		t.Fatal("genesis without validators accepted")
+	}
+}
@@ -17,12 +17,16 @@
 package main
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -147,6 +151,29 @@ func (w *wizard) deployNode(boot bool) {
 				}
 			}
 		}
+		if w.conf.Genesis.Config.HotStuff != nil {
+			// If a previous validator was already set, offer to reuse it
+			if validator := infos.validator(); validator != "" {
+				fmt.Println()
+				fmt.Printf("Reuse previous (%s) validator node key (y/n)? (default = yes)\n", validator)
+				if !w.readDefaultYesNo(true) {
+					infos.nodeKey = ""
+				}
+			} else {
+				infos.nodeKey = ""
+			}
+			// HotStuff validators sign with their node key, ask if unavailable
+			if infos.nodeKey == "" {
+				fmt.Println()
+				fmt.Println("What's the validator's hex node key? (won't be echoed)")
+				key := w.readNodeKey()
+				infos.nodeKey = hex.EncodeToString(crypto.FromECDSA(key))
+
+				if !isValidator(w.conf.Genesis, crypto.PubkeyToAddress(key.PublicKey)) {
+					log.Warn("Node key is not a genesis validator", "address", crypto.PubkeyToAddress(key.PublicKey))
+				}
+			}
+		}
 		// Establish the gas dynamics to be enforced by the signer
 		fmt.Println()
 		fmt.Printf("What gas limit should full blocks target (MGas)? (default = %0.3f)\n", infos.gasLimit)
@@ -176,3 +203,17 @@ func (w *wizard) deployNode(boot bool) {
 
 	w.networkStats()
 }
+
+// isValidator reports whether the address is a validator of the hotstuff genesis.
+func isValidator(genesis *core.Genesis, address common.Address) bool {
+	extra, err := types.ExtractBftExtra(&types.Header{Extra: genesis.ExtraData})
+	if err != nil {
+		return false
+	}
+	for _, validator := range extra.Validators {
+		if validator == address {
+			return true
+		}
+	}
+	return false
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bft/wal"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -178,6 +179,11 @@ var (
 		Value:    11500000,
 		Category: flags.DevCategory,
 	}
+	DeveloperBFTFlag = &cli.BoolFlag{
+		Name:     "dev.bft",
+		Usage:    "Seal the developer chain with hotstuff, the node key being the single validator",
+		Category: flags.DevCategory,
+	}
 
 	IdentityFlag = &cli.StringFlag{
 		Name:     "identity",
@@ -575,6 +581,94 @@ var (
 		Category: flags.MinerCategory,
 	}
 
+	// HotStuff settings, overriding the hotstuff section of the chain config
+	HotStuffProtocolFlag = &cli.StringFlag{
+		Name:     "hotstuff.protocol",
+		Usage:    "HotStuff protocol variant (basic, event_driven)",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffRequestTimeoutFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.requesttimeout",
+		Usage:    "Timeout for each HotStuff round in milliseconds",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffMaxTimeoutFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.maxtimeout",
+		Usage:    "Cap of the exponential HotStuff round timeout backoff in milliseconds",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffBlockPeriodFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.blockperiod",
+		Usage:    "Minimum block interval, in seconds for basic and in milliseconds for event_driven",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffLeaderPolicyFlag = &cli.StringFlag{
+		Name:     "hotstuff.policy",
+		Usage:    "Proposer selection policy (roundrobin, sticky, vrf)",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffEpochFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.epoch",
+		Usage:    "Number of blocks after which to checkpoint and reset the pending votes",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffSkipEmptyFlag = &cli.BoolFlag{
+		Name:     "hotstuff.skipempty",
+		Usage:    "Hold back empty blocks until the max idle interval passed since the parent",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffMaxIdleFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.maxidle",
+		Usage:    "Max interval between blocks in milliseconds when empty blocks are skipped",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffBuildDeadlineFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.builddeadline",
+		Usage:    "Milliseconds after the start of a height at which the proposer stops adding transactions (0 = no deadline)",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffRefreshPayloadFlag = &cli.BoolFlag{
+		Name:     "hotstuff.refreshpayload",
+		Usage:    "Let the proposer swap in a fresher block until it proposes",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffWALFlag = &flags.DirectoryFlag{
+		Name:     "hotstuff.wal",
+		Usage:    "Directory of the write-ahead log of the consensus messages (disabled if empty)",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffWALMaxSizeFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.wal.maxsize",
+		Usage:    "Size in megabytes after which the consensus message log rotates to a new file",
+		Value:    wal.DefaultMaxSize / 1024 / 1024,
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffWALMaxFilesFlag = &cli.IntFlag{
+		Name:     "hotstuff.wal.maxfiles",
+		Usage:    "Number of consensus message log files kept (0 = keep all)",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffSignerFlag = &cli.StringFlag{
+		Name:     "hotstuff.signer",
+		Usage:    "External signer (url or path to ipc file) holding the validator key, instead of the node key",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffSignerAccountFlag = &cli.StringFlag{
+		Name:     "hotstuff.signer.account",
+		Usage:    "Validator account of the external signer (default = the only account of the signer)",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffAbsentEpochsFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.absent.epochs",
+		Usage:    "Consecutive epochs of absence after which the node votes to remove a validator (0 = no votes)",
+		Category: flags.HotStuffCategory,
+	}
+	HotStuffAbsentVotesFlag = &cli.Uint64Flag{
+		Name:     "hotstuff.absent.votes",
+		Usage:    "Percentage of the blocks of an epoch committed with the seal of a validator up to which it is absent",
+		Category: flags.HotStuffCategory,
+	}
+
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
 		Name:     "unlock",
@@ -1488,6 +1582,16 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	SetDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
 
+	// The ephemeral node key of --dev.bft is the validator of the genesis and the
+	// engine, so it must not change between uses.
+	if ctx.Bool(DeveloperBFTFlag.Name) && cfg.DataDir == "" && cfg.P2P.PrivateKey == nil {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			Fatalf("Failed to generate developer validator key: %v", err)
+		}
+		cfg.P2P.PrivateKey = key
+	}
+
 	if ctx.IsSet(JWTSecretFlag.Name) {
 		cfg.JWTSecret = ctx.String(JWTSecretFlag.Name)
 	}
@@ -1655,6 +1759,90 @@ func setEthash(ctx *cli.Context, cfg *ethconfig.Config) {
 	}
 }
 
+// setHotStuff applies the hotstuff flags on top of any overrides from the config file.
+func setHotStuff(ctx *cli.Context, cfg *ethconfig.Config) {
+	if ctx.IsSet(HotStuffWALFlag.Name) {
+		cfg.HotStuffWAL.Dir = ctx.String(HotStuffWALFlag.Name)
+	}
+	if ctx.IsSet(HotStuffWALMaxSizeFlag.Name) {
+		cfg.HotStuffWAL.MaxSize = ctx.Uint64(HotStuffWALMaxSizeFlag.Name) * 1024 * 1024
+	}
+	if ctx.IsSet(HotStuffWALMaxFilesFlag.Name) {
+		cfg.HotStuffWAL.MaxFiles = ctx.Int(HotStuffWALMaxFilesFlag.Name)
+	}
+	if ctx.IsSet(HotStuffSignerFlag.Name) {
+		cfg.HotStuffSigner.Endpoint = ctx.String(HotStuffSignerFlag.Name)
+	}
+	if ctx.IsSet(HotStuffSignerAccountFlag.Name) {
+		account := ctx.String(HotStuffSignerAccountFlag.Name)
+		if !common.IsHexAddress(account) {
+			Fatalf("Invalid hotstuff signer account %q", account)
+		}
+		cfg.HotStuffSigner.Account = common.HexToAddress(account)
+	}
+	if ctx.IsSet(HotStuffAbsentEpochsFlag.Name) {
+		cfg.HotStuffAbsence.AbsentEpochs = ctx.Uint64(HotStuffAbsentEpochsFlag.Name)
+	}
+	if ctx.IsSet(HotStuffAbsentVotesFlag.Name) {
+		votes := ctx.Uint64(HotStuffAbsentVotesFlag.Name)
+		if votes > 100 {
+			Fatalf("Invalid hotstuff absent votes percentage %d", votes)
+		}
+		cfg.HotStuffAbsence.AbsentVotes = votes
+	}
+	override := MakeHotStuffOverride(ctx)
+	if override == nil {
+		return
+	}
+	if cfg.HotStuff != nil {
+		override = cfg.HotStuff.Override(override)
+	}
+	cfg.HotStuff = override
+}
+
+// MakeHotStuffOverride collects the hotstuff flags set on the command line, or
+// returns nil if none of them were given.
+func MakeHotStuffOverride(ctx *cli.Context) *params.HotStuffConfig {
+	var (
+		override params.HotStuffConfig
+		set      bool
+	)
+	if ctx.IsSet(HotStuffProtocolFlag.Name) {
+		override.Protocol, set = ctx.String(HotStuffProtocolFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffRequestTimeoutFlag.Name) {
+		override.RequestTimeout, set = ctx.Uint64(HotStuffRequestTimeoutFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffMaxTimeoutFlag.Name) {
+		override.MaxTimeout, set = ctx.Uint64(HotStuffMaxTimeoutFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffBlockPeriodFlag.Name) {
+		override.BlockPeriod, set = ctx.Uint64(HotStuffBlockPeriodFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffLeaderPolicyFlag.Name) {
+		override.LeaderPolicy, set = ctx.String(HotStuffLeaderPolicyFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffEpochFlag.Name) {
+		override.Epoch, set = ctx.Uint64(HotStuffEpochFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffSkipEmptyFlag.Name) {
+		override.SkipEmpty, set = ctx.Bool(HotStuffSkipEmptyFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffMaxIdleFlag.Name) {
+		override.MaxIdle, set = ctx.Uint64(HotStuffMaxIdleFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffBuildDeadlineFlag.Name) {
+		override.BuildDeadline, set = ctx.Uint64(HotStuffBuildDeadlineFlag.Name), true
+	}
+	if ctx.IsSet(HotStuffRefreshPayloadFlag.Name) {
+		override.RefreshPayload, set = ctx.Bool(HotStuffRefreshPayloadFlag.Name), true
+	}
+	if !set {
+		return nil
+	}
+	return &override
+}
+
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerNotifyFlag.Name) {
 		cfg.Notify = strings.Split(ctx.String(MinerNotifyFlag.Name), ",")
@@ -1755,6 +1943,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, RopstenFlag, RinkebyFlag, GoerliFlag, SepoliaFlag, KilnFlag)
 	CheckExclusive(ctx, LightServeFlag, SyncModeFlag, "light")
 	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	if ctx.Bool(DeveloperBFTFlag.Name) && !ctx.Bool(DeveloperFlag.Name) {
+		Fatalf("Option %q requires %q", DeveloperBFTFlag.Name, DeveloperFlag.Name)
+	}
 	if ctx.String(GCModeFlag.Name) == "archive" && ctx.Uint64(TxLookupLimitFlag.Name) != 0 {
 		ctx.Set(TxLookupLimitFlag.Name, "0")
 		log.Warn("Disable transaction unindexing for archive node")
@@ -1770,6 +1961,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setGPO(ctx, &cfg.GPO, ctx.String(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
+	setHotStuff(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
@@ -1986,7 +2178,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Info("Using developer account", "address", developer.Address)
 
 		// Create a new developer genesis block or reuse existing one
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.Int(DeveloperPeriodFlag.Name)), ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
+		if ctx.Bool(DeveloperBFTFlag.Name) {
+			validator := crypto.PubkeyToAddress(stack.Config().NodeKey().PublicKey)
+			log.Info("Using developer validator", "address", validator)
+			cfg.Genesis = core.DeveloperBftGenesisBlock(uint64(ctx.Int(DeveloperPeriodFlag.Name)), ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address, validator)
+		} else {
+			cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.Int(DeveloperPeriodFlag.Name)), ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
+		}
 		if ctx.IsSet(DataDirFlag.Name) {
 			// If datadir doesn't exist we need to open db in write-mode
 			// so leveldb can create files.
@@ -2260,15 +2458,21 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		gspec   = MakeGenesis(ctx)
 		chainDb = MakeChainDatabase(ctx, stack, readonly)
 	)
-	cliqueConfig, err := core.LoadCliqueConfig(chainDb, gspec)
+	config, err := core.LoadChainConfig(chainDb, gspec)
 	if err != nil {
 		Fatalf("%v", err)
 	}
+	if override := MakeHotStuffOverride(ctx); override != nil && config.HotStuff != nil {
+		config.HotStuff = config.HotStuff.Override(override)
+	}
 	ethashConfig := ethconfig.Defaults.Ethash
 	if ctx.Bool(FakePoWFlag.Name) {
 		ethashConfig.PowMode = ethash.ModeFake
 	}
-	engine := ethconfig.CreateConsensusEngine(stack, &ethashConfig, cliqueConfig, nil, false, chainDb)
+	engine, err := ethconfig.CreateConsensusEngine(stack, config, &ethashConfig, nil, nil, nil, false, chainDb)
+	if err != nil {
+		Fatalf("Can't create consensus engine: %v", err)
+	}
 	if gcmode := ctx.String(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
@@ -18,8 +18,12 @@
 package utils
 
 import (
+	"flag"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/urfave/cli/v2"
 )
 
 func Test_SplitTagsFlag(t *testing.T) {
@@ -62,3 +66,32 @@ func Test_SplitTagsFlag(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeHotStuffOverride(t *testing.T) {
+	hotstuffFlags := []cli.Flag{HotStuffProtocolFlag, HotStuffRequestTimeoutFlag, HotStuffBlockPeriodFlag, HotStuffLeaderPolicyFlag, HotStuffEpochFlag}
+	tests := []struct {
+		args []string
+		want *params.HotStuffConfig
+	}{
+		{args: nil, want: nil},
+		{args: []string{"--hotstuff.requesttimeout", "8000"}, want: &params.HotStuffConfig{RequestTimeout: 8000}},
+		{
+			args: []string{"--hotstuff.protocol", "event_driven", "--hotstuff.blockperiod", "2000", "--hotstuff.policy", "vrf", "--hotstuff.epoch", "100"},
+			want: &params.HotStuffConfig{Protocol: "event_driven", BlockPeriod: 2000, LeaderPolicy: "vrf", Epoch: 100},
+		},
+	}
+	for i, tt := range tests {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		for _, f := range hotstuffFlags {
+			if err := f.Apply(set); err != nil {
+				t.Fatalf("failed to apply flag %v: %v", f.Names(), err)
+			}
+		}
+		if err := set.Parse(tt.args); err != nil {
+			t.Fatalf("test %d: failed to parse flags: %v", i, err)
+		}
+		if have := MakeHotStuffOverride(cli.NewContext(nil, set, nil)); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: override mismatch: have %+v, want %+v", i, have, tt.want)
+		}
+	}
+}
@@ -39,6 +39,18 @@ type Backend interface {
 	// The delivered proposal will be put into blockchain.
 	Commit(proposal Proposal) error
 
+	// Certify stores a proposal certified by a quorum cert beside the chain, the
+	// next proposals extend it. The event driven protocol commits it once decided.
+	Certify(proposal Proposal) error
+
+	// FetchProposal requests the certified proposal and it's ancestors from the
+	// validator, they are certified once received
+	FetchProposal(hash common.Hash, from common.Address)
+
+	// Decide marks a committed proposal and it's ancestors as final, they can't be
+	// reverted anymore. Proposals of the basic protocol are final once committed.
+	Decide(proposal Proposal) error
+
 	// Verify verifies the proposal. If a consensus.ErrFutureBlock error is returned,
 	// the time difference of the proposal and current time is also returned.
 	Verify(Proposal) (time.Duration, error)
@@ -50,12 +62,25 @@ type Backend interface {
 	// LastProposal retrieves latest committed proposal and the address of proposer
 	LastProposal() (Proposal, common.Address)
 
+	// GetProposal retrieves a committed proposal by hash
+	GetProposal(hash common.Hash) Proposal
+
 	// HasBadBlock returns whether the block with the hash is a bad block
 	HasBadProposal(hash common.Hash) bool
 
 	// ValidateBlock execute block which contained in prepare message, and validate block state
 	ValidateBlock(block *types.Block) error
 
+	// ReadRoundState returns the voting state persisted by WriteRoundState
+	ReadRoundState() []byte
+
+	// WriteRoundState persists the voting state of the validator, it's written
+	// before every vote and reloaded when the core restarts
+	WriteRoundState(state []byte) error
+
+	// HandleEvidence persists and gossips the evidence of an equivocating validator
+	HandleEvidence(ev *Evidence) error
+
 	Close() error
 }
 
@@ -74,6 +99,10 @@ type CoreEngine interface {
 	// pending request is populated right at the request stage so this would give us the earliest verification
 	// to avoid any race condition of coming propagated blocks
 	IsCurrentProposal(blockHash common.Hash) bool
+
+	// RoundState returns a snapshot of the consensus state, or nil if the engine is
+	// not running
+	RoundState() *RoundState
 }
 
 type BftProtocol string
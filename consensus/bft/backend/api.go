@@ -2,14 +2,73 @@ package backend
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // API is a user facing RPC API to allow controlling the address and voting
 // mechanisms of the HotStuff scheme.
 type API struct {
-	chain    consensus.ChainHeaderReader
-	bft *backend
+	chain consensus.ChainHeaderReader
+	bft   *backend
+}
+
+// ValidatorPower is the voting power of a validator.
+type ValidatorPower struct {
+	Address common.Address `json:"address"`
+	Power   uint64         `json:"power"`
+}
+
+// Validators is the validator set which verifies a block.
+type Validators struct {
+	Number     uint64            `json:"number"`
+	Validators []*ValidatorPower `json:"validators"`
+	TotalPower uint64            `json:"totalPower"`
+	Quorum     uint64            `json:"quorum"`
+}
+
+// GetValidators retrieves the validators and their voting power at the given block,
+// or at the current head if the block number is not given.
+func (api *API) GetValidators(number *rpc.BlockNumber) (*Validators, error) {
+	height, err := blockHeight(api.chain, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.bft.rpcValidators(height), nil
+}
+
+// blockHeight resolves the block number of an rpc request, the current head is used
+// if the number is not given.
+func blockHeight(chain consensus.ChainHeaderReader, number *rpc.BlockNumber) (uint64, error) {
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header := chain.CurrentHeader()
+		if header == nil {
+			return 0, errUnknownBlock
+		}
+		return header.Number.Uint64(), nil
+	}
+	if *number < 0 {
+		return 0, errUnknownBlock
+	}
+	return uint64(number.Int64()), nil
+}
+
+// rpcValidators returns the validators and their voting power at the given height.
+func (s *backend) rpcValidators(height uint64) *Validators {
+	valSet := s.Validators(height)
+	validators := &Validators{
+		Number:     height,
+		Validators: make([]*ValidatorPower, 0, valSet.Size()),
+		TotalPower: valSet.TotalPower(),
+		Quorum:     valSet.Q(),
+	}
+	for _, val := range valSet.List() {
+		validators.Validators = append(validators.Validators, &ValidatorPower{Address: val.Address(), Power: val.Power()})
+	}
+	return validators
 }
 
 // Proposals returns the current proposals the node tries to uphold and vote on.
@@ -24,14 +83,32 @@ func (api *API) Proposals() map[common.Address]bool {
 	return proposals
 }
 
-// todo: add/del candidate validators approach console or api
 // Propose injects a new authorization candidate that the validator will attempt to
-// push through.
-func (api *API) Propose(address common.Address, auth bool) {
+// push through. The vote is carried in the headers proposed by this node and the
+// validator set changes at the epoch boundary once validators with more than half
+// of the voting power agree. Chains with bls signatures require the bls key
+// registration of authorized candidates, as returned by their BLSKey.
+func (api *API) Propose(address common.Address, auth bool, blsKey *hexutil.Bytes) error {
+	if api.bft.hasValidatorContract() {
+		return errVotesDisabled
+	}
+	if blsKey != nil {
+		if !auth {
+			return errInvalidVote
+		}
+		if _, err := snr.ParseBLSKey(*blsKey); err != nil {
+			return err
+		}
+	}
 	api.bft.sigMu.Lock()
 	defer api.bft.sigMu.Unlock()
 
 	api.bft.proposals[address] = auth
+	delete(api.bft.proposalKeys, address)
+	if blsKey != nil {
+		api.bft.proposalKeys[address] = *blsKey
+	}
+	return nil
 }
 
 // Discard drops a currently running candidate, stopping the validator from casting
@@ -41,4 +118,170 @@ func (api *API) Discard(address common.Address) {
 	defer api.bft.sigMu.Unlock()
 
 	delete(api.bft.proposals, address)
+	delete(api.bft.proposalKeys, address)
+}
+
+// BLSKey returns the bls public key and proof of possession of the node, which is
+// registered in the genesis or proposed with the authorization of the node.
+func (api *API) BLSKey() (hexutil.Bytes, error) {
+	signer, ok := api.bft.signer.(*snr.BLSSigner)
+	if !ok {
+		return nil, errBLSDisabled
+	}
+	return signer.Registration(), nil
+}
+
+// ConsensusAPI exposes the state of the consensus engine and the validators of the
+// chain for introspection.
+type ConsensusAPI struct {
+	chain consensus.ChainHeaderReader
+	bft   *backend
+}
+
+// RPCView is the height and round of a consensus view.
+type RPCView struct {
+	Height uint64 `json:"height"`
+	Round  uint64 `json:"round"`
+}
+
+// RPCQuorumCert is a quorum cert of the consensus state, without the seals.
+type RPCQuorumCert struct {
+	View     *RPCView       `json:"view"`
+	Hash     common.Hash    `json:"hash"`
+	Proposer common.Address `json:"proposer"`
+}
+
+// RPCRoundState is the consensus state of the node in the current view.
+type RPCRoundState struct {
+	View     *RPCView                    `json:"view"`
+	State    string                      `json:"state"`
+	Proposer common.Address              `json:"proposer"`
+	Proposal *common.Hash                `json:"proposal"`
+	LockedQC *RPCQuorumCert              `json:"lockedQC"`
+	HighQC   *RPCQuorumCert              `json:"highQC"`
+	Backlogs map[common.Address]int      `json:"backlogs"`
+	LastSeen map[common.Address]*RPCView `json:"lastSeen"`
+}
+
+// RPCEpoch is an epoch of the validator set.
+type RPCEpoch struct {
+	StartHeight          uint64                           `json:"startHeight"`
+	LastEpochStartHeight uint64                           `json:"lastEpochStartHeight"`
+	Validators           []*ValidatorPower                `json:"validators"`
+	BLSKeys              map[common.Address]hexutil.Bytes `json:"blsKeys,omitempty"`
+}
+
+// Signers are the proposer and the committers of a block.
+type Signers struct {
+	Number     uint64           `json:"number"`
+	Hash       common.Hash      `json:"hash"`
+	Proposer   common.Address   `json:"proposer"`
+	Committers []common.Address `json:"committers"`
+}
+
+func rpcView(view *bft.View) *RPCView {
+	if view == nil {
+		return nil
+	}
+	return &RPCView{Height: view.Height.Uint64(), Round: view.Round.Uint64()}
+}
+
+func rpcQuorumCert(qc *bft.QuorumCert) *RPCQuorumCert {
+	if qc == nil {
+		return nil
+	}
+	return &RPCQuorumCert{View: rpcView(qc.View), Hash: qc.Hash, Proposer: qc.Proposer}
+}
+
+// GetRoundState returns the consensus state of the node, the engine must be started.
+func (api *ConsensusAPI) GetRoundState() (*RPCRoundState, error) {
+	api.bft.coreMu.RLock()
+	started := api.bft.coreStarted
+	api.bft.coreMu.RUnlock()
+
+	if !started {
+		return nil, ErrStoppedEngine
+	}
+	rs := api.bft.core.RoundState()
+	if rs == nil {
+		return nil, ErrStoppedEngine
+	}
+	state := &RPCRoundState{
+		View:     rpcView(rs.View),
+		State:    rs.State,
+		Proposer: rs.Proposer,
+		LockedQC: rpcQuorumCert(rs.LockedQC),
+		HighQC:   rpcQuorumCert(rs.HighQC),
+		Backlogs: rs.Backlogs,
+		LastSeen: make(map[common.Address]*RPCView, len(rs.LastSeen)),
+	}
+	if rs.Proposal != (common.Hash{}) {
+		state.Proposal = &rs.Proposal
+	}
+	for addr, view := range rs.LastSeen {
+		state.LastSeen[addr] = rpcView(view)
+	}
+	return state, nil
+}
+
+// GetValidators retrieves the validators and their voting power at the given block,
+// or at the current head if the block number is not given.
+func (api *ConsensusAPI) GetValidators(number *rpc.BlockNumber) (*Validators, error) {
+	height, err := blockHeight(api.chain, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.bft.rpcValidators(height), nil
+}
+
+// GetEpochs returns the epochs of the validator set, in ascending order of their
+// start heights.
+func (api *ConsensusAPI) GetEpochs() ([]*RPCEpoch, error) {
+	api.bft.epochMu.RLock()
+	defer api.bft.epochMu.RUnlock()
+
+	epochs := make([]*RPCEpoch, 0, len(api.bft.epochHeights))
+	for i := range api.bft.epochHeights {
+		epoch, err := api.bft.readEpoch(i)
+		if err != nil {
+			return nil, err
+		}
+		enc := &RPCEpoch{
+			StartHeight:          epoch.StartHeight,
+			LastEpochStartHeight: epoch.LastEpochStartHeight,
+			Validators:           make([]*ValidatorPower, 0, epoch.ValSet.Size()),
+		}
+		for _, val := range epoch.ValSet.List() {
+			enc.Validators = append(enc.Validators, &ValidatorPower{Address: val.Address(), Power: val.Power()})
+		}
+		if len(epoch.BLSKeys) > 0 {
+			enc.BLSKeys = make(map[common.Address]hexutil.Bytes, len(epoch.BLSKeys))
+			for addr, key := range epoch.BLSKeys {
+				enc.BLSKeys[addr] = key
+			}
+		}
+		epochs = append(epochs, enc)
+	}
+	return epochs, nil
+}
+
+// GetSigners returns the proposer and the committers of the given block, or of the
+// current head if the block number is not given.
+func (api *ConsensusAPI) GetSigners(number *rpc.BlockNumber) (*Signers, error) {
+	height, err := blockHeight(api.chain, number)
+	if err != nil {
+		return nil, err
+	}
+	header := api.chain.GetHeaderByNumber(height)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	signers := &Signers{Number: height, Hash: header.Hash()}
+	if height == 0 {
+		return signers, nil
+	}
+	if signers.Proposer, signers.Committers, err = api.bft.Signers(header); err != nil {
+		return nil, err
+	}
+	return signers, nil
 }
@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the consensus api reports the round state, epochs and block signers
+// of a running network, with ecdsa and bls committed seals, and that the engine
+// reports it's consensus health.
+func TestConsensusAPI(t *testing.T) {
+	t.Run("ecdsa", func(t *testing.T) { testConsensusAPI(t, testNetworkConfig()) })
+	t.Run("bls", func(t *testing.T) {
+		config := testNetworkConfig()
+		config.Signature = bft.BLSSignature
+		testConsensusAPI(t, config)
+	})
+}
+
+func testConsensusAPI(t *testing.T, config bft.Config) {
+	net := newTestNetwork(t, 4, config)
+	net.start()
+	net.waitHeight(3, 30*time.Second)
+
+	node := net.nodes[0]
+	api := &ConsensusAPI{chain: node.chain, bft: node.engine()}
+
+	state, err := api.GetRoundState()
+	if err != nil {
+		t.Fatalf("failed to get round state: %v", err)
+	}
+	if state.View.Height < 3 {
+		t.Errorf("round state height mismatch: have %d, want >= 3", state.View.Height)
+	}
+	validators := make(map[string]bool)
+	for _, n := range net.nodes {
+		validators[n.addr.Hex()] = true
+	}
+	if !validators[state.Proposer.Hex()] {
+		t.Errorf("round state proposer %x is not a validator", state.Proposer)
+	}
+	health := api.bft.Health()
+	if health == nil {
+		t.Fatalf("no consensus health of a running engine")
+	}
+	if health.Rounds < 3 || health.ProposerRounds > health.Rounds || len(health.PhaseTimes) == 0 {
+		t.Errorf("consensus health mismatch: have %+v", health)
+	}
+
+	epochs, err := api.GetEpochs()
+	if err != nil {
+		t.Fatalf("failed to get epochs: %v", err)
+	}
+	if len(epochs) != 1 || epochs[0].StartHeight != 0 || len(epochs[0].Validators) != 4 {
+		t.Fatalf("epochs mismatch: have %+v", epochs)
+	}
+	if (config.Signature == bft.BLSSignature) != (len(epochs[0].BLSKeys) == 4) {
+		t.Errorf("epoch bls keys mismatch: have %d", len(epochs[0].BLSKeys))
+	}
+
+	number := rpc.BlockNumber(2)
+	signers, err := api.GetSigners(&number)
+	if err != nil {
+		t.Fatalf("failed to get signers: %v", err)
+	}
+	if signers.Hash != node.chain.GetHeaderByNumber(2).Hash() {
+		t.Errorf("signers hash mismatch: have %x", signers.Hash)
+	}
+	if !validators[signers.Proposer.Hex()] {
+		t.Errorf("proposer %x is not a validator", signers.Proposer)
+	}
+	if uint64(len(signers.Committers)) < api.bft.Validators(2).Q() {
+		t.Errorf("committers below quorum: have %d", len(signers.Committers))
+	}
+	for _, committer := range signers.Committers {
+		if !validators[committer.Hex()] {
+			t.Errorf("committer %x is not a validator", committer)
+		}
+	}
+}
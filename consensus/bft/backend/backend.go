@@ -12,6 +12,8 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/bft/core"
 	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/consensus/bft/wal"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -41,13 +43,18 @@ type backend struct {
 	recentMessages *lru.ARCCache // the cache of peer's messages
 	knownMessages  *lru.ARCCache // the cache of self messages
 
-	epochs              map[uint64]*Epoch // map epoch start height to epochs
-	maxEpochStartHeight uint64
+	epochMu       sync.RWMutex   // Protects the epoch fields
+	epochHeights  []uint64       // Start heights of the stored epochs in ascending order
+	epochCache    *lru.ARCCache  // Recently used epochs by start height
+	epochFreezer  *rawdb.Freezer // Ancient store of the finalized epochs, nil without ancients
+	frozenEpochs  uint64         // Number of epochs moved to the freezer
+	pendingEpochs []*Epoch       // Epochs of the validator contract awaiting the state of their boundary, ascending
 
 	// The channels for bft engine notifications
 	sealMu            sync.Mutex
 	commitCh          chan *types.Block
 	proposedBlockHash common.Hash
+	proposedMu        sync.RWMutex // Protects the proposed block hash, it's read by the core
 	coreStarted       bool
 	sigMu             sync.RWMutex // Protects the address fields
 	consenMu          sync.Mutex   // Ensure a round can only start after the last one has finished
@@ -58,34 +65,99 @@ type backend struct {
 
 	eventMux *event.TypeMux
 
-	proposals map[common.Address]bool // Current list of proposals we are pushing
+	proposals    map[common.Address]bool   // Current list of proposals we are pushing
+	proposalKeys map[common.Address][]byte // bls key registrations of the authorized candidates
+
+	evidenceMu sync.Mutex // Serializes the handling of equivocation evidence
+
+	decidedMu sync.RWMutex
+	decided   *types.Header // Highest block decided by the event driven protocol, persisted across restarts
+
+	certifiedMu   sync.RWMutex
+	certified     *types.Block  // Highest block certified by the event driven protocol, the proposals extend it
+	certifiedFeed event.Feed    // Blocks certified by the event driven protocol
+	fetches       *lru.ARCCache // Recently fetched certified proposals
+
+	wal *wal.WAL // Write-ahead log of the consensus messages, nil if disabled
+
+	missedVotes uint64 // Committed blocks without the seal of the validator, accessed atomically
+
+	headMu     sync.RWMutex
+	headNumber uint64    // Number of the latest head notified by the chain
+	headTime   time.Time // Time at which the latest head was notified, the start of the next height
 }
 
 func New(config *bft.Config, privateKey *ecdsa.PrivateKey, db ethdb.Database) consensus.BFT {
+	backend := newBackend(config, db)
+	return backend.setup(newSigner(config, privateKey, backend))
+}
+
+// NewWithSigner creates the engine with a signer holding the validator key outside
+// of the node, e.g. an external signer. The signer only signs ecdsa seals without
+// vrf proofs.
+func NewWithSigner(config *bft.Config, signer bft.Signer, db ethdb.Database) consensus.BFT {
+	return newBackend(config, db).setup(signer)
+}
+
+func newBackend(config *bft.Config, db ethdb.Database) *backend {
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	recentMessages, _ := lru.NewARC(inmemoryPeers)
 	knownMessages, _ := lru.NewARC(inmemoryMessages)
+	epochCache, _ := lru.NewARC(inmemoryEpochs)
+	fetches, _ := lru.NewARC(inmemoryFetches)
 
-	signer := snr.NewSigner(privateKey)
-	backend := &backend{
+	return &backend{
 		config:         config,
 		db:             db,
 		logger:         log.New(),
 		commitCh:       make(chan *types.Block, 1),
 		coreStarted:    false,
 		eventMux:       new(event.TypeMux),
-		signer:         signer,
 		recentMessages: recentMessages,
 		knownMessages:  knownMessages,
 		recents:        recents,
+		epochCache:     epochCache,
+		fetches:        fetches,
 		proposals:      make(map[common.Address]bool),
+		proposalKeys:   make(map[common.Address][]byte),
 	}
+}
 
-	backend.core = core.New(backend, config, signer)
-	if err := backend.LoadEpoch(); err != nil {
+// setup creates the consensus core of the backend with the signer.
+func (s *backend) setup(signer bft.Signer) *backend {
+	s.signer = signer
+	if s.config.Protocol == bft.BFT_PROTOCOL_EVENT_DRIVEN {
+		s.core = core.NewChained(s, s.config, signer)
+	} else {
+		s.core = core.New(s, s.config, signer)
+	}
+	if err := s.LoadEpoch(); err != nil {
 		panic(fmt.Sprintf("load epoch failed, err: %v", err))
 	}
-	return backend
+	s.decided = s.loadDecided()
+	if s.config.WAL.Dir != "" {
+		w, err := wal.Open(s.config.WAL.Dir, s.config.WAL.MaxSize, s.config.WAL.MaxFiles)
+		if err != nil {
+			log.Error("Failed to open consensus wal", "dir", s.config.WAL.Dir, "err", err)
+		} else {
+			log.Info("Logging consensus messages", "dir", s.config.WAL.Dir)
+			s.wal = w
+		}
+	}
+	return s
+}
+
+// newSigner creates the signer of the configured committed seal scheme, the bls key
+// of the node is derived from it's node key.
+func newSigner(config *bft.Config, privateKey *ecdsa.PrivateKey, reader bft.BLSKeyReader) bft.Signer {
+	if config.Signature != bft.BLSSignature {
+		return snr.NewSigner(privateKey)
+	}
+	blsKey, err := snr.DeriveBLSKey(privateKey)
+	if err != nil {
+		panic(fmt.Sprintf("derive bls key failed, err: %v", err))
+	}
+	return snr.NewBLSSigner(privateKey, blsKey, reader)
 }
 
 // Address implements bft.Backend.Address
@@ -112,8 +184,26 @@ func (s *backend) Broadcast(valSet bft.ValidatorSet, payload []byte) error {
 	return nil
 }
 
+// logMessage appends a consensus message sent or received by the node to the wal,
+// if it's enabled.
+func (s *backend) logMessage(dir wal.Direction, peer common.Address, payload []byte) {
+	if s.wal == nil {
+		return
+	}
+	entry := &wal.Entry{
+		Time:    uint64(time.Now().UnixNano()),
+		Dir:     dir,
+		Peer:    peer,
+		Payload: payload,
+	}
+	if err := s.wal.Write(entry); err != nil {
+		s.logger.Warn("Failed to log consensus message", "err", err)
+	}
+}
+
 // Broadcast implements bft.Backend.Gossip
 func (s *backend) Gossip(valSet bft.ValidatorSet, payload []byte) error {
+	s.logMessage(wal.Outbound, common.Address{}, payload)
 	hash := bft.RLPHash(payload)
 	s.knownMessages.Add(hash, true)
 
@@ -148,6 +238,8 @@ func (s *backend) Gossip(valSet bft.ValidatorSet, payload []byte) error {
 
 // Unicast implements bft.Backend.Unicast
 func (s *backend) Unicast(valSet bft.ValidatorSet, payload []byte) error {
+	s.logMessage(wal.Outbound, common.Address{}, payload)
+
 	msg := bft.MessageEvent{Payload: payload}
 	leader := valSet.GetProposer()
 	target := leader.Address()
@@ -228,15 +320,24 @@ func (s *backend) Commit(proposal bft.Proposal) error {
 		s.logger.Error("Committed to miner worker", "proposal", "not block")
 		return errInvalidProposal
 	}
-	
+	eventDriven := s.config.Protocol == bft.BFT_PROTOCOL_EVENT_DRIVEN
+	if eventDriven && block.NumberU64() <= s.currentBlock().NumberU64() {
+		return nil
+	}
 	s.logger.Info("Committed", "address", s.Address(), "hash", proposal.Hash(), "number", proposal.Number().Uint64())
+	// the decided blocks of the event driven protocol and their ancestors were
+	// stored once certified, the chain is moved to them
+	if eventDriven {
+		_, err := s.chain.SetCanonical(block)
+		return err
+	}
 	// - if the proposed and committed blocks are the same, send the proposed hash
 	//   to commit channel, which is being watched inside the engine.Seal() function.
 	// - otherwise, we try to insert the block.
 	// -- if success, the ChainHeadEvent event will be broadcasted, try to build
 	//    the next block and the previous Seal() will be stopped.
 	// -- otherwise, a error will be returned and a round change event will be fired.
-	if s.proposedBlockHash == block.Hash() {
+	if s.proposedHash() == block.Hash() {
 		// feed block hash to Seal() and wait the Seal() result
 		s.commitCh <- block
 		return nil
@@ -248,6 +349,84 @@ func (s *backend) Commit(proposal bft.Proposal) error {
 	return nil
 }
 
+// Decide implements bft.Backend.Decide
+func (s *backend) Decide(proposal bft.Proposal) error {
+	block, ok := proposal.(*types.Block)
+	if !ok {
+		return errInvalidProposal
+	}
+	s.decidedMu.Lock()
+	defer s.decidedMu.Unlock()
+
+	if s.decided != nil && s.decided.Number.Cmp(block.Number()) >= 0 {
+		return nil
+	}
+	rawdb.WriteBftDecidedHash(s.db, block.Hash())
+	s.decided = block.Header()
+	s.logger.Info("Decided", "address", s.Address(), "hash", block.Hash(), "number", block.NumberU64())
+	return nil
+}
+
+// loadDecided returns the highest block decided before the restart, nil if the
+// node didn't decide any block or it's header is gone.
+func (s *backend) loadDecided() *types.Header {
+	hash := rawdb.ReadBftDecidedHash(s.db)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	number := rawdb.ReadHeaderNumber(s.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadHeader(s.db, hash, *number)
+}
+
+// Decided returns the highest final block, blocks of the basic protocol are final
+// once committed.
+func (s *backend) Decided() *types.Header {
+	if s.config.Protocol != bft.BFT_PROTOCOL_EVENT_DRIVEN {
+		if s.currentBlock == nil {
+			return nil
+		}
+		return s.currentBlock().Header()
+	}
+	s.decidedMu.RLock()
+	defer s.decidedMu.RUnlock()
+	return s.decided
+}
+
+// Finalized implements consensus.Finality. The blocks of the basic protocol are
+// imported with a committed seal quorum, so the head itself is final. In the
+// event driven protocol only the validators know which blocks were decided by a
+// three-chain, the followers return nil. The headers don't carry the rounds of
+// the blocks, so a follower can't tell a three-chain of consecutive rounds from
+// the quorum certs of its chain, and a byzantine peer may feed it a certified
+// block which is never decided.
+func (s *backend) Finalized(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	if s.config.Protocol != bft.BFT_PROTOCOL_EVENT_DRIVEN {
+		return head
+	}
+	decided := s.Decided()
+	if decided == nil || decided.Number.Cmp(head.Number) > 0 {
+		return nil
+	}
+	return decided
+}
+
+// proposedHash returns the hash of the block being sealed.
+func (s *backend) proposedHash() common.Hash {
+	s.proposedMu.RLock()
+	defer s.proposedMu.RUnlock()
+	return s.proposedBlockHash
+}
+
+// setProposedHash sets the hash of the block being sealed.
+func (s *backend) setProposedHash(hash common.Hash) {
+	s.proposedMu.Lock()
+	defer s.proposedMu.Unlock()
+	s.proposedBlockHash = hash
+}
+
 // Verify implements bft.Backend.Verify
 func (s *backend) Verify(proposal bft.Proposal) (time.Duration, error) {
 	// Check if the proposal is a valid block
@@ -276,7 +455,7 @@ func (s *backend) Verify(proposal bft.Proposal) (time.Duration, error) {
 	// verify the header of proposed block
 	err := s.VerifyHeader(s.chain, block.Header(), false)
 	if err == nil {
-		return 0, nil
+		return 0, s.verifyContractProposal(block.Header())
 	} else if err == consensus.ErrFutureBlock {
 		return time.Unix(int64(block.Header().Time), 0).Sub(now()), consensus.ErrFutureBlock
 	}
@@ -309,7 +488,7 @@ func (s *backend) VerifyUnsealedProposal(proposal bft.Proposal) (time.Duration,
 
 	// verify the header of proposed block
 	if err := s.VerifyHeader(s.chain, block.Header(), false); err == nil {
-		return 0, nil
+		return 0, s.verifyContractProposal(block.Header())
 	} else if err == consensus.ErrFutureBlock {
 		return time.Unix(int64(block.Header().Time), 0).Sub(now()), consensus.ErrFutureBlock
 	} else {
@@ -323,6 +502,9 @@ func (s *backend) LastProposal() (bft.Proposal, common.Address) {
 	}
 
 	block := s.currentBlock()
+	if certified := s.CertifiedHead(); certified != nil {
+		block = certified
+	}
 	var proposer common.Address
 	if block.Number().Cmp(common.Big0) > 0 {
 		var err error
@@ -361,3 +543,13 @@ func (s *backend) HasBadProposal(hash common.Hash) bool {
 	}
 	return s.hasBadBlock(hash)
 }
+
+// ReadRoundState implements bft.Backend.ReadRoundState
+func (s *backend) ReadRoundState() []byte {
+	return rawdb.ReadBftRoundState(s.db)
+}
+
+// WriteRoundState implements bft.Backend.WriteRoundState
+func (s *backend) WriteRoundState(state []byte) error {
+	return rawdb.WriteBftRoundState(s.db, state)
+}
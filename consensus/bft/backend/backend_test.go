@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the highest decided block survives a restart of the engine, and that
+// lower decisions don't replace it.
+func TestDecidedPersisted(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	db := rawdb.NewMemoryDatabase()
+	config := testChainedConfig()
+
+	var headers []*types.Header
+	for i := int64(1); i <= 2; i++ {
+		header := &types.Header{Number: big.NewInt(i), MixDigest: types.BftDigest}
+		rawdb.WriteHeader(db, header)
+		headers = append(headers, header)
+	}
+	engine := New(&config, key, db).(*backend)
+	if decided := engine.Decided(); decided != nil {
+		t.Fatalf("decided block on a fresh database: %d", decided.Number)
+	}
+	if err := engine.Decide(types.NewBlockWithHeader(headers[1])); err != nil {
+		t.Fatalf("failed to decide block: %v", err)
+	}
+	if err := engine.Decide(types.NewBlockWithHeader(headers[0])); err != nil {
+		t.Fatalf("failed to decide block: %v", err)
+	}
+	engine.Close()
+
+	engine = New(&config, key, db).(*backend)
+	defer engine.Close()
+	if decided := engine.Decided(); decided == nil || decided.Hash() != headers[1].Hash() {
+		t.Fatalf("decided block mismatch after restart: have %v, want %x", decided, headers[1].Hash())
+	}
+}
+
+// Tests that the timestamps of consecutive blocks move forward by the block period
+// in seconds, even if the event driven period is below a second.
+func TestTimestampPeriod(t *testing.T) {
+	tests := []struct {
+		protocol bft.BftProtocol
+		period   uint64
+		want     uint64
+	}{
+		{bft.BFT_PROTOCOL_BASIC, 0, 0},
+		{bft.BFT_PROTOCOL_BASIC, 3, 3},
+		{bft.BFT_PROTOCOL_EVENT_DRIVEN, 0, 0},
+		{bft.BFT_PROTOCOL_EVENT_DRIVEN, 500, 1},
+		{bft.BFT_PROTOCOL_EVENT_DRIVEN, 1500, 1},
+		{bft.BFT_PROTOCOL_EVENT_DRIVEN, 2000, 2},
+	}
+	for _, tt := range tests {
+		config := &bft.Config{Protocol: tt.protocol, BlockPeriod: tt.period}
+		if have := config.TimestampPeriod(); have != tt.want {
+			t.Errorf("%s period %d: timestamp period mismatch: have %d, want %d", tt.protocol, tt.period, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package backend
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	inmemoryFetches    = 128 // Number of recently fetched proposals, they are not requested again
+	maxCertifiedBlocks = 32  // Maximum number of certified blocks sent for a fetch
+)
+
+// Certify implements bft.Backend.Certify, the block is executed and stored beside
+// the chain. The highest certified block is persisted, the proposals of the node
+// extend it.
+func (s *backend) Certify(proposal bft.Proposal) error {
+	block, ok := proposal.(*types.Block)
+	if !ok {
+		return errInvalidProposal
+	}
+	if s.chain.GetBlockByHash(block.Hash()) == nil {
+		if err := s.chain.InsertBlockWithoutSetHead(block); err != nil {
+			return err
+		}
+	} else if block.NumberU64() <= s.currentBlock().NumberU64() {
+		return nil
+	}
+	s.certifiedMu.Lock()
+	if s.certified != nil && (s.certified.NumberU64() > block.NumberU64() || s.certified.Hash() == block.Hash()) {
+		s.certifiedMu.Unlock()
+		return nil
+	}
+	rawdb.WriteBftCertifiedHash(s.db, block.Hash())
+	s.certified = block
+	s.certifiedMu.Unlock()
+
+	s.logger.Info("Certified", "address", s.Address(), "hash", block.Hash(), "number", block.NumberU64())
+	s.certifiedFeed.Send(block)
+	go s.eventMux.Post(bft.CertifiedEvent{Header: block.Header()})
+	return nil
+}
+
+// CertifiedHead implements consensus.Pipeliner, it returns the highest certified
+// block if it's above the chain head.
+func (s *backend) CertifiedHead() *types.Block {
+	s.certifiedMu.RLock()
+	defer s.certifiedMu.RUnlock()
+
+	if s.certified == nil || s.currentBlock == nil || s.certified.NumberU64() <= s.currentBlock().NumberU64() {
+		return nil
+	}
+	return s.certified
+}
+
+// SubscribeCertifiedHead implements consensus.Pipeliner.
+func (s *backend) SubscribeCertifiedHead(ch chan<- *types.Block) event.Subscription {
+	return s.certifiedFeed.Subscribe(ch)
+}
+
+// loadCertified returns the highest block certified before the restart, nil if
+// the node didn't certify any block or it's gone. The state of the block may be
+// gone with the restart, it's executed again.
+func (s *backend) loadCertified(chain consensus.ChainReader) *types.Block {
+	hash := rawdb.ReadBftCertifiedHash(s.db)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	block := chain.GetBlockByHash(hash)
+	if block == nil {
+		return nil
+	}
+	if err := chain.InsertBlockWithoutSetHead(block); err != nil {
+		s.logger.Warn("Failed to recover certified block", "number", block.NumberU64(), "hash", hash, "err", err)
+		return nil
+	}
+	return block
+}
+
+// FetchProposal implements bft.Backend.FetchProposal, proposals are requested
+// once, the later proposals of the validators refer to the next ones anyway.
+func (s *backend) FetchProposal(hash common.Hash, from common.Address) {
+	if s.broadcaster == nil || s.fetches.Contains(hash) {
+		return
+	}
+	p := s.broadcaster.FindPeer(from)
+	if p == nil {
+		return
+	}
+	s.fetches.Add(hash, true)
+	s.logger.Debug("Fetching certified proposal", "hash", hash, "from", from)
+	go p.Send(bftGetCertifiedMsg, hash)
+}
+
+// handleGetCertifiedMsg sends the requested block to the peer, with it's ancestors
+// which the peer may miss as well, oldest first.
+func (s *backend) handleGetCertifiedMsg(addr common.Address, msg p2p.Msg) error {
+	var hash common.Hash
+	if err := msg.Decode(&hash); err != nil {
+		return errDecodeFailed
+	}
+	if s.broadcaster == nil || s.chain == nil {
+		return nil
+	}
+	var blocks []*types.Block
+	for block := s.chain.GetBlockByHash(hash); block != nil && block.NumberU64() > 0 && len(blocks) < maxCertifiedBlocks; {
+		blocks = append(blocks, block)
+		block = s.chain.GetBlockByHash(block.ParentHash())
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	if p := s.broadcaster.FindPeer(addr); p != nil {
+		go p.Send(bftCertifiedMsg, blocks)
+	}
+	return nil
+}
+
+// handleCertifiedMsg certifies the fetched blocks, their quorum certs are verified
+// with the headers. The blocks above a missing ancestor are dropped, the missing
+// ones are decided meanwhile and synced with the chain.
+func (s *backend) handleCertifiedMsg(addr common.Address, msg p2p.Msg) error {
+	var blocks []*types.Block
+	if err := msg.Decode(&blocks); err != nil {
+		return errDecodeFailed
+	}
+	if s.chain == nil {
+		return nil
+	}
+	go func() {
+		for _, block := range blocks {
+			if err := s.Certify(block); err != nil {
+				s.logger.Debug("Failed to certify fetched proposal", "number", block.NumberU64(), "hash", block.Hash(), "from", addr, "err", err)
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,221 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// validatorContractABI is the interface of the contract managing the validators,
+// it returns the validators of the next epoch and optionally their powers, an empty
+// power list keeps the epoch unweighted.
+const validatorContractABI = `[{"inputs":[],"name":"getValidators","outputs":[{"name":"validators","type":"address[]"},{"name":"powers","type":"uint256[]"}],"stateMutability":"view","type":"function"}]`
+
+// validatorContractGas is the gas allowance of the read-only validator call.
+const validatorContractGas = 50_000_000
+
+var validatorContract = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(validatorContractABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// stateReader is implemented by the chains which give access to the state of their
+// blocks, the proposer reads the validator contract through it.
+type stateReader interface {
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// chainContext adapts a header reader to the chain context of the evm.
+type chainContext struct {
+	consensus.ChainHeaderReader
+	engine consensus.Engine
+}
+
+func (c *chainContext) Engine() consensus.Engine {
+	return c.engine
+}
+
+// hasValidatorContract returns whether the validators of the epochs are read from
+// the validator contract instead of tallied from the votes.
+func (s *backend) hasValidatorContract() bool {
+	return s.config.ValidatorContract != (common.Address{})
+}
+
+// contractValidators calls the validator contract against the parent state of the
+// epoch boundary header, and returns the validators and powers of the next epoch,
+// or nil if they don't change. The state is not modified.
+func (s *backend) contractValidators(chain consensus.ChainHeaderReader, header *types.Header, parent *state.StateDB) ([]common.Address, []uint64, error) {
+	input, err := validatorContract.Pack("getValidators")
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		coinbase = header.Coinbase
+		context  = core.NewEVMBlockContext(header, &chainContext{ChainHeaderReader: chain, engine: s}, &coinbase)
+		evm      = vm.NewEVM(context, vm.TxContext{}, parent.Copy(), chain.Config(), vm.Config{NoBaseFee: true})
+	)
+	ret, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), s.config.ValidatorContract, input, validatorContractGas)
+	if err != nil {
+		return nil, nil, fmt.Errorf("validator contract call failed: %w", err)
+	}
+	out, err := validatorContract.Unpack("getValidators", ret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid validator contract result: %w", err)
+	}
+	validators, powers := out[0].([]common.Address), out[1].([]*big.Int)
+	if len(validators) == 0 || (len(powers) > 0 && len(powers) != len(validators)) {
+		return nil, nil, fmt.Errorf("invalid validator contract result: %d validators, %d powers", len(validators), len(powers))
+	}
+	var weights []uint64
+	for _, power := range powers {
+		if power.Sign() <= 0 || !power.IsUint64() {
+			return nil, nil, fmt.Errorf("invalid validator contract power %v", power)
+		}
+		weights = append(weights, power.Uint64())
+	}
+	// Normalize the result through the validator set, so the header carries the
+	// validators in the order of the epochs
+	next, err := newValSet(validators, weights)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid validator contract result: %w", err)
+	}
+	if next.Size() != len(validators) {
+		return nil, nil, fmt.Errorf("invalid validator contract result: duplicate validators")
+	}
+	if sameValidators(s.Validators(header.Number.Uint64()), next) {
+		return nil, nil, nil
+	}
+	return next.AddressList(), validator.Powers(next), nil
+}
+
+// prepareContractValidators reads the validators of the next epoch from the parent
+// state of the boundary header being proposed.
+func (s *backend) prepareContractValidators(chain consensus.ChainHeaderReader, header *types.Header) ([]common.Address, []uint64, error) {
+	reader, ok := chain.(stateReader)
+	if !ok {
+		return nil, nil, fmt.Errorf("validator contract requires the chain state")
+	}
+	parent, err := s.getPendingParentHeader(chain, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	statedb, err := reader.StateAt(parent.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	validators, powers, err := s.contractValidators(chain, header, statedb)
+	if err != nil {
+		return nil, nil, err
+	}
+	if validators != nil {
+		log.Info("Validator set changed by contract", "number", header.Number, "validators", validators, "powers", powers)
+	}
+	return validators, powers, nil
+}
+
+// VerifyState implements consensus.StateVerifier, checking that the validators of
+// an epoch boundary header are the ones of the validator contract. The headers are
+// verified before their parent state is known, so the contract is only checked
+// once the block is processed, the epoch of the boundary is stored then. Nodes
+// syncing without the state trust the quorum of the committed seals.
+func (s *backend) VerifyState(chain consensus.ChainHeaderReader, header *types.Header, parent *state.StateDB) error {
+	number := header.Number.Uint64()
+	if !s.hasValidatorContract() || !s.isEpochBoundary(number) {
+		return nil
+	}
+	validators, powers, err := s.checkContractValidators(chain, header, parent)
+	if err != nil {
+		s.dropPendingEpochs(number+1, math.MaxUint64)
+		return err
+	}
+	return s.storeVerifiedEpoch(number+1, validators, powers)
+}
+
+// verifyContractProposal checks the validators of a proposed boundary header with
+// the validator contract on the parent state, so the validators don't vote for a
+// block which fails the import.
+func (s *backend) verifyContractProposal(header *types.Header) error {
+	number := header.Number.Uint64()
+	if !s.hasValidatorContract() || !s.isEpochBoundary(number) {
+		return nil
+	}
+	reader, ok := s.chain.(stateReader)
+	if !ok {
+		return fmt.Errorf("validator contract requires the chain state")
+	}
+	parent := s.chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	statedb, err := reader.StateAt(parent.Root)
+	if err != nil {
+		return err
+	}
+	if _, _, err := s.checkContractValidators(s.chain, header, statedb); err != nil {
+		s.dropPendingEpochs(number+1, math.MaxUint64)
+		return err
+	}
+	return nil
+}
+
+// checkContractValidators checks that the boundary header carries the validators
+// and powers of the contract, and returns them, nil if they don't change.
+func (s *backend) checkContractValidators(chain consensus.ChainHeaderReader, header *types.Header, parent *state.StateDB) ([]common.Address, []uint64, error) {
+	extra, err := types.ExtractBftExtra(header)
+	if err != nil {
+		return nil, nil, errInvalidExtraDataFormat
+	}
+	validators, powers, err := s.contractValidators(chain, header, parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(validators) != len(extra.Validators) || len(powers) != len(extra.Powers) {
+		return nil, nil, errInvalidEpochValidators
+	}
+	for i, val := range validators {
+		if extra.Validators[i] != val {
+			return nil, nil, errInvalidEpochValidators
+		}
+	}
+	for i, power := range powers {
+		if extra.Powers[i] != power {
+			return nil, nil, errInvalidEpochValidators
+		}
+	}
+	return validators, powers, nil
+}
+
+// sameValidators returns whether the sets have the same validators and powers.
+func sameValidators(a, b bft.ValidatorSet) bool {
+	addrsA, addrsB := a.AddressList(), b.AddressList()
+	powersA, powersB := validator.Powers(a), validator.Powers(b)
+	if len(addrsA) != len(addrsB) || len(powersA) != len(powersB) {
+		return false
+	}
+	for i := range addrsA {
+		if addrsA[i] != addrsB[i] {
+			return false
+		}
+	}
+	for i := range powersA {
+		if powersA[i] != powersB[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package backend
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the validators of an epoch boundary are read from the contract, and
+// that boundary headers with other validators fail the state verification.
+func TestValidatorContract(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	validators := make([]common.Address, 4)
+	for i := range validators {
+		validators[i] = common.BytesToAddress([]byte{byte(i + 1)})
+	}
+	genesis := &types.Header{Number: common.Big0, Difficulty: common.Big1, MixDigest: types.BftDigest}
+	if err := types.BftHeaderFillWithValidators(genesis, validators); err != nil {
+		t.Fatalf("failed to fill genesis validators: %v", err)
+	}
+	if err := core.StoreGenesis(db, genesis); err != nil {
+		t.Fatalf("failed to store genesis epoch: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	config := testNetworkConfig()
+	config.Epoch = 10
+	config.ValidatorContract = common.HexToAddress("0xc0")
+	engine := New(&config, key, db).(*backend)
+	defer engine.Close()
+
+	chain := newTestChain(types.NewBlockWithHeader(genesis), nil)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	header := func(number int64, vals []common.Address) *types.Header {
+		h := &types.Header{Number: big.NewInt(number), Difficulty: common.Big1, GasLimit: 8_000_000, MixDigest: types.BftDigest}
+		if err := types.BftHeaderFillWithValidators(h, vals); err != nil {
+			t.Fatalf("failed to fill header validators: %v", err)
+		}
+		return h
+	}
+	// The contract drops the last validator
+	next := validators[:3]
+	statedb.SetCode(config.ValidatorContract, returnCode(t, next, []*big.Int{}))
+
+	have, powers, err := engine.contractValidators(chain, header(10, nil), statedb)
+	if err != nil {
+		t.Fatalf("failed to read validator contract: %v", err)
+	}
+	if len(have) != len(next) || powers != nil {
+		t.Fatalf("contract validators mismatch: have %v, powers %v", have, powers)
+	}
+	if err := engine.VerifyState(chain, header(10, have), statedb); err != nil {
+		t.Errorf("boundary with contract validators rejected: %v", err)
+	}
+	if err := engine.VerifyState(chain, header(10, validators), statedb); !errors.Is(err, errInvalidEpochValidators) {
+		t.Errorf("boundary with stale validators: have %v, want %v", err, errInvalidEpochValidators)
+	}
+	if err := engine.VerifyState(chain, header(9, nil), statedb); err != nil {
+		t.Errorf("non-boundary header rejected: %v", err)
+	}
+	// An unchanged set keeps the boundary header without validators
+	statedb.SetCode(config.ValidatorContract, returnCode(t, validators, []*big.Int{}))
+	if err := engine.VerifyState(chain, header(10, nil), statedb); err != nil {
+		t.Errorf("boundary with unchanged validators rejected: %v", err)
+	}
+}
+
+// stateChain is a test chain serving a single state for every root.
+type stateChain struct {
+	*testChain
+	statedb *state.StateDB
+}
+
+func (c *stateChain) StateAt(root common.Hash) (*state.StateDB, error) { return c.statedb, nil }
+
+// Tests that the epochs of boundary headers stay pending until the state of the
+// boundary is verified, and that proposals are checked with the contract before
+// voting.
+func TestValidatorContractPending(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	validators := make([]common.Address, 4)
+	for i := range validators {
+		validators[i] = common.BytesToAddress([]byte{byte(i + 1)})
+	}
+	genesis := &types.Header{Number: common.Big0, Difficulty: common.Big1, MixDigest: types.BftDigest}
+	if err := types.BftHeaderFillWithValidators(genesis, validators); err != nil {
+		t.Fatalf("failed to fill genesis validators: %v", err)
+	}
+	if err := core.StoreGenesis(db, genesis); err != nil {
+		t.Fatalf("failed to store genesis epoch: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	config := testNetworkConfig()
+	config.Epoch = 1
+	config.ValidatorContract = common.HexToAddress("0xc0")
+	engine := New(&config, key, db).(*backend)
+	defer engine.Close()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	chain := &stateChain{testChain: newTestChain(types.NewBlockWithHeader(genesis), nil), statedb: statedb}
+	engine.chain = chain
+
+	header := func(parent *types.Header, vals []common.Address) *types.Header {
+		h := &types.Header{ParentHash: parent.Hash(), Number: new(big.Int).Add(parent.Number, common.Big1), Difficulty: common.Big1, GasLimit: 8_000_000, MixDigest: types.BftDigest}
+		if err := types.BftHeaderFillWithValidators(h, vals); err != nil {
+			t.Fatalf("failed to fill header validators: %v", err)
+		}
+		return h
+	}
+	// The contract drops the last validator, the boundary header carries them
+	next := validators[:3]
+	statedb.SetCode(config.ValidatorContract, returnCode(t, next, []*big.Int{}))
+
+	boundary := header(genesis, next)
+	if err := engine.verifyContractProposal(boundary); err != nil {
+		t.Errorf("proposal with contract validators rejected: %v", err)
+	}
+	if err := engine.verifyContractProposal(header(genesis, validators)); !errors.Is(err, errInvalidEpochValidators) {
+		t.Errorf("proposal with stale validators: have %v, want %v", err, errInvalidEpochValidators)
+	}
+	if _, err := chain.link(types.NewBlockWithHeader(boundary), 0); err != nil {
+		t.Fatalf("failed to link boundary block: %v", err)
+	}
+	// The epoch of the boundary is pending until it's state is verified
+	if err := engine.UpdateEpoch(chain, boundary, header(boundary, nil)); err != nil {
+		t.Fatalf("failed to update epoch: %v", err)
+	}
+	if size := engine.Validators(2).Size(); size != len(next) {
+		t.Errorf("pending validators mismatch: have %d, want %d", size, len(next))
+	}
+	if latest := engine.LatestEpoch(); latest != 0 {
+		t.Errorf("pending epoch stored: latest epoch %d", latest)
+	}
+	// A failed state verification drops the pending epoch
+	statedb.SetCode(config.ValidatorContract, returnCode(t, validators, []*big.Int{}))
+	if err := engine.VerifyState(chain, boundary, statedb); !errors.Is(err, errInvalidEpochValidators) {
+		t.Fatalf("boundary with stale validators: have %v, want %v", err, errInvalidEpochValidators)
+	}
+	if size := engine.Validators(2).Size(); size != len(validators) {
+		t.Errorf("dropped validators mismatch: have %d, want %d", size, len(validators))
+	}
+	// A verified state stores the epoch
+	statedb.SetCode(config.ValidatorContract, returnCode(t, next, []*big.Int{}))
+	if err := engine.UpdateEpoch(chain, boundary, header(boundary, nil)); err != nil {
+		t.Fatalf("failed to update epoch: %v", err)
+	}
+	if err := engine.VerifyState(chain, boundary, statedb); err != nil {
+		t.Fatalf("boundary with contract validators rejected: %v", err)
+	}
+	if latest := engine.LatestEpoch(); latest != 2 {
+		t.Errorf("verified epoch not stored: latest epoch %d", latest)
+	}
+	if size := engine.Validators(2).Size(); size != len(next) {
+		t.Errorf("stored validators mismatch: have %d, want %d", size, len(next))
+	}
+}
+
+// returnCode assembles a contract which returns the abi encoded validators and
+// powers on any call.
+func returnCode(t *testing.T, validators []common.Address, powers []*big.Int) []byte {
+	t.Helper()
+
+	data, err := validatorContract.Methods["getValidators"].Outputs.Pack(validators, powers)
+	if err != nil {
+		t.Fatalf("failed to encode contract result: %v", err)
+	}
+	size := []byte{byte(len(data) >> 8), byte(len(data))}
+	code := []byte{
+		0x61, size[0], size[1], // PUSH2 size
+		0x60, 0x0e, // PUSH1 offset of the data
+		0x60, 0x00, // PUSH1 0
+		0x39,                   // CODECOPY
+		0x61, size[0], size[1], // PUSH2 size
+		0x60, 0x00, // PUSH1 0
+		0xf3, // RETURN
+	}
+	return append(code, data...)
+}
@@ -2,6 +2,9 @@ package backend
 
 import (
 	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -9,6 +12,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
 )
@@ -17,6 +21,7 @@ const (
 	inmemorySnapshots = 128 // Number of recent vote snapshots to keep in memory
 	inmemoryPeers     = 1000
 	inmemoryMessages  = 1024
+	inmemoryEpochs    = 64 // Number of recent epochs to keep in memory
 )
 
 // HotStuff protocol constants.
@@ -31,31 +36,179 @@ func (s *backend) Author(header *types.Header) (common.Address, error) {
 	return s.signer.Recover(header)
 }
 
+// Signers implements consensus.BFT, returning the proposer of the block and the
+// validators whose committed seals are in the header.
+func (s *backend) Signers(header *types.Header) (common.Address, []common.Address, error) {
+	proposer, err := s.signer.Recover(header)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	height := header.Number.Uint64()
+	committers, err := s.signer.Committers(header, s.Validators(height))
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return proposer, committers, nil
+}
+
+// maxMissedRounds is the number of rounds searched for the proposer of a block,
+// the weighted validators may be elected more than once per cycle.
+const maxMissedRounds = 256
+
+// Participation implements consensus.BFT. The rounds which failed before the block
+// are not recorded in the header, their proposers are elected in turn after the
+// proposer of the parent until the proposer of the block.
+func (s *backend) Participation(parent, header *types.Header) (*consensus.Participation, error) {
+	proposer, committers, err := s.Signers(header)
+	if err != nil {
+		return nil, err
+	}
+	// The parent of the first block of a migrated chain has no proposer seal, the
+	// core starts it's rounds without last proposer as well
+	var lastProposer common.Address
+	if parent.Number.Sign() > 0 {
+		lastProposer, _ = s.Author(parent)
+	}
+	valSet := s.Validators(header.Number.Uint64())
+	if valSet.Policy() == bft.VRF {
+		valSet.SetSeed(s.signer.Randomness(parent))
+	}
+	var missed []common.Address
+	for round := uint64(0); ; round++ {
+		if round == maxMissedRounds {
+			return nil, errUnelectedProposer
+		}
+		valSet.CalcProposer(lastProposer, round)
+		elected := valSet.GetProposer()
+		if elected == nil {
+			return nil, errUnelectedProposer
+		}
+		if elected.Address() == proposer {
+			break
+		}
+		missed = append(missed, elected.Address())
+	}
+	return &consensus.Participation{
+		Validators: valSet.AddressList(),
+		Proposer:   proposer,
+		Missed:     missed,
+		Committers: committers,
+	}, nil
+}
+
 func (s *backend) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
 	return s.verifyHeader(chain, header, nil, seal)
 }
 
+// VerifyHeaders verifies the headers epoch by epoch. The fields and votes of the
+// headers are checked in order, they define the validators of the next epochs,
+// while the seals of an epoch are checked concurrently. The headers of an epoch
+// are only verified once the seal of the preceding epoch change passed, so a
+// syncing node walks the chain from the genesis validators without trusting any
+// other validator set.
 func (s *backend) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
 	abort := make(chan struct{})
 	results := make(chan error, len(headers))
 	go func() {
-		for i, header := range headers {
-			seal := false
-			if seals != nil && len(seals) > i {
-				seal = seals[i]
-			}
-			err := s.verifyHeader(chain, header, headers[:i], seal)
-
+		// The headers before the first epoch of a migrated chain are verified by
+		// the legacy engine
+		var (
+			failed bool
+			start  int
+			first  = s.firstEpoch()
+		)
+		for ; start < len(headers) && headers[start].Number.Uint64() < first; start++ {
 			select {
 			case <-abort:
 				return
-			case results <- err:
+			case results <- nil:
+			}
+		}
+		for start < len(headers) {
+			// The epoch ends with it's epoch change header, or the batch
+			end := start + 1
+			for end < len(headers) && !s.isEpochChange(headers[end-1]) {
+				end++
 			}
+			var errs []error
+			if !failed {
+				errs = s.verifyEpochHeaders(chain, headers[:end], start, seals)
+			}
+			for i := start; i < end; i++ {
+				err := errInvalidAncestor
+				if !failed {
+					err = errs[i-start]
+				}
+				failed = failed || err != nil
+
+				select {
+				case <-abort:
+					return
+				case results <- err:
+				}
+			}
+			start = end
 		}
 	}()
 	return abort, results
 }
 
+// verifyEpochHeaders verifies the headers from the start index, all of them are
+// validated by the same validators. The headers after a failed one are not
+// verified and reported with errInvalidAncestor.
+func (s *backend) verifyEpochHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, start int, seals []bool) []error {
+	var (
+		errs = make([]error, len(headers)-start)
+		vals = make([]bft.ValidatorSet, len(headers)-start)
+	)
+	for i := start; i < len(headers); i++ {
+		vals[i-start], errs[i-start] = s.verifyHeaderFields(chain, headers[i], headers[:i])
+		if errs[i-start] != nil {
+			for j := i + 1; j < len(headers); j++ {
+				errs[j-start] = errInvalidAncestor
+			}
+			break
+		}
+	}
+	var (
+		wg      sync.WaitGroup
+		workers = make(chan struct{}, runtime.GOMAXPROCS(0))
+	)
+	for i := start; i < len(headers); i++ {
+		if errs[i-start] != nil || vals[i-start] == nil {
+			continue
+		}
+		seal := (i < len(seals) && seals[i]) || s.isEpochChange(headers[i])
+
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(index int, header *types.Header, vals bft.ValidatorSet) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			errs[index] = s.signer.VerifyHeader(header, vals, seal)
+		}(i-start, headers[i], vals[i-start])
+	}
+	wg.Wait()
+	return errs
+}
+
+// isEpochChange returns whether the header carries the validators of the next
+// epoch. The seals of such headers are always verified in batches, even if the
+// caller skips the seal checks, they prove the next validators to the syncing
+// nodes.
+func (s *backend) isEpochChange(header *types.Header) bool {
+	if header.Number == nil || header.Number.Sign() == 0 {
+		return false
+	}
+	extra, err := types.ExtractBftExtra(header)
+	if err != nil {
+		return false
+	}
+	return len(extra.Validators) > 0
+}
+
 func (s *backend) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
 	if len(block.Uncles()) > 0 {
 		return errInvalidUncleHash
@@ -79,11 +232,30 @@ func (s *backend) Prepare(chain consensus.ChainHeaderReader, header *types.Heade
 	header.Difficulty = defaultDifficulty
 
 	// set header's timestamp
-	header.Time = parent.Time + s.config.BlockPeriod
+	header.Time = parent.Time + s.config.TimestampPeriod()
 	if header.Time < uint64(time.Now().Unix()) {
 		header.Time = uint64(time.Now().Unix())
 	}
 
+	// assemble the bft extra while keeping the miner vanity, seals will be filled
+	// in `Seal` and after consensus.
+	if _, err := types.ExtractBftExtra(header); err != nil {
+		if err := types.BftHeaderFillWithValidators(header, nil); err != nil {
+			return err
+		}
+	}
+
+	// cast the proposer's validator vote, or fill the validators of next epoch
+	if err := s.prepareVotes(chain, header); err != nil {
+		return err
+	}
+
+	// proposer proves it's eligibility for the next height's leader election
+	if s.config.LeaderPolicy == bft.VRF {
+		if err := s.signer.SealVRF(header); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -99,31 +271,65 @@ func (s *backend) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = nilUncleHash
 
+	// Empty blocks are held back until the max idle interval passed since the parent.
+	// The timestamp is part of the seal hash, so it's fixed before the worker takes
+	// the block as a sealing task.
+	if s.config.SkipEmpty && s.config.MaxIdle > 0 && len(txs) == 0 {
+		if parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); parent != nil {
+			idle := parent.Time + (s.config.MaxIdle+999)/1000
+			if header.Time < idle {
+				header.Time = idle
+			}
+		}
+	}
 	// Assemble and return the final block for sealing
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
 }
 
-func (s *backend) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block,  stop <-chan struct{}) (err error) {
+func (s *backend) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) (err error) {
 	// update the block header timestamp and signature and propose the block to core engine
 	header := block.Header()
 
+	// on-demand chains don't seal empty blocks, the worker seals again once
+	// transactions arrive
+	if s.config.OnDemand && len(block.Transactions()) == 0 {
+		return errWaitTransactions
+	}
+
 	// sign the sig hash and fill extra seal
 	if err = s.signer.SealBeforeCommit(header); err != nil {
 		return err
 	}
 	block = block.WithSeal(header)
 
+	// the core waits for the proposal time itself, the block is held back here only
+	// if the worker may still replace it, with a fresher payload or with a block of
+	// transactions instead of an idle empty block
+	var delay time.Duration
+	if s.config.RefreshPayload || (s.config.SkipEmpty && len(block.Transactions()) == 0) {
+		delay = time.Until(s.proposalTime(header))
+	}
+
 	go func() {
 		// get the proposed block hash and clear it if the seal() is completed.
 		s.sealMu.Lock()
-		s.proposedBlockHash = block.Hash()
+		s.setProposedHash(block.Hash())
 		s.logger.Trace("WorkerSealNewBlock", "hash", block.Hash(), "number", block.Number())
 
 		defer func() {
-			s.proposedBlockHash = common.EmptyHash
+			s.setProposedHash(common.EmptyHash)
 			s.sealMu.Unlock()
 		}()
 
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-stop:
+				results <- nil
+				return
+			}
+		}
+
 		// post block into Istanbul engine
 		go s.EventMux().Post(bft.RequestEvent{
 			Proposal: block,
@@ -147,6 +353,39 @@ func (s *backend) Seal(chain consensus.ChainHeaderReader, block *types.Block, re
 	return nil
 }
 
+// BuildDeadline implements consensus.BuildDeadliner, the proposer stops adding
+// transactions to the block the configured time after the start of the height.
+func (s *backend) BuildDeadline(header *types.Header) (time.Time, bool) {
+	if s.config.BuildDeadline == 0 {
+		return time.Time{}, false
+	}
+	return s.heightStart(header.Number.Uint64()).Add(time.Duration(s.config.BuildDeadline) * time.Millisecond), true
+}
+
+// heightStart returns the time at which the node started the height, when it was
+// notified of the parent head. Heights the node didn't reach yet start now.
+func (s *backend) heightStart(number uint64) time.Time {
+	s.headMu.RLock()
+	defer s.headMu.RUnlock()
+
+	if number == s.headNumber+1 && !s.headTime.IsZero() {
+		return s.headTime
+	}
+	return time.Now()
+}
+
+// proposalTime returns the time at which the core proposes the block, it's
+// timestamp, and a block period after the start of the height for event driven.
+func (s *backend) proposalTime(header *types.Header) time.Time {
+	at := time.Unix(int64(header.Time), 0)
+	if s.config.Protocol == bft.BFT_PROTOCOL_EVENT_DRIVEN {
+		if start := s.heightStart(header.Number.Uint64()).Add(s.config.Period()); start.After(at) {
+			at = start
+		}
+	}
+	return at
+}
+
 func (s *backend) SealHash(header *types.Header) common.Hash {
 	return s.signer.SigHash(header)
 }
@@ -166,6 +405,16 @@ func (s *backend) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 		Version:   "1.0",
 		Service:   &API{chain: chain, bft: s},
 		Public:    true,
+	}, {
+		Namespace: "bft",
+		Version:   "1.0",
+		Service:   &EvidenceAPI{bft: s},
+		Public:    true,
+	}, {
+		Namespace: "bft",
+		Version:   "1.0",
+		Service:   &ConsensusAPI{chain: chain, bft: s},
+		Public:    true,
 	}}
 }
 
@@ -189,6 +438,11 @@ func (s *backend) Start(chain consensus.ChainReader, currentBlock func() *types.
 	s.getBlockByHash = getBlockByHash
 	s.hasBadBlock = hasBadBlock
 
+	if s.config.Protocol == bft.BFT_PROTOCOL_EVENT_DRIVEN {
+		s.certifiedMu.Lock()
+		s.certified = s.loadCertified(chain)
+		s.certifiedMu.Unlock()
+	}
 	if err := s.core.Start(chain); err != nil {
 		return err
 	}
@@ -211,8 +465,49 @@ func (s *backend) Stop() error {
 	return nil
 }
 
+// Health implements consensus.BFT, returning the round counters and phase times
+// of the core with the votes missed by the validator.
+func (s *backend) Health() *consensus.Health {
+	s.coreMu.RLock()
+	started := s.coreStarted
+	s.coreMu.RUnlock()
+
+	if !started {
+		return nil
+	}
+	rs := s.core.RoundState()
+	if rs == nil {
+		return nil
+	}
+	return &consensus.Health{
+		Height:         rs.View.Height.Uint64(),
+		Round:          rs.View.Round.Uint64(),
+		State:          rs.State,
+		PhaseTimes:     rs.PhaseTimes,
+		Rounds:         rs.Rounds,
+		RoundChanges:   rs.RoundChanges,
+		ProposerRounds: rs.ProposerRounds,
+		MissedVotes:    atomic.LoadUint64(&s.missedVotes),
+	}
+}
+
+// Close implements consensus.Engine, closing the consensus wal and the freezer of
+// the finalized epochs.
 func (s *backend) Close() error {
-	return nil
+	if s.wal != nil {
+		if err := s.wal.Close(); err != nil {
+			s.logger.Warn("Failed to close consensus wal", "err", err)
+		}
+	}
+	s.epochMu.Lock()
+	defer s.epochMu.Unlock()
+
+	if s.epochFreezer == nil {
+		return nil
+	}
+	err := s.epochFreezer.Close()
+	s.epochFreezer, s.frozenEpochs = nil, 0
+	return err
 }
 
 // verifyHeader checks whether a header conforms to the consensus rules.The
@@ -220,21 +515,32 @@ func (s *backend) Close() error {
 // looking those up from the database. This is useful for concurrently verifying
 // a batch of new headers.
 func (s *backend) verifyHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, seal bool) error {
+	vals, err := s.verifyHeaderFields(chain, header, parents)
+	if err != nil || vals == nil {
+		return err
+	}
+	return s.signer.VerifyHeader(header, vals, seal)
+}
+
+// verifyHeaderFields checks the header fields and votes, and returns the validators
+// the seals of the header are verified with. The genesis header has no validators
+// to verify with.
+func (s *backend) verifyHeaderFields(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) (bft.ValidatorSet, error) {
 	if header.Number == nil {
-		return errUnknownBlock
+		return nil, errUnknownBlock
 	}
 
 	// Ensure that the mix digest is zero as we don't have fork protection currently
 	if header.MixDigest != types.BftDigest {
-		return errInvalidMixDigest
+		return nil, errInvalidMixDigest
 	}
 	// Ensure that the block doesn't contain any uncles which are meaningless in Istanbul
 	if header.UncleHash != nilUncleHash {
-		return errInvalidUncleHash
+		return nil, errInvalidUncleHash
 	}
 	// Ensure that the block's difficulty is meaningful (may not be correct at this point)
 	if header.Difficulty == nil || header.Difficulty.Cmp(defaultDifficulty) != 0 {
-		return errInvalidDifficulty
+		return nil, errInvalidDifficulty
 	}
 
 	// verifyCascadingFields verifies all the header fields that are not standalone,
@@ -244,7 +550,7 @@ func (s *backend) verifyHeader(chain consensus.ChainHeaderReader, header *types.
 	// The genesis block is the always valid dead-end
 	number := header.Number.Uint64()
 	if number == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// Ensure that the block's timestamp isn't too close to it's parent
@@ -255,17 +561,22 @@ func (s *backend) verifyHeader(chain consensus.ChainHeaderReader, header *types.
 		parent = chain.GetHeader(header.ParentHash, number-1)
 	}
 	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
-		return consensus.ErrUnknownAncestor
+		return nil, consensus.ErrUnknownAncestor
+	}
+	if header.Time > parent.Time+s.config.TimestampPeriod() && header.Time > uint64(now().Unix()) {
+		return nil, errInvalidTimestamp
 	}
-	if header.Time > parent.Time+s.config.BlockPeriod && header.Time > uint64(now().Unix()) {
-		return errInvalidTimestamp
+	if s.config.BlockPeriod != 0 && header.Time <= parent.Time {
+		return nil, errInvalidTimestamp
 	}
 
-	if err := s.UpdateEpoch(parent, header); err != nil {
-		return err
+	if err := s.UpdateEpoch(chain, parent, header); err != nil {
+		return nil, err
 	}
-	vals := s.Validators(number)
-	return s.signer.VerifyHeader(header, vals, seal)
+	if err := s.verifyVotes(chain, header, parents); err != nil {
+		return nil, err
+	}
+	return s.validators(number)
 }
 
 func (s *backend) getPendingParentHeader(chain consensus.ChainHeaderReader, header *types.Header) (*types.Header, error) {
@@ -276,3 +587,13 @@ func (s *backend) getPendingParentHeader(chain consensus.ChainHeaderReader, head
 	}
 	return parent, nil
 }
+
+// writeExtra encodes the bft extra into the header while keeping the vanity.
+func writeExtra(header *types.Header, extra *types.BftExtra) error {
+	payload, err := rlp.EncodeToBytes(&extra)
+	if err != nil {
+		return err
+	}
+	header.Extra = append(header.Extra[:types.BftExtraVanity], payload...)
+	return nil
+}
@@ -3,76 +3,201 @@ package backend
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/bft"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
 	"github.com/ethereum/go-ethereum/consensus/bft/validator"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
+// priorityCheckpointInterval is the number of blocks between the checkpoints of the
+// proposer priorities of weighted epochs.
+const priorityCheckpointInterval = 1024
+
 func init() {
 	core.StoreGenesis = func(db ethdb.Database, header *types.Header) error {
 		extra, err := types.ExtractBftExtra(header)
 		if err != nil {
 			return err
 		}
+		valSet, err := newValSet(extra.Validators, extra.Powers)
+		if err != nil {
+			return err
+		}
+		keys, err := genesisBLSKeys(extra)
+		if err != nil {
+			return err
+		}
 		epoch := &Epoch{
 			StartHeight:          0,
-			ValSet:               newValSet(extra.Validators),
+			ValSet:               valSet,
 			LastEpochStartHeight: 0,
+			BLSKeys:              keys,
 		}
-		return storeCurEpoch(db, epoch)
+		blob, err := rlp.EncodeToBytes(epoch)
+		if err != nil {
+			return err
+		}
+		batch := db.NewBatch()
+		rawdb.WriteBftEpoch(batch, 0, blob)
+		rawdb.WriteBftEpochIndex(batch, []uint64{0})
+		return batch.Write()
 	}
 }
 
+// Validators implements bft.Backend.Validators. An epoch which can't be read has
+// no validators, nothing is verified with them.
 func (s *backend) Validators(height uint64) bft.ValidatorSet {
-	startHeight := s.maxEpochStartHeight
-	for height < startHeight {
-		epoch := s.epochs[startHeight]
-		if height >= epoch.StartHeight {
-			return s.epochs[epoch.StartHeight].ValSet.Copy()
-		} else {
-			startHeight = epoch.LastEpochStartHeight
-		}
+	valSet, err := s.validators(height)
+	if err != nil {
+		log.Error("Failed to read bft epoch", "height", height, "err", err)
+		return validator.NewSet(nil, s.config.LeaderPolicy)
 	}
-	return s.epochs[startHeight].ValSet.Copy()
+	return valSet
 }
 
-func (s *backend) LoadEpoch() error {
-	if s.epochs == nil {
-		s.epochs = make(map[uint64]*Epoch)
+// validators returns the validators of the epoch which contains the given height.
+func (s *backend) validators(height uint64) (bft.ValidatorSet, error) {
+	epoch, err := s.epochAt(height)
+	if err != nil {
+		return nil, err
 	}
-	epoch, err := getCurEpoch(s.db)
+	return s.withPolicy(epoch, height), nil
+}
+
+// BLSKeys implements bft.BLSKeyReader, returning the bls key registrations of the
+// epoch which contains the given height.
+func (s *backend) BLSKeys(height uint64) map[common.Address][]byte {
+	epoch, err := s.epochAt(height)
 	if err != nil {
-		return err
+		log.Error("Failed to read bft epoch", "height", height, "err", err)
+		return nil
 	}
-	s.maxEpochStartHeight = epoch.StartHeight
-	s.epochs[s.maxEpochStartHeight] = epoch
+	return epoch.BLSKeys
+}
+
+// epochAt returns the epoch which contains the given height, it's found by binary
+// search in the start heights of the epochs.
+func (s *backend) epochAt(height uint64) (*Epoch, error) {
+	s.epochMu.RLock()
+	defer s.epochMu.RUnlock()
+
+	// The pending epochs start after the stored ones
+	for i := len(s.pendingEpochs) - 1; i >= 0; i-- {
+		if s.pendingEpochs[i].StartHeight <= height {
+			return s.pendingEpochs[i], nil
+		}
+	}
+	// A migrated chain has no validators before it's first epoch is initialized
+	if len(s.epochHeights) == 0 {
+		return &Epoch{ValSet: validator.NewSet(nil, bft.RoundRobin)}, nil
+	}
+	// The genesis epoch starts at 0, every height is contained by an epoch. The
+	// heights before the first epoch of a migrated chain use it's validators.
+	i := sort.Search(len(s.epochHeights), func(i int) bool {
+		return s.epochHeights[i] > height
+	})
+	if i > 0 {
+		i--
+	}
+	return s.readEpoch(i)
+}
+
+// latestEpoch returns the start height of the latest epoch.
+//
+// Note, this function assumes that the `epochMu` mutex is held!
+func (s *backend) latestEpoch() uint64 {
+	if len(s.epochHeights) == 0 {
+		return 0
+	}
+	return s.epochHeights[len(s.epochHeights)-1]
+}
 
-	log.Info("[epoch]", "load current epoch", epoch.String())
-	startHeight := epoch.LastEpochStartHeight
-	for startHeight > 0 {
-		ep, err := s.readEpoch(startHeight)
+// firstEpoch returns the start height of the first epoch, the genesis for chains
+// sealed by the engine since their genesis, or the fork block of migrated chains.
+func (s *backend) firstEpoch() uint64 {
+	s.epochMu.RLock()
+	defer s.epochMu.RUnlock()
+
+	if len(s.epochHeights) == 0 {
+		return 0
+	}
+	return s.epochHeights[0]
+}
+
+// withPolicy copies the epoch validators with the configured proposer policy, epochs
+// are persisted without policy. weighted validators carry the proposer priorities of
+// the given height.
+func (s *backend) withPolicy(epoch *Epoch, height uint64) bft.ValidatorSet {
+	if validator.Powers(epoch.ValSet) != nil {
+		return epoch.prioritySet(height, s.config.LeaderPolicy)
+	}
+	return validator.NewSet(epoch.ValSet.AddressList(), s.config.LeaderPolicy)
+}
+
+// LoadEpoch loads the index of the stored epochs, and opens the freezer of the
+// finalized epochs if the database has an ancient store. The epochs are read
+// on demand. The epochs of a chain migrated to the engine are initialized at
+// the fork block, they may be missing.
+func (s *backend) LoadEpoch() error {
+	s.epochMu.Lock()
+	defer s.epochMu.Unlock()
+
+	heights := rawdb.ReadBftEpochIndex(s.db)
+	s.epochHeights = heights
+
+	if ancient, err := s.db.AncientDatadir(); err == nil {
+		freezer, err := rawdb.NewBftFreezer(ancient, false)
 		if err != nil {
 			return err
 		}
-		startHeight = ep.LastEpochStartHeight
-	}
-	if startHeight == 0 {
-		if _, err := s.readEpoch(0); err != nil {
+		frozen, err := freezer.Ancients()
+		if err != nil {
+			freezer.Close()
 			return err
 		}
+		// The index is rewound before the freezer, drop the frozen epochs which
+		// were left by an interrupted rewind
+		if frozen > uint64(len(heights)) {
+			if err := freezer.TruncateHead(uint64(len(heights))); err != nil {
+				freezer.Close()
+				return err
+			}
+			frozen = uint64(len(heights))
+		}
+		s.epochFreezer, s.frozenEpochs = freezer, frozen
+	}
+	if len(heights) == 0 {
+		log.Warn("[epoch]", "load current epoch", "no epochs stored, waiting for the fork block")
+		return nil
 	}
+	epoch, err := s.readEpoch(len(heights) - 1)
+	if err != nil {
+		return err
+	}
+	log.Info("[epoch]", "load current epoch", epoch.String(), "epochs", len(heights), "frozen", s.frozenEpochs)
 	return nil
 }
 
-func (s *backend) UpdateEpoch(parent, header *types.Header) error {
+// UpdateEpoch stores the epoch defined by the parent of the header. The epochs of
+// the validator contract are kept pending until the state of their boundary block
+// is verified, the headers are verified with them meanwhile. Chains without state
+// trust the committed seals of the boundary.
+func (s *backend) UpdateEpoch(chain consensus.ChainHeaderReader, parent, header *types.Header) error {
 	height := header.Number.Uint64()
-	if height <= s.maxEpochStartHeight || height == 1 {
+	if height <= s.LatestEpoch() || height == 1 {
 		return nil
 	}
 
@@ -84,90 +209,397 @@ func (s *backend) UpdateEpoch(parent, header *types.Header) error {
 	if parentExt.Validators == nil || len(parentExt.Validators) == 0 {
 		return nil
 	}
-	return s.saveEpoch(height, parentExt.Validators)
+	keys, err := s.blsKeys(parentExt.Validators, parentExt.BLSKeys)
+	if err != nil {
+		return err
+	}
+	if _, ok := chain.(stateReader); ok && s.hasValidatorContract() {
+		return s.addPendingEpoch(height, parentExt.Validators, parentExt.Powers, keys)
+	}
+	return s.saveEpoch(height, parentExt.Validators, parentExt.Powers, keys)
 }
 
+// addPendingEpoch keeps the epoch of a validator contract boundary until the state
+// of the boundary is verified. The pending epochs of another fork from the height
+// on are replaced.
+func (s *backend) addPendingEpoch(height uint64, list []common.Address, powers []uint64, keys map[common.Address][]byte) error {
+	valSet, err := newValSet(list, powers)
+	if err != nil {
+		return err
+	}
+	s.epochMu.Lock()
+	defer s.epochMu.Unlock()
+
+	latest := s.latestEpoch()
+	if height <= latest {
+		return nil
+	}
+	i := sort.Search(len(s.pendingEpochs), func(i int) bool {
+		return s.pendingEpochs[i].StartHeight >= height
+	})
+	if i < len(s.pendingEpochs) && s.pendingEpochs[i].StartHeight == height && sameValidators(s.pendingEpochs[i].ValSet, valSet) {
+		return nil
+	}
+	if i > 0 {
+		latest = s.pendingEpochs[i-1].StartHeight
+	}
+	s.pendingEpochs = append(s.pendingEpochs[:i], &Epoch{
+		StartHeight:          height,
+		ValSet:               valSet,
+		LastEpochStartHeight: latest,
+		BLSKeys:              keys,
+	})
+	return nil
+}
+
+// storeVerifiedEpoch stores the epoch of a validator contract boundary once it's
+// state is verified, nil validators keep the epoch. The pending epochs below it
+// are stored as well, their boundaries were imported without state and trusted
+// by the committed seals.
+func (s *backend) storeVerifiedEpoch(height uint64, list []common.Address, powers []uint64) error {
+	s.epochMu.RLock()
+	pending := append([]*Epoch{}, s.pendingEpochs...)
+	s.epochMu.RUnlock()
+
+	for _, epoch := range pending {
+		if epoch.StartHeight >= height {
+			break
+		}
+		if err := s.saveEpoch(epoch.StartHeight, epoch.ValSet.AddressList(), validator.Powers(epoch.ValSet), epoch.BLSKeys); err != nil {
+			return err
+		}
+	}
+	if list != nil {
+		keys, err := s.blsKeys(list, nil)
+		if err != nil {
+			return err
+		}
+		if err := s.saveEpoch(height, list, powers, keys); err != nil {
+			return err
+		}
+	}
+	s.dropPendingEpochs(0, height)
+	return nil
+}
+
+// dropPendingEpochs drops the pending epochs starting in [from, to].
+func (s *backend) dropPendingEpochs(from, to uint64) {
+	s.epochMu.Lock()
+	defer s.epochMu.Unlock()
+
+	kept := s.pendingEpochs[:0]
+	for _, epoch := range s.pendingEpochs {
+		if epoch.StartHeight < from || epoch.StartHeight > to {
+			kept = append(kept, epoch)
+		}
+	}
+	s.pendingEpochs = kept
+}
+
+// ChangeEpoch implements consensus.BFT.ChangeEpoch, the validators of the new epoch
+// are unweighted and keep their bls keys of the current epoch.
 func (s *backend) ChangeEpoch(height uint64, list []common.Address) error {
-	return s.saveEpoch(height, list)
+	keys, err := s.blsKeys(list, nil)
+	if err != nil {
+		return err
+	}
+	return s.saveEpoch(height, list, nil, keys)
 }
 
-func (s *backend) DumpEpochs() string {
-	str := ""
-	for _, v := range s.epochs {
-		str += v.String() + "\r\n"
+// InitEpoch implements consensus.BFT.InitEpoch, the first epoch of a migrated chain
+// starts at the fork block with unweighted validators. The validators have no
+// bls keys, they register them by votes once the chain is migrated.
+func (s *backend) InitEpoch(height uint64, list []common.Address) error {
+	s.epochMu.RLock()
+	initialized := len(s.epochHeights) > 0
+	s.epochMu.RUnlock()
+
+	if initialized {
+		return nil
 	}
-	return str
+	return s.saveEpoch(height, list, nil, nil)
+}
+
+// blsKeys maps the validators to their bls key registrations by index, validators
+// without registration keep the key of the latest epoch. it returns nil if no
+// validator has a key.
+func (s *backend) blsKeys(list []common.Address, registrations [][]byte) (map[common.Address][]byte, error) {
+	latest, err := s.epochAt(s.LatestEpoch())
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[common.Address][]byte)
+	for i, addr := range list {
+		if i < len(registrations) && len(registrations[i]) > 0 {
+			keys[addr] = registrations[i]
+		} else if len(latest.BLSKeys[addr]) > 0 {
+			keys[addr] = latest.BLSKeys[addr]
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return keys, nil
+}
+
+// genesisBLSKeys returns the bls key registrations of the genesis validators, the
+// registrations must be aligned with the validators and prove their possession.
+func genesisBLSKeys(extra *types.BftExtra) (map[common.Address][]byte, error) {
+	if len(extra.BLSKeys) == 0 {
+		return nil, nil
+	}
+	if len(extra.BLSKeys) != len(extra.Validators) {
+		return nil, snr.ErrInvalidBLSKey
+	}
+	keys := make(map[common.Address][]byte, len(extra.Validators))
+	for i, addr := range extra.Validators {
+		if _, err := snr.ParseBLSKey(extra.BLSKeys[i]); err != nil {
+			return nil, fmt.Errorf("validator %s: %w", addr, err)
+		}
+		keys[addr] = extra.BLSKeys[i]
+	}
+	return keys, nil
+}
+
+// LatestEpoch implements consensus.EpochProver, returning the start height of the
+// latest known epoch.
+func (s *backend) LatestEpoch() uint64 {
+	s.epochMu.RLock()
+	defer s.epochMu.RUnlock()
+	return s.latestEpoch()
+}
+
+// EpochProof implements consensus.EpochProver. The validators of an epoch are
+// carried by the last header of the previous epoch.
+func (s *backend) EpochProof(chain consensus.ChainHeaderReader, from uint64, limit int) []*types.Header {
+	s.epochMu.RLock()
+	i := sort.Search(len(s.epochHeights), func(i int) bool {
+		return s.epochHeights[i] > from
+	})
+	starts := s.epochHeights[i:]
+	s.epochMu.RUnlock()
+
+	var headers []*types.Header
+	for _, start := range starts {
+		if len(headers) >= limit {
+			break
+		}
+		header := chain.GetHeaderByNumber(start - 1)
+		if header == nil {
+			break
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// VerifyEpochProof implements consensus.EpochProver. Every header is verified with
+// the validators of it's epoch, including the quorum of it's committed seals, and
+// the epoch it proves is stored before the next header is verified. The epochs
+// proven before a failed header are kept, the epochs which were proven meanwhile
+// are verified again but not stored.
+func (s *backend) VerifyEpochProof(from uint64, headers []*types.Header) error {
+	for _, header := range headers {
+		if header.Number == nil || header.Number.Uint64() < from || !s.isEpochChange(header) {
+			return errInvalidEpochProof
+		}
+		number := header.Number.Uint64()
+		from = number + 1
+		valSet, err := s.validators(number)
+		if err != nil {
+			return err
+		}
+		if err := s.signer.VerifyHeader(header, valSet, true); err != nil {
+			return err
+		}
+		extra, err := types.ExtractBftExtra(header)
+		if err != nil {
+			return err
+		}
+		keys, err := s.blsKeys(extra.Validators, extra.BLSKeys)
+		if err != nil {
+			return err
+		}
+		if err := s.saveEpoch(number+1, extra.Validators, extra.Powers, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RewindEpochs implements consensus.EpochStore. The epoch starting after the head
+// is defined by the head itself, it's kept.
+func (s *backend) RewindEpochs(head uint64) error {
+	s.dropPendingEpochs(head+2, math.MaxUint64)
+
+	s.epochMu.Lock()
+	defer s.epochMu.Unlock()
+	return s.truncateEpochs(head + 2)
 }
 
-func (s *backend) saveEpoch(height uint64, list []common.Address) error {
-	if _, ok := s.epochs[height]; ok {
+// FreezeEpochs implements consensus.EpochStore, moving the epochs which started
+// at or before the finalized block from the key-value store to the freezer. The
+// frozen epochs are only dropped by rewinds below the finalized block.
+func (s *backend) FreezeEpochs(finalized uint64) error {
+	s.epochMu.Lock()
+	defer s.epochMu.Unlock()
+
+	if s.epochFreezer == nil {
 		return nil
 	}
-	if s.maxEpochStartHeight == height {
-		log.Warn("[epoch]", "dump epoch", "epoch should be persisted before", "max epoch height", s.maxEpochStartHeight)
+	n := uint64(sort.Search(len(s.epochHeights), func(i int) bool {
+		return s.epochHeights[i] > finalized
+	}))
+	if n <= s.frozenEpochs {
 		return nil
 	}
+	heights := s.epochHeights[s.frozenEpochs:n]
+	blobs := make([][]byte, 0, len(heights))
+	for _, height := range heights {
+		blob := rawdb.ReadBftEpoch(s.db, height)
+		if len(blob) == 0 {
+			return fmt.Errorf("%w: %d", errMissingEpochs, height)
+		}
+		blobs = append(blobs, blob)
+	}
+	if err := rawdb.WriteBftFrozenEpochs(s.epochFreezer, s.frozenEpochs, blobs); err != nil {
+		return err
+	}
+	if err := s.epochFreezer.Sync(); err != nil {
+		return err
+	}
+	// The epochs are readable from the freezer, delete them from the key-value store
+	batch := s.db.NewBatch()
+	for _, height := range heights {
+		rawdb.DeleteBftEpoch(batch, height)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	s.frozenEpochs = n
+	log.Debug("Froze bft epochs", "count", len(heights), "frozen", n, "finalized", finalized)
+	return nil
+}
 
-	epoch := &Epoch{
-		StartHeight:          height,
-		ValSet:               newValSet(list),
-		LastEpochStartHeight: s.maxEpochStartHeight,
+// truncateEpochs drops the epochs starting at or after the given height, the
+// first epoch of a migrated chain is dropped by rewinds below the fork block.
+// The index is written before the freezer is truncated, the frozen epochs
+// beyond the index are dropped at startup if the truncation is interrupted.
+//
+// Note, this function assumes that the `epochMu` mutex is held!
+func (s *backend) truncateEpochs(height uint64) error {
+	n := sort.Search(len(s.epochHeights), func(i int) bool {
+		return s.epochHeights[i] >= height
+	})
+	if n == len(s.epochHeights) {
+		return nil
 	}
-	if err := storeCurEpoch(s.db, epoch); err != nil {
+	dropped := s.epochHeights[n:]
+
+	batch := s.db.NewBatch()
+	for _, height := range dropped {
+		rawdb.DeleteBftEpoch(batch, height)
+	}
+	rawdb.WriteBftEpochIndex(batch, s.epochHeights[:n])
+	if err := batch.Write(); err != nil {
 		return err
 	}
-	s.epochs[height] = epoch
-	s.maxEpochStartHeight = height
+	for _, height := range dropped {
+		s.epochCache.Remove(height)
+	}
+	s.epochHeights = s.epochHeights[:n]
 
-	log.Info("[epoch]", "save epoch", epoch.String())
+	if uint64(n) < s.frozenEpochs {
+		if err := s.epochFreezer.TruncateHead(uint64(n)); err != nil {
+			return err
+		}
+		s.frozenEpochs = uint64(n)
+	}
+	log.Info("[epoch]", "rewind epochs", len(dropped), "latest", s.latestEpoch())
 	return nil
 }
 
-func (s *backend) readEpoch(height uint64) (*Epoch, error) {
-	epoch, err := getEpochByHeight(s.db, height)
-	if err != nil {
-		log.Warn("[epoch]", "read epoch failed", err)
-		return nil, err
-	}
-	s.epochs[epoch.StartHeight] = epoch
-	if epoch.StartHeight > s.maxEpochStartHeight {
-		s.maxEpochStartHeight = epoch.StartHeight
+func (s *backend) DumpEpochs() string {
+	s.epochMu.RLock()
+	defer s.epochMu.RUnlock()
+
+	str := ""
+	for i := range s.epochHeights {
+		epoch, err := s.readEpoch(i)
+		if err != nil {
+			str += err.Error() + "\r\n"
+			continue
+		}
+		str += epoch.String() + "\r\n"
 	}
-	log.Info("[epoch]", "read epoch", epoch.String())
-	return epoch, nil
+	return str
 }
 
-func storeCurEpoch(db ethdb.Database, epoch *Epoch) error {
-	blob, err := epoch.MarshalJSON()
+func (s *backend) saveEpoch(height uint64, list []common.Address, powers []uint64, keys map[common.Address][]byte) error {
+	s.epochMu.Lock()
+	defer s.epochMu.Unlock()
+
+	latest := s.latestEpoch()
+	if height < latest {
+		return nil
+	}
+	if height == latest && len(s.epochHeights) > 0 {
+		log.Warn("[epoch]", "dump epoch", "epoch should be persisted before", "max epoch height", latest)
+		return nil
+	}
+
+	valSet, err := newValSet(list, powers)
 	if err != nil {
 		return err
 	}
-	// ToDo rawdb 部分需要单独存储Epoch信息
-	if err := rawdb.WriteEpoch(db, epoch.StartHeight, blob); err != nil {
+	epoch := &Epoch{
+		StartHeight:          height,
+		ValSet:               valSet,
+		LastEpochStartHeight: latest,
+		BLSKeys:              keys,
+	}
+	blob, err := rlp.EncodeToBytes(epoch)
+	if err != nil {
 		return err
 	}
-	return rawdb.WriteCurrentEpochHeight(db, epoch.StartHeight)
-}
+	heights := append(s.epochHeights, height)
 
-func getCurEpoch(db ethdb.Database) (*Epoch, error) {
-	// ToDo rawdb 部分需要单独存储Epoch信息
-	height, err := rawdb.ReadCurrentEpochHeight(db)
-	if err != nil {
-		return nil, err
+	batch := s.db.NewBatch()
+	rawdb.WriteBftEpoch(batch, height, blob)
+	rawdb.WriteBftEpochIndex(batch, heights)
+	if err := batch.Write(); err != nil {
+		return err
 	}
-	return getEpochByHeight(db, height)
+	s.epochHeights = heights
+	s.epochCache.Add(height, epoch)
+
+	log.Info("[epoch]", "save epoch", epoch.String())
+	return nil
 }
 
-func getEpochByHeight(db ethdb.Database, height uint64) (*Epoch, error) {
-	// ToDo rawdb 部分需要单独存储Epoch信息
-	blob, err := rawdb.ReadEpoch(db, height)
-	if err != nil {
-		return nil, err
+// readEpoch returns the i-th epoch of the index, the epochs are read from the
+// freezer once they are finalized.
+//
+// Note, this function assumes that the `epochMu` mutex is held!
+func (s *backend) readEpoch(i int) (*Epoch, error) {
+	height := s.epochHeights[i]
+	if cached, ok := s.epochCache.Get(height); ok {
+		return cached.(*Epoch), nil
+	}
+	var blob []byte
+	if uint64(i) < s.frozenEpochs {
+		blob = rawdb.ReadBftFrozenEpoch(s.epochFreezer, uint64(i))
+	} else {
+		blob = rawdb.ReadBftEpoch(s.db, height)
+	}
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("%w: %d", errMissingEpochs, height)
 	}
 	epoch := new(Epoch)
-	if err := epoch.UnmarshalJSON(blob); err != nil {
+	if err := rlp.DecodeBytes(blob, epoch); err != nil {
 		return nil, err
 	}
+	s.epochCache.Add(height, epoch)
 	return epoch, nil
 }
 
@@ -175,33 +607,95 @@ type Epoch struct {
 	StartHeight          uint64
 	ValSet               bft.ValidatorSet
 	LastEpochStartHeight uint64
+	BLSKeys              map[common.Address][]byte // bls key registrations of the validators, only used by bls signatures
+
+	priorityMu         sync.Mutex
+	priorities         bft.ValidatorSet // weighted validators with the proposer priorities of `priorityHeight`
+	priorityHeight     uint64
+	priorityCheckpoint [][]int64 // proposer priorities every priorityCheckpointInterval blocks since the epoch start
+}
+
+// prioritySet returns the weighted validators with the proposer priorities advanced
+// once for every block since the epoch start. the last result is cached because
+// the requested height mostly grows, earlier heights are replayed from the last
+// checkpoint below them.
+func (e *Epoch) prioritySet(height uint64, policy bft.SelectProposerPolicy) bft.ValidatorSet {
+	e.priorityMu.Lock()
+	defer e.priorityMu.Unlock()
+
+	if height < e.StartHeight {
+		height = e.StartHeight
+	}
+	if e.priorities == nil || e.priorities.Policy() != policy {
+		// the epoch validators are already validated, the copy can't fail
+		e.priorities, _ = validator.NewWeightedSet(e.ValSet.AddressList(), validator.Powers(e.ValSet), policy)
+		e.priorityHeight = e.StartHeight
+		e.priorityCheckpoint = [][]int64{validator.ProposerPriorities(e.priorities)}
+	}
+	if height < e.priorityHeight {
+		// every checkpoint up to the cached height is recorded
+		i := (height - e.StartHeight) / priorityCheckpointInterval
+		validator.SetProposerPriorities(e.priorities, e.priorityCheckpoint[i])
+		e.priorityHeight = e.StartHeight + i*priorityCheckpointInterval
+	}
+	for e.priorityHeight < height {
+		steps := priorityCheckpointInterval - (e.priorityHeight-e.StartHeight)%priorityCheckpointInterval
+		if steps > height-e.priorityHeight {
+			steps = height - e.priorityHeight
+		}
+		validator.IncrementProposerPriority(e.priorities, steps)
+		e.priorityHeight += steps
+
+		offset := e.priorityHeight - e.StartHeight
+		if offset%priorityCheckpointInterval == 0 && offset/priorityCheckpointInterval == uint64(len(e.priorityCheckpoint)) {
+			e.priorityCheckpoint = append(e.priorityCheckpoint, validator.ProposerPriorities(e.priorities))
+		}
+	}
+	return e.priorities.Copy()
 }
 
 func (e *Epoch) Copy() *Epoch {
-	return &Epoch{
+	cpy := &Epoch{
 		StartHeight:          e.StartHeight,
 		ValSet:               e.ValSet.Copy(),
 		LastEpochStartHeight: e.LastEpochStartHeight,
 	}
+	if e.BLSKeys != nil {
+		cpy.BLSKeys = make(map[common.Address][]byte, len(e.BLSKeys))
+		for addr, key := range e.BLSKeys {
+			cpy.BLSKeys[addr] = common.CopyBytes(key)
+		}
+	}
+	return cpy
 }
 
 func (e *Epoch) String() string {
-	return fmt.Sprintf("{StartHeight: %d, LastStartHeight: %d, Valset: %v, Size: %d}",
-		e.StartHeight, e.LastEpochStartHeight, e.ValSet.AddressList(), e.ValSet.Size())
+	return fmt.Sprintf("{StartHeight: %d, LastStartHeight: %d, Valset: %v, Powers: %v, BLSKeys: %d, Size: %d}",
+		e.StartHeight, e.LastEpochStartHeight, e.ValSet.AddressList(), validator.Powers(e.ValSet), len(e.BLSKeys), e.ValSet.Size())
 }
 
 type epochJSON struct {
-	StartHeight          uint64           `json:"start_height"`
-	Validators           []common.Address `json:"validators"`
-	LastEpochStartHeight uint64           `json:"last_epoch_start_height"`
+	StartHeight          uint64                           `json:"start_height"`
+	Validators           []common.Address                 `json:"validators"`
+	Powers               []uint64                         `json:"powers,omitempty"`
+	LastEpochStartHeight uint64                           `json:"last_epoch_start_height"`
+	BLSKeys              map[common.Address]hexutil.Bytes `json:"bls_keys,omitempty"`
 }
 
 func (e *Epoch) toJSONStruct() *epochJSON {
-	return &epochJSON{
+	j := &epochJSON{
 		StartHeight:          e.StartHeight,
 		Validators:           e.ValSet.AddressList(),
+		Powers:               validator.Powers(e.ValSet),
 		LastEpochStartHeight: e.LastEpochStartHeight,
 	}
+	if len(e.BLSKeys) > 0 {
+		j.BLSKeys = make(map[common.Address]hexutil.Bytes, len(e.BLSKeys))
+		for addr, key := range e.BLSKeys {
+			j.BLSKeys[addr] = key
+		}
+	}
+	return j
 }
 
 // Unmarshal from a json byte array
@@ -211,9 +705,20 @@ func (e *Epoch) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	valSet, err := newValSet(j.Validators, j.Powers)
+	if err != nil {
+		return err
+	}
 	e.StartHeight = j.StartHeight
-	e.ValSet = newValSet(j.Validators)
+	e.ValSet = valSet
 	e.LastEpochStartHeight = j.LastEpochStartHeight
+	e.BLSKeys = nil
+	if len(j.BLSKeys) > 0 {
+		e.BLSKeys = make(map[common.Address][]byte, len(j.BLSKeys))
+		for addr, key := range j.BLSKeys {
+			e.BLSKeys[addr] = key
+		}
+	}
 	return nil
 }
 
@@ -223,6 +728,66 @@ func (e *Epoch) MarshalJSON() ([]byte, error) {
 	return json.Marshal(j)
 }
 
-func newValSet(list []common.Address) bft.ValidatorSet {
-	return validator.NewSet(list, bft.RoundRobin)
+// epochRLP is the consensus encoding of an epoch, the bls keys are aligned with
+// the validators and empty if not registered.
+type epochRLP struct {
+	StartHeight          uint64
+	LastEpochStartHeight uint64
+	Validators           []common.Address
+	Powers               []uint64
+	BLSKeys              [][]byte
+}
+
+// EncodeRLP implements rlp.Encoder
+func (e *Epoch) EncodeRLP(w io.Writer) error {
+	enc := &epochRLP{
+		StartHeight:          e.StartHeight,
+		LastEpochStartHeight: e.LastEpochStartHeight,
+		Validators:           e.ValSet.AddressList(),
+		Powers:               validator.Powers(e.ValSet),
+	}
+	if len(e.BLSKeys) > 0 {
+		enc.BLSKeys = make([][]byte, len(enc.Validators))
+		for i, addr := range enc.Validators {
+			enc.BLSKeys[i] = e.BLSKeys[addr]
+		}
+	}
+	return rlp.Encode(w, enc)
+}
+
+// DecodeRLP implements rlp.Decoder
+func (e *Epoch) DecodeRLP(stream *rlp.Stream) error {
+	var dec epochRLP
+	if err := stream.Decode(&dec); err != nil {
+		return err
+	}
+	if len(dec.BLSKeys) > 0 && len(dec.BLSKeys) != len(dec.Validators) {
+		return errInvalidEpochValidators
+	}
+	valSet, err := newValSet(dec.Validators, dec.Powers)
+	if err != nil {
+		return err
+	}
+	e.StartHeight = dec.StartHeight
+	e.ValSet = valSet
+	e.LastEpochStartHeight = dec.LastEpochStartHeight
+	e.BLSKeys = nil
+	for i, key := range dec.BLSKeys {
+		if len(key) == 0 {
+			continue
+		}
+		if e.BLSKeys == nil {
+			e.BLSKeys = make(map[common.Address][]byte)
+		}
+		e.BLSKeys[dec.Validators[i]] = key
+	}
+	return nil
+}
+
+// newValSet creates the validators of an epoch, the set is weighted if powers given.
+func newValSet(list []common.Address, powers []uint64) (bft.ValidatorSet, error) {
+	if len(powers) == 0 {
+		return validator.NewSet(list, bft.RoundRobin), nil
+	}
+	return validator.NewWeightedSet(list, powers, bft.RoundRobin)
 }
@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// Tests that the stored epochs are found by height after a restart, are moved to
+// the freezer once finalized and are dropped by rewinds, frozen or not.
+func TestEpochStore(t *testing.T) {
+	db, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	validators := make([]common.Address, 4)
+	for i := range validators {
+		validators[i] = common.BytesToAddress([]byte{byte(i + 1)})
+	}
+	genesis := &types.Header{Number: common.Big0, MixDigest: types.BftDigest}
+	if err := types.BftHeaderFillWithValidators(genesis, validators); err != nil {
+		t.Fatalf("failed to fill genesis validators: %v", err)
+	}
+	if err := core.StoreGenesis(db, genesis); err != nil {
+		t.Fatalf("failed to store genesis epoch: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	config := testNetworkConfig()
+	open := func() *backend {
+		return New(&config, key, db).(*backend)
+	}
+	check := func(engine *backend, sizes map[uint64]int) {
+		t.Helper()
+		for height, size := range sizes {
+			if have := engine.Validators(height).Size(); have != size {
+				t.Errorf("height %d: validator count mismatch: have %d, want %d", height, have, size)
+			}
+		}
+	}
+	// Every epoch drops one validator of the previous epoch
+	engine := open()
+	for i, height := range []uint64{10, 20, 30} {
+		if err := engine.ChangeEpoch(height, validators[:len(validators)-i-1]); err != nil {
+			t.Fatalf("failed to change epoch at %d: %v", height, err)
+		}
+	}
+	sizes := map[uint64]int{0: 4, 9: 4, 10: 3, 19: 3, 20: 2, 29: 2, 30: 1, 100: 1}
+	check(engine, sizes)
+
+	if err := engine.FreezeEpochs(25); err != nil {
+		t.Fatalf("failed to freeze epochs: %v", err)
+	}
+	if engine.frozenEpochs != 3 {
+		t.Fatalf("frozen epoch count mismatch: have %d, want 3", engine.frozenEpochs)
+	}
+	if blob := rawdb.ReadBftEpoch(db, 20); blob != nil {
+		t.Fatalf("frozen epoch left in key-value store")
+	}
+	engine.Close()
+
+	// Reopen the epochs and rewind below the frozen ones
+	engine = open()
+	defer engine.Close()
+	check(engine, sizes)
+
+	if err := engine.RewindEpochs(15); err != nil {
+		t.Fatalf("failed to rewind epochs: %v", err)
+	}
+	if latest := engine.LatestEpoch(); latest != 10 {
+		t.Fatalf("latest epoch mismatch: have %d, want 10", latest)
+	}
+	if engine.frozenEpochs != 2 {
+		t.Fatalf("frozen epoch count mismatch: have %d, want 2", engine.frozenEpochs)
+	}
+	check(engine, map[uint64]int{9: 4, 10: 3, 100: 3})
+}
+
+// Tests that an epoch missing from the database fails the lookups of it's heights,
+// without bringing the node down.
+func TestEpochMissing(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	validators := []common.Address{{1}, {2}, {3}, {4}}
+	genesis := &types.Header{Number: common.Big0, MixDigest: types.BftDigest}
+	if err := types.BftHeaderFillWithValidators(genesis, validators); err != nil {
+		t.Fatalf("failed to fill genesis validators: %v", err)
+	}
+	if err := core.StoreGenesis(db, genesis); err != nil {
+		t.Fatalf("failed to store genesis epoch: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	config := testNetworkConfig()
+	engine := New(&config, key, db).(*backend)
+	for i, height := range []uint64{10, 20} {
+		if err := engine.ChangeEpoch(height, validators[:len(validators)-i-1]); err != nil {
+			t.Fatalf("failed to change epoch at %d: %v", height, err)
+		}
+	}
+	engine.Close()
+
+	rawdb.DeleteBftEpoch(db, 10)
+	engine = New(&config, key, db).(*backend)
+	defer engine.Close()
+
+	if _, err := engine.validators(15); !errors.Is(err, errMissingEpochs) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errMissingEpochs)
+	}
+	if size := engine.Validators(15).Size(); size != 0 {
+		t.Fatalf("validators of a missing epoch: have %d, want 0", size)
+	}
+	if size := engine.Validators(25).Size(); size != 2 {
+		t.Fatalf("validators after the missing epoch: have %d, want 2", size)
+	}
+}
+
+// Tests that the first epoch of a migrated chain starts at the fork block, and is
+// dropped by rewinds below the fork.
+func TestEpochStoreMigrated(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	key, _ := crypto.GenerateKey()
+	config := testNetworkConfig()
+	engine := New(&config, key, db).(*backend)
+	defer engine.Close()
+
+	if size := engine.Validators(100).Size(); size != 0 {
+		t.Fatalf("validators before the fork: have %d, want 0", size)
+	}
+	validators := []common.Address{{1}, {2}, {3}}
+	if err := engine.InitEpoch(100, validators); err != nil {
+		t.Fatalf("failed to init epoch: %v", err)
+	}
+	if err := engine.InitEpoch(100, validators[:1]); err != nil {
+		t.Fatalf("failed to init epoch again: %v", err)
+	}
+	if size := engine.Validators(100).Size(); size != 3 {
+		t.Fatalf("validators of the fork block: have %d, want 3", size)
+	}
+	if first := engine.firstEpoch(); first != 100 {
+		t.Fatalf("first epoch mismatch: have %d, want 100", first)
+	}
+	if err := engine.RewindEpochs(99); err != nil {
+		t.Fatalf("failed to rewind epochs: %v", err)
+	}
+	if size := engine.Validators(100).Size(); size != 3 {
+		t.Fatalf("validators after rewind to the fork parent: have %d, want 3", size)
+	}
+	if err := engine.RewindEpochs(98); err != nil {
+		t.Fatalf("failed to rewind epochs: %v", err)
+	}
+	if size := engine.Validators(100).Size(); size != 0 {
+		t.Fatalf("validators after rewind below the fork: have %d, want 0", size)
+	}
+}
+
+// Tests that the proposer priorities of a weighted epoch don't depend on the order
+// the heights are requested in, the earlier heights being replayed from checkpoints.
+func TestEpochPrioritySet(t *testing.T) {
+	validators := make([]common.Address, 4)
+	for i := range validators {
+		validators[i] = common.BytesToAddress([]byte{byte(i + 1)})
+	}
+	powers := []uint64{1, 3, 5, 7}
+	valSet, err := validator.NewWeightedSet(validators, powers, bft.RoundRobin)
+	if err != nil {
+		t.Fatalf("failed to create weighted set: %v", err)
+	}
+	epoch := &Epoch{StartHeight: 100, ValSet: valSet}
+
+	// replay advances a fresh set from the epoch start
+	replay := func(height uint64) []int64 {
+		set, _ := validator.NewWeightedSet(validators, powers, bft.RoundRobin)
+		validator.IncrementProposerPriority(set, height-epoch.StartHeight)
+		return validator.ProposerPriorities(set)
+	}
+	end := epoch.StartHeight + 3*priorityCheckpointInterval + 10
+	heights := []uint64{end, epoch.StartHeight, end - 1, 50, epoch.StartHeight + priorityCheckpointInterval, epoch.StartHeight + priorityCheckpointInterval - 1}
+	for i := 0; i < 32; i++ {
+		heights = append(heights, epoch.StartHeight+uint64(rand.Int63n(int64(end+priorityCheckpointInterval-epoch.StartHeight))))
+	}
+	var highest uint64
+	for _, height := range heights {
+		if height > highest {
+			highest = height
+		}
+		want := replay(epoch.StartHeight)
+		if height > epoch.StartHeight {
+			want = replay(height)
+		}
+		have := validator.ProposerPriorities(epoch.prioritySet(height, bft.RoundRobin))
+		for i := range want {
+			if have[i] != want[i] {
+				t.Fatalf("height %d: priorities mismatch: have %v, want %v", height, have, want)
+			}
+		}
+	}
+	// Only the checkpoints up to the highest height are kept
+	if have, want := uint64(len(epoch.priorityCheckpoint)), (highest-epoch.StartHeight)/priorityCheckpointInterval+1; have != want {
+		t.Errorf("checkpoint count mismatch: have %d, want %d", have, want)
+	}
+}
@@ -35,4 +35,39 @@ var (
 	errDecodeFailed = errors.New("decode p2p message failed")
 	// errBadProposal
 	errBADProposal = errors.New("bad proposal")
+	// errInvalidVotingChain is returned if an authorization list is attempted to
+	// be modified via out-of-range or non-contiguous headers.
+	errInvalidVotingChain = errors.New("invalid voting chain")
+	// errInvalidVote is returned if a header votes to add an existing validator or
+	// to remove a non validator.
+	errInvalidVote = errors.New("invalid validator vote")
+	// errInvalidBoundaryVote is returned if an epoch boundary header carries a vote.
+	errInvalidBoundaryVote = errors.New("vote on epoch boundary block")
+	// errInvalidEpochValidators is returned if the validators in a header are not
+	// the outcome of the epoch votes.
+	errInvalidEpochValidators = errors.New("invalid epoch validators")
+	// errBLSDisabled is returned if bls keys are requested from a chain which signs
+	// committed seals with ecdsa.
+	errBLSDisabled = errors.New("bls signatures disabled")
+	// errInvalidAncestor is returned if a header is not verified because one of
+	// it's ancestors in the batch failed the verification.
+	errInvalidAncestor = errors.New("invalid ancestor")
+	// errInvalidEpochProof is returned if a header of an epoch proof doesn't change
+	// the validators of the latest known epoch.
+	errInvalidEpochProof = errors.New("invalid epoch proof")
+	// errUnknownEvidence is returned if the requested equivocation evidence is not
+	// stored.
+	errUnknownEvidence = errors.New("unknown evidence")
+	// errVotesDisabled is returned if a vote is proposed while the validators are
+	// read from the validator contract.
+	errVotesDisabled = errors.New("votes disabled by the validator contract")
+	// errWaitTransactions is returned if an empty block is sealed while blocks are
+	// only sealed on demand.
+	errWaitTransactions = errors.New("waiting for transactions")
+	// errUnelectedProposer is returned if the proposer of a block is not elected in
+	// any round of it's height.
+	errUnelectedProposer = errors.New("proposer not elected")
+	// errMissingEpochs is returned if the stored epochs are not found in the
+	// database.
+	errMissingEpochs = errors.New("missing bft epochs")
 )
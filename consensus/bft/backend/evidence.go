@@ -0,0 +1,162 @@
+package backend
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// HandleEvidence implements bft.Backend.HandleEvidence, the evidence is verified
+// against the validators of it's view, persisted and gossiped to the validators.
+// known evidence is ignored.
+func (s *backend) HandleEvidence(ev *bft.Evidence) error {
+	if ev.View == nil || ev.View.Height == nil {
+		return core.ErrInvalidEvidence
+	}
+	s.evidenceMu.Lock()
+	defer s.evidenceMu.Unlock()
+
+	hash := ev.Hash()
+	if rawdb.HasBftEvidence(s.db, hash) {
+		return nil
+	}
+	valSet, err := s.validators(ev.View.Height.Uint64())
+	if err != nil {
+		return err
+	}
+	if err := core.VerifyEvidence(ev, s.signer, valSet); err != nil {
+		return err
+	}
+	blob, err := rlp.EncodeToBytes(ev)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteBftEvidence(s.db, hash, blob)
+	s.logger.Warn("Stored equivocation evidence", "evidence", ev)
+
+	s.gossipEvidence(hash, blob)
+	if s.config.DropEquivocators {
+		s.dropEquivocator(ev.Offender)
+	}
+	return nil
+}
+
+// handleEvidenceMsg decodes the evidence gossiped by a peer and handles it.
+func (s *backend) handleEvidenceMsg(addr common.Address, msg p2p.Msg) error {
+	var blob []byte
+	if err := msg.Decode(&blob); err != nil {
+		return errDecodeFailed
+	}
+	ev := new(bft.Evidence)
+	if err := rlp.DecodeBytes(blob, ev); err != nil {
+		return errDecodeFailed
+	}
+	s.markPeerMessage(addr, ev.Hash())
+	return s.HandleEvidence(ev)
+}
+
+// gossipEvidence sends the evidence to the connected validators which haven't
+// seen it yet.
+func (s *backend) gossipEvidence(hash common.Hash, blob []byte) {
+	if s.broadcaster == nil || s.currentBlock == nil {
+		return
+	}
+	targets := make(map[common.Address]bool)
+	for _, val := range s.Validators(s.currentBlock().NumberU64() + 1).List() {
+		if val.Address() != s.Address() {
+			targets[val.Address()] = true
+		}
+	}
+	for addr, p := range s.broadcaster.FindPeers(targets) {
+		if !s.markPeerMessage(addr, hash) {
+			continue
+		}
+		go p.Send(bftEvidenceMsg, blob)
+	}
+}
+
+// markPeerMessage records that the peer knows the message, it returns false if
+// the peer already knew it.
+func (s *backend) markPeerMessage(addr common.Address, hash common.Hash) bool {
+	var m *lru.ARCCache
+	if ms, ok := s.recentMessages.Get(addr); ok {
+		m, _ = ms.(*lru.ARCCache)
+	} else {
+		m, _ = lru.NewARC(inmemoryMessages)
+		s.recentMessages.Add(addr, m)
+	}
+	if _, ok := m.Get(hash); ok {
+		return false
+	}
+	m.Add(hash, true)
+	return true
+}
+
+// dropEquivocator votes the offender out of the validators. the removal vote is
+// cast in the headers the node proposes, and the offender leaves the validators
+// once the epoch votes pass, like with any other vote. the validators are never
+// changed from local evidence alone, which differs between the nodes.
+func (s *backend) dropEquivocator(offender common.Address) {
+	if s.config.Epoch == 0 {
+		s.logger.Warn("Can't vote out equivocating validator without epochs", "offender", offender)
+		return
+	}
+	if err := s.Propose(offender, false); err != nil {
+		s.logger.Warn("Failed to vote out equivocating validator", "offender", offender, "err", err)
+		return
+	}
+	s.logger.Warn("Voting out equivocating validator", "offender", offender)
+}
+
+// RPCEvidence is the equivocation evidence returned over RPC.
+type RPCEvidence struct {
+	Hash     common.Hash    `json:"hash"`
+	Offender common.Address `json:"offender"`
+	Code     uint64         `json:"code"`
+	Height   uint64         `json:"height"`
+	Round    uint64         `json:"round"`
+	First    hexutil.Bytes  `json:"first"`
+	Second   hexutil.Bytes  `json:"second"`
+}
+
+// EvidenceAPI exposes the equivocation evidence collected by the node.
+type EvidenceAPI struct {
+	bft *backend
+}
+
+// GetEvidence returns the equivocation evidence of the given hash, or all the
+// stored evidence if no hash is given.
+func (api *EvidenceAPI) GetEvidence(hash *common.Hash) ([]*RPCEvidence, error) {
+	var blobs [][]byte
+	if hash == nil {
+		blobs = rawdb.ReadAllBftEvidence(api.bft.db)
+	} else {
+		blob := rawdb.ReadBftEvidence(api.bft.db, *hash)
+		if len(blob) == 0 {
+			return nil, errUnknownEvidence
+		}
+		blobs = [][]byte{blob}
+	}
+	evidence := make([]*RPCEvidence, 0, len(blobs))
+	for _, blob := range blobs {
+		ev := new(bft.Evidence)
+		if err := rlp.DecodeBytes(blob, ev); err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, &RPCEvidence{
+			Hash:     ev.Hash(),
+			Offender: ev.Offender,
+			Code:     ev.Code,
+			Height:   ev.View.Height.Uint64(),
+			Round:    ev.View.Round.Uint64(),
+			First:    ev.First,
+			Second:   ev.Second,
+		})
+	}
+	return evidence, nil
+}
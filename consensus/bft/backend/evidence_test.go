@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signedVote returns the payload of a prepare vote for the digest, signed by key.
+func signedVote(t *testing.T, key *ecdsa.PrivateKey, view *bft.View, digest common.Hash) []byte {
+	t.Helper()
+
+	enc, err := core.Encode(&core.Vote{View: view, Digest: digest})
+	if err != nil {
+		t.Fatalf("failed to encode vote: %v", err)
+	}
+	msg := &bft.Message{Code: core.MsgTypePrepareVote, View: view, Msg: enc, Address: crypto.PubkeyToAddress(key.PublicKey)}
+	data, err := msg.PayloadNoSig()
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	if msg.Signature, err = crypto.Sign(crypto.Keccak256(data), key); err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+	payload, err := msg.Payload()
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	return payload
+}
+
+// Tests that the evidence of an equivocating validator is stored and turned into a
+// removal vote of the proposed headers, without changing the local validators.
+func TestDropEquivocator(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	validators := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		validators[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	db := rawdb.NewMemoryDatabase()
+	config := *bft.DefaultBasicConfig
+	config.Epoch = 8
+	config.DropEquivocators = true
+	engine := New(&config, keys[0], db).(*backend)
+	defer engine.Close()
+
+	if err := engine.ChangeEpoch(0, validators); err != nil {
+		t.Fatalf("failed to store genesis epoch: %v", err)
+	}
+
+	view := &bft.View{Height: big.NewInt(5), Round: big.NewInt(0)}
+	ev := &bft.Evidence{
+		Offender: validators[3],
+		Code:     core.MsgTypePrepareVote.Value(),
+		View:     view,
+		First:    signedVote(t, keys[3], view, common.HexToHash("0x01")),
+		Second:   signedVote(t, keys[3], view, common.HexToHash("0x02")),
+	}
+	if err := engine.HandleEvidence(ev); err != nil {
+		t.Fatalf("failed to handle evidence: %v", err)
+	}
+	if !rawdb.HasBftEvidence(db, ev.Hash()) {
+		t.Fatalf("evidence not stored")
+	}
+	// The offender stays a validator until the epoch votes remove it
+	if latest := engine.LatestEpoch(); latest != 0 {
+		t.Fatalf("epoch changed by local evidence: latest epoch %d", latest)
+	}
+	if _, val := engine.Validators(9).GetByAddress(validators[3]); val == nil {
+		t.Fatalf("offender dropped from the next epoch by local evidence")
+	}
+	if authorize, ok := engine.proposals[validators[3]]; !ok || authorize {
+		t.Fatalf("removal vote mismatch: have %v %v, want removal", authorize, ok)
+	}
+	header := &types.Header{Number: big.NewInt(6), MixDigest: types.BftDigest}
+	if err := types.BftHeaderFillWithValidators(header, nil); err != nil {
+		t.Fatalf("failed to fill header extra: %v", err)
+	}
+	if err := engine.prepareVotes(nil, header); err != nil {
+		t.Fatalf("failed to prepare votes: %v", err)
+	}
+	extra, err := types.ExtractBftExtra(header)
+	if err != nil {
+		t.Fatalf("failed to extract header extra: %v", err)
+	}
+	if extra.Vote == nil || extra.Vote.Candidate != validators[3] || extra.Vote.Authorize {
+		t.Fatalf("header vote mismatch: have %+v, want removal of %x", extra.Vote, validators[3])
+	}
+	// Conflicting messages of a non-validator are no evidence
+	outsider, _ := crypto.GenerateKey()
+	ev = &bft.Evidence{
+		Offender: crypto.PubkeyToAddress(outsider.PublicKey),
+		Code:     core.MsgTypePrepareVote.Value(),
+		View:     view,
+		First:    signedVote(t, outsider, view, common.HexToHash("0x01")),
+		Second:   signedVote(t, outsider, view, common.HexToHash("0x02")),
+	}
+	if err := engine.HandleEvidence(ev); err == nil {
+		t.Fatalf("evidence of a non-validator accepted")
+	}
+	if _, ok := engine.proposals[ev.Offender]; ok {
+		t.Fatalf("non-validator voted out")
+	}
+}
@@ -1,22 +1,24 @@
 package backend
 
 import (
-	"bytes"
-	"io/ioutil"
-	"math/big"
-	"reflect"
+	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/wal"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/p2p"
 	lru "github.com/hashicorp/golang-lru"
 )
 
+// Message codes of the `bft` devp2p protocol, see eth/protocols/bft.
 const (
-	NewBlockMsg = 0x07
-	bftMsg = 0x11
+	bftMsg             = 0x01
+	bftEvidenceMsg     = 0x02
+	bftGetCertifiedMsg = 0x03
+	bftCertifiedMsg    = 0x04
 )
 
 func (s *backend) decode(msg p2p.Msg) ([]byte, common.Hash, error) {
@@ -57,41 +59,36 @@ func (s *backend) HandleMsg(addr common.Address, msg p2p.Msg) (bool, error) {
 			return true, nil
 		}
 		s.knownMessages.Add(hash, true)
+		s.logMessage(wal.Inbound, addr, data)
 
 		go s.eventMux.Post(bft.MessageEvent{
 			Payload: data,
 		})
 		return true, nil
 	}
-	if msg.Code == NewBlockMsg && s.coreStarted && s.core.IsProposer() { // eth.NewBlockMsg: import cycle
-		// this case is to safeguard the race of similar block which gets propagated from other node while this node is proposing
-		// as p2p.Msg can only be decoded once (get EOF for any subsequence read), we need to make sure the payload is restored after we decode it
-		s.logger.Debug("proposer received NewBlockMsg", "size", msg.Size, "payload.type", reflect.TypeOf(msg.Payload), "sender", addr)
-		if reader, ok := msg.Payload.(*bytes.Reader); ok {
-			payload, err := ioutil.ReadAll(reader)
-			if err != nil {
-				return true, err
-			}
-			reader.Reset(payload)       // ready to be decoded
-			defer reader.Reset(payload) // restore so main eth/handler can decode
-			var request struct {        // this has to be same as eth/protocol.go#newBlockData as we are reading NewBlockMsg
-				Block *types.Block
-				TD    *big.Int
-			}
-			if err := msg.Decode(&request); err != nil {
-				s.logger.Debug("Proposer was unable to decode the NewBlockMsg", "error", err)
-				return false, nil
-			}
-			newRequestedBlock := request.Block
-			if newRequestedBlock.Header().MixDigest == types.BftDigest && s.core.IsCurrentProposal(newRequestedBlock.Hash()) {
-				s.logger.Debug("Proposer already proposed this block", "hash", newRequestedBlock.Hash(), "sender", addr)
-				return true, nil
-			}
-		}
+	if msg.Code == bftEvidenceMsg {
+		return true, s.handleEvidenceMsg(addr, msg)
+	}
+	if msg.Code == bftGetCertifiedMsg {
+		return true, s.handleGetCertifiedMsg(addr, msg)
+	}
+	if msg.Code == bftCertifiedMsg {
+		return true, s.handleCertifiedMsg(addr, msg)
 	}
 	return false, nil
 }
 
+// SignHandshake implements consensus.Handler.SignHandshake
+func (s *backend) SignHandshake(data []byte) ([]byte, error) {
+	return s.signer.SignHandshake(data)
+}
+
+// IsValidator implements consensus.Handler.IsValidator
+func (s *backend) IsValidator(height uint64, address common.Address) bool {
+	_, val := s.Validators(height).GetByAddress(address)
+	return val != nil
+}
+
 // SetBroadcaster implements consensus.Handler.SetBroadcaster
 func (s *backend) SetBroadcaster(broadcaster consensus.Broadcaster) {
 	s.broadcaster = broadcaster
@@ -103,6 +100,30 @@ func (s *backend) NewChainHead(header *types.Header) error {
 	if !s.coreStarted {
 		return ErrStoppedEngine
 	}
+	s.headMu.Lock()
+	s.headNumber, s.headTime = header.Number.Uint64(), time.Now()
+	s.headMu.Unlock()
+
 	go s.eventMux.Post(bft.FinalCommittedEvent{Header: header})
+	go s.checkCommitted(header)
 	return nil
 }
+
+// checkCommitted counts the blocks committed without the seal of the validator.
+func (s *backend) checkCommitted(header *types.Header) {
+	height := header.Number.Uint64()
+	if height == 0 || !s.IsValidator(height, s.Address()) {
+		return
+	}
+	committers, err := s.signer.Committers(header, s.Validators(height))
+	if err != nil {
+		s.logger.Debug("Failed to retrieve committers", "number", height, "err", err)
+		return
+	}
+	for _, committer := range committers {
+		if committer == s.Address() {
+			return
+		}
+	}
+	atomic.AddUint64(&s.missedVotes, 1)
+}
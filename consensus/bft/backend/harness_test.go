@@ -0,0 +1,814 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var errConflictingBlock = errors.New("conflicting committed block")
+
+// testChain is the in-memory chain of a simulated validator. Blocks of other forks
+// are kept, the chain reorgs to a longer fork unless it reverts final blocks.
+type testChain struct {
+	config *params.ChainConfig
+	node   *testNode
+
+	mu     sync.RWMutex
+	blocks []*types.Block // canonical blocks by number
+	hashes map[common.Hash]*types.Block
+	feed   event.Feed // new chain heads
+}
+
+func newTestChain(genesis *types.Block, node *testNode) *testChain {
+	return &testChain{
+		config: params.TestChainConfig,
+		node:   node,
+		blocks: []*types.Block{genesis},
+		hashes: map[common.Hash]*types.Block{genesis.Hash(): genesis},
+	}
+}
+
+func (c *testChain) Config() *params.ChainConfig { return c.config }
+
+func (c *testChain) CurrentBlock() *types.Block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.blocks[len(c.blocks)-1]
+}
+
+func (c *testChain) CurrentHeader() *types.Header { return c.CurrentBlock().Header() }
+
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block := c.GetBlockByHash(hash); block != nil && block.NumberU64() == number {
+		return block
+	}
+	return nil
+}
+
+func (c *testChain) GetBlockByHash(hash common.Hash) *types.Block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.hashes[hash]
+}
+
+func (c *testChain) GetBlockByNumber(number uint64) *types.Block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if number < uint64(len(c.blocks)) {
+		return c.blocks[number]
+	}
+	return nil
+}
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if block := c.GetBlock(hash, number); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if block := c.GetBlockByNumber(number); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if block := c.GetBlockByHash(hash); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+func (c *testChain) GetTd(hash common.Hash, number uint64) *big.Int {
+	return new(big.Int).SetUint64(number + 1)
+}
+
+// PreExecuteBlock accepts every block, the simulated blocks carry no transactions.
+func (c *testChain) PreExecuteBlock(block *types.Block) error { return nil }
+
+// InsertBlockWithoutSetHead verifies the certified block and keeps it beside the
+// chain.
+func (c *testChain) InsertBlockWithoutSetHead(block *types.Block) error {
+	if c.GetBlockByHash(block.Hash()) != nil {
+		return nil
+	}
+	if c.GetBlockByHash(block.ParentHash()) == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if err := c.node.engine().VerifyHeader(c, block.Header(), true); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.hashes[block.Hash()] = block
+	c.mu.Unlock()
+	return nil
+}
+
+// SetCanonical moves the chain to the stored block, unless it reverts the final
+// blocks.
+func (c *testChain) SetCanonical(head *types.Block) (common.Hash, error) {
+	if c.GetBlockByHash(head.Hash()) == nil {
+		return common.Hash{}, consensus.ErrUnknownAncestor
+	}
+	ok, err := c.link(head, c.node.finalized())
+	if err != nil || !ok {
+		return common.Hash{}, err
+	}
+	c.feed.Send(head)
+	return head.Hash(), nil
+}
+
+// insert verifies the committed block and adds it to the chain. Blocks which are
+// already known are skipped, while a different block at a final height is a
+// conflicting commit.
+func (c *testChain) insert(block *types.Block) error {
+	number := block.NumberU64()
+	if c.GetBlockByHash(block.Hash()) != nil {
+		return nil
+	}
+	known := c.GetBlockByNumber(number)
+	if known != nil && proposalHash(known) == proposalHash(block) {
+		return nil
+	}
+	if c.GetBlockByHash(block.ParentHash()) == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	final := c.node.finalized()
+	if known != nil && number <= final {
+		return fmt.Errorf("%w: number %d, have %v, got %v", errConflictingBlock, number, known.Hash(), block.Hash())
+	}
+	if err := c.node.engine().VerifyHeader(c, block.Header(), true); err != nil {
+		return err
+	}
+	head, err := c.link(block, final)
+	if err != nil || !head {
+		return err
+	}
+	c.feed.Send(block)
+	return nil
+}
+
+// link adds the verified block to the chain, and returns whether it became the
+// head. The chain reorgs to longer forks unless they revert the final blocks.
+func (c *testChain) link(block *types.Block, final uint64) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	number := block.NumberU64()
+	if number <= c.blocks[len(c.blocks)-1].NumberU64() {
+		c.hashes[block.Hash()] = block
+		return false, nil
+	}
+	// collect the blocks of the fork above the common ancestor
+	var fork []*types.Block
+	for ancestor := block; ; {
+		parent := ancestor.NumberU64() - 1
+		if parent < uint64(len(c.blocks)) && c.blocks[parent].Hash() == ancestor.ParentHash() {
+			break
+		}
+		if parent <= final {
+			return false, fmt.Errorf("%w: reorg below final block %d", errConflictingBlock, final)
+		}
+		ancestor = c.hashes[ancestor.ParentHash()]
+		fork = append(fork, ancestor)
+	}
+	c.hashes[block.Hash()] = block
+	c.blocks = c.blocks[:number-uint64(len(fork))]
+	for i := len(fork) - 1; i >= 0; i-- {
+		c.blocks = append(c.blocks, fork[i])
+	}
+	c.blocks = append(c.blocks, block)
+	return true, nil
+}
+
+// proposalHash returns the hash of the block without committed seals, which
+// differ between the commits of the same proposal.
+func proposalHash(block *types.Block) common.Hash {
+	return types.BftFilteredHeader(block.Header(), true).Hash()
+}
+
+// testNode is a simulated validator, it runs the engine on it's own database and
+// chain, and mines on top of every new chain head like the miner worker. The event
+// driven protocol mines on top of the certified blocks instead.
+type testNode struct {
+	net   *testNetwork
+	index int
+	key   *ecdsa.PrivateKey
+	addr  common.Address
+	db    ethdb.Database
+	chain *testChain
+
+	importMu sync.Mutex // Serializes the block imports
+
+	mu      sync.RWMutex
+	backend *backend
+	running bool
+	quit    chan struct{}
+}
+
+func (n *testNode) engine() *backend {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.backend
+}
+
+// height returns the height of the highest block of the node, the blocks certified
+// by the event driven protocol are above the chain head.
+func (n *testNode) height() uint64 {
+	if head := n.engine().CertifiedHead(); head != nil {
+		return head.NumberU64()
+	}
+	return n.chain.CurrentBlock().NumberU64()
+}
+
+// finalized returns the height of the last final block of the node.
+func (n *testNode) finalized() uint64 {
+	if header := n.engine().Finalized(n.chain, n.chain.CurrentHeader()); header != nil {
+		return header.Number.Uint64()
+	}
+	return 0
+}
+
+func (n *testNode) alive() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.running
+}
+
+// start creates a fresh engine on the node database and starts mining, like a
+// validator process (re)starting.
+func (n *testNode) start() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.running {
+		return
+	}
+	config := n.net.config
+	n.backend = New(&config, n.key, n.db).(*backend)
+	n.backend.SetBroadcaster(n)
+	if err := n.backend.Start(n.chain, n.chain.CurrentBlock, n.chain.GetBlockByHash, nil); err != nil {
+		n.net.t.Fatalf("node %d: failed to start engine: %v", n.index, err)
+	}
+	n.running = true
+	n.quit = make(chan struct{})
+
+	n.net.wg.Add(1)
+	go n.mine(n.backend, n.quit)
+}
+
+// stop crashes the node, it's database and chain are kept for a restart. The
+// engine is stopped without holding the lock, it waits for the consensus events
+// being handled, which may import blocks into the chain of the node.
+func (n *testNode) stop() {
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return
+	}
+	close(n.quit)
+	n.running = false
+	engine := n.backend
+	n.mu.Unlock()
+
+	engine.Stop()
+}
+
+// mine seals a new block on every chain head, or on every certified block above
+// it. the sealing of the previous head is aborted like the miner worker does. The
+// chain heads are propagated like the announced blocks, the event driven protocol
+// commits them without the block fetcher.
+func (n *testNode) mine(engine *backend, quit chan struct{}) {
+	defer n.net.wg.Done()
+
+	heads := make(chan *types.Block, 16)
+	sub := n.chain.feed.Subscribe(heads)
+	defer sub.Unsubscribe()
+
+	certified := make(chan *types.Block, 16)
+	certifiedSub := engine.SubscribeCertifiedHead(certified)
+	defer certifiedSub.Unsubscribe()
+
+	var stop chan struct{}
+	seal := func(parent *types.Block) {
+		if stop != nil {
+			close(stop)
+		}
+		stop = make(chan struct{})
+
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number(), common.Big1),
+			GasLimit:   parent.GasLimit(),
+			UncleHash:  nilUncleHash,
+		}
+		if err := engine.Prepare(n.chain, header); err != nil {
+			n.net.t.Logf("node %d: failed to prepare block %d: %v", n.index, header.Number, err)
+			return
+		}
+		block := types.NewBlock(header, nil, nil, nil, trie.NewStackTrie(nil))
+		results := make(chan *types.Block, 1)
+		if err := engine.Seal(n.chain, block, results, stop); err != nil {
+			n.net.t.Logf("node %d: failed to seal block %d: %v", n.index, header.Number, err)
+			return
+		}
+		go func() {
+			if block := <-results; block != nil {
+				n.Enqueue(fetcherID, block)
+			}
+		}()
+	}
+	if head := engine.CertifiedHead(); head != nil {
+		seal(head)
+	} else {
+		seal(n.chain.CurrentBlock())
+	}
+	for {
+		select {
+		case head := <-heads:
+			engine.NewChainHead(head.Header())
+			if n.net.config.Protocol == bft.BFT_PROTOCOL_EVENT_DRIVEN {
+				n.net.propagate(n, head)
+			}
+			if engine.CertifiedHead() == nil {
+				seal(head)
+			}
+		case head := <-certified:
+			seal(head)
+		case <-quit:
+			close(stop)
+			return
+		}
+	}
+}
+
+// importBlock inserts a block received from the sender, missing ancestors are
+// fetched from the sender like the downloader would.
+func (n *testNode) importBlock(block *types.Block, from *testNode) error {
+	n.importMu.Lock()
+	defer n.importMu.Unlock()
+
+	if from != nil {
+		var ancestors []*types.Block
+		for hash := block.ParentHash(); n.chain.GetBlockByHash(hash) == nil; {
+			ancestor := from.chain.GetBlockByHash(hash)
+			if ancestor == nil {
+				return consensus.ErrUnknownAncestor
+			}
+			ancestors = append(ancestors, ancestor)
+			hash = ancestor.ParentHash()
+		}
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			if err := n.chain.insert(ancestors[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return n.chain.insert(block)
+}
+
+// Enqueue implements consensus.Broadcaster, the committed block is imported and
+// propagated to the other nodes.
+func (n *testNode) Enqueue(id string, block *types.Block) {
+	if err := n.importBlock(block, nil); err != nil {
+		n.net.fail(n, block, err)
+		return
+	}
+	n.net.propagate(n, block)
+}
+
+// FindPeers implements consensus.Broadcaster.
+func (n *testNode) FindPeers(targets map[common.Address]bool) map[common.Address]consensus.Peer {
+	peers := make(map[common.Address]consensus.Peer)
+	for _, node := range n.net.nodes {
+		if node != n && targets[node.addr] {
+			peers[node.addr] = &testPeer{from: n, to: node}
+		}
+	}
+	return peers
+}
+
+// FindPeer implements consensus.Broadcaster.
+func (n *testNode) FindPeer(target common.Address) consensus.Peer {
+	for _, node := range n.net.nodes {
+		if node != n && node.addr == target {
+			return &testPeer{from: n, to: node}
+		}
+	}
+	return nil
+}
+
+// PeerCount implements consensus.Broadcaster.
+func (n *testNode) PeerCount() int { return len(n.net.nodes) - 1 }
+
+// testPeer is the connection of a node to another, messages are delivered by the
+// network.
+type testPeer struct {
+	from, to *testNode
+}
+
+// Send implements consensus.Peer.
+func (p *testPeer) Send(msgcode uint64, data interface{}) error {
+	payload, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return err
+	}
+	p.from.net.send(p.from, p.to, msgcode, payload)
+	return nil
+}
+
+// tamperFn rewrites the encoded consensus message sent by a byzantine node, it
+// returns the messages which are sent instead.
+type tamperFn func(node *testNode, payload []byte) [][]byte
+
+// testNetwork connects the simulated validators, and applies the injected faults
+// to the consensus messages between them.
+type testNetwork struct {
+	t      *testing.T
+	config bft.Config
+	nodes  []*testNode
+
+	mu        sync.Mutex
+	rand      *rand.Rand
+	dropRate  float64                     // Probability of dropping a consensus message
+	minDelay  time.Duration               // Minimum delivery delay of the consensus messages
+	maxDelay  time.Duration               // Maximum delivery delay, random delays reorder messages
+	partition map[common.Address]int      // Partition of the nodes, messages don't cross partitions
+	tamper    map[common.Address]tamperFn // Message rewriting of the byzantine nodes
+	failures  []error                     // Conflicting commits and invalid blocks
+	stopped   bool                        // Whether the network is shut down, nothing is delivered anymore
+
+	wg sync.WaitGroup
+}
+
+// testNetworkConfig returns an engine config with short rounds.
+func testNetworkConfig() bft.Config {
+	config := *bft.DefaultBasicConfig
+	config.BlockPeriod = 0
+	config.RequestTimeout = 1000
+	config.Epoch = 0
+	return config
+}
+
+// testChainedConfig returns an event driven engine config with short rounds.
+func testChainedConfig() bft.Config {
+	config := *bft.DefaultEventDrivenConfig
+	config.BlockPeriod = 0
+	config.RequestTimeout = 1000
+	config.Epoch = 0
+	return config
+}
+
+// newTestNetwork creates a network of n validators in the genesis, the nodes are
+// started by start.
+func newTestNetwork(t *testing.T, n int, config bft.Config) *testNetwork {
+	net := &testNetwork{
+		t:         t,
+		config:    config,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		partition: make(map[common.Address]int),
+		tamper:    make(map[common.Address]tamperFn),
+	}
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		net.nodes = append(net.nodes, &testNode{
+			net:  net,
+			key:  key,
+			addr: crypto.PubkeyToAddress(key.PublicKey),
+			db:   rawdb.NewMemoryDatabase(),
+		})
+	}
+	sort.Slice(net.nodes, func(i, j int) bool {
+		return net.nodes[i].addr.Hex() < net.nodes[j].addr.Hex()
+	})
+	genesis := net.genesis()
+	for i, node := range net.nodes {
+		node.index = i
+		node.chain = newTestChain(genesis, node)
+		if err := core.StoreGenesis(node.db, genesis.Header()); err != nil {
+			t.Fatalf("failed to store genesis epoch: %v", err)
+		}
+	}
+	return net
+}
+
+// newFollower creates the engine of a new node outside the network, which only
+// knows the genesis validators, and it's empty chain.
+func (net *testNetwork) newFollower() (*backend, *testChain) {
+	db := rawdb.NewMemoryDatabase()
+	genesis := net.nodes[0].chain.GetBlockByNumber(0)
+	if err := core.StoreGenesis(db, genesis.Header()); err != nil {
+		net.t.Fatalf("failed to store genesis epoch: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	config := net.config
+	return New(&config, key, db).(*backend), newTestChain(genesis, nil)
+}
+
+// genesis returns the genesis block with all nodes as validators.
+func (net *testNetwork) genesis() *types.Block {
+	var validators []common.Address
+	for _, node := range net.nodes {
+		validators = append(validators, node.addr)
+	}
+	header := &types.Header{
+		Number:     common.Big0,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: defaultDifficulty,
+		MixDigest:  types.BftDigest,
+		UncleHash:  nilUncleHash,
+	}
+	if err := types.BftHeaderFillWithValidators(header, validators); err != nil {
+		net.t.Fatalf("failed to fill genesis validators: %v", err)
+	}
+	if net.config.Signature == bft.BLSSignature {
+		extra, _ := types.ExtractBftExtra(header)
+		for _, node := range net.nodes {
+			key, err := snr.DeriveBLSKey(node.key)
+			if err != nil {
+				net.t.Fatalf("failed to derive bls key: %v", err)
+			}
+			extra.BLSKeys = append(extra.BLSKeys, snr.BLSKeyRegistration(key))
+		}
+		if err := writeExtra(header, extra); err != nil {
+			net.t.Fatalf("failed to write genesis extra: %v", err)
+		}
+	}
+	return types.NewBlockWithHeader(header)
+}
+
+// start starts all nodes, and stops them when the test ends.
+func (net *testNetwork) start() {
+	for _, node := range net.nodes {
+		node.start()
+	}
+	net.t.Cleanup(net.stop)
+}
+
+func (net *testNetwork) stop() {
+	for _, node := range net.nodes {
+		node.stop()
+	}
+	net.mu.Lock()
+	net.stopped = true
+	net.mu.Unlock()
+
+	net.wg.Wait()
+}
+
+// track adds a delivery to the wait group, it returns false once the network is
+// shut down.
+func (net *testNetwork) track() bool {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	if net.stopped {
+		return false
+	}
+	net.wg.Add(1)
+	return true
+}
+
+// setDelay delivers the consensus messages after a random delay in the range.
+func (net *testNetwork) setDelay(min, max time.Duration) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.minDelay, net.maxDelay = min, max
+}
+
+// setDropRate drops the consensus messages with the given probability.
+func (net *testNetwork) setDropRate(rate float64) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.dropRate = rate
+}
+
+// setPartition splits the nodes into the given groups, nodes which are not in a
+// group are isolated. nil heals the network.
+func (net *testNetwork) setPartition(groups ...[]int) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+
+	net.partition = make(map[common.Address]int)
+	if groups == nil {
+		return
+	}
+	for _, node := range net.nodes {
+		net.partition[node.addr] = -1 - node.index
+	}
+	for id, group := range groups {
+		for _, i := range group {
+			net.partition[net.nodes[i].addr] = id
+		}
+	}
+}
+
+// setByzantine rewrites the consensus messages sent by the node.
+func (net *testNetwork) setByzantine(i int, fn tamperFn) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.tamper[net.nodes[i].addr] = fn
+}
+
+// connected returns whether messages flow between the nodes.
+func (net *testNetwork) connected(from, to *testNode) bool {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	return net.partition[from.addr] == net.partition[to.addr] && to.alive()
+}
+
+// send delivers a consensus message to the node, the faults are applied to each
+// message independently.
+func (net *testNetwork) send(from, to *testNode, code uint64, payload []byte) {
+	payloads := [][]byte{payload}
+
+	net.mu.Lock()
+	if fn := net.tamper[from.addr]; fn != nil && code == bftMsg {
+		var data []byte
+		if err := rlp.DecodeBytes(payload, &data); err == nil {
+			payloads = payloads[:0]
+			for _, data := range fn(from, data) {
+				enc, _ := rlp.EncodeToBytes(data)
+				payloads = append(payloads, enc)
+			}
+		}
+	}
+	type delivery struct {
+		payload []byte
+		delay   time.Duration
+	}
+	var deliveries []delivery
+	for _, payload := range payloads {
+		if net.rand.Float64() < net.dropRate {
+			continue
+		}
+		delay := net.minDelay
+		if net.maxDelay > net.minDelay {
+			delay += time.Duration(net.rand.Int63n(int64(net.maxDelay - net.minDelay)))
+		}
+		deliveries = append(deliveries, delivery{payload, delay})
+	}
+	net.mu.Unlock()
+
+	for _, d := range deliveries {
+		d := d
+		if !net.track() {
+			return
+		}
+		time.AfterFunc(d.delay, func() {
+			defer net.wg.Done()
+			if !net.connected(from, to) {
+				return
+			}
+			msg := p2p.Msg{Code: code, Size: uint32(len(d.payload)), Payload: bytes.NewReader(d.payload)}
+			to.engine().HandleMsg(from.addr, msg)
+		})
+	}
+}
+
+// propagate sends the committed block to the other nodes, blocks are not dropped
+// but don't cross partitions.
+func (net *testNetwork) propagate(from *testNode, block *types.Block) {
+	for _, node := range net.nodes {
+		if node == from {
+			continue
+		}
+		node := node
+		if !net.track() {
+			return
+		}
+		go func() {
+			defer net.wg.Done()
+			if !net.connected(from, node) {
+				return
+			}
+			if err := node.importBlock(block, from); err != nil {
+				net.fail(node, block, err)
+			}
+		}()
+	}
+}
+
+// fail records a block which the node rejected, conflicting commits break the
+// safety of the network.
+func (net *testNetwork) fail(node *testNode, block *types.Block, err error) {
+	if errors.Is(err, consensus.ErrUnknownAncestor) {
+		return
+	}
+	net.mu.Lock()
+	defer net.mu.Unlock()
+	net.failures = append(net.failures, fmt.Errorf("node %d: block %d %v: %w", node.index, block.NumberU64(), block.Hash(), err))
+}
+
+// waitHeight waits until the given nodes reach the height, or fails the test after
+// the timeout.
+func (net *testNetwork) waitHeight(height uint64, timeout time.Duration, nodes ...int) {
+	net.t.Helper()
+	net.wait("reach height", height, timeout, (*testNode).height, nodes)
+}
+
+// waitFinalized waits until the given nodes finalize the height, or fails the test
+// after the timeout.
+func (net *testNetwork) waitFinalized(height uint64, timeout time.Duration, nodes ...int) {
+	net.t.Helper()
+	net.wait("finalize height", height, timeout, (*testNode).finalized, nodes)
+}
+
+func (net *testNetwork) wait(what string, height uint64, timeout time.Duration, heightFn func(*testNode) uint64, nodes []int) {
+	net.t.Helper()
+
+	if nodes == nil {
+		for i := range net.nodes {
+			nodes = append(nodes, i)
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		reached := true
+		for _, i := range nodes {
+			if heightFn(net.nodes[i]) < height {
+				reached = false
+			}
+		}
+		if reached {
+			return
+		}
+		if time.Now().After(deadline) {
+			net.t.Fatalf("nodes %v didn't %s %d in %v, heights %v", nodes, what, height, timeout, net.heights())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// heights returns the height of every node.
+func (net *testNetwork) heights() []uint64 {
+	heights := make([]uint64, len(net.nodes))
+	for i, node := range net.nodes {
+		heights[i] = node.height()
+	}
+	return heights
+}
+
+// finalizedHeights returns the finalized height of every node.
+func (net *testNetwork) finalizedHeights() []uint64 {
+	heights := make([]uint64, len(net.nodes))
+	for i, node := range net.nodes {
+		heights[i] = node.finalized()
+	}
+	return heights
+}
+
+// checkSafety fails the test if the nodes finalized different blocks at the same
+// height, or rejected a committed block.
+func (net *testNetwork) checkSafety() {
+	net.t.Helper()
+
+	net.mu.Lock()
+	for _, err := range net.failures {
+		net.t.Error(err)
+	}
+	net.mu.Unlock()
+
+	for number := uint64(1); ; number++ {
+		var (
+			hash  common.Hash
+			found bool
+		)
+		for _, node := range net.nodes {
+			block := node.chain.GetBlockByNumber(number)
+			if block == nil || number > node.finalized() {
+				continue
+			}
+			if found && proposalHash(block) != hash {
+				net.t.Fatalf("conflicting commits at height %d: %v and %v", number, hash, proposalHash(block))
+			}
+			hash, found = proposalHash(block), true
+		}
+		if !found {
+			return
+		}
+	}
+}
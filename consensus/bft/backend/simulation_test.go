@@ -0,0 +1,394 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that a healthy network of validators commits the same blocks.
+func TestSimulationHealthy(t *testing.T) {
+	net := newTestNetwork(t, 4, testNetworkConfig())
+	net.start()
+
+	net.waitHeight(5, 30*time.Second)
+	net.checkSafety()
+}
+
+// Tests that the committed seals aggregated with bls are accepted by all nodes.
+func TestSimulationBLS(t *testing.T) {
+	config := testNetworkConfig()
+	config.Signature = bft.BLSSignature
+	net := newTestNetwork(t, 4, config)
+	net.start()
+
+	net.waitHeight(3, 30*time.Second)
+	net.checkSafety()
+}
+
+// Tests that delayed and reordered messages don't break consensus.
+func TestSimulationDelayReorder(t *testing.T) {
+	net := newTestNetwork(t, 4, testNetworkConfig())
+	net.setDelay(0, 50*time.Millisecond)
+	net.start()
+
+	net.waitHeight(4, 60*time.Second)
+	net.checkSafety()
+}
+
+// Tests that the network recovers from dropped messages with round changes.
+func TestSimulationDrops(t *testing.T) {
+	net := newTestNetwork(t, 4, testNetworkConfig())
+	net.setDropRate(0.05)
+	net.start()
+
+	net.waitHeight(3, 90*time.Second)
+	net.checkSafety()
+}
+
+// Tests that the network makes progress without a crashed validator, and that the
+// validator catches up after a restart.
+func TestSimulationCrash(t *testing.T) {
+	net := newTestNetwork(t, 4, testNetworkConfig())
+	net.start()
+	net.waitHeight(2, 30*time.Second)
+
+	net.nodes[3].stop()
+	height := net.nodes[0].chain.CurrentBlock().NumberU64()
+	net.waitHeight(height+3, 60*time.Second, 0, 1, 2)
+
+	net.nodes[3].start()
+	height = net.nodes[0].chain.CurrentBlock().NumberU64()
+	net.waitHeight(height+2, 60*time.Second)
+	net.checkSafety()
+}
+
+// Tests that no partition without a quorum commits blocks, and that the network
+// resumes once it heals.
+func TestSimulationPartition(t *testing.T) {
+	net := newTestNetwork(t, 4, testNetworkConfig())
+	net.start()
+	net.waitHeight(2, 30*time.Second)
+
+	net.setPartition([]int{0, 1}, []int{2, 3})
+	time.Sleep(time.Second)
+	heights := net.heights()
+	time.Sleep(3 * time.Second)
+	for i, height := range net.heights() {
+		if height != heights[i] {
+			t.Fatalf("node %d committed without quorum: height %d -> %d", i, heights[i], height)
+		}
+	}
+	net.setPartition()
+	net.waitHeight(heights[0]+2, 90*time.Second)
+	net.checkSafety()
+}
+
+// Tests that a validator signing conflicting votes neither halts nor forks the
+// network, and that the proposers keep the evidence.
+func TestSimulationByzantine(t *testing.T) {
+	net := newTestNetwork(t, 4, testNetworkConfig())
+	net.setByzantine(3, equivocateVotes)
+	net.start()
+
+	net.waitHeight(4, 60*time.Second)
+	net.checkSafety()
+
+	for _, node := range net.nodes[:3] {
+		if len(rawdb.ReadAllBftEvidence(node.db)) > 0 {
+			return
+		}
+	}
+	t.Fatal("no evidence of the equivocating validator")
+}
+
+// equivocateVotes sends a vote for another digest before each vote of the node.
+func equivocateVotes(node *testNode, payload []byte) [][]byte {
+	msg := new(bft.Message)
+	if err := msg.FromPayload(payload, nil); err != nil {
+		return [][]byte{payload}
+	}
+	switch msg.Code {
+	case core.MsgTypePrepareVote, core.MsgTypePreCommitVote, core.MsgTypeCommitVote:
+	default:
+		return [][]byte{payload}
+	}
+	var vote *core.Vote
+	if err := msg.Decode(&vote); err != nil {
+		return [][]byte{payload}
+	}
+	vote.Digest = crypto.Keccak256Hash(vote.Digest.Bytes())
+	enc, err := core.Encode(vote)
+	if err != nil {
+		return [][]byte{payload}
+	}
+	msg.Msg = enc
+	data, err := msg.PayloadNoSig()
+	if err != nil {
+		return [][]byte{payload}
+	}
+	if msg.Signature, err = crypto.Sign(crypto.Keccak256(data), node.key); err != nil {
+		return [][]byte{payload}
+	}
+	conflicting, err := msg.Payload()
+	if err != nil {
+		return [][]byte{payload}
+	}
+	return [][]byte{conflicting, payload}
+}
+
+// changeEpoch votes the last validator out of the started network, and returns
+// the headers up to the first one of the next epoch with the index of the epoch
+// change header. The network is stopped.
+func changeEpoch(net *testNetwork) (common.Address, []*types.Header, int) {
+	candidate := net.nodes[len(net.nodes)-1].addr
+	for _, node := range net.nodes[:len(net.nodes)-1] {
+		api := &API{chain: node.chain, bft: node.engine()}
+		if err := api.Propose(candidate, false, nil); err != nil {
+			net.t.Fatalf("failed to propose candidate: %v", err)
+		}
+	}
+	// Wait for the epoch change and a header of the next epoch
+	var (
+		headers []*types.Header
+		change  = -1
+	)
+	for number := uint64(1); change < 0 || len(headers) < change+2; number++ {
+		if number > 5*net.config.Epoch {
+			net.t.Fatal("validators didn't change in 5 epochs")
+		}
+		net.waitHeight(number, 60*time.Second, 0)
+		header := net.nodes[0].chain.GetHeaderByNumber(number)
+		if extra, _ := types.ExtractBftExtra(header); change < 0 && len(extra.Validators) > 0 {
+			change = len(headers)
+		}
+		headers = append(headers, header)
+	}
+	net.stop()
+	return candidate, headers, change
+}
+
+// Tests that a new node verifies the chain epoch by epoch from the genesis
+// validators, and that the seals of an epoch change are verified even if the
+// caller skips the seal checks.
+func TestSimulationEpochSync(t *testing.T) {
+	config := testNetworkConfig()
+	config.Epoch = 8
+	net := newTestNetwork(t, 4, config)
+	net.start()
+
+	candidate, headers, change := changeEpoch(net)
+
+	engine, chain := net.newFollower()
+	_, results := engine.VerifyHeaders(chain, headers, nil)
+	for i := range headers {
+		if err := <-results; err != nil {
+			t.Fatalf("header %d: verification failed: %v", headers[i].Number, err)
+		}
+	}
+	last := headers[len(headers)-1].Number.Uint64()
+	if _, val := engine.Validators(last).GetByAddress(candidate); val != nil {
+		t.Fatalf("candidate still a validator at height %d", last)
+	}
+	// Strip the committed seals of the epoch change, it must be rejected
+	forged := types.CopyHeader(headers[change])
+	extra, _ := types.ExtractBftExtra(forged)
+	extra.CommittedSeal = nil
+	if err := writeExtra(forged, extra); err != nil {
+		t.Fatalf("failed to write extra: %v", err)
+	}
+	engine, chain = net.newFollower()
+	batch := append(append([]*types.Header{}, headers[:change]...), forged)
+	_, results = engine.VerifyHeaders(chain, batch, make([]bool, len(batch)))
+	for i := 0; i < change; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("header %d: verification failed: %v", batch[i].Number, err)
+		}
+	}
+	if err := <-results; err == nil {
+		t.Fatal("epoch change without committed seals accepted")
+	}
+}
+
+// Tests that a node which only knows the genesis validators learns the validators
+// of the next epochs from an epoch proof, and rejects forged proofs.
+func TestSimulationEpochProof(t *testing.T) {
+	config := testNetworkConfig()
+	config.Epoch = 8
+	net := newTestNetwork(t, 4, config)
+	net.start()
+
+	candidate, headers, change := changeEpoch(net)
+	server := net.nodes[0]
+	proof := server.engine().EpochProof(server.chain, 0, 16)
+	if len(proof) != 1 || proof[0].Hash() != headers[change].Hash() {
+		t.Fatalf("epoch proof mismatch: have %d headers, want epoch change %d", len(proof), headers[change].Number)
+	}
+	if proof := server.engine().EpochProof(server.chain, server.engine().LatestEpoch(), 16); len(proof) != 0 {
+		t.Fatalf("epoch proof after the latest epoch: have %d headers, want none", len(proof))
+	}
+	engine, _ := net.newFollower()
+	if err := engine.VerifyEpochProof(0, proof); err != nil {
+		t.Fatalf("failed to verify epoch proof: %v", err)
+	}
+	last := headers[len(headers)-1].Number.Uint64()
+	if engine.LatestEpoch() != last {
+		t.Fatalf("latest epoch mismatch: have %d, want %d", engine.LatestEpoch(), last)
+	}
+	if _, val := engine.Validators(last).GetByAddress(candidate); val != nil {
+		t.Fatalf("candidate still a validator at height %d", last)
+	}
+	// A reply to a request sent before the epoch was proven is still valid, but the
+	// epochs before the requested one are rejected
+	if err := engine.VerifyEpochProof(0, proof); err != nil {
+		t.Fatalf("failed to verify known epoch proof: %v", err)
+	}
+	if engine.LatestEpoch() != last {
+		t.Fatalf("latest epoch mismatch: have %d, want %d", engine.LatestEpoch(), last)
+	}
+	if err := engine.VerifyEpochProof(engine.LatestEpoch(), proof); err != errInvalidEpochProof {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidEpochProof)
+	}
+	// Headers without validators or committed seals don't prove an epoch
+	engine, _ = net.newFollower()
+	if err := engine.VerifyEpochProof(0, headers[:1]); err != errInvalidEpochProof {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidEpochProof)
+	}
+	forged := types.CopyHeader(proof[0])
+	extra, _ := types.ExtractBftExtra(forged)
+	extra.CommittedSeal = nil
+	if err := writeExtra(forged, extra); err != nil {
+		t.Fatalf("failed to write extra: %v", err)
+	}
+	if err := engine.VerifyEpochProof(0, []*types.Header{forged}); err == nil {
+		t.Fatal("epoch proof without committed seals accepted")
+	}
+	// Committed seals truncated below the quorum don't prove an epoch either
+	truncated := types.CopyHeader(proof[0])
+	extra, _ = types.ExtractBftExtra(truncated)
+	extra.CommittedSeal = extra.CommittedSeal[:1]
+	if err := writeExtra(truncated, extra); err != nil {
+		t.Fatalf("failed to write extra: %v", err)
+	}
+	if err := engine.VerifyEpochProof(0, []*types.Header{truncated}); err == nil {
+		t.Fatal("epoch proof with truncated committed seals accepted")
+	}
+	if engine.LatestEpoch() != 0 {
+		t.Fatalf("forged epoch stored at height %d", engine.LatestEpoch())
+	}
+	// The proven epochs must be in ascending order
+	if err := engine.VerifyEpochProof(0, []*types.Header{proof[0], proof[0]}); err != errInvalidEpochProof {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidEpochProof)
+	}
+	if engine.LatestEpoch() != last {
+		t.Fatalf("latest epoch mismatch: have %d, want %d", engine.LatestEpoch(), last)
+	}
+}
+
+// Tests that a healthy network of event driven validators finalizes the same
+// blocks.
+func TestChainedSimulationHealthy(t *testing.T) {
+	net := newTestNetwork(t, 4, testChainedConfig())
+	net.start()
+
+	net.waitFinalized(5, 30*time.Second)
+	net.checkSafety()
+}
+
+// Tests that the event driven quorum certs aggregated with bls are accepted by all
+// nodes.
+func TestChainedSimulationBLS(t *testing.T) {
+	config := testChainedConfig()
+	config.Signature = bft.BLSSignature
+	net := newTestNetwork(t, 4, config)
+	net.start()
+
+	net.waitFinalized(3, 30*time.Second)
+	net.checkSafety()
+}
+
+// Tests that delayed and reordered messages don't break the event driven protocol.
+func TestChainedSimulationDelayReorder(t *testing.T) {
+	net := newTestNetwork(t, 4, testChainedConfig())
+	net.setDelay(0, 50*time.Millisecond)
+	net.start()
+
+	net.waitFinalized(4, 60*time.Second)
+	net.checkSafety()
+}
+
+// Tests that the event driven protocol recovers from dropped messages with round
+// changes.
+func TestChainedSimulationDrops(t *testing.T) {
+	net := newTestNetwork(t, 4, testChainedConfig())
+	net.setDropRate(0.05)
+	net.start()
+
+	net.waitFinalized(3, 90*time.Second)
+	net.checkSafety()
+}
+
+// Tests that the event driven protocol certifies blocks without a crashed
+// validator, and that the validator catches up after a restart. Blocks are not
+// finalized meanwhile, the votes sent to the crashed leader break every three-chain
+// of the round robin.
+func TestChainedSimulationCrash(t *testing.T) {
+	net := newTestNetwork(t, 4, testChainedConfig())
+	net.start()
+	net.waitFinalized(2, 30*time.Second)
+
+	net.nodes[3].stop()
+	height := net.nodes[0].height()
+	net.waitHeight(height+3, 60*time.Second, 0, 1, 2)
+
+	net.nodes[3].start()
+	height = net.nodes[0].height()
+	net.waitFinalized(height+2, 60*time.Second)
+	net.checkSafety()
+}
+
+// Tests that no partition without a quorum finalizes blocks in the event driven
+// protocol, and that the network resumes once it heals. Blocks certified before
+// the partition may still be committed by the nodes which missed them.
+func TestChainedSimulationPartition(t *testing.T) {
+	net := newTestNetwork(t, 4, testChainedConfig())
+	net.start()
+	net.waitFinalized(2, 30*time.Second)
+
+	net.setPartition([]int{0, 1}, []int{2, 3})
+	time.Sleep(time.Second)
+	heights := net.finalizedHeights()
+	time.Sleep(3 * time.Second)
+	for i, height := range net.finalizedHeights() {
+		if height != heights[i] {
+			t.Fatalf("node %d finalized without quorum: height %d -> %d", i, heights[i], height)
+		}
+	}
+	net.setPartition()
+	net.waitFinalized(heights[0]+2, 90*time.Second)
+	net.checkSafety()
+}
+
+// Tests that an equivocating validator neither halts nor forks the event driven
+// protocol, and that the next leaders keep the evidence.
+func TestChainedSimulationByzantine(t *testing.T) {
+	net := newTestNetwork(t, 4, testChainedConfig())
+	net.setByzantine(3, equivocateVotes)
+	net.start()
+
+	net.waitFinalized(4, 60*time.Second)
+	net.checkSafety()
+
+	for _, node := range net.nodes[:3] {
+		if len(rawdb.ReadAllBftEvidence(node.db)) > 0 {
+			return
+		}
+	}
+	t.Fatal("no evidence of the equivocating validator")
+}
@@ -0,0 +1,364 @@
+package backend
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// votes is the state of the validator votes cast since the last epoch boundary up
+// to a given block. every validator keeps at most one vote per candidate, a later
+// vote replaces the earlier one.
+type votes struct {
+	Number uint64                                     // Block number where the votes were tallied
+	Hash   common.Hash                                // Block hash where the votes were tallied
+	Votes  map[common.Address]map[common.Address]bool // Voter -> candidate -> authorize
+	Keys   map[common.Address][]byte                  // Candidate -> bls key registration of the last authorize vote
+}
+
+func newVotes(number uint64, hash common.Hash) *votes {
+	return &votes{
+		Number: number,
+		Hash:   hash,
+		Votes:  make(map[common.Address]map[common.Address]bool),
+		Keys:   make(map[common.Address][]byte),
+	}
+}
+
+func (v *votes) copy() *votes {
+	cpy := newVotes(v.Number, v.Hash)
+	for voter, cast := range v.Votes {
+		cpy.Votes[voter] = make(map[common.Address]bool, len(cast))
+		for candidate, authorize := range cast {
+			cpy.Votes[voter][candidate] = authorize
+		}
+	}
+	for candidate, key := range v.Keys {
+		cpy.Keys[candidate] = key
+	}
+	return cpy
+}
+
+// apply creates a new votes state by applying the votes of the given headers,
+// which must be continuous and follow the current state.
+func (v *votes) apply(s *backend, headers []*types.Header) (*votes, error) {
+	if len(headers) == 0 {
+		return v, nil
+	}
+	for i := 0; i < len(headers)-1; i++ {
+		if headers[i+1].Number.Uint64() != headers[i].Number.Uint64()+1 {
+			return nil, errInvalidVotingChain
+		}
+	}
+	if headers[0].Number.Uint64() != v.Number+1 {
+		return nil, errInvalidVotingChain
+	}
+	next := v.copy()
+	for _, header := range headers {
+		extra, err := types.ExtractBftExtra(header)
+		if err != nil {
+			return nil, err
+		}
+		if extra.Vote != nil {
+			voter, err := s.Author(header)
+			if err != nil {
+				return nil, err
+			}
+			if next.Votes[voter] == nil {
+				next.Votes[voter] = make(map[common.Address]bool)
+			}
+			next.Votes[voter][extra.Vote.Candidate] = extra.Vote.Authorize
+			if extra.Vote.Authorize && len(extra.Vote.BLSKey) > 0 {
+				next.Keys[extra.Vote.Candidate] = extra.Vote.BLSKey
+			}
+		}
+	}
+	last := headers[len(headers)-1]
+	next.Number, next.Hash = last.Number.Uint64(), last.Hash()
+	return next, nil
+}
+
+// result returns the validators of the next epoch after applying every candidate
+// which got the votes of more than half of the current voting power, or nil if the
+// validator set does not change. validators added to a weighted set get the
+// default power. the candidates are applied in the order of their addresses, the
+// last validator can't be removed.
+func (v *votes) result(valSet bft.ValidatorSet) bft.ValidatorSet {
+	tally := make(map[common.Address]uint64)
+	for voter, cast := range v.Votes {
+		_, val := valSet.GetByAddress(voter)
+		if val == nil {
+			continue
+		}
+		for candidate, authorize := range cast {
+			if validVote(valSet, candidate, authorize) {
+				tally[candidate] += val.Power()
+			}
+		}
+	}
+	var passed []common.Address
+	for candidate, power := range tally {
+		if power*2 > valSet.TotalPower() {
+			passed = append(passed, candidate)
+		}
+	}
+	sort.Slice(passed, func(i, j int) bool {
+		return bytes.Compare(passed[i][:], passed[j][:]) < 0
+	})
+	next := valSet.Copy()
+	changed := false
+	for _, candidate := range passed {
+		if _, val := valSet.GetByAddress(candidate); val == nil {
+			changed = next.AddValidator(candidate) || changed
+		} else if next.Size() > 1 {
+			changed = next.RemoveValidator(candidate) || changed
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return next
+}
+
+// Propose implements consensus.Governance, the vote is cast without bls key so the
+// candidates added this way can't commit blocks with bls signatures.
+func (s *backend) Propose(candidate common.Address, authorize bool) error {
+	if s.hasValidatorContract() {
+		return errVotesDisabled
+	}
+	s.sigMu.Lock()
+	defer s.sigMu.Unlock()
+
+	s.proposals[candidate] = authorize
+	delete(s.proposalKeys, candidate)
+	return nil
+}
+
+// validVote returns whether it makes sense to cast the specified vote in the
+// given validator set, e.g. don't try to add an already authorized validator.
+func validVote(valSet bft.ValidatorSet, candidate common.Address, authorize bool) bool {
+	_, val := valSet.GetByAddress(candidate)
+	return (val != nil && !authorize) || (val == nil && authorize)
+}
+
+// isEpochBoundary returns whether the votes are tallied at the given height.
+// votes are disabled if the epoch length is not configured.
+func (s *backend) isEpochBoundary(number uint64) bool {
+	return s.config.Epoch > 0 && number > 0 && number%s.config.Epoch == 0
+}
+
+// votes retrieves the votes state at the given block, the state is rebuilt from
+// the last epoch boundary if it's not cached.
+func (s *backend) votes(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*votes, error) {
+	var (
+		headers []*types.Header
+		state   *votes
+	)
+	for state == nil {
+		if cached, ok := s.recents.Get(hash); ok {
+			state = cached.(*votes)
+			break
+		}
+		// Votes are cleared after every epoch boundary, and start with the first
+		// epoch of a migrated chain
+		if number == 0 || s.isEpochBoundary(number) || number+1 == s.firstEpoch() {
+			state = newVotes(number, hash)
+			break
+		}
+		var header *types.Header
+		if len(parents) > 0 {
+			header = parents[len(parents)-1]
+			if header.Hash() != hash || header.Number.Uint64() != number {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			parents = parents[:len(parents)-1]
+		} else {
+			header = chain.GetHeader(hash, number)
+			if header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+		}
+		headers = append(headers, header)
+		number, hash = number-1, header.ParentHash
+	}
+	for i := 0; i < len(headers)/2; i++ {
+		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
+	}
+	state, err := state.apply(s, headers)
+	if err != nil {
+		return nil, err
+	}
+	s.recents.Add(state.Hash, state)
+	return state, nil
+}
+
+// nextEpochValidators tallies the votes of the epoch ending with the given header
+// and returns the validators, powers and bls keys of the next epoch, or nil if
+// nothing changes. the bls keys are only returned with bls signatures, validators
+// keep their registered key and the added ones take the key of their votes.
+func (s *backend) nextEpochValidators(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) ([]common.Address, []uint64, [][]byte, error) {
+	number := header.Number.Uint64()
+	state, err := s.votes(chain, number-1, header.ParentHash, parents)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	epoch, err := s.epochAt(number)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	next := state.result(s.withPolicy(epoch, number))
+	if next == nil {
+		return nil, nil, nil, nil
+	}
+	var keys [][]byte
+	if s.config.Signature == bft.BLSSignature {
+		for _, addr := range next.AddressList() {
+			if key, ok := epoch.BLSKeys[addr]; ok {
+				keys = append(keys, key)
+			} else {
+				keys = append(keys, state.Keys[addr])
+			}
+		}
+	}
+	return next.AddressList(), validator.Powers(next), keys, nil
+}
+
+// prepareVotes fills the epoch validators of the boundary header, or the vote of
+// the proposer for other headers.
+func (s *backend) prepareVotes(chain consensus.ChainHeaderReader, header *types.Header) error {
+	if s.config.Epoch == 0 {
+		return nil
+	}
+	extra, err := types.ExtractBftExtra(header)
+	if err != nil {
+		return err
+	}
+	number := header.Number.Uint64()
+	if s.hasValidatorContract() {
+		// The validator contract replaces the votes, membership is managed by the
+		// transactions of the contract
+		extra.Vote = nil
+		if s.isEpochBoundary(number) {
+			validators, powers, err := s.prepareContractValidators(chain, header)
+			if err != nil {
+				return err
+			}
+			extra.Validators, extra.Powers, extra.BLSKeys = validators, powers, nil
+		}
+	} else if s.isEpochBoundary(number) {
+		validators, powers, keys, err := s.nextEpochValidators(chain, header, nil)
+		if err != nil {
+			return err
+		}
+		if validators != nil {
+			log.Info("Validator set changed by votes", "number", number, "validators", validators, "powers", powers)
+		}
+		extra.Validators, extra.Powers, extra.BLSKeys, extra.Vote = validators, powers, keys, nil
+	} else {
+		valSet := s.Validators(number)
+
+		s.sigMu.RLock()
+		candidates := make([]common.Address, 0, len(s.proposals))
+		for candidate, authorize := range s.proposals {
+			if !validVote(valSet, candidate, authorize) {
+				continue
+			}
+			// validators can't commit blocks without a bls key
+			if s.config.Signature == bft.BLSSignature && authorize && len(s.proposalKeys[candidate]) == 0 {
+				continue
+			}
+			candidates = append(candidates, candidate)
+		}
+		if len(candidates) > 0 {
+			candidate := candidates[rand.Intn(len(candidates))]
+			extra.Vote = &types.BftVote{Candidate: candidate, Authorize: s.proposals[candidate]}
+			if extra.Vote.Authorize {
+				extra.Vote.BLSKey = s.proposalKeys[candidate]
+			}
+		}
+		s.sigMu.RUnlock()
+	}
+	return writeExtra(header, extra)
+}
+
+// verifyVotes checks the vote of a non-boundary header, and that the validators of
+// a boundary header are the outcome of the epoch votes.
+func (s *backend) verifyVotes(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) error {
+	if s.config.Epoch == 0 {
+		return nil
+	}
+	extra, err := types.ExtractBftExtra(header)
+	if err != nil {
+		return errInvalidExtraDataFormat
+	}
+	number := header.Number.Uint64()
+	if !s.isEpochBoundary(number) {
+		if len(extra.Validators) > 0 || len(extra.Powers) > 0 || len(extra.BLSKeys) > 0 {
+			return errInvalidEpochValidators
+		}
+		if extra.Vote != nil {
+			if s.hasValidatorContract() || !validVote(s.Validators(number), extra.Vote.Candidate, extra.Vote.Authorize) {
+				return errInvalidVote
+			}
+			return s.verifyVoteKey(extra.Vote)
+		}
+		return nil
+	}
+	if extra.Vote != nil {
+		return errInvalidBoundaryVote
+	}
+	// The validators of the contract are checked against the parent state before
+	// voting and once the block is processed
+	if s.hasValidatorContract() {
+		if len(extra.BLSKeys) > 0 || (len(extra.Powers) > 0 && len(extra.Powers) != len(extra.Validators)) {
+			return errInvalidEpochValidators
+		}
+		return nil
+	}
+	validators, powers, keys, err := s.nextEpochValidators(chain, header, parents)
+	if err != nil {
+		return err
+	}
+	if len(validators) != len(extra.Validators) || len(powers) != len(extra.Powers) || len(keys) != len(extra.BLSKeys) {
+		return errInvalidEpochValidators
+	}
+	for i, val := range validators {
+		if extra.Validators[i] != val {
+			return errInvalidEpochValidators
+		}
+	}
+	for i, power := range powers {
+		if extra.Powers[i] != power {
+			return errInvalidEpochValidators
+		}
+	}
+	for i, key := range keys {
+		if !bytes.Equal(extra.BLSKeys[i], key) {
+			return errInvalidEpochValidators
+		}
+	}
+	return nil
+}
+
+// verifyVoteKey checks the bls key carried by a vote. with bls signatures every
+// authorized candidate registers a valid key, otherwise votes carry no key.
+func (s *backend) verifyVoteKey(vote *types.BftVote) error {
+	if s.config.Signature != bft.BLSSignature || !vote.Authorize {
+		if len(vote.BLSKey) > 0 {
+			return errInvalidVote
+		}
+		return nil
+	}
+	if _, err := snr.ParseBLSKey(vote.BLSKey); err != nil {
+		return errInvalidVote
+	}
+	return nil
+}
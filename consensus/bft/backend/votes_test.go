@@ -0,0 +1,159 @@
+package backend
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// voteHeader returns a header carrying the vote, sealed by the voter.
+func voteHeader(t *testing.T, number int64, voter *ecdsa.PrivateKey, vote *types.BftVote) *types.Header {
+	t.Helper()
+
+	header := &types.Header{Number: big.NewInt(number), MixDigest: types.BftDigest}
+	if err := types.BftHeaderFillWithValidators(header, nil); err != nil {
+		t.Fatalf("failed to fill header extra: %v", err)
+	}
+	extra, err := types.ExtractBftExtra(header)
+	if err != nil {
+		t.Fatalf("failed to extract header extra: %v", err)
+	}
+	extra.Vote = vote
+	if err := writeExtra(header, extra); err != nil {
+		t.Fatalf("failed to write header vote: %v", err)
+	}
+	if err := snr.NewSigner(voter).SealBeforeCommit(header); err != nil {
+		t.Fatalf("failed to seal header: %v", err)
+	}
+	return header
+}
+
+// Tests that the votes of continuous headers are applied by their sealers, a later
+// vote replacing the earlier one, and that gaps in the headers are rejected.
+func TestVotesApply(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	voter0, voter1 := crypto.PubkeyToAddress(keys[0].PublicKey), crypto.PubkeyToAddress(keys[1].PublicKey)
+	candidate := common.HexToAddress("0xca")
+
+	config := *bft.DefaultBasicConfig
+	engine := New(&config, keys[0], rawdb.NewMemoryDatabase()).(*backend)
+	defer engine.Close()
+
+	headers := []*types.Header{
+		voteHeader(t, 11, keys[0], &types.BftVote{Candidate: candidate, Authorize: true}),
+		voteHeader(t, 12, keys[1], &types.BftVote{Candidate: candidate, Authorize: true}),
+		voteHeader(t, 13, keys[0], nil),
+		voteHeader(t, 14, keys[0], &types.BftVote{Candidate: candidate, Authorize: false}),
+	}
+	state, err := newVotes(10, common.Hash{}).apply(engine, headers)
+	if err != nil {
+		t.Fatalf("failed to apply votes: %v", err)
+	}
+	if state.Number != 14 || state.Hash != headers[3].Hash() {
+		t.Errorf("votes head mismatch: have %d %x, want 14 %x", state.Number, state.Hash, headers[3].Hash())
+	}
+	if len(state.Votes) != 2 || len(state.Votes[voter0]) != 1 || len(state.Votes[voter1]) != 1 {
+		t.Fatalf("votes mismatch: have %v", state.Votes)
+	}
+	if authorize, ok := state.Votes[voter0][candidate]; !ok || authorize {
+		t.Errorf("replaced vote mismatch: have %v, want removal", authorize)
+	}
+	if authorize, ok := state.Votes[voter1][candidate]; !ok || !authorize {
+		t.Errorf("vote mismatch: have %v, want authorization", authorize)
+	}
+	// The headers must be continuous and follow the state
+	if _, err := newVotes(10, common.Hash{}).apply(engine, []*types.Header{headers[0], headers[2]}); !errors.Is(err, errInvalidVotingChain) {
+		t.Errorf("gapped headers: have %v, want %v", err, errInvalidVotingChain)
+	}
+	if _, err := newVotes(11, common.Hash{}).apply(engine, headers); !errors.Is(err, errInvalidVotingChain) {
+		t.Errorf("headers not following the votes: have %v, want %v", err, errInvalidVotingChain)
+	}
+}
+
+// Tests that the candidates voted by more than half of the voting power are added
+// or removed, and that the outcome doesn't depend on the tally order.
+func TestVotesResult(t *testing.T) {
+	vals := []common.Address{common.HexToAddress("0x0a"), common.HexToAddress("0x0b"), common.HexToAddress("0x0c")}
+	candidate := common.HexToAddress("0xca")
+	outsider := common.HexToAddress("0x0d")
+
+	tests := []struct {
+		validators []common.Address
+		votes      map[common.Address]map[common.Address]bool
+		want       []common.Address // nil if the set doesn't change
+	}{
+		// A single vote of three validators changes nothing
+		{
+			validators: vals,
+			votes: map[common.Address]map[common.Address]bool{
+				vals[0]: {candidate: true},
+			},
+		},
+		// Two of three validators add the candidate, votes of outsiders don't count
+		{
+			validators: vals,
+			votes: map[common.Address]map[common.Address]bool{
+				vals[0]:  {candidate: true},
+				vals[1]:  {candidate: true},
+				outsider: {vals[2]: false},
+			},
+			want: []common.Address{vals[0], vals[1], vals[2], candidate},
+		},
+		// Votes which don't change the set are ignored
+		{
+			validators: vals,
+			votes: map[common.Address]map[common.Address]bool{
+				vals[0]: {vals[1]: true},
+				vals[1]: {vals[1]: true},
+			},
+		},
+		// Both validators of a pair vote each other out, the lower one goes
+		{
+			validators: vals[:2],
+			votes: map[common.Address]map[common.Address]bool{
+				vals[0]: {vals[0]: false, vals[1]: false},
+				vals[1]: {vals[0]: false, vals[1]: false},
+			},
+			want: []common.Address{vals[1]},
+		},
+	}
+	for i, tt := range tests {
+		// Run every case repeatedly, the map iteration order changes between runs
+		for run := 0; run < 16; run++ {
+			state := newVotes(10, common.Hash{})
+			state.Votes = tt.votes
+
+			next := state.result(validator.NewSet(tt.validators, bft.RoundRobin))
+			if tt.want == nil {
+				if next != nil {
+					t.Fatalf("test %d: unexpected validator change: have %v", i, next.AddressList())
+				}
+				continue
+			}
+			if next == nil {
+				t.Fatalf("test %d: validators unchanged, want %v", i, tt.want)
+			}
+			have, want := next.AddressList(), validator.NewSet(tt.want, bft.RoundRobin).AddressList()
+			if len(have) != len(want) {
+				t.Fatalf("test %d: validators mismatch: have %v, want %v", i, have, want)
+			}
+			for j := range have {
+				if have[j] != want[j] {
+					t.Fatalf("test %d: validators mismatch: have %v, want %v", i, have, want)
+				}
+			}
+		}
+	}
+}
@@ -1,5 +1,15 @@
 package bft
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
 type SelectProposerPolicy uint64
 
 const (
@@ -8,17 +18,141 @@ const (
 	VRF
 )
 
+var policyNames = map[SelectProposerPolicy]string{
+	RoundRobin: "roundrobin",
+	Sticky:     "sticky",
+	VRF:        "vrf",
+}
+
+// String implements the stringer interface, returning the name used for the
+// policy in the chain config and on the command line.
+func (p SelectProposerPolicy) String() string {
+	if name, ok := policyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", uint64(p))
+}
+
+// ParseSelectProposerPolicy returns the policy of the given name.
+func ParseSelectProposerPolicy(name string) (SelectProposerPolicy, error) {
+	for policy, n := range policyNames {
+		if strings.EqualFold(n, name) {
+			return policy, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownLeaderPolicy, name)
+}
+
+// SignatureScheme is the signature algorithm of the committed seals.
+type SignatureScheme uint64
+
+const (
+	// ECDSASignature keeps a recoverable secp256k1 signature of every committer.
+	ECDSASignature SignatureScheme = iota
+	// BLSSignature aggregates the committers into a single BLS12-381 signature and
+	// a participation bitmap.
+	BLSSignature
+)
+
+var signatureNames = map[SignatureScheme]string{
+	ECDSASignature: "ecdsa",
+	BLSSignature:   "bls",
+}
+
+// String implements the stringer interface, returning the name used for the
+// scheme in the chain config.
+func (s SignatureScheme) String() string {
+	if name, ok := signatureNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", uint64(s))
+}
+
+// ParseSignatureScheme returns the signature scheme of the given name.
+func ParseSignatureScheme(name string) (SignatureScheme, error) {
+	for scheme, n := range signatureNames {
+		if strings.EqualFold(n, name) {
+			return scheme, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownSignatureScheme, name)
+}
+
+var (
+	// ErrMissingHotStuffConfig is returned if the chain config has no hotstuff section.
+	ErrMissingHotStuffConfig = errors.New("missing hotstuff config")
+	// ErrUnknownProtocol is returned if the configured protocol is neither basic
+	// nor event driven.
+	ErrUnknownProtocol = errors.New("unknown hotstuff protocol")
+	// ErrUnknownLeaderPolicy is returned if the configured proposer selection
+	// policy does not exist.
+	ErrUnknownLeaderPolicy = errors.New("unknown leader policy")
+	// ErrInvalidRequestTimeout is returned if the round timeout is zero, does not
+	// leave room for a full block period, or exceeds the max timeout.
+	ErrInvalidRequestTimeout = errors.New("invalid request timeout")
+	// ErrUnknownSignatureScheme is returned if the configured committed seal scheme
+	// does not exist.
+	ErrUnknownSignatureScheme = errors.New("unknown signature scheme")
+	// ErrInvalidValidatorContract is returned if the validator contract is set
+	// without epochs, or with bls signatures whose keys it can't register.
+	ErrInvalidValidatorContract = errors.New("invalid validator contract")
+	// ErrInvalidOnDemand is returned if on-demand sealing is set with the event
+	// driven protocol, which needs the descendants of a block to commit it.
+	ErrInvalidOnDemand = errors.New("on-demand sealing requires the basic protocol")
+	// ErrInvalidMaxIdle is returned if empty blocks are skipped without a max idle
+	// interval, or the interval is set without skipping or below the block period.
+	ErrInvalidMaxIdle = errors.New("invalid max idle interval")
+	// ErrInvalidBuildDeadline is returned if the block building deadline doesn't
+	// leave room to propose the block before the round times out.
+	ErrInvalidBuildDeadline = errors.New("invalid block building deadline")
+	// ErrInvalidExternalSigner is returned if the external signer is set with bls
+	// signatures or vrf proofs, which need the key on the node.
+	ErrInvalidExternalSigner = errors.New("invalid external signer")
+)
+
 type Config struct {
+	Protocol       BftProtocol          `toml:",omitempty"` // The hotstuff protocol variant
 	RequestTimeout uint64               `toml:",omitempty"` // The timeout for each Istanbul round in milliseconds.
+	MaxTimeout     uint64               `toml:",omitempty"` // The cap of the round timeout backoff in milliseconds, zero disables the backoff
 	BlockPeriod    uint64               `toml:",omitempty"` // Default minimum difference between two consecutive block's timestamps in second for basic bft and mill-seconds for event-driven
 	LeaderPolicy   SelectProposerPolicy `toml:",omitempty"` // The policy for speaker selection
 	Test           bool                 `toml:",omitempty"`
 	Epoch          uint64               `toml:",omitempty"` // The number of blocks after which to checkpoint and reset the pending votes
+	Signature      SignatureScheme      `toml:",omitempty"` // The signature scheme of committed seals
+	OnDemand       bool                 `toml:",omitempty"` // Seal blocks only with pending transactions, the block period is zero
+	SkipEmpty      bool                 `toml:",omitempty"` // Hold back empty blocks until the max idle interval passed since the parent
+	MaxIdle        uint64               `toml:",omitempty"` // Max interval between blocks in milliseconds when empty blocks are skipped
+	BuildDeadline  uint64               `toml:",omitempty"` // Milliseconds after the start of a height at which the proposer stops adding transactions, zero disables it
+	RefreshPayload bool                 `toml:",omitempty"` // Let the worker swap in a fresher block until the proposal time
+
+	DropEquivocators bool `toml:",omitempty"` // Vote out validators with equivocation evidence
+
+	ValidatorContract common.Address `toml:",omitempty"` // Contract read for the validators at every epoch boundary, the votes are used if empty
+
+	WAL    WALConfig    `toml:",omitempty"` // Write-ahead log of the consensus messages, it's local to the node
+	Signer SignerConfig `toml:",omitempty"` // External signer of the consensus data, it's local to the node
+}
+
+// WALConfig is the write-ahead log of the consensus messages seen by the node,
+// which are replayed offline to debug stalled networks.
+type WALConfig struct {
+	Dir      string `toml:",omitempty"` // Directory of the log files, the log is disabled if empty
+	MaxSize  uint64 `toml:",omitempty"` // Size in bytes after which the log rotates to a new file
+	MaxFiles int    `toml:",omitempty"` // Number of log files kept, zero keeps all of them
+}
+
+// SignerConfig is the external signer holding the validator key, e.g. clef, which
+// signs the consensus data instead of the node key.
+type SignerConfig struct {
+	Endpoint string         `toml:",omitempty"` // Rpc endpoint of the external signer, the node key signs if empty
+	Account  common.Address `toml:",omitempty"` // Validator account, the only account of the signer if empty
 }
 
 // todo: modify request timeout, and miner recommit default value is 3s. recommit time should be > blockPeriod
 var DefaultBasicConfig = &Config{
+	Protocol:       BFT_PROTOCOL_BASIC,
 	RequestTimeout: 6000,
+	MaxTimeout:     60000,
 	BlockPeriod:    3,
 	LeaderPolicy:   RoundRobin,
 	Epoch:          30000,
@@ -26,9 +160,167 @@ var DefaultBasicConfig = &Config{
 }
 
 var DefaultEventDrivenConfig = &Config{
+	Protocol:       BFT_PROTOCOL_EVENT_DRIVEN,
 	RequestTimeout: 4000,
+	MaxTimeout:     60000,
 	BlockPeriod:    2000,
 	LeaderPolicy:   RoundRobin,
 	Epoch:          0,
 	Test:           false,
 }
+
+// NewConfig assembles the engine config from the hotstuff section of the chain
+// config. Fields left zero take the defaults of the selected protocol, and the
+// result is validated before it is returned.
+func NewConfig(hs *params.HotStuffConfig) (*Config, error) {
+	if hs == nil {
+		return nil, ErrMissingHotStuffConfig
+	}
+	var config Config
+	switch BftProtocol(hs.Protocol) {
+	case BFT_PROTOCOL_BASIC:
+		config = *DefaultBasicConfig
+	case BFT_PROTOCOL_EVENT_DRIVEN:
+		config = *DefaultEventDrivenConfig
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProtocol, hs.Protocol)
+	}
+	if hs.RequestTimeout != 0 {
+		config.RequestTimeout = hs.RequestTimeout
+	}
+	if hs.MaxTimeout != 0 {
+		config.MaxTimeout = hs.MaxTimeout
+	}
+	if hs.BlockPeriod != 0 {
+		config.BlockPeriod = hs.BlockPeriod
+	}
+	if hs.LeaderPolicy != "" {
+		policy, err := ParseSelectProposerPolicy(hs.LeaderPolicy)
+		if err != nil {
+			return nil, err
+		}
+		config.LeaderPolicy = policy
+	}
+	if hs.Epoch != 0 {
+		config.Epoch = hs.Epoch
+	}
+	if hs.Signature != "" {
+		scheme, err := ParseSignatureScheme(hs.Signature)
+		if err != nil {
+			return nil, err
+		}
+		config.Signature = scheme
+	}
+	config.DropEquivocators = hs.DropEquivocators
+	config.SkipEmpty = hs.SkipEmpty
+	config.MaxIdle = hs.MaxIdle
+	config.BuildDeadline = hs.BuildDeadline
+	config.RefreshPayload = hs.RefreshPayload
+	if hs.OnDemand {
+		config.OnDemand = true
+		config.SkipEmpty = true
+		config.BlockPeriod = 0
+	}
+	if hs.ValidatorContract != nil {
+		config.ValidatorContract = *hs.ValidatorContract
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Period returns the minimum time between two consecutive blocks. The block period
+// is kept in seconds for basic and in milliseconds for event driven.
+func (c *Config) Period() time.Duration {
+	if c.Protocol == BFT_PROTOCOL_EVENT_DRIVEN {
+		return time.Duration(c.BlockPeriod) * time.Millisecond
+	}
+	return time.Duration(c.BlockPeriod) * time.Second
+}
+
+// TimestampPeriod returns the minimum difference between two consecutive block's
+// timestamps in seconds. Timestamps have a resolution of a second, so a non-zero
+// event driven period below a second still moves them forward by one.
+func (c *Config) TimestampPeriod() uint64 {
+	period := uint64(c.Period() / time.Second)
+	if period == 0 && c.BlockPeriod != 0 {
+		period = 1
+	}
+	return period
+}
+
+// RoundTimeout returns the timeout of the given round. It doubles with every round
+// of the height, up to the max timeout, so that validators which drifted apart
+// eventually spend long enough in the same round to assemble a timeout cert.
+//
+// The first round of a height also waits for the max idle interval if empty blocks
+// are skipped.
+func (c *Config) RoundTimeout(round uint64) time.Duration {
+	var (
+		timeout = time.Duration(c.RequestTimeout) * time.Millisecond
+		max     = time.Duration(c.MaxTimeout) * time.Millisecond
+	)
+	if round == 0 && c.SkipEmpty {
+		return timeout + time.Duration(c.MaxIdle)*time.Millisecond
+	}
+	for i := uint64(0); i < round && timeout < max; i++ {
+		timeout *= 2
+	}
+	if max != 0 && timeout > max {
+		timeout = max
+	}
+	return timeout
+}
+
+// Validate checks that the config can drive a running engine.
+func (c *Config) Validate() error {
+	if c.Protocol != BFT_PROTOCOL_BASIC && c.Protocol != BFT_PROTOCOL_EVENT_DRIVEN {
+		return fmt.Errorf("%w: %q", ErrUnknownProtocol, c.Protocol)
+	}
+	period := uint64(c.Period().Milliseconds())
+	if _, ok := policyNames[c.LeaderPolicy]; !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownLeaderPolicy, c.LeaderPolicy)
+	}
+	if _, ok := signatureNames[c.Signature]; !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownSignatureScheme, c.Signature)
+	}
+	if c.RequestTimeout == 0 || c.RequestTimeout <= period {
+		return fmt.Errorf("%w: %dms, block period %dms", ErrInvalidRequestTimeout, c.RequestTimeout, period)
+	}
+	if c.MaxTimeout != 0 && c.MaxTimeout < c.RequestTimeout {
+		return fmt.Errorf("%w: max timeout %dms below %dms", ErrInvalidRequestTimeout, c.MaxTimeout, c.RequestTimeout)
+	}
+	if c.OnDemand && c.Protocol != BFT_PROTOCOL_BASIC {
+		return fmt.Errorf("%w: %q", ErrInvalidOnDemand, c.Protocol)
+	}
+	if c.SkipEmpty && c.MaxIdle == 0 && !c.OnDemand {
+		return fmt.Errorf("%w: empty blocks skipped without max idle interval", ErrInvalidMaxIdle)
+	}
+	if c.MaxIdle != 0 && !c.SkipEmpty {
+		return fmt.Errorf("%w: %dms without skipping empty blocks", ErrInvalidMaxIdle, c.MaxIdle)
+	}
+	if c.MaxIdle != 0 && c.MaxIdle < period {
+		return fmt.Errorf("%w: %dms, block period %dms", ErrInvalidMaxIdle, c.MaxIdle, period)
+	}
+	if c.BuildDeadline != 0 && c.BuildDeadline >= c.RequestTimeout {
+		return fmt.Errorf("%w: %dms, request timeout %dms", ErrInvalidBuildDeadline, c.BuildDeadline, c.RequestTimeout)
+	}
+	if c.ValidatorContract != (common.Address{}) {
+		if c.Epoch == 0 {
+			return fmt.Errorf("%w: no epoch length", ErrInvalidValidatorContract)
+		}
+		if c.Signature == BLSSignature {
+			return fmt.Errorf("%w: bls signatures", ErrInvalidValidatorContract)
+		}
+	}
+	if c.Signer.Endpoint != "" {
+		if c.Signature == BLSSignature {
+			return fmt.Errorf("%w: bls signatures", ErrInvalidExternalSigner)
+		}
+		if c.LeaderPolicy == VRF {
+			return fmt.Errorf("%w: vrf proposer policy", ErrInvalidExternalSigner)
+		}
+	}
+	return nil
+}
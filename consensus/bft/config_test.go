@@ -0,0 +1,55 @@
+package bft
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the engine config takes the defaults of the protocol for the fields
+// left zero in the chain config, and that invalid settings are refused.
+func TestNewConfig(t *testing.T) {
+	custom := *DefaultBasicConfig
+	custom.RequestTimeout, custom.BlockPeriod, custom.LeaderPolicy, custom.Epoch = 8000, 5, VRF, 100
+
+	tests := []struct {
+		hs   *params.HotStuffConfig
+		want *Config
+		err  error
+	}{
+		{hs: nil, err: ErrMissingHotStuffConfig},
+		{hs: &params.HotStuffConfig{Protocol: "basic"}, want: DefaultBasicConfig},
+		{hs: &params.HotStuffConfig{Protocol: "event_driven"}, want: DefaultEventDrivenConfig},
+		{hs: &params.HotStuffConfig{Protocol: "basic", RequestTimeout: 8000, BlockPeriod: 5, LeaderPolicy: "VRF", Epoch: 100}, want: &custom},
+		{hs: &params.HotStuffConfig{Protocol: "pbft"}, err: ErrUnknownProtocol},
+		{hs: &params.HotStuffConfig{Protocol: "basic", LeaderPolicy: "random"}, err: ErrUnknownLeaderPolicy},
+		// The round timeout must leave room for the block period, in seconds for basic
+		// and in milliseconds for event driven
+		{hs: &params.HotStuffConfig{Protocol: "basic", RequestTimeout: 5000, BlockPeriod: 5}, err: ErrInvalidRequestTimeout},
+		{hs: &params.HotStuffConfig{Protocol: "event_driven", RequestTimeout: 3000, BlockPeriod: 3000}, err: ErrInvalidRequestTimeout},
+	}
+	for i, tt := range tests {
+		config, err := NewConfig(tt.hs)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if tt.want != nil && *config != *tt.want {
+			t.Errorf("test %d: config mismatch: have %+v, want %+v", i, config, tt.want)
+		}
+	}
+}
+
+// Tests that the proposer policies are parsed from the names they print as.
+func TestParseSelectProposerPolicy(t *testing.T) {
+	for _, policy := range []SelectProposerPolicy{RoundRobin, Sticky, VRF} {
+		parsed, err := ParseSelectProposerPolicy(policy.String())
+		if err != nil || parsed != policy {
+			t.Errorf("policy %v: have %v, %v", policy, parsed, err)
+		}
+	}
+	if _, err := ParseSelectProposerPolicy("unknown"); !errors.Is(err, ErrUnknownLeaderPolicy) {
+		t.Errorf("unknown policy error mismatch: have %v, want %v", err, ErrUnknownLeaderPolicy)
+	}
+}
@@ -117,9 +117,25 @@ var messagePriorityTable = map[bft.MsgType]int64{
 	MsgTypeCommit:        6,
 	MsgTypeCommitVote:    7,
 	MsgTypeDecide:        8,
+	MsgTypeTimeout:       9,
+	MsgTypeTimeoutCert:   9,
 }
 
 func (b *backlog) toPriority(msgCode bft.MsgType, view *bft.View) int64 {
 	priority := -(view.Height.Int64()*100 + view.Round.Int64()*10 + int64(messagePriorityTable[msgCode]))
 	return priority
 }
+
+// sizes returns the number of messages in the backlog by sender
+func (b *backlog) sizes() map[common.Address]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sizes := make(map[common.Address]int, len(b.queue))
+	for addr, queue := range b.queue {
+		if queue != nil && !queue.Empty() {
+			sizes[addr] = queue.Size()
+		}
+	}
+	return sizes
+}
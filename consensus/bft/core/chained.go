@@ -0,0 +1,436 @@
+package core
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/message_set"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// chainedCore runs the event driven (chained) HotStuff protocol. There is a single
+// voting phase per view: every proposal carries the quorum cert of it's parent,
+// and the votes are sent to the leader of the next height, which seals the block
+// with them and proposes the next one on top of it. The phases of the basic
+// protocol are pipelined over the descendants of a block, it's locked once it has
+// a certified child, and decided once it heads a three-chain of certified blocks
+// proposed in consecutive views. Certified blocks are kept beside the chain, only
+// the decided ones are committed.
+type chainedCore struct {
+	config *bft.Config
+	logger log.Logger
+
+	backend bft.Backend
+	signer  bft.Signer
+
+	mu       sync.RWMutex // Protects the view, validators, proposal and running flag, they are used outside of the event loop
+	view     *bft.View
+	valSet   bft.ValidatorSet
+	proposal bft.Proposal // proposal sent by the node in the current view
+
+	viewStart    time.Time
+	proposeTimer *time.Timer // delays the proposal of the view until the block period passed
+
+	head      *types.Block                            // highest certified block, the proposals of the view extend it
+	highQC    *bft.QuorumCert                         // quorum cert of the head
+	lockedQC  *bft.QuorumCert                         // quorum cert of the locked block
+	request   *bft.Request                            // latest request of the node for the view
+	newViews  *message_set.MessageSet                 // new views of the round, collected by the leader
+	votes     map[common.Hash]*message_set.MessageSet // votes for the proposals of the height, by proposal hash
+	certified map[common.Hash]bool                    // proposals already sealed by the node as the next leader
+	proposals map[common.Hash]*chainedProposal        // valid proposals of the recent heights, by proposal hash
+	views     map[common.Hash]*bft.View               // views of the recent proposals, by hash without the committed seals
+
+	requests *requestSet
+	backlogs *backlog
+	lastSeen map[common.Address]*bft.View // view of the latest message by validator
+	signed   *signedDigests               // digests signed by validators, for equivocation detection
+	safety   *safetyState                 // persisted voting state of the validator
+
+	phases         *phaseTimer // time spent waiting for the proposal and the next round
+	rounds         uint64      // rounds started since the engine started
+	roundChanges   uint64      // rounds started after the previous round of the height
+	proposerRounds uint64      // rounds led by the node
+
+	events            *event.TypeMuxSubscription
+	timeoutSub        *event.TypeMuxSubscription
+	finalCommittedSub *event.TypeMuxSubscription
+
+	pacemaker *pacemaker
+
+	validateFn func([]byte, []byte) (common.Address, error)
+	isRunning  bool
+	loopDone   chan struct{} // closed once the event loop returns
+}
+
+// chainedProposal is a proposal received by the node, it's kept until the block is
+// committed, so that the node can seal it with the quorum cert carried by the
+// proposals of the next height.
+type chainedProposal struct {
+	block *types.Block
+	view  *bft.View
+}
+
+// proposalRound returns the round the block was proposed in, if the node saw the
+// proposal.
+func (c *chainedCore) proposalRound(block *types.Block) *big.Int {
+	if view, ok := c.views[proposalHash(block.Header())]; ok {
+		return new(big.Int).Set(view.Round)
+	}
+	return new(big.Int)
+}
+
+// NewChained creates an event driven HotStuff consensus core
+func NewChained(backend bft.Backend, config *bft.Config, signer bft.Signer) bft.CoreEngine {
+	c := &chainedCore{
+		config:  config,
+		logger:  log.New("address", backend.Address()),
+		backend: backend,
+		signer:  signer,
+		signed:  newSignedDigests(),
+		safety:  new(safetyState),
+	}
+	c.pacemaker = newPacemaker(config, backend, signer)
+	c.validateFn = c.checkValidatorSignature
+	return c
+}
+
+// Start implements core.Engine.Start
+func (c *chainedCore) Start(chain consensus.ChainReader) error {
+	registerMsgTypes()
+
+	state, err := readSafetyState(c.backend, c.logger)
+	if err != nil {
+		return err
+	}
+	c.safety = state
+	c.lockedQC = state.LockedQC
+	c.setRunning(true)
+	c.requests = newRequestSet()
+	c.backlogs = newBackLog()
+	c.lastSeen = make(map[common.Address]*bft.View)
+	c.phases = newPhaseTimer()
+	c.rounds, c.roundChanges, c.proposerRounds = 0, 0, 0
+	c.proposals = make(map[common.Hash]*chainedProposal)
+	c.views = make(map[common.Hash]*bft.View)
+	c.view = nil
+
+	// the events posted by the first round are delivered once the loop runs
+	c.subscribeEvents()
+	c.startNewRound(common.Big0)
+
+	c.loopDone = make(chan struct{})
+	go c.handleEvents()
+	return nil
+}
+
+// Stop implements core.Engine.Stop, it returns once the event loop stopped.
+func (c *chainedCore) Stop() error {
+	c.pacemaker.stop()
+	c.unsubscribeEvents()
+	<-c.loopDone
+	c.stopProposeTimer()
+	c.setRunning(false)
+	return nil
+}
+
+func (c *chainedCore) setRunning(running bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isRunning = running
+}
+
+func (c *chainedCore) running() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.isRunning
+}
+
+func (c *chainedCore) IsProposer() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.valSet == nil {
+		return false
+	}
+	return c.valSet.IsProposer(c.signer.Address())
+}
+
+func (c *chainedCore) IsCurrentProposal(blockHash common.Hash) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.proposal != nil && c.proposal.Hash() == blockHash
+}
+
+// startNewRound moves to the given round of the height above the highest certified
+// block, or to the first round of the next height if a block was certified since.
+func (c *chainedCore) startNewRound(round *big.Int) {
+	if !c.running() {
+		c.logger.Trace("Start engine first")
+		return
+	}
+	lastProposal, lastProposer := c.backend.LastProposal()
+	head, ok := lastProposal.(*types.Block)
+	if !ok || head == nil {
+		c.logger.Warn("Last proposal should not be nil")
+		return
+	}
+	view := &bft.View{
+		Height: new(big.Int).Add(head.Number(), common.Big1),
+		Round:  new(big.Int),
+	}
+	sameHead := c.view != nil && c.head.Hash() == head.Hash()
+	if sameHead {
+		if round.Cmp(c.view.Round) <= 0 {
+			return
+		}
+		view.Round.Set(round)
+	}
+
+	valSet := c.backend.Validators(view.Height.Uint64())
+	if valSet.Policy() == bft.VRF {
+		valSet.SetSeed(c.signer.Randomness(head.Header()))
+	}
+	valSet.CalcProposer(lastProposer, view.Round.Uint64())
+
+	c.mu.Lock()
+	c.view, c.valSet, c.proposal = view, valSet, nil
+	c.mu.Unlock()
+
+	c.viewStart = time.Now()
+	c.stopProposeTimer()
+	c.head = head
+	c.newViews = message_set.NewMessageSet(valSet)
+	if !sameHead {
+		c.highQC = proposal2QC(head, c.proposalRound(head))
+		c.votes = make(map[common.Hash]*message_set.MessageSet)
+		c.certified = make(map[common.Hash]bool)
+		c.request = nil
+		for req := c.requests.PopRequest(view); req != nil; req = c.requests.PopRequest(view) {
+			c.request = req
+		}
+		c.pruneProposals(head.NumberU64())
+	}
+	// The single voting phase of the view starts with waiting for the proposal
+	c.phases.enter(StateAcceptRequest)
+	c.rounds++
+	if view.Round.Sign() > 0 {
+		c.roundChanges++
+	}
+	if c.IsProposer() {
+		c.proposerRounds++
+	}
+	c.logger.Debug("New round", "view", view, "proposer", valSet.GetProposer(), "IsProposer", c.IsProposer())
+
+	// Leaders of the first round propose right away, the quorum cert of the head
+	// was assembled from the votes. Later rounds wait for the new views to learn
+	// the quorum cert of the head.
+	if view.Round.Sign() > 0 {
+		c.sendNewView()
+	} else {
+		c.sendProposal()
+	}
+	c.pacemaker.startRound(view, valSet)
+	c.processBacklog()
+}
+
+// pruneProposals drops the proposals which can't be committed anymore, and the
+// views which the commit rule doesn't look at anymore.
+func (c *chainedCore) pruneProposals(height uint64) {
+	for hash, proposal := range c.proposals {
+		if proposal.block.NumberU64() <= height {
+			delete(c.proposals, hash)
+		}
+	}
+	for hash, view := range c.views {
+		if view.Height.Uint64()+2 < height {
+			delete(c.views, hash)
+		}
+	}
+}
+
+func (c *chainedCore) currentView() *bft.View {
+	return &bft.View{
+		Height: new(big.Int).Set(c.view.Height),
+		Round:  new(big.Int).Set(c.view.Round),
+	}
+}
+
+// nextValidators returns the validators of the height above the proposal, with the
+// leader of it's first round, who collects the votes for the proposal.
+func (c *chainedCore) nextValidators(block *types.Block) bft.ValidatorSet {
+	valSet := c.backend.Validators(block.NumberU64() + 1)
+	if valSet.Policy() == bft.VRF {
+		valSet.SetSeed(c.signer.Randomness(block.Header()))
+	}
+	valSet.CalcProposer(block.Coinbase(), 0)
+	return valSet
+}
+
+func (c *chainedCore) subscribeEvents() {
+	c.events = c.backend.EventMux().Subscribe(
+		// external events
+		bft.RequestEvent{},
+		bft.MessageEvent{},
+		// internal events
+		backlogEvent{},
+		proposeEvent{},
+		roundStateEvent{},
+	)
+	c.timeoutSub = c.backend.EventMux().Subscribe(
+		timeoutEvent{},
+	)
+	c.finalCommittedSub = c.backend.EventMux().Subscribe(
+		bft.FinalCommittedEvent{},
+		bft.CertifiedEvent{},
+	)
+}
+
+func (c *chainedCore) unsubscribeEvents() {
+	c.events.Unsubscribe()
+	c.timeoutSub.Unsubscribe()
+	c.finalCommittedSub.Unsubscribe()
+}
+
+func (c *chainedCore) handleEvents() {
+	defer close(c.loopDone)
+
+	for {
+		select {
+		case event, ok := <-c.events.Chan():
+			if !ok {
+				return
+			}
+			switch ev := event.Data.(type) {
+			case bft.RequestEvent:
+				c.handleRequest(&bft.Request{Proposal: ev.Proposal})
+
+			case bft.MessageEvent:
+				c.handleMsg(ev.Payload)
+
+			case backlogEvent:
+				c.handleCheckedMsg(ev.msg, ev.src)
+
+			case proposeEvent:
+				// proposals delayed in the rounds already left are dropped
+				if ev.view.Cmp(c.view) == 0 {
+					c.sendProposal()
+				}
+
+			case roundStateEvent:
+				ev.result <- c.roundState()
+			}
+
+		case evt, ok := <-c.timeoutSub.Chan():
+			if !ok {
+				return
+			}
+			// timers of the rounds already left are ignored
+			if ev, ok := evt.Data.(timeoutEvent); ok && ev.view.Cmp(c.view) == 0 {
+				c.logger.Trace("handleTimeout", "view", c.view)
+				c.sendTimeout()
+			}
+
+		case evt, ok := <-c.finalCommittedSub.Chan():
+			if !ok {
+				return
+			}
+			// the round is kept unless the head changed
+			switch evt.Data.(type) {
+			case bft.FinalCommittedEvent, bft.CertifiedEvent:
+				c.startNewRound(common.Big0)
+			}
+		}
+	}
+}
+
+// RoundState implements bft.CoreEngine.RoundState
+func (c *chainedCore) RoundState() *bft.RoundState {
+	if !c.running() {
+		return nil
+	}
+	return requestRoundState(c.backend.EventMux())
+}
+
+// roundState returns a snapshot of the consensus state, it's called by the event loop.
+func (c *chainedCore) roundState() *bft.RoundState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.view == nil {
+		return nil
+	}
+	rs := &bft.RoundState{
+		View:     c.currentView(),
+		State:    c.phases.state.String(),
+		Proposer: c.valSet.GetProposer().Address(),
+		LockedQC: copyQC(c.lockedQC),
+		HighQC:   copyQC(c.highQC),
+		Backlogs: c.backlogs.sizes(),
+		LastSeen: copyViews(c.lastSeen),
+
+		Rounds:         c.rounds,
+		RoundChanges:   c.roundChanges,
+		ProposerRounds: c.proposerRounds,
+		PhaseTimes:     c.phases.snapshot(),
+	}
+	if c.proposal != nil {
+		rs.Proposal = c.proposal.Hash()
+	}
+	return rs
+}
+
+func (c *chainedCore) sendEvent(ev interface{}) {
+	c.backend.EventMux().Post(ev)
+}
+
+func (c *chainedCore) checkValidatorSignature(data []byte, sig []byte) (common.Address, error) {
+	c.mu.RLock()
+	valSet := c.valSet
+	c.mu.RUnlock()
+	return c.signer.CheckSignature(valSet, data, sig)
+}
+
+// processBacklog replays the stored messages of the current view.
+func (c *chainedCore) processBacklog() {
+	c.backlogs.mu.Lock()
+	defer c.backlogs.mu.Unlock()
+
+	for addr, queue := range c.backlogs.queue {
+		_, src := c.valSet.GetByAddress(addr)
+		if src == nil {
+			continue
+		}
+		for !queue.Empty() {
+			data, priority := queue.Pop()
+			msg, ok := data.(*bft.Message)
+			if !ok {
+				continue
+			}
+			if err := c.checkView(msg); err != nil {
+				if err == errFutureMessage {
+					queue.Push(data, priority)
+					break
+				}
+				continue
+			}
+			go c.sendEvent(backlogEvent{src: src, msg: msg})
+		}
+	}
+}
+
+// proposalHash returns the hash of the header without the committed seals, it
+// tells apart the proposals whether they are sealed or not.
+func proposalHash(header *types.Header) common.Hash {
+	if filtered := types.BftFilteredHeader(header, true); filtered != nil {
+		return filtered.Hash()
+	}
+	return header.Hash()
+}
@@ -0,0 +1,568 @@
+package core
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/message_set"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func (c *chainedCore) handleRequest(req *bft.Request) error {
+	if req.Proposal == nil {
+		return errInvalidMessage
+	}
+	if cmp := req.Proposal.Number().Cmp(c.view.Height); cmp > 0 {
+		c.requests.StoreRequest(req)
+		return nil
+	} else if cmp < 0 {
+		return errOldMessage
+	}
+	c.request = req
+	c.sendProposal()
+
+	c.logger.Trace("handleRequest", "height", req.Proposal.Number(), "proposal", req.Proposal.Hash())
+	return nil
+}
+
+func (c *chainedCore) handleMsg(payload []byte) error {
+	// Decode Message and check its signature
+	msg := new(bft.Message)
+	if err := msg.FromPayload(payload, c.validateFn); err != nil {
+		c.logger.Error("Failed to decode Message from payload", "err", err)
+		return err
+	}
+
+	// Only accept Message if the address is valid
+	_, src := c.valSet.GetByAddress(msg.Address)
+	if src == nil {
+		c.logger.Error("Invalid address in Message", "msg", msg)
+		return errInvalidSigner
+	}
+	if msg.View != nil && msg.View.Height != nil && msg.View.Round != nil {
+		c.lastSeen[src.Address()] = msg.View
+	}
+
+	// Record the signed digest and report the sender if it conflicts
+	reportEquivocation(c.signed, c.backend, c.logger, msg, payload, c.view.Height.Uint64())
+
+	return c.handleCheckedMsg(msg, src)
+}
+
+func (c *chainedCore) handleCheckedMsg(msg *bft.Message, src bft.Validator) (err error) {
+	switch msg.Code {
+	case MsgTypeNewView:
+		err = c.handleNewView(msg, src)
+	case MsgTypePrepare:
+		err = c.handleProposal(msg, src)
+	case MsgTypePrepareVote:
+		err = c.handleVote(msg, src)
+	case MsgTypeTimeout:
+		err = c.handleTimeout(msg, src)
+	case MsgTypeTimeoutCert:
+		err = c.handleTimeoutCert(msg, src)
+	default:
+		err = errInvalidMessage
+		c.logger.Error("msg type invalid", "unknown type", msg.Code)
+	}
+
+	if err == errFutureMessage && src.Address() != c.signer.Address() {
+		c.backlogs.Push(msg)
+	}
+	return
+}
+
+// checkView returns errOldMessage or errFutureMessage if the message is not for
+// the current view. The votes for the proposals of all rounds of the height are
+// collected, they are sent to the leader of the next height. Timeouts of the later
+// rounds of the height move the node to them.
+func (c *chainedCore) checkView(msg *bft.Message) error {
+	switch msg.Code {
+	case MsgTypePrepareVote:
+	case MsgTypeTimeout, MsgTypeTimeoutCert:
+		return checkTimeoutView(msg.View, c.view)
+	default:
+		return compareView(msg.View, c.view)
+	}
+	if msg.View == nil || msg.View.Height == nil || msg.View.Round == nil {
+		return errInvalidMessage
+	}
+	if cmp := msg.View.Height.Cmp(c.view.Height); cmp < 0 {
+		return errOldMessage
+	} else if cmp > 0 {
+		return errFutureMessage
+	}
+	return nil
+}
+
+// sendNewView hands the quorum cert of the head to the leader of the round, it
+// proposes once a quorum of validators gave up the previous round.
+func (c *chainedCore) sendNewView() {
+	msg := &MsgNewView{
+		View:      c.currentView(),
+		PrepareQC: c.highQC.Copy(),
+	}
+	payload, err := Encode(msg)
+	if err != nil {
+		c.logger.Trace("Failed to encode", "msg", MsgTypeNewView, "err", err)
+		return
+	}
+	c.send(c.valSet, &bft.Message{Code: MsgTypeNewView, Msg: payload}, false)
+	c.logger.Trace("sendNewView", "view", msg.View, "highQC", msg.PrepareQC.Hash)
+}
+
+func (c *chainedCore) handleNewView(data *bft.Message, src bft.Validator) error {
+	var msg *MsgNewView
+	if err := data.Decode(&msg); err != nil {
+		return errFailedDecodeNewView
+	}
+	if err := compareView(msg.View, c.view); err != nil {
+		return err
+	}
+	if !c.IsProposer() {
+		return errNotToProposer
+	}
+	if err := c.verifyQC(msg.PrepareQC); err != nil {
+		c.logger.Trace("Failed to verify highQC", "msg", MsgTypeNewView, "err", err)
+		return err
+	}
+	if err := c.newViews.Add(data); err != nil {
+		return errAddNewViews
+	}
+	c.logger.Trace("handleNewView", "src", src.Address(), "view", msg.View, "power", c.newViews.Power())
+
+	c.sendProposal()
+	return nil
+}
+
+// sendProposal proposes the request of the node on top of the head, if it leads
+// the round. The view is proposed once: a later request only replaces the one
+// still waiting for the proposal timer, a second block would equivocate.
+func (c *chainedCore) sendProposal() {
+	if !c.IsProposer() || c.proposal != nil {
+		return
+	}
+	if c.view.Round.Sign() > 0 && c.newViews.Power() < c.valSet.Q() {
+		return
+	}
+	if c.request == nil {
+		c.logger.Trace("Failed to create proposal", "err", errNoRequest, "view", c.view)
+		return
+	}
+	block, ok := c.request.Proposal.(*types.Block)
+	if !ok || block.ParentHash() != c.highQC.Hash {
+		c.logger.Trace("Failed to create proposal", "err", errExtend, "view", c.view)
+		return
+	}
+	// keep the block period between the views, and wait for the block timestamp.
+	// The event loop isn't blocked meanwhile, the proposal is sent once the timer
+	// posts it's event.
+	delay := time.Until(c.viewStart.Add(c.config.Period()))
+	if until := time.Until(time.Unix(int64(block.Time()), 0)); until > delay {
+		delay = until
+	}
+	if delay > 0 {
+		c.scheduleProposal(delay)
+		return
+	}
+	prepare := &MsgPrepare{
+		View:     c.currentView(),
+		Proposal: block,
+		HighQC:   c.highQC,
+	}
+	payload, err := Encode(prepare)
+	if err != nil {
+		c.logger.Trace("Failed to encode", "msg", MsgTypePrepare, "err", err)
+		return
+	}
+
+	c.mu.Lock()
+	c.proposal = block
+	c.mu.Unlock()
+
+	c.send(c.valSet, &bft.Message{Code: MsgTypePrepare, Msg: payload}, true)
+	c.logger.Trace("sendProposal", "view", prepare.View, "proposal", block.Hash())
+}
+
+// scheduleProposal arms the timer sending the proposal of the view after the delay,
+// the pending proposal of the view is rescheduled.
+func (c *chainedCore) scheduleProposal(delay time.Duration) {
+	c.stopProposeTimer()
+
+	view := c.currentView()
+	c.proposeTimer = time.AfterFunc(delay, func() {
+		c.sendEvent(proposeEvent{view: view})
+	})
+	c.logger.Trace("Delay proposal", "view", view, "delay", delay)
+}
+
+func (c *chainedCore) stopProposeTimer() {
+	if c.proposeTimer != nil {
+		c.proposeTimer.Stop()
+		c.proposeTimer = nil
+	}
+}
+
+func (c *chainedCore) handleProposal(data *bft.Message, src bft.Validator) error {
+	var msg *MsgPrepare
+	if err := data.Decode(&msg); err != nil {
+		return errFailedDecodePrepare
+	}
+	block, ok := msg.Proposal.(*types.Block)
+	if !ok || msg.HighQC == nil || msg.HighQC.View == nil || msg.HighQC.View.Height == nil {
+		return errInvalidMessage
+	}
+	justify := msg.HighQC
+	if err := compareView(msg.View, c.view); err != nil {
+		// the proposal of the next height certifies a proposal of the current
+		// height, it's certified even if the node missed the sealed block
+		if err == errFutureMessage && msg.View.Height.Cmp(c.view.Height) > 0 {
+			c.certifyJustified(justify, src)
+		}
+		return err
+	}
+	if !c.valSet.IsProposer(src.Address()) {
+		return errNotFromProposer
+	}
+	if block.Number().Cmp(msg.View.Height) != 0 || block.ParentHash() != justify.Hash {
+		return errExtend
+	}
+	if err := c.verifyQC(justify); err != nil {
+		c.logger.Trace("Failed to verify justify qc", "msg", MsgTypePrepare, "err", err)
+		return err
+	}
+	if _, err := c.backend.VerifyUnsealedProposal(block); err != nil {
+		c.logger.Trace("Failed to verify unsealed proposal", "msg", MsgTypePrepare, "err", err)
+		return errVerifyUnsealedProposal
+	}
+	if err := c.safeNode(block, justify); err != nil {
+		c.logger.Trace("Failed to check safeNode", "msg", MsgTypePrepare, "err", err)
+		return err
+	}
+	if err := c.backend.ValidateBlock(block); err != nil {
+		c.logger.Trace("Failed to pre-execute block", "msg", MsgTypePrepare, "err", err)
+		return err
+	}
+	c.logger.Trace("handleProposal", "src", src.Address(), "view", msg.View, "hash", block.Hash())
+
+	c.proposals[block.Hash()] = &chainedProposal{block: block, view: msg.View}
+	c.views[proposalHash(block.Header())] = msg.View
+	c.updateChain(justify)
+	c.sendVote(block, msg.View)
+
+	// the votes may have arrived before the proposal
+	c.certify(block.Hash())
+	return nil
+}
+
+// updateChain applies the quorum cert carried by a proposal. The certified parent
+// of the proposal provides the highest quorum cert, the grandparent gets locked,
+// and the block below is decided, it starts a three-chain of certified blocks.
+// The blocks of the chain must be proposed in consecutive views, so the two blocks
+// above it must be proposed in the first round of their height. The decided block
+// is committed with it's ancestors, the other forks can't be committed anymore.
+func (c *chainedCore) updateChain(justify *bft.QuorumCert) {
+	if justify.HeightU64() > c.highQC.HeightU64() {
+		c.highQC = justify
+	}
+	parent, ok := c.backend.GetProposal(justify.Hash).(*types.Block)
+	if !ok || parent == nil || parent.NumberU64() < 2 {
+		return
+	}
+	locked, ok := c.backend.GetProposal(parent.ParentHash()).(*types.Block)
+	if !ok || locked == nil {
+		return
+	}
+	if c.lockedQC == nil || locked.NumberU64() > c.lockedQC.HeightU64() {
+		c.lockedQC = proposal2QC(locked, c.proposalRound(locked))
+	}
+
+	parentView, lockedView := c.views[proposalHash(parent.Header())], c.views[proposalHash(locked.Header())]
+	if parentView == nil || lockedView == nil || parentView.Round.Sign() != 0 || lockedView.Round.Sign() != 0 {
+		return
+	}
+	decided := c.backend.GetProposal(locked.ParentHash())
+	if block, ok := decided.(*types.Block); !ok || block == nil {
+		return
+	}
+	if err := c.backend.Commit(decided); err != nil {
+		c.logger.Warn("Failed to commit decided proposal", "number", decided.Number(), "hash", decided.Hash(), "err", err)
+		return
+	}
+	if err := c.backend.Decide(decided); err != nil {
+		c.logger.Warn("Failed to decide proposal", "number", decided.Number(), "hash", decided.Hash(), "err", err)
+	}
+}
+
+// safeNode accepts proposals which extend the locked block, or which carry a quorum
+// cert higher than the locked one.
+func (c *chainedCore) safeNode(block *types.Block, justify *bft.QuorumCert) error {
+	if c.lockedQC == nil || justify.HeightU64() > c.lockedQC.HeightU64() {
+		return nil
+	}
+	ancestor := c.backend.GetProposal(block.ParentHash())
+	for ancestor != nil && ancestor.Number().Uint64() > c.lockedQC.HeightU64() {
+		ancestor = c.backend.GetProposal(ancestor.(*types.Block).ParentHash())
+	}
+	if block, ok := ancestor.(*types.Block); ok && block != nil && block.Hash() == c.lockedQC.Hash {
+		return nil
+	}
+	return errSafeNode
+}
+
+// sendVote persists the vote and sends it with the committed seal of the proposal
+// to the leader of the next height.
+func (c *chainedCore) sendVote(block *types.Block, view *bft.View) {
+	vote := &Vote{View: view, Digest: block.Hash()}
+	if err := c.safety.allowVote(MsgTypePrepareVote, vote); err != nil {
+		c.logger.Warn("Failed to record vote", "msg", MsgTypePrepareVote, "vote", vote, "err", err)
+		return
+	}
+	next := c.safety.voted(MsgTypePrepareVote, vote)
+	if c.lockedQC != nil {
+		next.LockedQC = c.lockedQC.Copy()
+	}
+	next.HighQC = c.highQC.Copy()
+	if err := writeSafetyState(c.backend, next); err != nil {
+		c.logger.Warn("Failed to record vote", "msg", MsgTypePrepareVote, "vote", vote, "err", err)
+		return
+	}
+	c.safety = next
+	c.phases.enter(StatePrepared)
+
+	payload, err := Encode(vote)
+	if err != nil {
+		c.logger.Trace("Failed to encode", "msg", MsgTypePrepareVote, "err", err)
+		return
+	}
+	seal, err := c.signer.SignVote(view, block.Header())
+	if err != nil {
+		c.logger.Error("Failed to seal proposal", "hash", block.Hash(), "err", err)
+		return
+	}
+	msg := &bft.Message{Code: MsgTypePrepareVote, Msg: payload, View: view, CommittedSeal: seal}
+	c.send(c.nextValidators(block), msg, false)
+	c.logger.Trace("sendVote", "view", view, "vote", vote.Digest)
+}
+
+func (c *chainedCore) handleVote(data *bft.Message, src bft.Validator) error {
+	var vote *Vote
+	if err := data.Decode(&vote); err != nil {
+		return errFailedDecodePrepareVote
+	}
+	if vote.View == nil || vote.View.Cmp(data.View) != 0 {
+		return errInvalidMessage
+	}
+	if err := c.checkView(data); err != nil {
+		return err
+	}
+	votes, ok := c.votes[vote.Digest]
+	if !ok {
+		votes = message_set.NewMessageSet(c.valSet)
+		c.votes[vote.Digest] = votes
+	}
+	if err := votes.Add(data); err != nil {
+		return errAddPrepareVote
+	}
+	c.logger.Trace("handleVote", "src", src.Address(), "view", vote.View, "hash", vote.Digest, "power", votes.Power())
+
+	c.certify(vote.Digest)
+	return nil
+}
+
+// certify seals the proposal with the votes once they reach the quorum, and stores
+// the certified block. The node leads the next height, it proposes on top of the
+// block once it's stored.
+func (c *chainedCore) certify(hash common.Hash) {
+	proposal, votes := c.proposals[hash], c.votes[hash]
+	if proposal == nil || votes == nil || c.certified[hash] {
+		return
+	}
+	if !c.nextValidators(proposal.block).IsProposer(c.signer.Address()) {
+		return
+	}
+	var (
+		sealHash = c.signer.CommittedSealHash(proposal.block.Header())
+		seals    [][]byte
+		power    uint64
+	)
+	for _, vote := range votes.Values() {
+		if err := c.signer.VerifyHash(c.valSet, proposal.block.NumberU64(), sealHash, vote.CommittedSeal); err != nil {
+			continue
+		}
+		if _, val := c.valSet.GetByAddress(vote.Address); val != nil {
+			seals = append(seals, vote.CommittedSeal)
+			power += val.Power()
+		}
+	}
+	if power < c.valSet.Q() {
+		return
+	}
+	sealed, err := c.backend.PreCommit(proposal.block, seals)
+	if err != nil {
+		c.logger.Trace("Failed to assemble committed seal", "err", err)
+		return
+	}
+	c.certified[hash] = true
+	c.logger.Trace("certify", "view", proposal.view, "hash", sealed.Hash(), "power", power)
+
+	if err := c.backend.Certify(sealed); err != nil {
+		c.logger.Trace("Failed to certify proposal", "err", err)
+	}
+}
+
+// certifyJustified stores the proposal of the current height certified by the
+// quorum cert. The node missed the proposals of the higher heights, the certified
+// block is fetched from the validator which sent the quorum cert.
+func (c *chainedCore) certifyJustified(justify *bft.QuorumCert, src bft.Validator) {
+	if justify.View.Height.Cmp(c.view.Height) < 0 {
+		return
+	}
+	if block, ok := c.backend.GetProposal(justify.Hash).(*types.Block); ok && block != nil {
+		return
+	}
+	if justify.View.Height.Cmp(c.view.Height) > 0 {
+		c.backend.FetchProposal(justify.Hash, src.Address())
+		return
+	}
+	for hash, proposal := range c.proposals {
+		if proposal.block.Number().Cmp(justify.View.Height) != 0 {
+			continue
+		}
+		sealed, err := c.backend.ForwardCommit(proposal.block, justify.Extra)
+		if err != nil || sealed.Hash() != justify.Hash {
+			continue
+		}
+		if err := c.signer.VerifyQC(justify, sealed.(*types.Block).Header(), c.valSet); err != nil {
+			c.logger.Trace("Failed to verify justify qc", "err", err)
+			return
+		}
+		delete(c.proposals, hash)
+		if err := c.backend.Certify(sealed); err != nil {
+			c.logger.Trace("Failed to certify proposal", "err", err)
+		}
+		return
+	}
+	c.backend.FetchProposal(justify.Hash, src.Address())
+}
+
+// sendTimeout broadcasts the timeout of the current round, the node moves to the
+// next round once the timeouts of a quorum make a timeout cert.
+func (c *chainedCore) sendTimeout() {
+	msg, err := c.pacemaker.timeout(c.highQC)
+	if err != nil {
+		c.logger.Trace("Failed to create timeout", "msg", MsgTypeTimeout, "err", err)
+		return
+	}
+	c.send(c.valSet, msg, true)
+	c.logger.Trace("sendTimeout", "view", msg.View)
+}
+
+func (c *chainedCore) handleTimeout(data *bft.Message, src bft.Validator) error {
+	if err := c.checkView(data); err != nil {
+		// the timeouts of the next height certify a proposal of the current height,
+		// it's certified even if the node missed the sealed block
+		if err == errFutureMessage {
+			if timeout, err := decodeTimeout(data); err == nil {
+				c.certifyJustified(timeout.HighQC, src)
+			}
+		}
+		return err
+	}
+	tc, err := c.pacemaker.addTimeout(data, c.valSet)
+	if err != nil {
+		c.logger.Trace("Failed to add timeout", "msg", MsgTypeTimeout, "src", src.Address(), "err", err)
+		return err
+	}
+	c.logger.Trace("handleTimeout", "src", src.Address(), "view", data.View)
+
+	if tc != nil {
+		c.sendTimeoutCert(tc)
+		c.startNewRound(c.pacemaker.advance(tc))
+		return nil
+	}
+	if round, ok := c.pacemaker.joinRound(c.valSet); ok {
+		c.logger.Trace("Join timeout", "round", round)
+		c.startNewRound(new(big.Int).SetUint64(round))
+		c.sendTimeout()
+	}
+	return nil
+}
+
+// sendTimeoutCert hands the timeout cert assembled by the node to the validators
+// which missed some of the timeouts.
+func (c *chainedCore) sendTimeoutCert(tc *bft.TimeoutCert) {
+	payload, err := Encode(tc)
+	if err != nil {
+		c.logger.Trace("Failed to encode", "msg", MsgTypeTimeoutCert, "err", err)
+		return
+	}
+	c.send(c.valSet, &bft.Message{Code: MsgTypeTimeoutCert, Msg: payload}, true)
+	c.logger.Trace("sendTimeoutCert", "tc", tc)
+}
+
+func (c *chainedCore) handleTimeoutCert(data *bft.Message, src bft.Validator) error {
+	var tc *bft.TimeoutCert
+	if err := data.Decode(&tc); err != nil {
+		return errFailedDecodeTimeoutCert
+	}
+	if tc.View == nil {
+		return errInvalidMessage
+	}
+	if err := checkTimeoutView(tc.View, c.view); err != nil {
+		return err
+	}
+	if err := c.pacemaker.verifyTimeoutCert(tc, c.valSet); err != nil {
+		c.logger.Trace("Failed to verify timeout cert", "msg", MsgTypeTimeoutCert, "err", err)
+		return err
+	}
+	c.logger.Trace("handleTimeoutCert", "src", src.Address(), "tc", tc)
+
+	c.startNewRound(c.pacemaker.advance(tc))
+	return nil
+}
+
+// verifyQC verifies the quorum cert against the committed block it certifies.
+func (c *chainedCore) verifyQC(qc *bft.QuorumCert) error {
+	if qc == nil || qc.View == nil || qc.View.Height == nil {
+		return errInvalidMessage
+	}
+	block, ok := c.backend.GetProposal(qc.Hash).(*types.Block)
+	if !ok || block == nil {
+		return errInvalidProposal
+	}
+	return c.signer.VerifyQC(qc, block.Header(), c.backend.Validators(qc.HeightU64()))
+}
+
+// send signs the message and broadcasts it, or sends it to the leader of the given
+// validators.
+func (c *chainedCore) send(valSet bft.ValidatorSet, msg *bft.Message, broadcast bool) {
+	msg.Address = c.signer.Address()
+	if msg.View == nil {
+		msg.View = c.currentView()
+	}
+	data, err := msg.PayloadNoSig()
+	if err != nil {
+		c.logger.Error("Failed to finalize Message", "msg", msg, "err", err)
+		return
+	}
+	if msg.Signature, err = c.signer.Sign(data); err != nil {
+		c.logger.Error("Failed to finalize Message", "msg", msg, "err", err)
+		return
+	}
+	payload, err := msg.Payload()
+	if err != nil {
+		c.logger.Error("Failed to finalize Message", "msg", msg, "err", err)
+		return
+	}
+	if broadcast {
+		err = c.backend.Broadcast(valSet, payload)
+	} else {
+		err = c.backend.Unicast(valSet, payload)
+	}
+	if err != nil {
+		c.logger.Error("Failed to send Message", "msg", msg, "err", err)
+	}
+}
@@ -0,0 +1,437 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/message_set"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// newTestChainedCore creates the event driven core of the backend validator, the
+// event loop is stopped right away so the test drives the core on it's own.
+func newTestChainedCore(t *testing.T, b *testBackend) *chainedCore {
+	config := *bft.DefaultEventDrivenConfig
+	config.RequestTimeout = 60000
+	config.BlockPeriod = 0
+	c := NewChained(b, &config, b.signer).(*chainedCore)
+	if err := c.Start(nil); err != nil {
+		t.Fatalf("failed to start core: %v", err)
+	}
+	c.Stop()
+	return c
+}
+
+// newTestCertifiedChain certifies n blocks on top of the parent in the backend,
+// and records the views they were proposed in. The rounds default to zero.
+func newTestCertifiedChain(c *chainedCore, b *testBackend, parent *types.Block, addrs []common.Address, rounds map[uint64]int64, n int, nonce uint64) []*types.Block {
+	blocks := make([]*types.Block, n)
+	for i := range blocks {
+		blocks[i] = newTestBlock(parent, addrs, addrs[i%len(addrs)], nonce)
+		b.Certify(blocks[i])
+		c.views[proposalHash(blocks[i].Header())] = &bft.View{
+			Height: blocks[i].Number(),
+			Round:  big.NewInt(rounds[blocks[i].NumberU64()]),
+		}
+		parent = blocks[i]
+	}
+	return blocks
+}
+
+// newTestSealedVote returns the vote of the key for the proposal, carrying it's
+// committed seal over the given block.
+func newTestSealedVote(t *testing.T, key *ecdsa.PrivateKey, view *bft.View, proposal, sealed *types.Block) *bft.Message {
+	signer := snr.NewSigner(key)
+	payload, err := Encode(&Vote{View: view, Digest: proposal.Hash()})
+	if err != nil {
+		t.Fatalf("failed to encode vote: %v", err)
+	}
+	seal, err := signer.SignHash(view, signer.CommittedSealHash(sealed.Header()))
+	if err != nil {
+		t.Fatalf("failed to seal vote: %v", err)
+	}
+	return &bft.Message{Code: MsgTypePrepareVote, View: view, Msg: payload, Address: signer.Address(), CommittedSeal: seal}
+}
+
+// Tests that the quorum certs of the proposals raise the high qc, lock the
+// grandparent, and decide and commit the block below once the two blocks above it
+// were proposed in the first round of their height.
+func TestChainedUpdateChain(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+	backend := newTestBackend(keys[0], addrs, genesis)
+	c := newTestChainedCore(t, backend)
+
+	// The block 3 was proposed after a round change
+	blocks := newTestCertifiedChain(c, backend, genesis, addrs, map[uint64]int64{3: 1}, 6, 0)
+
+	tests := []struct {
+		justify *types.Block
+		locked  *types.Block // nil if nothing is locked
+		decided *types.Block // nil if nothing is decided
+	}{
+		// The certified child of the genesis locks nothing
+		{justify: blocks[0]},
+		// The certified grandchild locks the child, the genesis isn't a proposal
+		{justify: blocks[1], locked: blocks[0]},
+		// The block 1 isn't decided, the block 3 was proposed in a later round
+		{justify: blocks[2], locked: blocks[1]},
+		{justify: blocks[3], locked: blocks[2]},
+		// The blocks 4 and 5 were proposed in consecutive views, they decide 3
+		{justify: blocks[4], locked: blocks[3], decided: blocks[2]},
+		{justify: blocks[5], locked: blocks[4], decided: blocks[3]},
+	}
+	for i, tt := range tests {
+		decided := len(backend.decided)
+		c.updateChain(proposal2QC(tt.justify, common.Big0))
+
+		if c.highQC.Hash != tt.justify.Hash() {
+			t.Errorf("test %d: high qc mismatch: have %x, want %x", i, c.highQC.Hash, tt.justify.Hash())
+		}
+		switch {
+		case tt.locked == nil && c.lockedQC != nil:
+			t.Errorf("test %d: unexpected lock on %x", i, c.lockedQC.Hash)
+		case tt.locked != nil && (c.lockedQC == nil || c.lockedQC.Hash != tt.locked.Hash()):
+			t.Errorf("test %d: locked qc mismatch: have %v, want %x", i, c.lockedQC, tt.locked.Hash())
+		}
+		switch {
+		case tt.decided == nil && len(backend.decided) != decided:
+			t.Errorf("test %d: unexpected decision of %x", i, backend.decided[len(backend.decided)-1].Hash())
+		case tt.decided != nil && (len(backend.decided) != decided+1 || backend.decided[decided].Hash() != tt.decided.Hash()):
+			t.Errorf("test %d: decided mismatch: have %d decisions, want %x", i, len(backend.decided)-decided, tt.decided.Hash())
+		}
+		// Only the decided blocks are committed
+		if len(backend.committed) != len(backend.decided) {
+			t.Errorf("test %d: committed mismatch: have %d, want %d", i, len(backend.committed), len(backend.decided))
+		}
+	}
+	// Lower quorum certs don't move the high qc or the lock back
+	c.updateChain(proposal2QC(blocks[1], common.Big0))
+	if c.highQC.Hash != blocks[5].Hash() || c.lockedQC.Hash != blocks[4].Hash() {
+		t.Errorf("quorum certs moved back: high qc %x, locked qc %x", c.highQC.Hash, c.lockedQC.Hash)
+	}
+}
+
+// Tests that proposals are safe if they extend the locked block, or if they carry
+// a quorum cert above it.
+func TestChainedSafeNode(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+	backend := newTestBackend(keys[0], addrs, genesis)
+	c := newTestChainedCore(t, backend)
+
+	blocks := newTestCertifiedChain(c, backend, genesis, addrs, nil, 3, 0)
+	fork := newTestBlock(blocks[0], addrs, addrs[1], 1)
+	backend.Certify(fork)
+
+	// Nothing locked, everything is safe
+	if err := c.safeNode(newTestBlock(fork, addrs, addrs[2], 0), proposal2QC(fork, common.Big0)); err != nil {
+		t.Fatalf("proposal rejected without a lock: %v", err)
+	}
+	c.lockedQC = proposal2QC(blocks[1], common.Big0)
+
+	tests := []struct {
+		parent  *types.Block
+		justify *types.Block
+		err     error
+	}{
+		// Extending the locked block or it's descendants
+		{parent: blocks[1], justify: blocks[1]},
+		{parent: blocks[2], justify: blocks[1]},
+		// Extending a conflicting block of the same height
+		{parent: fork, justify: fork, err: errSafeNode},
+		// Extending a conflicting block with a higher quorum cert
+		{parent: fork, justify: blocks[2]},
+	}
+	for i, tt := range tests {
+		block := newTestBlock(tt.parent, addrs, addrs[3], 0)
+		if err := c.safeNode(block, proposal2QC(tt.justify, common.Big0)); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the leader of the next height seals and certifies a proposal once the
+// valid votes reach the quorum, and only once. The proposal isn't committed before
+// it's decided.
+func TestChainedCertify(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+
+	// The votes for the proposal of the first height go to the leader of the second
+	valSet := validator.NewSet(addrs, bft.RoundRobin)
+	valSet.CalcProposer(addrs[0], 0)
+	leader := valSet.GetProposer().Address()
+
+	var key *ecdsa.PrivateKey
+	for i, addr := range addrs {
+		if addr == leader {
+			key = keys[i]
+		}
+	}
+	backend := newTestBackend(key, addrs, genesis)
+	c := newTestChainedCore(t, backend)
+
+	view := &bft.View{Height: big.NewInt(1), Round: big.NewInt(0)}
+	block := newTestBlock(genesis, addrs, addrs[0], 0)
+	c.proposals[block.Hash()] = &chainedProposal{block: block, view: view}
+	c.votes[block.Hash()] = message_set.NewMessageSet(c.valSet)
+
+	vote := func(key *ecdsa.PrivateKey, sealed *types.Block) {
+		if err := c.votes[block.Hash()].Add(newTestSealedVote(t, key, view, block, sealed)); err != nil {
+			t.Fatalf("failed to add vote: %v", err)
+		}
+		c.certify(block.Hash())
+	}
+	// Votes with a seal of another block don't count
+	vote(keys[0], block)
+	vote(keys[1], block)
+	vote(keys[2], newTestBlock(genesis, addrs, addrs[0], 1))
+	if len(backend.certified) != 0 {
+		t.Fatalf("proposal certified below the quorum")
+	}
+	vote(keys[3], block)
+	if len(backend.certified) != 1 {
+		t.Fatalf("certified proposals mismatch: have %d, want 1", len(backend.certified))
+	}
+	if len(backend.committed) != 0 {
+		t.Fatalf("certified proposal committed before it's decided")
+	}
+	certified := backend.certified[0].(*types.Block)
+	if proposalHash(certified.Header()) != proposalHash(block.Header()) {
+		t.Fatalf("certified proposal mismatch: have %x, want %x", certified.Hash(), block.Hash())
+	}
+	extra, err := types.ExtractBftExtra(certified.Header())
+	if err != nil {
+		t.Fatalf("failed to extract committed seals: %v", err)
+	}
+	if len(extra.CommittedSeal) != 3 {
+		t.Fatalf("committed seals mismatch: have %d, want 3", len(extra.CommittedSeal))
+	}
+	// Later votes don't certify the proposal again
+	c.certify(block.Hash())
+	if len(backend.certified) != 1 {
+		t.Fatalf("proposal certified twice")
+	}
+}
+
+// Tests that a proposal of the current height is certified with the quorum cert
+// of the next height's proposals, if the cert has a valid quorum of seals. The
+// proposals certified by the certs of higher heights are fetched.
+func TestChainedCertifyJustified(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+	backend := newTestBackend(keys[1], addrs, genesis)
+	c := newTestChainedCore(t, backend)
+
+	view := &bft.View{Height: big.NewInt(1), Round: big.NewInt(0)}
+	block := newTestBlock(genesis, addrs, addrs[0], 0)
+	header := block.Header()
+	if err := snr.NewSigner(keys[0]).SealBeforeCommit(header); err != nil {
+		t.Fatalf("failed to seal proposal: %v", err)
+	}
+	block = block.WithSeal(header)
+	c.proposals[block.Hash()] = &chainedProposal{block: block, view: view}
+
+	// seal assembles the quorum cert of the proposal with the seals of the keys
+	seal := func(keys []*ecdsa.PrivateKey) *bft.QuorumCert {
+		var seals [][]byte
+		for _, key := range keys {
+			signer := snr.NewSigner(key)
+			seal, err := signer.SignHash(view, signer.CommittedSealHash(block.Header()))
+			if err != nil {
+				t.Fatalf("failed to seal proposal: %v", err)
+			}
+			seals = append(seals, seal)
+		}
+		sealed, err := backend.PreCommit(block, seals)
+		if err != nil {
+			t.Fatalf("failed to assemble committed seals: %v", err)
+		}
+		return proposal2QC(sealed, common.Big0)
+	}
+	_, src := c.valSet.GetByAddress(addrs[2])
+
+	// Certs of other heights, or without a quorum of seals, don't certify
+	future := seal(keys[:3])
+	future.Hash = common.HexToHash("0x01")
+	future.View = &bft.View{Height: big.NewInt(2), Round: big.NewInt(0)}
+	c.certifyJustified(future, src)
+	c.certifyJustified(seal(keys[:2]), src)
+	if len(backend.certified) != 0 || c.proposals[block.Hash()] == nil {
+		t.Fatalf("proposal certified without a valid quorum cert")
+	}
+	// The proposal of the higher height was missed
+	if len(backend.fetched) != 1 || backend.fetched[0] != future.Hash {
+		t.Fatalf("fetched proposals mismatch: have %v, want %x", backend.fetched, future.Hash)
+	}
+	justify := seal(keys[:3])
+	c.certifyJustified(justify, src)
+	if len(backend.certified) != 1 || backend.certified[0].Hash() != justify.Hash {
+		t.Fatalf("certified proposals mismatch: have %v, want %x", backend.certified, justify.Hash)
+	}
+	if len(backend.committed) != 0 {
+		t.Fatalf("certified proposal committed before it's decided")
+	}
+	if c.proposals[block.Hash()] != nil {
+		t.Fatalf("certified proposal kept")
+	}
+}
+
+// Tests that the blocks certified by a single quorum cert are not committed, a
+// competing fork certified in later views is committed instead once it's decided
+// by a three-chain.
+func TestChainedCompetingFork(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+	backend := newTestBackend(keys[0], addrs, genesis)
+	c := newTestChainedCore(t, backend)
+
+	// The blocks 1 and 2 are certified in the first round of their height
+	blocks := newTestCertifiedChain(c, backend, genesis, addrs, nil, 2, 0)
+	c.updateChain(proposal2QC(blocks[0], common.Big0))
+	c.updateChain(proposal2QC(blocks[1], common.Big0))
+	if len(backend.committed) != 0 {
+		t.Fatalf("block committed with a two-chain: %x", backend.committed[0].Hash())
+	}
+	// The leader of the third height missed the quorum cert of the block 2, the
+	// next round certifies a competing block 2 and the blocks above it
+	fork := newTestCertifiedChain(c, backend, blocks[0], addrs, map[uint64]int64{2: 1}, 3, 1)
+	for i, block := range fork {
+		c.updateChain(proposal2QC(block, common.Big0))
+		if i < len(fork)-1 && len(backend.committed) != 0 {
+			t.Fatalf("fork block %d committed without a three-chain: %x", i, backend.committed[0].Hash())
+		}
+	}
+	// The blocks 3 and 4 of the fork are proposed in consecutive views, they
+	// decide the competing block 2, the block 2 certified first is never committed
+	if len(backend.committed) != 1 || backend.committed[0].Hash() != fork[0].Hash() {
+		t.Fatalf("committed blocks mismatch: have %v, want %x", backend.committed, fork[0].Hash())
+	}
+	if len(backend.decided) != 1 || backend.decided[0].Hash() != fork[0].Hash() {
+		t.Fatalf("decided blocks mismatch: have %v, want %x", backend.decided, fork[0].Hash())
+	}
+	for _, committed := range backend.committed {
+		if committed.Hash() == blocks[1].Hash() {
+			t.Fatalf("reverted block committed: %x", blocks[1].Hash())
+		}
+	}
+}
+
+// Tests that the leader waits for the block period without blocking the event
+// loop, the proposal is sent once the timer posted it's event.
+func TestChainedProposalDelay(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+
+	valSet := validator.NewSet(addrs, bft.RoundRobin)
+	valSet.CalcProposer(genesis.Coinbase(), 0)
+	leader := valSet.GetProposer().Address()
+
+	var key *ecdsa.PrivateKey
+	for i, addr := range addrs {
+		if addr == leader {
+			key = keys[i]
+		}
+	}
+	backend := newTestBackend(key, addrs, genesis)
+	c := newTestChainedCore(t, backend)
+
+	sub := backend.mux.Subscribe(proposeEvent{})
+	defer sub.Unsubscribe()
+
+	c.config.BlockPeriod = 200
+	c.viewStart = time.Now()
+	c.request = &bft.Request{Proposal: newTestBlock(genesis, addrs, leader, 0)}
+
+	start := time.Now()
+	c.sendProposal()
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Fatalf("proposal blocked for %v", elapsed)
+	}
+	if backend.sentMessages(MsgTypePrepare) != 0 || c.proposal != nil {
+		t.Fatalf("proposal sent before the block period passed")
+	}
+	select {
+	case ev := <-sub.Chan():
+		if view := ev.Data.(proposeEvent).view; view.Cmp(c.view) != 0 {
+			t.Fatalf("propose event view mismatch: have %v, want %v", view, c.view)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("propose event not posted")
+	}
+	c.sendProposal()
+	if backend.sentMessages(MsgTypePrepare) != 1 || c.proposal == nil {
+		t.Fatalf("proposal not sent once the block period passed")
+	}
+}
+
+// Tests that the leader proposes a single block per view: a later request only
+// replaces the one waiting for the proposal timer, and is dropped once the view
+// was proposed.
+func TestChainedProposeOnce(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+
+	valSet := validator.NewSet(addrs, bft.RoundRobin)
+	valSet.CalcProposer(genesis.Coinbase(), 0)
+	leader := valSet.GetProposer().Address()
+
+	var key *ecdsa.PrivateKey
+	for i, addr := range addrs {
+		if addr == leader {
+			key = keys[i]
+		}
+	}
+	backend := newTestBackend(key, addrs, genesis)
+	c := newTestChainedCore(t, backend)
+
+	sub := backend.mux.Subscribe(proposeEvent{})
+	defer sub.Unsubscribe()
+
+	// The fresher request swaps the scheduled one
+	c.config.BlockPeriod = 200
+	c.viewStart = time.Now()
+	first := newTestBlock(genesis, addrs, leader, 1)
+	second := newTestBlock(genesis, addrs, leader, 2)
+
+	if err := c.handleRequest(&bft.Request{Proposal: first}); err != nil {
+		t.Fatalf("failed to handle request: %v", err)
+	}
+	if err := c.handleRequest(&bft.Request{Proposal: second}); err != nil {
+		t.Fatalf("failed to handle request: %v", err)
+	}
+	if backend.sentMessages(MsgTypePrepare) != 0 {
+		t.Fatalf("proposal sent before the block period passed")
+	}
+	select {
+	case <-sub.Chan():
+	case <-time.After(time.Second):
+		t.Fatalf("propose event not posted")
+	}
+	c.sendProposal()
+	if backend.sentMessages(MsgTypePrepare) != 1 {
+		t.Fatalf("proposal not sent once the block period passed")
+	}
+	if !c.IsCurrentProposal(second.Hash()) {
+		t.Fatalf("proposal mismatch: have %v, want %v", c.proposal.Hash(), second.Hash())
+	}
+	// Requests after the proposal don't propose the view again
+	third := newTestBlock(genesis, addrs, leader, 3)
+	if err := c.handleRequest(&bft.Request{Proposal: third}); err != nil {
+		t.Fatalf("failed to handle request: %v", err)
+	}
+	c.sendProposal()
+	if n := backend.sentMessages(MsgTypePrepare); n != 1 {
+		t.Fatalf("proposals sent in the view mismatch: have %d, want 1", n)
+	}
+	if !c.IsCurrentProposal(second.Hash()) {
+		t.Fatalf("proposal replaced: have %v, want %v", c.proposal.Hash(), second.Hash())
+	}
+}
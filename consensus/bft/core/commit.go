@@ -40,9 +40,9 @@ func (c *core) handlePreCommitVote(data *bft.Message, src bft.Validator) error {
 
 	logger.Trace("handlePreCommitVote", "src", src.Address(), "hash", vote.Digest)
 
-	if size := c.current.PreCommitVoteSize(); size >= c.Q() && c.currentState() < StatePreCommitted {
+	if power := c.current.PreCommitVotePower(); power >= c.Q() && c.currentState() < StatePreCommitted {
 		c.lockQCAndProposal(c.current.PrepareQC())
-		logger.Trace("acceptPreCommitted", "msg", msgTyp, "src", src.Address(), "hash", c.current.PreCommittedQC().Hash, "msgSize", size)
+		logger.Trace("acceptPreCommitted", "msg", msgTyp, "src", src.Address(), "hash", c.current.PreCommittedQC().Hash, "power", power)
 		c.sendCommit()
 	}
 	return nil
@@ -85,7 +85,7 @@ func (c *core) handleCommit(data *bft.Message, src bft.Validator) error {
 		logger.Trace("Failed to check prepareQC", "msg", msgTyp, "err", err)
 		return err
 	}
-	if err := c.signer.VerifyQC(msg, c.valSet); err != nil {
+	if err := c.verifyQC(msg, c.current.Proposal(), c.valSet); err != nil {
 		logger.Trace("Failed to check verify qc", "msg", msgTyp, "err", err)
 		return err
 	}
@@ -123,6 +123,10 @@ func (c *core) sendCommitVote() {
 		logger.Error("Failed to encode", "msg", msgTyp, "err", err)
 		return
 	}
+	if err := c.recordVote(msgTyp, vote); err != nil {
+		logger.Warn("Failed to record vote", "msg", msgTyp, "vote", vote, "err", err)
+		return
+	}
 	c.broadcast(&bft.Message{Code: msgTyp, Msg: payload})
 	logger.Trace("sendCommitVote", "vote view", vote.View, "vote", vote.Digest)
 
@@ -1,12 +1,12 @@
 package core
 
 import (
-	"math"
 	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -21,15 +21,25 @@ type core struct {
 	valSet   bft.ValidatorSet
 	requests *requestSet
 	backlogs *backlog
+	lastSeen map[common.Address]*bft.View // view of the latest message by validator
+
+	phases         *phaseTimer // time spent in each state
+	rounds         uint64      // rounds started since the engine started
+	roundChanges   uint64      // rounds started after the previous round of the height
+	proposerRounds uint64      // rounds led by the node
 
 	events            *event.TypeMuxSubscription
 	timeoutSub        *event.TypeMuxSubscription
 	finalCommittedSub *event.TypeMuxSubscription
 
-	roundChangeTimer *time.Timer
+	pacemaker *pacemaker
+
+	signed *signedDigests // digests signed by validators, for equivocation detection
+	safety *safetyState   // persisted voting state of the validator
 
 	validateFn func([]byte, []byte) (common.Address, error)
 	isRunning  bool
+	loopDone   chan struct{} // closed once the event loop returns
 }
 
 // New creates an HotStuff consensus core
@@ -40,7 +50,10 @@ func New(backend bft.Backend, config *bft.Config, signer bft.Signer) bft.CoreEng
 		backend: backend,
 	}
 	c.validateFn = c.checkValidatorSignature
+	c.signed = newSignedDigests()
+	c.safety = new(safetyState)
 	c.signer = signer
+	c.pacemaker = newPacemaker(config, backend, signer)
 
 	return c
 }
@@ -66,6 +79,39 @@ func (c *core) IsCurrentProposal(blockHash common.Hash) bool {
 	return false
 }
 
+// RoundState implements bft.CoreEngine.RoundState
+func (c *core) RoundState() *bft.RoundState {
+	if !c.isRunning {
+		return nil
+	}
+	return requestRoundState(c.backend.EventMux())
+}
+
+// roundState returns a snapshot of the consensus state, it's called by the event loop.
+func (c *core) roundState() *bft.RoundState {
+	if c.current == nil {
+		return nil
+	}
+	rs := &bft.RoundState{
+		View:     c.currentView(),
+		State:    c.currentState().String(),
+		Proposer: c.currentProposer().Address(),
+		LockedQC: copyQC(c.current.PreCommittedQC()),
+		HighQC:   copyQC(c.current.HighQC()),
+		Backlogs: c.backlogs.sizes(),
+		LastSeen: copyViews(c.lastSeen),
+
+		Rounds:         c.rounds,
+		RoundChanges:   c.roundChanges,
+		ProposerRounds: c.proposerRounds,
+		PhaseTimes:     c.phases.snapshot(),
+	}
+	if proposal := c.current.Proposal(); proposal != nil {
+		rs.Proposal = proposal.Hash()
+	}
+	return rs
+}
+
 const maxRetry uint64 = 10
 
 func (c *core) startNewRound(round *big.Int) {
@@ -130,9 +176,20 @@ catchup:
 
 	// calculate new proposal and init round state
 	c.valSet = c.backend.Validators(newView.Height.Uint64())
+	if c.valSet.Policy() == bft.VRF {
+		// the proposer falls back to round robin without the vrf output of last block
+		var seed common.Hash
+		if block, ok := lastProposal.(*types.Block); ok {
+			seed = c.signer.Randomness(block.Header())
+		} else {
+			logger.Warn("Last proposal is not a block, select proposer by round robin", "hash", lastProposal.Hash())
+		}
+		c.valSet.SetSeed(seed)
+	}
 	c.valSet.CalcProposer(lastProposer, newView.Round.Uint64())
 	prepareQC := proposal2QC(lastProposal, common.Big0)
 	c.current = newRoundState(newView, c.valSet, prepareQC)
+	c.current.timer = c.phases
 	if changeView && lastProposalLocked && lastLockedProposal != nil {
 		c.current.SetProposal(lastLockedProposal)
 		c.current.LockProposal()
@@ -140,7 +197,17 @@ catchup:
 	if changeView && lastPendingRequest != nil {
 		c.current.SetPendingRequest(lastPendingRequest)
 	}
+	if qc := c.safety.HighQC; qc != nil && qc.Hash == lastProposal.Hash() {
+		c.current.SetHighQC(qc.Copy())
+	}
 
+	c.rounds++
+	if newView.Round.Sign() > 0 {
+		c.roundChanges++
+	}
+	if c.IsProposer() {
+		c.proposerRounds++
+	}
 	logger.Debug("New round", "state", c.currentState(), "newView", newView, "new_proposer", c.valSet.GetProposer(), "valSet", c.valSet.List(), "size", c.valSet.Size(), "IsProposer", c.IsProposer())
 
 	// process pending request
@@ -148,7 +215,7 @@ catchup:
 	c.sendNewView(newView)
 
 	// stop last timer and regenerate new timer
-	c.newRoundChangeTimer()
+	c.pacemaker.startRound(newView, c.valSet)
 }
 
 func (c *core) currentView() *bft.View {
@@ -171,26 +238,6 @@ func (c *core) currentProposer() bft.Validator {
 	return c.valSet.GetProposer()
 }
 
-func (c *core) Q() int {
+func (c *core) Q() uint64 {
 	return c.valSet.Q()
 }
-
-func (c *core) stopTimer() {
-	if c.roundChangeTimer != nil {
-		c.roundChangeTimer.Stop()
-	}
-}
-
-func (c *core) newRoundChangeTimer() {
-	c.stopTimer()
-
-	// set timeout based on the round number
-	timeout := time.Duration(c.config.RequestTimeout) * time.Millisecond
-	round := c.current.Round().Uint64()
-	if round > 0 {
-		timeout += time.Duration(math.Pow(2, float64(round))) * time.Second
-	}
-	c.roundChangeTimer = time.AfterFunc(timeout, func() {
-		c.sendEvent(timeoutEvent{})
-	})
-}
@@ -41,10 +41,10 @@ func (c *core) handleCommitVote(data *bft.Message, src bft.Validator) error {
 
 	logger.Trace("handleCommitVote", "msg", msgTyp, "src", src.Address(), "hash", vote.Digest)
 
-	if size := c.current.CommitVoteSize(); size >= c.Q() && c.currentState() < StateCommitted {
+	if power := c.current.CommitVotePower(); power >= c.Q() && c.currentState() < StateCommitted {
 		c.current.SetState(StateCommitted)
 		c.current.SetCommittedQC(c.current.PreCommittedQC())
-		logger.Trace("acceptCommit", "msg", msgTyp, "src", src.Address(), "hash", vote.Digest, "msgSize", size)
+		logger.Trace("acceptCommit", "msg", msgTyp, "src", src.Address(), "hash", vote.Digest, "power", power)
 		if err := c.backend.Commit(c.current.Proposal()); err != nil {
 			logger.Trace("Failed to commit proposal", "err", err)
 			return err
@@ -45,4 +45,18 @@ var (
 	errAddPrepareVote         = errors.New("add prepare vote error")
 	errAddPreCommitVote       = errors.New("add pre commit vote error")
 	errBadEpochValidators     = errors.New("last epoch validator set is empty")
+	// errFailedDecodeTimeout is returned when the TIMEOUT Message is malformed.
+	errFailedDecodeTimeout     = errors.New("failed to decode TIMEOUT")
+	errFailedDecodeTimeoutCert = errors.New("failed to decode TIMEOUT_CERT")
+	errAddTimeout              = errors.New("add timeout error")
+
+	// errVotedView is returned if a vote conflicts with the votes the validator
+	// already sent.
+	errVotedView = errors.New("view already voted")
+	// errLockedQC is returned if a proposal conflicts with the persisted lockedQC.
+	errLockedQC = errors.New("proposal conflicts with locked qc")
+
+	// ErrInvalidEvidence is returned if the messages of an equivocation evidence are
+	// not signed by the offender, or they don't conflict.
+	ErrInvalidEvidence = errors.New("invalid equivocation evidence")
 )
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// signedKey identifies the messages of a validator which must not conflict.
+type signedKey struct {
+	address common.Address
+	code    MsgType
+	height  uint64
+	round   uint64
+}
+
+// signedDigest is the first digest signed by a validator for a signedKey, along
+// with the signed message which proves it.
+type signedDigest struct {
+	digest  common.Hash
+	payload []byte
+}
+
+// signedDigests records the digests signed by validators in the recent views.
+type signedDigests struct {
+	mu      sync.Mutex
+	digests map[signedKey]*signedDigest
+	height  uint64 // lowest height of the recorded digests
+}
+
+func newSignedDigests() *signedDigests {
+	return &signedDigests{digests: make(map[signedKey]*signedDigest)}
+}
+
+// add records the digest of the message, and returns the earlier message if it
+// signed another digest. records below the given height are dropped because old
+// messages are not accepted anymore.
+func (s *signedDigests) add(key signedKey, digest common.Hash, payload []byte, height uint64) *signedDigest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if height > s.height {
+		for k := range s.digests {
+			if k.height < height {
+				delete(s.digests, k)
+			}
+		}
+		s.height = height
+	}
+	if key.height < s.height {
+		return nil
+	}
+	if prev, ok := s.digests[key]; ok {
+		if prev.digest != digest {
+			return prev
+		}
+		return nil
+	}
+	s.digests[key] = &signedDigest{digest: digest, payload: payload}
+	return nil
+}
+
+// checkEquivocation records the digest signed by the message, and hands the
+// evidence to the backend if the sender already signed a conflicting one in the
+// same view. only proposals and votes are tracked, other messages are derived
+// from them.
+func (c *core) checkEquivocation(msg *bft.Message, payload []byte) {
+	reportEquivocation(c.signed, c.backend, c.logger, msg, payload, c.currentView().Height.Uint64())
+}
+
+// reportEquivocation records the digest signed by the message, messages below the
+// given height are not tracked anymore.
+func reportEquivocation(signed *signedDigests, backend bft.Backend, logger log.Logger, msg *bft.Message, payload []byte, height uint64) {
+	code, ok := msg.Code.(MsgType)
+	if !ok || msg.View == nil || msg.View.Height == nil || msg.View.Round == nil {
+		return
+	}
+	digest, ok := messageDigest(msg)
+	if !ok {
+		return
+	}
+	key := signedKey{
+		address: msg.Address,
+		code:    code,
+		height:  msg.View.Height.Uint64(),
+		round:   msg.View.Round.Uint64(),
+	}
+	prev := signed.add(key, digest, payload, height)
+	if prev == nil {
+		return
+	}
+	// the messages are ordered by digest, nodes which received them in the other
+	// order hold the same evidence
+	first, second := prev.payload, payload
+	if bytes.Compare(digest.Bytes(), prev.digest.Bytes()) < 0 {
+		first, second = second, first
+	}
+	ev := &bft.Evidence{
+		Offender: msg.Address,
+		Code:     code.Value(),
+		View:     msg.View,
+		First:    first,
+		Second:   second,
+	}
+	logger.Warn("Validator equivocated", "offender", msg.Address, "type", code, "view", msg.View, "first", prev.digest, "second", digest)
+	if err := backend.HandleEvidence(ev); err != nil {
+		logger.Warn("Failed to handle equivocation evidence", "evidence", ev, "err", err)
+	}
+}
+
+// messageDigest returns the proposal digest signed by proposals and votes.
+func messageDigest(msg *bft.Message) (common.Hash, bool) {
+	switch msg.Code {
+	case MsgTypePrepare:
+		var prepare *MsgPrepare
+		if err := msg.Decode(&prepare); err != nil || prepare.Proposal == nil {
+			return common.Hash{}, false
+		}
+		return prepare.Proposal.Hash(), true
+
+	case MsgTypePrepareVote, MsgTypePreCommitVote, MsgTypeCommitVote:
+		var vote *Vote
+		if err := msg.Decode(&vote); err != nil {
+			return common.Hash{}, false
+		}
+		return vote.Digest, true
+	}
+	return common.Hash{}, false
+}
+
+// VerifyEvidence checks that both messages of the evidence are signed by the
+// offender, a validator of the given set, and that they sign different digests
+// in the same view. The first message must sign the lower digest, so the same
+// offence has a single evidence hash.
+func VerifyEvidence(ev *bft.Evidence, signer bft.Signer, valSet bft.ValidatorSet) error {
+	registerMsgTypes()
+
+	if ev.View == nil || ev.View.Height == nil || ev.View.Round == nil {
+		return ErrInvalidEvidence
+	}
+	validateFn := func(data []byte, sig []byte) (common.Address, error) {
+		return signer.CheckSignature(valSet, data, sig)
+	}
+	var digests []common.Hash
+	for _, payload := range [][]byte{ev.First, ev.Second} {
+		msg := new(bft.Message)
+		if err := msg.FromPayload(payload, validateFn); err != nil {
+			return ErrInvalidEvidence
+		}
+		if msg.Address != ev.Offender || msg.Code.Value() != ev.Code || msg.View == nil || msg.View.Cmp(ev.View) != 0 {
+			return ErrInvalidEvidence
+		}
+		digest, ok := messageDigest(msg)
+		if !ok {
+			return ErrInvalidEvidence
+		}
+		digests = append(digests, digest)
+	}
+	if bytes.Compare(digests[0].Bytes(), digests[1].Bytes()) >= 0 {
+		return ErrInvalidEvidence
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// evidenceBackend records the evidence handed over by the core.
+type evidenceBackend struct {
+	bft.Backend
+	evidence []*bft.Evidence
+}
+
+func (b *evidenceBackend) HandleEvidence(ev *bft.Evidence) error {
+	b.evidence = append(b.evidence, ev)
+	return nil
+}
+
+// newTestSignedVote returns the vote of the key for the digest, and it's signed
+// payload.
+func newTestSignedVote(t *testing.T, key *ecdsa.PrivateKey, view *bft.View, digest common.Hash) (*bft.Message, []byte) {
+	enc, err := Encode(&Vote{View: view, Digest: digest})
+	if err != nil {
+		t.Fatalf("failed to encode vote: %v", err)
+	}
+	msg := &bft.Message{Code: MsgTypePrepareVote, View: view, Msg: enc, Address: crypto.PubkeyToAddress(key.PublicKey)}
+	data, err := msg.PayloadNoSig()
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	if msg.Signature, err = crypto.Sign(crypto.Keccak256(data), key); err != nil {
+		t.Fatalf("failed to sign message: %v", err)
+	}
+	payload, err := msg.Payload()
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+	return msg, payload
+}
+
+// Tests that nodes which receive the conflicting messages of a validator in the
+// opposite order report the same evidence, and that evidence out of order is
+// refused.
+func TestEvidenceOrder(t *testing.T) {
+	registerMsgTypes()
+
+	keys := make([]*ecdsa.PrivateKey, 4)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	view := &bft.View{Height: big.NewInt(1), Round: big.NewInt(0)}
+
+	low, lowPayload := newTestSignedVote(t, keys[3], view, common.HexToHash("0x01"))
+	high, highPayload := newTestSignedVote(t, keys[3], view, common.HexToHash("0x02"))
+
+	var evidence []*bft.Evidence
+	for _, order := range [][]*bft.Message{{low, high}, {high, low}} {
+		backend := new(evidenceBackend)
+		c := &core{
+			backend: backend,
+			logger:  log.New(),
+			signed:  newSignedDigests(),
+			current: &roundState{height: big.NewInt(1), round: big.NewInt(0)},
+		}
+		for _, msg := range order {
+			payload := lowPayload
+			if msg == high {
+				payload = highPayload
+			}
+			c.checkEquivocation(msg, payload)
+		}
+		if len(backend.evidence) != 1 {
+			t.Fatalf("evidence count mismatch: have %d, want 1", len(backend.evidence))
+		}
+		evidence = append(evidence, backend.evidence[0])
+	}
+	if evidence[0].Hash() != evidence[1].Hash() {
+		t.Fatalf("evidence hash depends on the message order: %v != %v", evidence[0].Hash(), evidence[1].Hash())
+	}
+	valSet := validator.NewSet(addrs, bft.RoundRobin)
+	verifier := signer.NewSigner(keys[0])
+	if err := VerifyEvidence(evidence[0], verifier, valSet); err != nil {
+		t.Fatalf("failed to verify evidence: %v", err)
+	}
+	swapped := *evidence[0]
+	swapped.First, swapped.Second = swapped.Second, swapped.First
+	if err := VerifyEvidence(&swapped, verifier, valSet); err != ErrInvalidEvidence {
+		t.Fatalf("swapped evidence error mismatch: have %v, want %v", err, ErrInvalidEvidence)
+	}
+}
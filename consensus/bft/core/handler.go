@@ -1,7 +1,6 @@
 package core
 
 import (
-	"math/big"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -13,16 +12,17 @@ var once sync.Once
 
 // Start implements core.Engine.Start
 func (c *core) Start(chain consensus.ChainReader) error {
-	once.Do(func() {
-		bft.RegisterMsgTypeConvertHandler(func(data interface{}) bft.MsgType {
-			code := data.(uint64)
-			return MsgType(code)
-		})
-	})
+	registerMsgTypes()
 
+	if err := c.loadSafetyState(); err != nil {
+		return err
+	}
 	c.isRunning = true
 	c.requests = newRequestSet()
 	c.backlogs = newBackLog()
+	c.lastSeen = make(map[common.Address]*bft.View)
+	c.phases = newPhaseTimer()
+	c.rounds, c.roundChanges, c.proposerRounds = 0, 0, 0
 	c.current = nil
 
 	// Start a new round from last sequence + 1
@@ -31,14 +31,26 @@ func (c *core) Start(chain consensus.ChainReader) error {
 	// Tests will handle events itself, so we have to make subscribeEvents()
 	// be able to call in test.
 	c.subscribeEvents()
+	c.loopDone = make(chan struct{})
 	go c.handleEvents()
 	return nil
 }
 
-// Stop implements core.Engine.Stop
+// registerMsgTypes registers the message codes of core for decoding bft messages.
+func registerMsgTypes() {
+	once.Do(func() {
+		bft.RegisterMsgTypeConvertHandler(func(data interface{}) bft.MsgType {
+			code := data.(uint64)
+			return MsgType(code)
+		})
+	})
+}
+
+// Stop implements core.Engine.Stop, it returns once the event loop stopped.
 func (c *core) Stop() error {
-	c.stopTimer()
+	c.pacemaker.stop()
 	c.unsubscribeEvents()
+	<-c.loopDone
 	c.isRunning = false
 	return nil
 }
@@ -53,6 +65,7 @@ func (c *core) subscribeEvents() {
 		bft.MessageEvent{},
 		// internal events
 		backlogEvent{},
+		roundStateEvent{},
 	)
 	c.timeoutSub = c.backend.EventMux().Subscribe(
 		timeoutEvent{},
@@ -70,6 +83,7 @@ func (c *core) unsubscribeEvents() {
 }
 
 func (c *core) handleEvents() {
+	defer close(c.loopDone)
 	logger := c.logger.New("handleEvents", "state", c.currentState())
 
 	for {
@@ -89,15 +103,21 @@ func (c *core) handleEvents() {
 
 			case backlogEvent:
 				c.handleCheckedMsg(ev.msg, ev.src)
+
+			case roundStateEvent:
+				ev.result <- c.roundState()
 			}
 
-		case _, ok := <-c.timeoutSub.Chan():
+		case evt, ok := <-c.timeoutSub.Chan():
 			//logger.Trace("handle timeout Event")
 			if !ok {
 				logger.Error("Failed to receive timeout Event")
 				return
 			}
-			c.handleTimeoutMsg()
+			// timers of the rounds already left are ignored
+			if ev, ok := evt.Data.(timeoutEvent); ok && ev.view.Cmp(c.currentView()) == 0 {
+				c.handleTimeoutMsg()
+			}
 
 		case evt, ok := <-c.finalCommittedSub.Chan():
 			if !ok {
@@ -133,6 +153,12 @@ func (c *core) handleMsg(payload []byte) error {
 		logger.Error("Invalid address in Message", "msg", msg)
 		return errInvalidSigner
 	}
+	if msg.View != nil && msg.View.Height != nil && msg.View.Round != nil {
+		c.lastSeen[src.Address()] = msg.View
+	}
+
+	// Record the signed digest and report the sender if it conflicts
+	c.checkEquivocation(msg, payload)
 
 	// handle checked Message
 	if err := c.handleCheckedMsg(msg, src); err != nil {
@@ -157,6 +183,10 @@ func (c *core) handleCheckedMsg(msg *bft.Message, src bft.Validator) (err error)
 		err = c.handleCommit(msg, src)
 	case MsgTypeCommitVote:
 		err = c.handleCommitVote(msg, src)
+	case MsgTypeTimeout:
+		err = c.handleTimeout(msg, src)
+	case MsgTypeTimeoutCert:
+		err = c.handleTimeoutCert(msg, src)
 	default:
 		err = errInvalidMessage
 		c.logger.Error("msg type invalid", "unknown type", msg.Code)
@@ -170,6 +200,5 @@ func (c *core) handleCheckedMsg(msg *bft.Message, src bft.Validator) (err error)
 
 func (c *core) handleTimeoutMsg() {
 	c.logger.Trace("handleTimeout", "state", c.currentState(), "view", c.currentView())
-	round := new(big.Int).Add(c.current.Round(), common.Big1)
-	c.startNewRound(round)
+	c.sendTimeout()
 }
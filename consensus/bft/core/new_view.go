@@ -64,11 +64,11 @@ func (c *core) handleNewView(data *bft.Message, src bft.Validator) error {
 
 	logger.Trace("handleNewView", "msg", msgTyp, "src", src.Address(), "prepareQC", msg.PrepareQC.Hash)
 
-	if size := c.current.NewViewSize(); size >= c.Q() && c.currentState() < StateHighQC {
+	if power := c.current.NewViewPower(); power >= c.Q() && c.currentState() < StateHighQC {
 		highQC := c.getHighQC()
 		c.current.SetHighQC(highQC)
 		c.current.SetState(StateHighQC)
-		logger.Trace("acceptHighQC", "msg", msgTyp, "src", src.Address(), "prepareQC", msg.PrepareQC.Hash, "msgSize", size)
+		logger.Trace("acceptHighQC", "msg", msgTyp, "src", src.Address(), "prepareQC", msg.PrepareQC.Hash, "power", power)
 
 		c.sendPrepare()
 	}
@@ -0,0 +1,229 @@
+package core
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/message_set"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	roundDurationTimer = metrics.NewRegisteredTimer("bft/round/duration", nil)
+	roundGauge         = metrics.NewRegisteredGauge("bft/round/current", nil)
+	roundTimeoutMeter  = metrics.NewRegisteredMeter("bft/round/timeouts", nil)
+	timeoutCertMeter   = metrics.NewRegisteredMeter("bft/round/timeoutcerts", nil)
+)
+
+// pacemaker keeps the validators in the same round of a height. The timeout of a
+// round doubles with every round, a validator which times out broadcasts a signed
+// timeout, and the timeouts of a quorum make a timeout cert which moves every node
+// seeing it to the next round. Validators also give up a later round once more than
+// F of the voting power timed out in it, which gathers the nodes that drifted apart.
+type pacemaker struct {
+	config  *bft.Config
+	backend bft.Backend
+	signer  bft.Signer
+
+	view       *bft.View
+	single     bool // whether the node is the only validator of the height
+	roundStart time.Time
+	attempts   uint64                             // timeouts sent by the node in the round
+	timeouts   map[uint64]*message_set.MessageSet // timeouts of the height, by round
+	highTC     *bft.TimeoutCert                   // highest timeout cert of the height
+
+	timer   *time.Timer
+	timerMu sync.Mutex // Protects the timer, it's stopped outside of the event loop
+}
+
+func newPacemaker(config *bft.Config, backend bft.Backend, signer bft.Signer) *pacemaker {
+	return &pacemaker{
+		config:   config,
+		backend:  backend,
+		signer:   signer,
+		timeouts: make(map[uint64]*message_set.MessageSet),
+	}
+}
+
+// startRound records the duration of the previous round, and arms the timeout of
+// the new one for the validators of the height.
+func (p *pacemaker) startRound(view *bft.View, valSet bft.ValidatorSet) {
+	now := time.Now()
+	if p.view != nil {
+		roundDurationTimer.Update(now.Sub(p.roundStart))
+	}
+	if p.view == nil || p.view.Height.Cmp(view.Height) != 0 {
+		p.timeouts = make(map[uint64]*message_set.MessageSet)
+		p.highTC = nil
+	}
+	for round := range p.timeouts {
+		if round < view.Round.Uint64() {
+			delete(p.timeouts, round)
+		}
+	}
+	p.view = &bft.View{
+		Height: new(big.Int).Set(view.Height),
+		Round:  new(big.Int).Set(view.Round),
+	}
+	p.single = valSet.Size() == 1
+	p.roundStart = now
+	p.attempts = 0
+	roundGauge.Update(view.Round.Int64())
+
+	p.resetTimer()
+}
+
+// resetTimer arms the timeout of the current round. It's rearmed once the node timed
+// out, so the timeout is sent again until a timeout cert is assembled.
+func (p *pacemaker) resetTimer() {
+	p.stop()
+
+	p.timerMu.Lock()
+	defer p.timerMu.Unlock()
+
+	view := p.view
+	timeout := p.config.RoundTimeout(view.Round.Uint64())
+
+	// The single validator of an on-demand chain waits for transactions in the first
+	// round. With more validators, the others have to take over from an offline
+	// proposer.
+	if p.config.OnDemand && p.single && view.Round.Sign() == 0 {
+		timeout = 0
+	}
+	if timeout == 0 {
+		p.timer = nil
+		return
+	}
+	p.timer = time.AfterFunc(timeout, func() {
+		p.backend.EventMux().Post(timeoutEvent{view: view})
+	})
+}
+
+func (p *pacemaker) stop() {
+	p.timerMu.Lock()
+	defer p.timerMu.Unlock()
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+}
+
+// timeout returns the timeout message of the current round, sealed by the node.
+func (p *pacemaker) timeout(highQC *bft.QuorumCert) (*bft.Message, error) {
+	roundTimeoutMeter.Mark(1)
+	p.resetTimer()
+
+	view := &bft.View{
+		Height: new(big.Int).Set(p.view.Height),
+		Round:  new(big.Int).Set(p.view.Round),
+	}
+	p.attempts++
+	payload, err := Encode(&MsgTimeout{View: view, HighQC: highQC, Attempt: p.attempts})
+	if err != nil {
+		return nil, err
+	}
+	seal, err := p.signer.SignHash(view, bft.TimeoutDigest(view))
+	if err != nil {
+		return nil, err
+	}
+	return &bft.Message{Code: MsgTypeTimeout, Msg: payload, View: view, CommittedSeal: seal}, nil
+}
+
+// addTimeout records the timeout of a validator for a round of the current height,
+// and returns the timeout cert of the round once it's timeouts reach the quorum.
+// The cert is only returned by the timeout crossing the quorum, later timeouts of
+// the round don't assemble and broadcast it again.
+func (p *pacemaker) addTimeout(msg *bft.Message, valSet bft.ValidatorSet) (*bft.TimeoutCert, error) {
+	timeout, err := decodeTimeout(msg)
+	if err != nil {
+		return nil, err
+	}
+	digest := bft.TimeoutDigest(timeout.View)
+	if err := p.signer.VerifyHash(valSet, timeout.View.Height.Uint64(), digest, msg.CommittedSeal); err != nil {
+		return nil, err
+	}
+	round := timeout.View.Round.Uint64()
+	timeouts, ok := p.timeouts[round]
+	if !ok {
+		timeouts = message_set.NewMessageSet(valSet)
+		p.timeouts[round] = timeouts
+	}
+	reached := timeouts.Power() >= valSet.Q()
+	if err := timeouts.Add(msg); err != nil {
+		return nil, errAddTimeout
+	}
+	if reached || timeouts.Power() < valSet.Q() {
+		return nil, nil
+	}
+	seals := make([][]byte, 0, timeouts.Size())
+	for _, msg := range timeouts.Values() {
+		seals = append(seals, msg.CommittedSeal)
+	}
+	seal, err := p.signer.AggregateSeals(valSet, timeout.View.Height.Uint64(), digest, seals)
+	if err != nil {
+		return nil, err
+	}
+	return &bft.TimeoutCert{View: timeout.View, Seal: seal}, nil
+}
+
+// decodeTimeout decodes the timeout of the message, the view of the timeout must
+// be the view of the message.
+func decodeTimeout(msg *bft.Message) (*MsgTimeout, error) {
+	var timeout *MsgTimeout
+	if err := msg.Decode(&timeout); err != nil {
+		return nil, errFailedDecodeTimeout
+	}
+	if timeout.View == nil || timeout.View.Height == nil || timeout.View.Round == nil || timeout.View.Cmp(msg.View) != 0 {
+		return nil, errInvalidMessage
+	}
+	if timeout.HighQC == nil || timeout.HighQC.View == nil || timeout.HighQC.View.Height == nil {
+		return nil, errInvalidMessage
+	}
+	return timeout, nil
+}
+
+// joinRound returns the highest round above the current one which validators with
+// more than F of the voting power timed out in. An honest validator gave it up, so
+// the node may skip the rounds below and give it up as well.
+func (p *pacemaker) joinRound(valSet bft.ValidatorSet) (uint64, bool) {
+	var (
+		current = p.view.Round.Uint64()
+		join    uint64
+		ok      bool
+	)
+	for round, timeouts := range p.timeouts {
+		if round > current && round > join && timeouts.Power() > valSet.F() {
+			join, ok = round, true
+		}
+	}
+	return join, ok
+}
+
+// verifyTimeoutCert checks the aggregated seal of a timeout cert.
+func (p *pacemaker) verifyTimeoutCert(tc *bft.TimeoutCert, valSet bft.ValidatorSet) error {
+	if tc == nil || tc.View == nil || tc.View.Height == nil || tc.View.Round == nil {
+		return errInvalidMessage
+	}
+	return p.signer.VerifyAggregatedSeal(valSet, tc.View.Height.Uint64(), bft.TimeoutDigest(tc.View), tc.Seal)
+}
+
+// advance records the timeout cert which moves the node to the next round.
+func (p *pacemaker) advance(tc *bft.TimeoutCert) *big.Int {
+	timeoutCertMeter.Mark(1)
+	if p.highTC == nil || p.highTC.View.Cmp(tc.View) < 0 {
+		p.highTC = tc
+	}
+	return new(big.Int).Add(tc.View.Round, big.NewInt(1))
+}
+
+// checkTimeoutView accepts the timeouts and timeout certs of the current height
+// from the current round on, the later rounds are the ones they move the node to.
+func checkTimeoutView(view *bft.View, current *bft.View) error {
+	err := compareView(view, current)
+	if err == errFutureMessage && view.Height.Cmp(current.Height) == 0 {
+		return nil
+	}
+	return err
+}
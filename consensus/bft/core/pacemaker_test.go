@@ -0,0 +1,201 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+)
+
+// Tests that the round timeout doubles with every round up to the max timeout.
+func TestRoundTimeoutBackoff(t *testing.T) {
+	config := &bft.Config{RequestTimeout: 1000, MaxTimeout: 10000}
+	tests := []struct {
+		round   uint64
+		timeout time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second},
+		{1000, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if timeout := config.RoundTimeout(tt.round); timeout != tt.timeout {
+			t.Errorf("round %d: timeout mismatch: have %v, want %v", tt.round, timeout, tt.timeout)
+		}
+	}
+	config.MaxTimeout = 0
+	if timeout := config.RoundTimeout(5); timeout != time.Second {
+		t.Errorf("timeout without backoff mismatch: have %v, want %v", timeout, time.Second)
+	}
+}
+
+// Tests that the first round of a height waits for the max idle interval when empty
+// blocks are skipped.
+func TestRoundTimeoutIdle(t *testing.T) {
+	config := &bft.Config{RequestTimeout: 1000, MaxTimeout: 10000, SkipEmpty: true, MaxIdle: 5000}
+	if timeout := config.RoundTimeout(0); timeout != 6*time.Second {
+		t.Errorf("idle round timeout mismatch: have %v, want %v", timeout, 6*time.Second)
+	}
+	if timeout := config.RoundTimeout(1); timeout != 2*time.Second {
+		t.Errorf("later round timeout mismatch: have %v, want %v", timeout, 2*time.Second)
+	}
+}
+
+// Tests that the first round of an on-demand chain only waits for transactions
+// without timeout if the node is the single validator.
+func TestPacemakerOnDemand(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+	backend := newTestBackend(keys[0], addrs, genesis)
+
+	config := *bft.DefaultBasicConfig
+	config.OnDemand = true
+	p := newPacemaker(&config, backend, backend.signer)
+	defer p.stop()
+
+	tests := []struct {
+		validators []common.Address
+		round      int64
+		armed      bool
+	}{
+		{addrs[:1], 0, false},
+		{addrs[:1], 1, true},
+		{addrs, 0, true},
+		{addrs, 1, true},
+	}
+	for i, tt := range tests {
+		p.startRound(&bft.View{Height: big.NewInt(int64(i + 1)), Round: big.NewInt(tt.round)}, validator.NewSet(tt.validators, bft.RoundRobin))
+		if armed := p.timer != nil; armed != tt.armed {
+			t.Errorf("test %d: timer armed mismatch: have %v, want %v", i, armed, tt.armed)
+		}
+	}
+}
+
+// newTestTimeout returns the timeout message of the view signed by the key.
+func newTestTimeout(t *testing.T, key *ecdsa.PrivateKey, view *bft.View, highQC *bft.QuorumCert) *bft.Message {
+	signer := snr.NewSigner(key)
+	payload, err := Encode(&MsgTimeout{View: view, HighQC: highQC, Attempt: 1})
+	if err != nil {
+		t.Fatalf("failed to encode timeout: %v", err)
+	}
+	seal, err := signer.SignHash(view, bft.TimeoutDigest(view))
+	if err != nil {
+		t.Fatalf("failed to seal timeout: %v", err)
+	}
+	return &bft.Message{Code: MsgTypeTimeout, View: view, Msg: payload, Address: signer.Address(), CommittedSeal: seal}
+}
+
+// Tests that the timeouts of a quorum make a valid timeout cert, and that the node
+// joins a later round which more than F of the validators gave up.
+func TestPacemakerTimeouts(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+	backend := newTestBackend(keys[0], addrs, genesis)
+	valSet := validator.NewSet(addrs, bft.RoundRobin)
+	highQC := proposal2QC(genesis, common.Big0)
+
+	config := *bft.DefaultBasicConfig
+	p := newPacemaker(&config, backend, backend.signer)
+	p.startRound(&bft.View{Height: big.NewInt(1), Round: big.NewInt(0)}, valSet)
+	defer p.stop()
+
+	later := &bft.View{Height: big.NewInt(1), Round: big.NewInt(2)}
+	for i, key := range keys[1:] {
+		tc, err := p.addTimeout(newTestTimeout(t, key, later, highQC), valSet)
+		if err != nil {
+			t.Fatalf("failed to add timeout %d: %v", i, err)
+		}
+		round, joined := p.joinRound(valSet)
+		if i == 0 && joined {
+			t.Fatalf("joined round %d after a single timeout", round)
+		}
+		if i > 0 && (!joined || round != 2) {
+			t.Fatalf("join mismatch: have round %d (%v), want 2", round, joined)
+		}
+		if i < 2 {
+			if tc != nil {
+				t.Fatalf("timeout cert without quorum after %d timeouts", i+1)
+			}
+			continue
+		}
+		if tc == nil {
+			t.Fatal("no timeout cert with a quorum of timeouts")
+		}
+		if err := p.verifyTimeoutCert(tc, valSet); err != nil {
+			t.Fatalf("failed to verify timeout cert: %v", err)
+		}
+		if next := p.advance(tc); next.Uint64() != 3 {
+			t.Fatalf("next round mismatch: have %d, want 3", next)
+		}
+		tc.View = &bft.View{Height: big.NewInt(1), Round: big.NewInt(3)}
+		if err := p.verifyTimeoutCert(tc, valSet); err == nil {
+			t.Fatal("timeout cert of another view verified")
+		}
+	}
+
+	// timeouts after the quorum, or sent again, don't make another cert
+	for i, key := range keys[:2] {
+		tc, err := p.addTimeout(newTestTimeout(t, key, later, highQC), valSet)
+		if err != nil {
+			t.Fatalf("failed to add late timeout %d: %v", i, err)
+		}
+		if tc != nil {
+			t.Fatalf("timeout cert assembled again by late timeout %d", i)
+		}
+	}
+
+	// timeouts signed for another view are rejected
+	msg := newTestTimeout(t, keys[1], later, highQC)
+	msg.View = &bft.View{Height: big.NewInt(1), Round: big.NewInt(1)}
+	if _, err := p.addTimeout(msg, valSet); err == nil {
+		t.Fatal("timeout of a mismatching view accepted")
+	}
+}
+
+// Tests that a timeout cert moves the core to the round after it.
+func TestTimeoutCertAdvancesRound(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	backend := newTestBackend(keys[0], addrs, newTestGenesis(addrs))
+
+	// the messages are handled by the test, the event loop is not started
+	config := *bft.DefaultBasicConfig
+	c := New(backend, &config, backend.signer).(*core)
+	registerMsgTypes()
+	c.isRunning = true
+	c.requests = newRequestSet()
+	c.backlogs = newBackLog()
+	c.startNewRound(common.Big0)
+	defer c.pacemaker.stop()
+
+	view := &bft.View{Height: big.NewInt(1), Round: big.NewInt(1)}
+	var seals [][]byte
+	for _, key := range keys[1:] {
+		seals = append(seals, newTestTimeout(t, key, view, c.current.PrepareQC()).CommittedSeal)
+	}
+	seal, err := backend.signer.AggregateSeals(c.valSet, view.Height.Uint64(), bft.TimeoutDigest(view), seals)
+	if err != nil {
+		t.Fatalf("failed to aggregate seals: %v", err)
+	}
+	payload, err := Encode(&bft.TimeoutCert{View: view, Seal: seal})
+	if err != nil {
+		t.Fatalf("failed to encode timeout cert: %v", err)
+	}
+	_, src := c.valSet.GetByAddress(addrs[1])
+	msg := &bft.Message{Code: MsgTypeTimeoutCert, View: view, Msg: payload, Address: addrs[1]}
+	if err := c.handleTimeoutCert(msg, src); err != nil {
+		t.Fatalf("failed to handle timeout cert: %v", err)
+	}
+	if round := c.currentView().Round.Uint64(); round != 2 {
+		t.Fatalf("round mismatch: have %d, want 2", round)
+	}
+	if err := c.handleTimeoutCert(msg, src); err != errOldMessage {
+		t.Fatalf("replayed timeout cert error mismatch: have %v, want %v", err, errOldMessage)
+	}
+}
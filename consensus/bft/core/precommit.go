@@ -34,8 +34,8 @@ func (c *core) handlePrepareVote(data *bft.Message, src bft.Validator) error {
 
 	logger.Trace("handlePrepareVote", "msg", msgTyp, "src", src.Address(), "hash", vote.Digest)
 
-	if size := c.current.PrepareVoteSize(); size >= c.Q() && c.currentState() < StatePrepared {
-		seals := c.getMessageSeals(size)
+	if power := c.current.PrepareVotePower(); power >= c.Q() && c.currentState() < StatePrepared {
+		seals := c.getMessageSeals(c.current.PrepareVoteSize())
 		newProposal, err := c.backend.PreCommit(c.current.Proposal(), seals)
 		if err != nil {
 			logger.Trace("Failed to assemble committed seal", "err", err)
@@ -44,7 +44,7 @@ func (c *core) handlePrepareVote(data *bft.Message, src bft.Validator) error {
 
 		prepareQC := proposal2QC(newProposal, c.current.Round())
 		c.acceptPrepare(prepareQC, newProposal)
-		logger.Trace("acceptPrepare", "msg", msgTyp, "src", src.Address(), "hash", newProposal.Hash(), "msgSize", size)
+		logger.Trace("acceptPrepare", "msg", msgTyp, "src", src.Address(), "hash", newProposal.Hash(), "power", power)
 
 		c.sendPreCommit()
 	}
@@ -97,7 +97,7 @@ func (c *core) handlePreCommit(data *bft.Message, src bft.Validator) error {
 		logger.Trace("Failed to check verify proposal", "msg", msgTyp, "err", err)
 		return err
 	}
-	if err := c.signer.VerifyQC(msg.PrepareQC, c.valSet); err != nil {
+	if err := c.verifyQC(msg.PrepareQC, msg.Proposal, c.valSet); err != nil {
 		logger.Trace("Failed to verify prepareQC", "msg", msgTyp, "err", err)
 		return err
 	}
@@ -137,6 +137,10 @@ func (c *core) sendPreCommitVote() {
 		logger.Error("Failed to encode", "msg", msgTyp, "err", err)
 		return
 	}
+	if err := c.recordVote(msgTyp, vote); err != nil {
+		logger.Warn("Failed to record vote", "msg", msgTyp, "vote", vote, "err", err)
+		return
+	}
 	c.broadcast(&bft.Message{Code: msgTyp, Msg: payload})
 	logger.Trace("sendPreCommitVote", "vote view", vote.View, "vote", vote.Digest)
 }
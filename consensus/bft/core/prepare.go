@@ -130,6 +130,10 @@ func (c *core) sendPrepareVote() {
 		logger.Trace("Failed to encode", "msg", msgTyp, "err", err)
 		return
 	}
+	if err := c.recordVote(msgTyp, vote); err != nil {
+		logger.Warn("Failed to record vote", "msg", msgTyp, "vote", vote, "err", err)
+		return
+	}
 	c.broadcast(&bft.Message{Code: msgTyp, Msg: payload})
 	logger.Trace("sendPrepareVote", "vote view", vote.View, "vote", vote.Digest)
 }
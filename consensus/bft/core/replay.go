@@ -0,0 +1,223 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Replayer tallies the consensus messages seen by a node per view, to reproduce
+// the view changes and vote counts of a network offline from the write-ahead
+// logs of it's nodes. The messages are checked against the validators of their
+// height, but the protocol is not run, so the logs of a stalled network are
+// replayed without it's chain.
+type Replayer struct {
+	validators func(height uint64) bft.ValidatorSet
+
+	self    common.Address     // Sender of the node's own messages
+	view    *bft.View          // Latest view of the node's own messages
+	tallies map[viewKey]*Tally // Messages by view
+	changes []*ViewChange      // View changes of the node in the replayed order
+}
+
+// viewKey is the height and round of a view, views are compared by value.
+type viewKey struct {
+	height, round uint64
+}
+
+// Tally is the senders of the messages of a view, by message type and digest.
+type Tally struct {
+	View    *bft.View
+	Senders map[MsgType]map[common.Hash]map[common.Address]bool
+
+	valSet bft.ValidatorSet
+}
+
+// ViewChange is the node moving to a new view, it's detected by the first message
+// the node sent in the view.
+type ViewChange struct {
+	Time uint64
+	From *bft.View // Previous view of the node, nil for the first message
+	To   *bft.View
+	Code MsgType // Type of the first message of the view
+}
+
+// NewReplayer creates a replayer checking the messages against the validators of
+// their height.
+func NewReplayer(validators func(height uint64) bft.ValidatorSet) *Replayer {
+	registerMsgTypes()
+	return &Replayer{
+		validators: validators,
+		tallies:    make(map[viewKey]*Tally),
+	}
+}
+
+// Apply decodes and tallies a message received or sent by the node at the given
+// time, the decoded message is returned.
+func (r *Replayer) Apply(time uint64, payload []byte, outbound bool) (*bft.Message, error) {
+	msg := new(bft.Message)
+	if err := msg.FromPayload(payload, recoverSigner); err != nil {
+		return nil, err
+	}
+	if msg.View == nil || msg.View.Height == nil || msg.View.Round == nil {
+		return nil, errInvalidMessage
+	}
+	valSet := r.validators(msg.View.Height.Uint64())
+	if _, val := valSet.GetByAddress(msg.Address); val == nil {
+		return nil, errInvalidSigner
+	}
+	code, ok := msg.Code.(MsgType)
+	if !ok {
+		return nil, errInvalidMessage
+	}
+	digest, err := replayDigest(msg)
+	if err != nil {
+		return nil, err
+	}
+	if outbound && (r.self == common.Address{} || r.self == msg.Address) {
+		r.self = msg.Address
+		if r.view == nil || msg.View.Cmp(r.view) > 0 {
+			r.changes = append(r.changes, &ViewChange{Time: time, From: r.view, To: msg.View, Code: code})
+			r.view = msg.View
+		}
+	}
+	key := viewKey{height: msg.View.Height.Uint64(), round: msg.View.Round.Uint64()}
+	tally, ok := r.tallies[key]
+	if !ok {
+		tally = &Tally{
+			View:    msg.View,
+			Senders: make(map[MsgType]map[common.Hash]map[common.Address]bool),
+			valSet:  valSet,
+		}
+		r.tallies[key] = tally
+	}
+	if tally.Senders[code] == nil {
+		tally.Senders[code] = make(map[common.Hash]map[common.Address]bool)
+	}
+	if tally.Senders[code][digest] == nil {
+		tally.Senders[code][digest] = make(map[common.Address]bool)
+	}
+	tally.Senders[code][digest][msg.Address] = true
+	return msg, nil
+}
+
+// Self returns the address of the node, once it's own messages were replayed.
+func (r *Replayer) Self() common.Address {
+	return r.self
+}
+
+// ViewChanges returns the view changes of the node in the replayed order.
+func (r *Replayer) ViewChanges() []*ViewChange {
+	return r.changes
+}
+
+// Tallies returns the messages of every view seen by the node in ascending order.
+func (r *Replayer) Tallies() []*Tally {
+	tallies := make([]*Tally, 0, len(r.tallies))
+	for _, tally := range r.tallies {
+		tallies = append(tallies, tally)
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		return tallies[i].View.Cmp(tallies[j].View) < 0
+	})
+	return tallies
+}
+
+// Codes returns the message types of the tally in ascending order.
+func (t *Tally) Codes() []MsgType {
+	codes := make([]MsgType, 0, len(t.Senders))
+	for code := range t.Senders {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Digests returns the digests of the message type in ascending order.
+func (t *Tally) Digests(code MsgType) []common.Hash {
+	digests := make([]common.Hash, 0, len(t.Senders[code]))
+	for digest := range t.Senders[code] {
+		digests = append(digests, digest)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return digests[i].Big().Cmp(digests[j].Big()) < 0
+	})
+	return digests
+}
+
+// Power returns the voting power of the senders of the message type and digest.
+func (t *Tally) Power(code MsgType, digest common.Hash) uint64 {
+	var power uint64
+	for addr := range t.Senders[code][digest] {
+		if _, val := t.valSet.GetByAddress(addr); val != nil {
+			power += val.Power()
+		}
+	}
+	return power
+}
+
+// Quorum returns the voting power of a quorum of the validators of the view.
+func (t *Tally) Quorum() uint64 {
+	return t.valSet.Q()
+}
+
+var voteDecodeErrors = map[MsgType]error{
+	MsgTypePrepareVote:   errFailedDecodePrepareVote,
+	MsgTypePreCommitVote: errFailedDecodePreCommitVote,
+	MsgTypeCommitVote:    errFailedDecodeCommitVote,
+}
+
+// recoverSigner returns the sender of a message, the validators are checked by
+// the replayer.
+func recoverSigner(data []byte, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(data), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// replayDigest returns the hash the message is about, the proposal of proposals
+// and votes, or the certified block of quorum certs. Timeouts have no digest.
+func replayDigest(msg *bft.Message) (common.Hash, error) {
+	switch msg.Code {
+	case MsgTypeNewView:
+		var newView *MsgNewView
+		if err := msg.Decode(&newView); err != nil {
+			return common.Hash{}, errFailedDecodeNewView
+		}
+		if newView.PrepareQC == nil {
+			return common.Hash{}, nil
+		}
+		return newView.PrepareQC.Hash, nil
+	case MsgTypePrepare:
+		var prepare *MsgPrepare
+		if err := msg.Decode(&prepare); err != nil || prepare.Proposal == nil {
+			return common.Hash{}, errFailedDecodePrepare
+		}
+		return prepare.Proposal.Hash(), nil
+	case MsgTypePreCommit:
+		var preCommit *MsgPreCommit
+		if err := msg.Decode(&preCommit); err != nil || preCommit.Proposal == nil {
+			return common.Hash{}, errFailedDecodePreCommit
+		}
+		return preCommit.Proposal.Hash(), nil
+	case MsgTypeCommit, MsgTypeDecide:
+		var qc *bft.QuorumCert
+		if err := msg.Decode(&qc); err != nil || qc == nil {
+			return common.Hash{}, errFailedDecodeCommit
+		}
+		return qc.Hash, nil
+	case MsgTypePrepareVote, MsgTypePreCommitVote, MsgTypeCommitVote:
+		var vote *Vote
+		if err := msg.Decode(&vote); err != nil {
+			return common.Hash{}, voteDecodeErrors[msg.Code.(MsgType)]
+		}
+		return vote.Digest, nil
+	case MsgTypeTimeout, MsgTypeTimeoutCert:
+		return common.Hash{}, nil
+	}
+	return common.Hash{}, errInvalidMessage
+}
@@ -75,6 +75,27 @@ func (s *requestSet) GetRequest(view *bft.View) *bft.Request {
 
 	maxRetry := 20
 retry:
+	if req := s.popRequest(view); req != nil {
+		return req
+	}
+	if maxRetry -= 1; maxRetry > 0 {
+		time.Sleep(500 * time.Millisecond)
+		goto retry
+	}
+	return nil
+}
+
+// PopRequest returns the request of the view without waiting for it to arrive.
+func (s *requestSet) PopRequest(view *bft.View) *bft.Request {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.popRequest(view)
+}
+
+// popRequest drops the stale requests and returns the request of the view, future
+// requests are kept.
+func (s *requestSet) popRequest(view *bft.View) *bft.Request {
 	for !s.pendingRequest.Empty() {
 		m, prior := s.pendingRequest.Pop()
 		req, ok := m.(*bft.Request)
@@ -93,10 +114,6 @@ retry:
 		}
 		return req
 	}
-	if maxRetry -= 1; maxRetry > 0 {
-		time.Sleep(500 * time.Millisecond)
-		goto retry
-	}
 	return nil
 }
 
@@ -2,9 +2,12 @@ package core
 
 import (
 	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/bft/message_set"
+	"github.com/ethereum/go-ethereum/event"
 )
 
 type roundState struct {
@@ -24,6 +27,8 @@ type roundState struct {
 	preCommitVotes *message_set.MessageSet
 	commitVotes    *message_set.MessageSet
 
+	timer *phaseTimer // accumulates the time spent in each state, nil if not tracked
+
 	highQC      *bft.QuorumCert // leader highQC
 	prepareQC   *bft.QuorumCert // prepareQC for repo and leader
 	lockedQC    *bft.QuorumCert // lockedQC for repo and pre-committedQC for leader
@@ -67,6 +72,9 @@ func (s *roundState) View() *bft.View {
 
 func (s *roundState) SetState(state State) {
 	s.state = state
+	if s.timer != nil {
+		s.timer.enter(state)
+	}
 }
 
 func (s *roundState) State() State {
@@ -133,6 +141,10 @@ func (s *roundState) NewViewSize() int {
 	return s.newViews.Size()
 }
 
+func (s *roundState) NewViewPower() uint64 {
+	return s.newViews.Power()
+}
+
 func (s *roundState) NewViews() []*bft.Message {
 	return s.newViews.Values()
 }
@@ -149,6 +161,10 @@ func (s *roundState) PrepareVoteSize() int {
 	return s.prepareVotes.Size()
 }
 
+func (s *roundState) PrepareVotePower() uint64 {
+	return s.prepareVotes.Power()
+}
+
 func (s *roundState) AddPreCommitVote(msg *bft.Message) error {
 	return s.preCommitVotes.Add(msg)
 }
@@ -157,6 +173,10 @@ func (s *roundState) PreCommitVoteSize() int {
 	return s.preCommitVotes.Size()
 }
 
+func (s *roundState) PreCommitVotePower() uint64 {
+	return s.preCommitVotes.Power()
+}
+
 func (s *roundState) AddCommitVote(msg *bft.Message) error {
 	return s.commitVotes.Add(msg)
 }
@@ -165,6 +185,10 @@ func (s *roundState) CommitVoteSize() int {
 	return s.commitVotes.Size()
 }
 
+func (s *roundState) CommitVotePower() uint64 {
+	return s.commitVotes.Power()
+}
+
 func (s *roundState) SetHighQC(qc *bft.QuorumCert) {
 	s.highQC = qc
 }
@@ -196,3 +220,74 @@ func (s *roundState) SetCommittedQC(qc *bft.QuorumCert) {
 func (s *roundState) CommittedQC() *bft.QuorumCert {
 	return s.committedQC
 }
+
+// roundStateTimeout is the time to wait for the event loop to take a snapshot of
+// the consensus state
+const roundStateTimeout = time.Second
+
+// requestRoundState asks the event loop of a core for a snapshot of the consensus
+// state, the snapshot is taken between the events so the state is not locked.
+func requestRoundState(mux *event.TypeMux) *bft.RoundState {
+	result := make(chan *bft.RoundState, 1)
+	go mux.Post(roundStateEvent{result: result})
+
+	select {
+	case rs := <-result:
+		return rs
+	case <-time.After(roundStateTimeout):
+		return nil
+	}
+}
+
+func copyQC(qc *bft.QuorumCert) *bft.QuorumCert {
+	if qc == nil {
+		return nil
+	}
+	return qc.Copy()
+}
+
+func copyViews(views map[common.Address]*bft.View) map[common.Address]*bft.View {
+	cpy := make(map[common.Address]*bft.View, len(views))
+	for addr, view := range views {
+		cpy[addr] = &bft.View{
+			Round:  new(big.Int).Set(view.Round),
+			Height: new(big.Int).Set(view.Height),
+		}
+	}
+	return cpy
+}
+
+// phaseTimer accumulates the time the node spends in each state of the protocol,
+// it's reported as the consensus health of the node.
+type phaseTimer struct {
+	state State
+	start time.Time
+	times map[State]time.Duration
+}
+
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{times: make(map[State]time.Duration)}
+}
+
+// enter moves the timer to the given state, the time since the last state change
+// is added to the previous state.
+func (t *phaseTimer) enter(state State) {
+	now := time.Now()
+	if t.state != 0 {
+		t.times[t.state] += now.Sub(t.start)
+	}
+	t.state, t.start = state, now
+}
+
+// snapshot returns the time spent in each state by name, including the time spent
+// in the current state so far.
+func (t *phaseTimer) snapshot() map[string]time.Duration {
+	times := make(map[string]time.Duration, len(t.times)+1)
+	for state, elapsed := range t.times {
+		times[state.String()] = elapsed
+	}
+	if t.state != 0 {
+		times[t.state.String()] += time.Since(t.start)
+	}
+	return times
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// safetyState is the voting state of the validator which must survive restarts.
+// a validator which forgets it could vote twice in a view, or vote against the
+// proposal it's locked on.
+type safetyState struct {
+	LastVoted  *bft.View       `rlp:"nil"` // view of the last vote
+	LastCode   uint64          // message type of the last vote
+	LastDigest common.Hash     // digest of the last vote
+	LockedQC   *bft.QuorumCert `rlp:"nil"` // highest pre-committed qc
+	HighQC     *bft.QuorumCert `rlp:"nil"` // highest qc of the accepted proposals
+}
+
+// loadSafetyState reads the voting state persisted before the last shutdown.
+func (c *core) loadSafetyState() error {
+	state, err := readSafetyState(c.backend, c.logger)
+	if err != nil {
+		return err
+	}
+	c.safety = state
+	return nil
+}
+
+// recordVote checks the vote against the voting state, and persists it along with
+// the current lockedQC and highQC before the vote is sent.
+func (c *core) recordVote(code MsgType, vote *Vote) error {
+	if err := c.safety.allowVote(code, vote); err != nil {
+		return err
+	}
+	next := c.safety.voted(code, vote)
+	if qc := c.current.PreCommittedQC(); qc != nil && (next.LockedQC == nil || qc.View.Cmp(next.LockedQC.View) > 0) {
+		next.LockedQC = qc.Copy()
+	}
+	if qc := c.current.HighQC(); qc != nil && (next.HighQC == nil || qc.View.Cmp(next.HighQC.View) > 0) {
+		next.HighQC = qc.Copy()
+	}
+	if err := writeSafetyState(c.backend, next); err != nil {
+		return err
+	}
+	c.safety = next
+	return nil
+}
+
+// readSafetyState reads the persisted voting state of the backend.
+func readSafetyState(backend bft.Backend, logger log.Logger) (*safetyState, error) {
+	state := new(safetyState)
+	if blob := backend.ReadRoundState(); len(blob) > 0 {
+		if err := rlp.DecodeBytes(blob, state); err != nil {
+			return nil, err
+		}
+		logger.Info("Loaded bft round state", "voted", state.LastVoted, "type", MsgType(state.LastCode), "digest", state.LastDigest)
+	}
+	return state, nil
+}
+
+// writeSafetyState persists the voting state, it must be written before the vote
+// is sent.
+func writeSafetyState(backend bft.Backend, state *safetyState) error {
+	blob, err := rlp.EncodeToBytes(state)
+	if err != nil {
+		return err
+	}
+	return backend.WriteRoundState(blob)
+}
+
+// allowVote checks the vote against the last vote. validators never vote in an
+// older view, go back to an earlier phase of the view, or vote for another digest
+// in the same phase.
+func (s *safetyState) allowVote(code MsgType, vote *Vote) error {
+	if s.LastVoted == nil {
+		return nil
+	}
+	switch cmp := vote.View.Cmp(s.LastVoted); {
+	case cmp < 0:
+		return errVotedView
+	case cmp == 0 && code.Value() < s.LastCode:
+		return errVotedView
+	case cmp == 0 && code.Value() == s.LastCode && vote.Digest != s.LastDigest:
+		return errVotedView
+	}
+	return nil
+}
+
+// voted returns the voting state after the vote, keeping the recorded qcs.
+func (s *safetyState) voted(code MsgType, vote *Vote) *safetyState {
+	return &safetyState{
+		LastVoted:  vote.View,
+		LastCode:   code.Value(),
+		LastDigest: vote.Digest,
+		LockedQC:   s.LockedQC,
+		HighQC:     s.HighQC,
+	}
+}
+
+// checkSafetyLock checks that the proposal doesn't conflict with the persisted
+// lockedQC. the lock of the current round state is lost on restarts, while the
+// persisted one stays until the height is committed.
+func (c *core) checkSafetyLock(proposal bft.Proposal) error {
+	qc := c.safety.LockedQC
+	if qc == nil || qc.Height().Cmp(proposal.Number()) != 0 {
+		return nil
+	}
+	block, ok := proposal.(*types.Block)
+	if !ok {
+		return errInvalidProposal
+	}
+	// The qc certifies the sealed header, which only differs by the extra-data
+	header := block.Header()
+	header.Extra = qc.Extra
+	if header.Hash() != qc.Hash && block.Hash() != qc.Hash {
+		return errLockedQC
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that a validator killed between the PreCommit and Commit phases reloads
+// it's votes and lock, and doesn't vote against them after the restart.
+func TestCrashRecoveryBetweenPreCommitAndCommit(t *testing.T) {
+	keys, addrs := newTestKeys(4)
+	genesis := newTestGenesis(addrs)
+
+	// Run a replica, the proposer of the first view is driven by the test
+	valSet := validator.NewSet(addrs, bft.RoundRobin)
+	valSet.CalcProposer(genesis.Coinbase(), 0)
+	proposer := valSet.GetProposer().Address()
+
+	var key *ecdsa.PrivateKey
+	for i, addr := range addrs {
+		if addr != proposer {
+			key = keys[i]
+			break
+		}
+	}
+	backend := newTestBackend(key, addrs, genesis)
+
+	// start runs the core from the persisted state, the event loop is stopped
+	// right away so the test drives the core on it's own
+	start := func() *core {
+		c, err := newTestCore(backend)
+		if err != nil {
+			t.Fatalf("failed to start core: %v", err)
+		}
+		c.Stop()
+		return c
+	}
+	c := start()
+	var (
+		view     = &bft.View{Height: big.NewInt(1), Round: big.NewInt(0)}
+		highQC   = proposal2QC(genesis, common.Big0)
+		proposal = newTestBlock(genesis, addrs, proposer, 1)
+		conflict = newTestBlock(genesis, addrs, proposer, 2)
+	)
+	prepare := func(c *core, proposal *types.Block) error {
+		payload, err := Encode(&MsgPrepare{View: view, Proposal: proposal, HighQC: highQC})
+		if err != nil {
+			return err
+		}
+		_, src := c.valSet.GetByAddress(proposer)
+		return c.handlePrepare(&bft.Message{Code: MsgTypePrepare, View: view, Msg: payload, Address: proposer}, src)
+	}
+
+	// Prepare and pre-commit the proposal
+	if err := prepare(c, proposal); err != nil {
+		t.Fatalf("failed to handle prepare: %v", err)
+	}
+	if votes := backend.sentVotes(MsgTypePrepareVote); len(votes) != 1 || votes[0].Digest != proposal.Hash() {
+		t.Fatalf("prepare votes mismatch: have %v, want %v", votes, proposal.Hash())
+	}
+	sealed, err := backend.PreCommit(proposal, [][]byte{make([]byte, types.BftExtraSeal), make([]byte, types.BftExtraSeal), make([]byte, types.BftExtraSeal)})
+	if err != nil {
+		t.Fatalf("failed to seal proposal: %v", err)
+	}
+	c.acceptPrepare(proposal2QC(sealed, common.Big0), sealed)
+	c.sendPreCommitVote()
+	if votes := backend.sentVotes(MsgTypePreCommitVote); len(votes) != 1 || votes[0].Digest != sealed.Hash() {
+		t.Fatalf("pre-commit votes mismatch: have %v, want %v", votes, sealed.Hash())
+	}
+
+	// Kill the validator before the commit message arrives and restart it
+	c = start()
+	if voted := c.safety.LastVoted; voted == nil || voted.Cmp(view) != 0 || c.safety.LastDigest != sealed.Hash() {
+		t.Fatalf("last vote mismatch: have %v %v, want %v %v", voted, c.safety.LastDigest, view, sealed.Hash())
+	}
+
+	// The restarted validator must not vote for a conflicting proposal in the view
+	if err := prepare(c, conflict); err != nil {
+		t.Fatalf("failed to handle conflicting prepare: %v", err)
+	}
+	if votes := backend.sentVotes(MsgTypePrepareVote); len(votes) != 1 {
+		t.Fatalf("restarted validator voted again: %v", votes)
+	}
+	if err := c.recordVote(MsgTypePreCommitVote, &Vote{View: view, Digest: conflict.Hash()}); err != errVotedView {
+		t.Fatalf("conflicting pre-commit vote: have %v, want %v", err, errVotedView)
+	}
+
+	// It may still lock on and commit the pre-committed proposal
+	qc := proposal2QC(sealed, common.Big0)
+	c.current.SetProposal(sealed)
+	c.lockQCAndProposal(qc)
+	if err := c.recordVote(MsgTypeCommitVote, c.current.Vote()); err != nil {
+		t.Fatalf("failed to record commit vote: %v", err)
+	}
+
+	// Once restarted again, the lock is kept for the next rounds of the height
+	c = start()
+	if locked := c.safety.LockedQC; locked == nil || locked.Hash != qc.Hash {
+		t.Fatalf("locked qc mismatch: have %v, want %v", locked, qc)
+	}
+	if err := c.checkLockedProposal(conflict); err != errLockedQC {
+		t.Fatalf("conflicting proposal: have %v, want %v", err, errLockedQC)
+	}
+	if err := c.checkLockedProposal(proposal); err != nil {
+		t.Fatalf("locked proposal rejected: %v", err)
+	}
+	if err := c.recordVote(MsgTypePrepareVote, &Vote{View: &bft.View{Height: big.NewInt(1), Round: big.NewInt(1)}, Digest: proposal.Hash()}); err != nil {
+		t.Fatalf("vote in the next round rejected: %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// testBackend is an in-memory bft.Backend of a single validator, it records the
+// messages sent by the core instead of delivering them.
+type testBackend struct {
+	mu         sync.Mutex
+	key        *ecdsa.PrivateKey
+	signer     bft.Signer
+	validators []common.Address
+	mux        *event.TypeMux
+
+	head       *types.Block
+	sent       []*bft.Message
+	committed  []bft.Proposal
+	certified  []bft.Proposal
+	fetched    []common.Hash
+	decided    []bft.Proposal
+	evidence   []*bft.Evidence
+	roundState []byte
+}
+
+func newTestBackend(key *ecdsa.PrivateKey, validators []common.Address, head *types.Block) *testBackend {
+	return &testBackend{
+		key:        key,
+		signer:     snr.NewSigner(key),
+		validators: validators,
+		mux:        new(event.TypeMux),
+		head:       head,
+	}
+}
+
+// newTestCore creates the core of the backend validator and starts it.
+func newTestCore(b *testBackend) (*core, error) {
+	config := *bft.DefaultBasicConfig
+	config.RequestTimeout = 60000
+	c := New(b, &config, b.signer).(*core)
+	if err := c.Start(nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (b *testBackend) Address() common.Address { return b.signer.Address() }
+
+func (b *testBackend) Validators(height uint64) bft.ValidatorSet {
+	return validator.NewSet(b.validators, bft.RoundRobin)
+}
+
+func (b *testBackend) EventMux() *event.TypeMux { return b.mux }
+
+func (b *testBackend) Broadcast(valSet bft.ValidatorSet, payload []byte) error {
+	return b.record(payload)
+}
+
+func (b *testBackend) Gossip(valSet bft.ValidatorSet, payload []byte) error {
+	return b.record(payload)
+}
+
+func (b *testBackend) Unicast(valSet bft.ValidatorSet, payload []byte) error {
+	return b.record(payload)
+}
+
+func (b *testBackend) record(payload []byte) error {
+	msg := new(bft.Message)
+	if err := msg.FromPayload(payload, nil); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = append(b.sent, msg)
+	return nil
+}
+
+// sentVotes returns the recorded votes of the given type.
+func (b *testBackend) sentVotes(code MsgType) []*Vote {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var votes []*Vote
+	for _, msg := range b.sent {
+		if msg.Code != code {
+			continue
+		}
+		var vote *Vote
+		if err := msg.Decode(&vote); err == nil {
+			votes = append(votes, vote)
+		}
+	}
+	return votes
+}
+
+// sentMessages returns the number of recorded messages of the given type.
+func (b *testBackend) sentMessages(code MsgType) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var n int
+	for _, msg := range b.sent {
+		if msg.Code == code {
+			n++
+		}
+	}
+	return n
+}
+
+func (b *testBackend) PreCommit(proposal bft.Proposal, seals [][]byte) (bft.Proposal, error) {
+	block := proposal.(*types.Block)
+	h := block.Header()
+	if err := b.signer.SealAfterCommit(h, seals); err != nil {
+		return nil, err
+	}
+	return block.WithSeal(h), nil
+}
+
+func (b *testBackend) ForwardCommit(proposal bft.Proposal, extra []byte) (bft.Proposal, error) {
+	block := proposal.(*types.Block)
+	h := block.Header()
+	h.Extra = extra
+	return block.WithSeal(h), nil
+}
+
+func (b *testBackend) Commit(proposal bft.Proposal) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.committed = append(b.committed, proposal)
+	if proposal.Number().Cmp(b.head.Number()) > 0 {
+		b.head = proposal.(*types.Block)
+	}
+	return nil
+}
+
+// Certify records the certified proposal, the next proposals extend it.
+func (b *testBackend) Certify(proposal bft.Proposal) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.certified = append(b.certified, proposal)
+	b.head = proposal.(*types.Block)
+	return nil
+}
+
+// FetchProposal records the hash of the proposal missed by the node.
+func (b *testBackend) FetchProposal(hash common.Hash, from common.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fetched = append(b.fetched, hash)
+}
+
+func (b *testBackend) Decide(proposal bft.Proposal) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.decided = append(b.decided, proposal)
+	return nil
+}
+
+func (b *testBackend) Verify(bft.Proposal) (time.Duration, error) { return 0, nil }
+
+func (b *testBackend) VerifyUnsealedProposal(bft.Proposal) (time.Duration, error) { return 0, nil }
+
+func (b *testBackend) LastProposal() (bft.Proposal, common.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.head, b.head.Coinbase()
+}
+
+func (b *testBackend) GetProposal(hash common.Hash) bft.Proposal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.head.Hash() == hash {
+		return b.head
+	}
+	for _, proposals := range [][]bft.Proposal{b.committed, b.certified} {
+		for _, proposal := range proposals {
+			if proposal.Hash() == hash {
+				return proposal
+			}
+		}
+	}
+	return nil
+}
+
+func (b *testBackend) HasBadProposal(hash common.Hash) bool { return false }
+
+func (b *testBackend) ValidateBlock(block *types.Block) error { return nil }
+
+func (b *testBackend) ReadRoundState() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return common.CopyBytes(b.roundState)
+}
+
+func (b *testBackend) WriteRoundState(state []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roundState = common.CopyBytes(state)
+	return nil
+}
+
+func (b *testBackend) HandleEvidence(ev *bft.Evidence) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.evidence = append(b.evidence, ev)
+	return nil
+}
+
+func (b *testBackend) Close() error { return nil }
+
+// newTestKeys generates the keys of n validators, sorted by address.
+func newTestKeys(n int) ([]*ecdsa.PrivateKey, []common.Address) {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if bytes.Compare(crypto.PubkeyToAddress(keys[j].PublicKey).Bytes(), crypto.PubkeyToAddress(keys[i].PublicKey).Bytes()) < 0 {
+				keys[i], keys[j] = keys[j], keys[i]
+			}
+		}
+	}
+	addrs := make([]common.Address, n)
+	for i, key := range keys {
+		addrs[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	return keys, addrs
+}
+
+// newTestBlock creates an unsealed block on top of the parent, the nonce tells
+// apart the blocks of the same parent.
+func newTestBlock(parent *types.Block, validators []common.Address, proposer common.Address, nonce uint64) *types.Block {
+	extra, _ := rlp.EncodeToBytes(&types.BftExtra{
+		Validators:    validators,
+		Seal:          make([]byte, types.BftExtraSeal),
+		CommittedSeal: [][]byte{},
+	})
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		Coinbase:   proposer,
+		Difficulty: common.Big1,
+		GasLimit:   parent.GasLimit(),
+		Time:       parent.Time() + 1,
+		Nonce:      types.EncodeNonce(nonce),
+		MixDigest:  types.BftDigest,
+		Extra:      append(make([]byte, types.BftExtraVanity), extra...),
+	}
+	return types.NewBlockWithHeader(header)
+}
+
+// newTestGenesis creates the genesis block of the validators.
+func newTestGenesis(validators []common.Address) *types.Block {
+	extra, _ := rlp.EncodeToBytes(&types.BftExtra{
+		Validators:    validators,
+		Seal:          make([]byte, types.BftExtraSeal),
+		CommittedSeal: [][]byte{},
+	})
+	return types.NewBlockWithHeader(&types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		GasLimit:   8000000,
+		MixDigest:  types.BftDigest,
+		Extra:      append(make([]byte, types.BftExtraVanity), extra...),
+	})
+}
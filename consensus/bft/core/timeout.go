@@ -0,0 +1,98 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/consensus/bft"
+)
+
+// sendTimeout broadcasts the timeout of the current round, the node moves to the
+// next round once the timeouts of a quorum make a timeout cert.
+func (c *core) sendTimeout() {
+	logger := c.newLogger()
+
+	msg, err := c.pacemaker.timeout(c.current.PrepareQC())
+	if err != nil {
+		logger.Trace("Failed to create timeout", "msg", MsgTypeTimeout, "err", err)
+		return
+	}
+	c.broadcast(msg)
+
+	logger.Trace("sendTimeout", "view", msg.View)
+}
+
+func (c *core) handleTimeout(data *bft.Message, src bft.Validator) error {
+	logger := c.newLogger()
+
+	msgTyp := MsgTypeTimeout
+	if err := c.checkView(msgTyp, data.View); err != nil {
+		logger.Trace("Failed to check view", "msg", msgTyp, "err", err)
+		return err
+	}
+	tc, err := c.pacemaker.addTimeout(data, c.valSet)
+	if err != nil {
+		logger.Trace("Failed to add timeout", "msg", msgTyp, "src", src.Address(), "err", err)
+		return err
+	}
+
+	logger.Trace("handleTimeout", "msg", msgTyp, "src", src.Address(), "view", data.View)
+
+	if tc != nil {
+		c.sendTimeoutCert(tc)
+		c.startNewRound(c.pacemaker.advance(tc))
+		return nil
+	}
+	if round, ok := c.pacemaker.joinRound(c.valSet); ok {
+		logger.Trace("Join timeout", "msg", msgTyp, "round", round)
+		c.startNewRound(new(big.Int).SetUint64(round))
+		c.sendTimeout()
+	}
+	return nil
+}
+
+// sendTimeoutCert hands the timeout cert assembled by the node to the validators
+// which missed some of the timeouts.
+func (c *core) sendTimeoutCert(tc *bft.TimeoutCert) {
+	logger := c.newLogger()
+
+	payload, err := Encode(tc)
+	if err != nil {
+		logger.Trace("Failed to encode", "msg", MsgTypeTimeoutCert, "err", err)
+		return
+	}
+	c.broadcast(&bft.Message{
+		Code: MsgTypeTimeoutCert,
+		Msg:  payload,
+	})
+
+	logger.Trace("sendTimeoutCert", "tc", tc)
+}
+
+func (c *core) handleTimeoutCert(data *bft.Message, src bft.Validator) error {
+	logger := c.newLogger()
+
+	var (
+		tc     *bft.TimeoutCert
+		msgTyp = MsgTypeTimeoutCert
+	)
+	if err := data.Decode(&tc); err != nil {
+		logger.Trace("Failed to decode", "msg", msgTyp, "err", err)
+		return errFailedDecodeTimeoutCert
+	}
+	if tc.View == nil {
+		return errInvalidMessage
+	}
+	if err := c.checkView(msgTyp, tc.View); err != nil {
+		logger.Trace("Failed to check view", "msg", msgTyp, "err", err)
+		return err
+	}
+	if err := c.pacemaker.verifyTimeoutCert(tc, c.valSet); err != nil {
+		logger.Trace("Failed to verify timeout cert", "msg", msgTyp, "err", err)
+		return err
+	}
+
+	logger.Trace("handleTimeoutCert", "msg", msgTyp, "src", src.Address(), "tc", tc)
+
+	c.startNewRound(c.pacemaker.advance(tc))
+	return nil
+}
@@ -27,6 +27,8 @@ const (
 	MsgTypeCommit        MsgType = 6
 	MsgTypeCommitVote    MsgType = 7
 	MsgTypeDecide        MsgType = 8
+	MsgTypeTimeout       MsgType = 9
+	MsgTypeTimeoutCert   MsgType = 10
 )
 
 func (m MsgType) String() string {
@@ -47,6 +49,10 @@ func (m MsgType) String() string {
 		return "COMMIT_VOTE"
 	case MsgTypeDecide:
 		return "DECIDE"
+	case MsgTypeTimeout:
+		return "TIMEOUT"
+	case MsgTypeTimeoutCert:
+		return "TIMEOUT_CERT"
 	default:
 		return "UNKNOWN"
 	}
@@ -214,7 +220,54 @@ func (b *Vote) String() string {
 	return fmt.Sprintf("{View: %v, Digest: %v}", b.View, b.Digest.String())
 }
 
-type timeoutEvent struct{}
+// MsgTimeout is sent by a validator which gave up the view, the committed seal of
+// the message signs the timeout digest of the view. The timeout carries the quorum
+// cert of the sender's head, validators which missed the block commit it from the
+// timeouts of the next height. The timeout is resent until the round ends, the
+// attempt tells the resent messages apart, peers drop the messages they saw.
+type MsgTimeout struct {
+	View    *bft.View
+	HighQC  *bft.QuorumCert
+	Attempt uint64
+}
+
+func (m *MsgTimeout) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, []interface{}{m.View, m.HighQC, m.Attempt})
+}
+
+func (m *MsgTimeout) DecodeRLP(s *rlp.Stream) error {
+	var timeout struct {
+		View    *bft.View
+		HighQC  *bft.QuorumCert
+		Attempt uint64
+	}
+
+	if err := s.Decode(&timeout); err != nil {
+		return err
+	}
+	m.View, m.HighQC, m.Attempt = timeout.View, timeout.HighQC, timeout.Attempt
+	return nil
+}
+
+func (m *MsgTimeout) String() string {
+	return fmt.Sprintf("{Timeout Height: %d Round: %d}", m.View.Height, m.View.Round)
+}
+
+// timeoutEvent is posted once the round of the view timed out
+type timeoutEvent struct {
+	view *bft.View
+}
+
+// proposeEvent is posted once the leader of the view may send it's proposal
+type proposeEvent struct {
+	view *bft.View
+}
+
+// roundStateEvent asks the event loop for a snapshot of the consensus state
+type roundStateEvent struct {
+	result chan *bft.RoundState
+}
+
 type backlogEvent struct {
 	src bft.Validator
 	msg *bft.Message
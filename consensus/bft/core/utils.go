@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -44,6 +45,9 @@ func (c *core) checkPrepareQC(qc *bft.QuorumCert) error {
 	if localQC.Hash != qc.Hash {
 		return fmt.Errorf("expect %v, got %v", localQC.Hash, qc.Hash)
 	}
+	if !bytes.Equal(localQC.Extra, qc.Extra) {
+		return fmt.Errorf("extra unsame, expect %x, got %x", localQC.Extra, qc.Extra)
+	}
 	return nil
 }
 
@@ -84,6 +88,9 @@ func (c *core) checkProposal(hash common.Hash) error {
 }
 
 func (c *core) checkLockedProposal(msg bft.Proposal) error {
+	if err := c.checkSafetyLock(msg); err != nil {
+		return err
+	}
 	isLocked, proposal := c.current.LastLockedProposal()
 	if !isLocked {
 		return nil
@@ -101,10 +108,17 @@ func (c *core) checkLockedProposal(msg bft.Proposal) error {
 // last epoch's val set if current height equals to epoch start height
 func (c *core) verifyCrossEpochQC(qc *bft.QuorumCert) error {
 	valset := c.backend.Validators(qc.HeightU64())
-	if err := c.signer.VerifyQC(qc, valset); err != nil {
-		return err
+	return c.verifyQC(qc, c.backend.GetProposal(qc.Hash), valset)
+}
+
+// verifyQC verifies the quorum certificate against the proposal it certifies, the
+// seals can't be checked without the proposal header.
+func (c *core) verifyQC(qc *bft.QuorumCert, proposal bft.Proposal, valSet bft.ValidatorSet) error {
+	block, ok := proposal.(*types.Block)
+	if !ok || block == nil {
+		return errInvalidProposal
 	}
-	return nil
+	return c.signer.VerifyQC(qc, block.Header(), valSet)
 }
 
 // checkView checks the Message state, remote msg view should not be nil(local view WONT be nil).
@@ -118,13 +132,25 @@ func (c *core) verifyCrossEpochQC(qc *bft.QuorumCert) error {
 // if the view is equal the current view, compare the Message type and round state, with the right
 // round state sequence, Message ahead of certain state is `old Message`, and Message behind certain
 // state is `future Message`. Message type and round state table as follow:
+//
+// timeouts and timeout certs of the current height are accepted from the current
+// round on, they move the node to the later rounds.
 func (c *core) checkView(msgCode bft.MsgType, view *bft.View) error {
+	if msgCode == MsgTypeTimeout || msgCode == MsgTypeTimeoutCert {
+		return checkTimeoutView(view, c.currentView())
+	}
+	return compareView(view, c.currentView())
+}
+
+// compareView returns errOldMessage or errFutureMessage if the message view is not
+// the current one, views more than one height ahead are too far away to keep.
+func compareView(view *bft.View, current *bft.View) error {
 	if view == nil || view.Height == nil || view.Round == nil {
 		return errInvalidMessage
 	}
 
 	// validators not in the same view
-	if hdiff, rdiff := view.Sub(c.currentView()); hdiff < 0 {
+	if hdiff, rdiff := view.Sub(current); hdiff < 0 {
 		return errOldMessage
 	} else if hdiff > 1 {
 		return errFarAwayFutureMessage
@@ -149,7 +175,11 @@ func (c *core) finalizeMessage(msg *bft.Message) ([]byte, error) {
 	// Add proof of consensus
 	proposal := c.current.Proposal()
 	if msg.Code == MsgTypePrepareVote && proposal != nil {
-		seal, err := c.signer.SignHash(proposal.Hash())
+		block, ok := proposal.(*types.Block)
+		if !ok {
+			return nil, errInvalidProposal
+		}
+		seal, err := c.signer.SignVote(msg.View, block.Header())
 		if err != nil {
 			return nil, err
 		}
@@ -199,7 +229,7 @@ func (c *core) broadcast(msg *bft.Message) {
 		if err := c.backend.Unicast(c.valSet, payload); err != nil {
 			logger.Error("Failed to unicast Message", "msg", msg, "err", err)
 		}
-	case MsgTypePrepare, MsgTypePreCommit, MsgTypeCommit, MsgTypeDecide:
+	case MsgTypePrepare, MsgTypePreCommit, MsgTypeCommit, MsgTypeDecide, MsgTypeTimeout, MsgTypeTimeoutCert:
 		if err := c.backend.Broadcast(c.valSet, payload); err != nil {
 			logger.Error("Failed to broadcast Message", "msg", msg, "err", err)
 		}
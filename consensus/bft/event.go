@@ -18,3 +18,9 @@ type MessageEvent struct {
 type FinalCommittedEvent struct {
 	Header *types.Header
 }
+
+// CertifiedEvent is posted when a proposal of the event driven protocol is
+// certified, the next proposals extend it
+type CertifiedEvent struct {
+	Header *types.Header
+}
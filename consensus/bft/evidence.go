@@ -0,0 +1,28 @@
+package bft
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Evidence proves that a validator equivocated, signing two conflicting messages
+// of the same type for the same view, e.g. proposing two blocks or voting for two
+// digests. Honest validators never do this, so the evidence is enough to slash
+// the offender.
+type Evidence struct {
+	Offender common.Address // validator which signed both messages
+	Code     uint64         // type of the conflicting messages
+	View     *View          // view of the conflicting messages
+	First    []byte         // signed payload of the message with the lower digest
+	Second   []byte         // signed payload of the message with the higher digest
+}
+
+// Hash returns the identifier of the evidence.
+func (ev *Evidence) Hash() common.Hash {
+	return RLPHash(ev)
+}
+
+func (ev *Evidence) String() string {
+	return fmt.Sprintf("{Evidence Offender: %v, Code: %d, View: %v, Hash: %v}", ev.Offender.Hex(), ev.Code, ev.View, ev.Hash().String())
+}
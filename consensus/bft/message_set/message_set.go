@@ -63,6 +63,20 @@ func (s *MessageSet) Size() int {
 	return len(s.msgs)
 }
 
+// Power returns the total voting power of the message senders.
+func (s *MessageSet) Power() uint64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	power := uint64(0)
+	for addr := range s.msgs {
+		if _, v := s.vs.GetByAddress(addr); v != nil {
+			power += v.Power()
+		}
+	}
+	return power
+}
+
 func (s *MessageSet) Get(addr common.Address) *bft.Message {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
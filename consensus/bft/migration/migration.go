@@ -0,0 +1,436 @@
+// Package migration implements the consensus engine which moves a running clique
+// or ethash chain to the HotStuff engine at the HotStuff fork block.
+package migration
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// errMissingValidators is returned if the validators of the fork block are not
+	// configured, and the legacy engine has no signers to take them from.
+	errMissingValidators = errors.New("missing hotstuff fork validators")
+
+	// errInvalidAncestor is returned if a header is not verified because one of
+	// it's ancestors in the batch failed the verification.
+	errInvalidAncestor = errors.New("invalid ancestor")
+)
+
+// Migration is a consensus engine which seals the blocks before the HotStuff fork
+// block with the legacy engine, and the blocks from the fork block onward with the
+// bft engine. The first epoch of the bft engine starts at the fork block, it's
+// validators are given in the config or are the last clique signers.
+type Migration struct {
+	legacy consensus.Engine // Engine of the blocks before the fork, clique or ethash
+	bft    consensus.BFT    // Engine of the blocks from the fork block onward
+	fork   *big.Int         // HotStuff fork block
+	vals   []common.Address // Validators of the fork block, taken from clique if empty
+
+	startMu sync.Mutex
+	start   func() error // Deferred start of the bft engine, until the fork block is next
+}
+
+// New creates a consensus engine switching from the legacy to the bft engine at the
+// given fork block.
+func New(legacy consensus.Engine, bft consensus.BFT, fork *big.Int, validators []common.Address) *Migration {
+	if _, ok := legacy.(*Migration); ok {
+		panic("nested consensus engine")
+	}
+	return &Migration{
+		legacy: legacy,
+		bft:    bft,
+		fork:   new(big.Int).Set(fork),
+		vals:   validators,
+	}
+}
+
+// isHotStuff returns whether the block of the given number is sealed by the bft engine.
+func (m *Migration) isHotStuff(number *big.Int) bool {
+	return number.Cmp(m.fork) >= 0
+}
+
+// initEpoch initializes the first epoch of the bft engine if the header is the fork
+// block. The parents are used to retrieve the clique signers if they are not in
+// the database.
+func (m *Migration) initEpoch(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) error {
+	if header.Number.Cmp(m.fork) != 0 {
+		return nil
+	}
+	validators := m.vals
+	if len(validators) == 0 {
+		c, ok := m.legacy.(*clique.Clique)
+		if !ok {
+			return errMissingValidators
+		}
+		signers, err := c.Signers(chain, header.Number.Uint64()-1, header.ParentHash, parents)
+		if err != nil {
+			return err
+		}
+		validators = signers
+	}
+	return m.bft.InitEpoch(header.Number.Uint64(), validators)
+}
+
+// Author implements consensus.Engine, returning the verified author of the block.
+func (m *Migration) Author(header *types.Header) (common.Address, error) {
+	if !m.isHotStuff(header.Number) {
+		return m.legacy.Author(header)
+	}
+	return m.bft.Author(header)
+}
+
+// VerifyHeader implements consensus.Engine, checking the header with the rules of
+// the engine sealing it.
+func (m *Migration) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	if !m.isHotStuff(header.Number) {
+		return m.legacy.VerifyHeader(chain, header, seal)
+	}
+	if err := m.initEpoch(chain, header, nil); err != nil {
+		return err
+	}
+	return m.bft.VerifyHeader(chain, header, seal)
+}
+
+// VerifyHeaders implements consensus.Engine. A batch crossing the fork block is
+// split, the headers before the fork are verified by the legacy engine first,
+// they define the validators of the fork block.
+func (m *Migration) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	if !m.isHotStuff(headers[len(headers)-1].Number) {
+		return m.legacy.VerifyHeaders(chain, headers, seals)
+	}
+	var split int
+	for split < len(headers) && !m.isHotStuff(headers[split].Number) {
+		split++
+	}
+	var (
+		abort   = make(chan struct{})
+		results = make(chan error, len(headers))
+	)
+	go func() {
+		var failed bool
+		if split > 0 {
+			legacyAbort, legacyResults := m.legacy.VerifyHeaders(chain, headers[:split], seals[:split])
+			for i := 0; i < split; i++ {
+				select {
+				case err := <-legacyResults:
+					failed = failed || err != nil
+					results <- err
+				case <-abort:
+					close(legacyAbort)
+					return
+				}
+			}
+		}
+		var err error
+		if failed {
+			err = errInvalidAncestor
+		} else {
+			err = m.initEpoch(chain, headers[split], headers[:split])
+		}
+		if err != nil {
+			for i := split; i < len(headers); i++ {
+				results <- err
+			}
+			return
+		}
+		// The bft engine skips the headers before the fork, they are the parents
+		// of the fork block
+		bftAbort, bftResults := m.bft.VerifyHeaders(chain, headers, seals)
+		for i := 0; i < len(headers); i++ {
+			select {
+			case err := <-bftResults:
+				if i >= split {
+					results <- err
+				}
+			case <-abort:
+				close(bftAbort)
+				return
+			}
+		}
+	}()
+	return abort, results
+}
+
+// VerifyUncles implements consensus.Engine, checking the uncles with the rules of
+// the engine sealing the block.
+func (m *Migration) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if !m.isHotStuff(block.Number()) {
+		return m.legacy.VerifyUncles(chain, block)
+	}
+	return m.bft.VerifyUncles(chain, block)
+}
+
+// Prepare implements consensus.Engine, the bft extra is written from the fork
+// block onward.
+func (m *Migration) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	if !m.isHotStuff(header.Number) {
+		return m.legacy.Prepare(chain, header)
+	}
+	if err := m.initEpoch(chain, header, nil); err != nil {
+		return err
+	}
+	return m.bft.Prepare(chain, header)
+}
+
+// Finalize implements consensus.Engine, setting the final state on the header.
+func (m *Migration) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	if !m.isHotStuff(header.Number) {
+		m.legacy.Finalize(chain, header, state, txs, uncles)
+		return
+	}
+	m.bft.Finalize(chain, header, state, txs, uncles)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, setting the final state and
+// assembling the block.
+func (m *Migration) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	if !m.isHotStuff(header.Number) {
+		return m.legacy.FinalizeAndAssemble(chain, header, state, txs, uncles, receipts)
+	}
+	return m.bft.FinalizeAndAssemble(chain, header, state, txs, uncles, receipts)
+}
+
+// Seal implements consensus.Engine, sealing the block with the engine of it's number.
+func (m *Migration) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	if !m.isHotStuff(block.Number()) {
+		return m.legacy.Seal(chain, block, results, stop)
+	}
+	return m.bft.Seal(chain, block, results, stop)
+}
+
+// SealHash implements consensus.Engine, returning the hash of a block prior to it
+// being sealed.
+func (m *Migration) SealHash(header *types.Header) common.Hash {
+	if !m.isHotStuff(header.Number) {
+		return m.legacy.SealHash(header)
+	}
+	return m.bft.SealHash(header)
+}
+
+// CalcDifficulty implements consensus.Engine, returning the difficulty of the block
+// following the parent.
+func (m *Migration) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	if !m.isHotStuff(new(big.Int).Add(parent.Number, common.Big1)) {
+		return m.legacy.CalcDifficulty(chain, time, parent)
+	}
+	return m.bft.CalcDifficulty(chain, time, parent)
+}
+
+// APIs implements consensus.Engine, returning the RPC APIs of both engines.
+func (m *Migration) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return append(m.legacy.APIs(chain), m.bft.APIs(chain)...)
+}
+
+// Close implements consensus.Engine, terminating both engines.
+func (m *Migration) Close() error {
+	err := m.bft.Close()
+	if legacyErr := m.legacy.Close(); err == nil {
+		err = legacyErr
+	}
+	return err
+}
+
+// Start implements consensus.BFT. The bft engine is started once the head is the
+// parent of the fork block, the legacy engine seals the blocks before.
+func (m *Migration) Start(chain consensus.ChainReader, currentBlock func() *types.Block, getBlockByHash func(hash common.Hash) *types.Block, hasBadBlock func(hash common.Hash) bool) error {
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+
+	start := func() error {
+		return m.bft.Start(chain, currentBlock, getBlockByHash, hasBadBlock)
+	}
+	if head := currentBlock().Number(); !m.isHotStuff(new(big.Int).Add(head, common.Big1)) {
+		log.Info("Deferring hotstuff engine start", "head", head, "fork", m.fork)
+		m.start = start
+		return nil
+	}
+	return start()
+}
+
+// Stop implements consensus.BFT, dropping the deferred start of the bft engine.
+func (m *Migration) Stop() error {
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+
+	if m.start != nil {
+		m.start = nil
+		return nil
+	}
+	return m.bft.Stop()
+}
+
+// ChangeEpoch implements consensus.BFT.
+func (m *Migration) ChangeEpoch(epochStartHeight uint64, list []common.Address) error {
+	return m.bft.ChangeEpoch(epochStartHeight, list)
+}
+
+// InitEpoch implements consensus.BFT.
+func (m *Migration) InitEpoch(epochStartHeight uint64, list []common.Address) error {
+	return m.bft.InitEpoch(epochStartHeight, list)
+}
+
+// VerifyState implements consensus.StateVerifier, the headers after the fork are
+// checked by the bft engine.
+func (m *Migration) VerifyState(chain consensus.ChainHeaderReader, header *types.Header, parent *state.StateDB) error {
+	verifier, ok := m.bft.(consensus.StateVerifier)
+	if !ok || !m.isHotStuff(header.Number) {
+		return nil
+	}
+	return verifier.VerifyState(chain, header, parent)
+}
+
+// Health implements consensus.BFT.
+func (m *Migration) Health() *consensus.Health {
+	return m.bft.Health()
+}
+
+// Signers implements consensus.BFT, the blocks before the fork have no committers.
+func (m *Migration) Signers(header *types.Header) (common.Address, []common.Address, error) {
+	if !m.isHotStuff(header.Number) {
+		author, err := m.legacy.Author(header)
+		return author, nil, err
+	}
+	return m.bft.Signers(header)
+}
+
+// Participation implements consensus.BFT, the blocks before the fork have no
+// validators.
+func (m *Migration) Participation(parent, header *types.Header) (*consensus.Participation, error) {
+	if !m.isHotStuff(header.Number) {
+		return nil, nil
+	}
+	return m.bft.Participation(parent, header)
+}
+
+// handler returns the message handler of the bft engine.
+func (m *Migration) handler() consensus.Handler {
+	return m.bft.(consensus.Handler)
+}
+
+// Address implements consensus.Handler.
+func (m *Migration) Address() common.Address {
+	return m.handler().Address()
+}
+
+// SignHandshake implements consensus.Handler.
+func (m *Migration) SignHandshake(data []byte) ([]byte, error) {
+	return m.handler().SignHandshake(data)
+}
+
+// IsValidator implements consensus.Handler, there are no validators before the
+// fork block.
+func (m *Migration) IsValidator(height uint64, address common.Address) bool {
+	if !m.isHotStuff(new(big.Int).SetUint64(height)) {
+		return false
+	}
+	return m.handler().IsValidator(height, address)
+}
+
+// HandleMsg implements consensus.Handler.
+func (m *Migration) HandleMsg(address common.Address, msg p2p.Msg) (bool, error) {
+	return m.handler().HandleMsg(address, msg)
+}
+
+// SetBroadcaster implements consensus.Handler.
+func (m *Migration) SetBroadcaster(broadcaster consensus.Broadcaster) {
+	m.handler().SetBroadcaster(broadcaster)
+}
+
+// NewChainHead implements consensus.Handler, starting the deferred bft engine once
+// the fork block is next.
+func (m *Migration) NewChainHead(header *types.Header) error {
+	if !m.isHotStuff(new(big.Int).Add(header.Number, common.Big1)) {
+		return nil
+	}
+	m.startMu.Lock()
+	if start := m.start; start != nil {
+		m.start = nil
+		if err := start(); err != nil {
+			m.startMu.Unlock()
+			return err
+		}
+		log.Info("Started hotstuff engine", "number", header.Number, "fork", m.fork)
+	}
+	m.startMu.Unlock()
+
+	return m.handler().NewChainHead(header)
+}
+
+// Finalized implements consensus.Finality, the blocks before the fork block are
+// never final.
+func (m *Migration) Finalized(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	if !m.isHotStuff(head.Number) {
+		return nil
+	}
+	finalized := m.bft.(consensus.Finality).Finalized(chain, head)
+	if finalized == nil || !m.isHotStuff(finalized.Number) {
+		return nil
+	}
+	return finalized
+}
+
+// BuildDeadline implements consensus.BuildDeadliner, the blocks before the fork
+// block are built without deadline.
+func (m *Migration) BuildDeadline(header *types.Header) (time.Time, bool) {
+	if !m.isHotStuff(header.Number) {
+		return time.Time{}, false
+	}
+	return m.bft.(consensus.BuildDeadliner).BuildDeadline(header)
+}
+
+// LatestEpoch implements consensus.EpochProver.
+func (m *Migration) LatestEpoch() uint64 {
+	return m.bft.(consensus.EpochProver).LatestEpoch()
+}
+
+// EpochProof implements consensus.EpochProver.
+func (m *Migration) EpochProof(chain consensus.ChainHeaderReader, from uint64, limit int) []*types.Header {
+	return m.bft.(consensus.EpochProver).EpochProof(chain, from, limit)
+}
+
+// VerifyEpochProof implements consensus.EpochProver.
+func (m *Migration) VerifyEpochProof(from uint64, headers []*types.Header) error {
+	return m.bft.(consensus.EpochProver).VerifyEpochProof(from, headers)
+}
+
+// Propose implements consensus.Governance.
+func (m *Migration) Propose(candidate common.Address, authorize bool) error {
+	return m.bft.(consensus.Governance).Propose(candidate, authorize)
+}
+
+// RewindEpochs implements consensus.EpochStore.
+func (m *Migration) RewindEpochs(head uint64) error {
+	return m.bft.(consensus.EpochStore).RewindEpochs(head)
+}
+
+// FreezeEpochs implements consensus.EpochStore.
+func (m *Migration) FreezeEpochs(finalized uint64) error {
+	return m.bft.(consensus.EpochStore).FreezeEpochs(finalized)
+}
+
+// LegacyEngine returns the engine sealing the blocks before the fork block.
+func (m *Migration) LegacyEngine() consensus.Engine {
+	return m.legacy
+}
+
+// SetThreads updates the mining threads of the legacy engine if it's threaded.
+func (m *Migration) SetThreads(threads int) {
+	type threaded interface {
+		SetThreads(threads int)
+	}
+	if th, ok := m.legacy.(threaded); ok {
+		th.SetThreads(threads)
+	}
+}
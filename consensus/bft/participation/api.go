@@ -0,0 +1,70 @@
+package participation
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// errNoCompleteEpoch is returned if the latest epoch is requested before any
+// epoch is complete.
+var errNoCompleteEpoch = errors.New("no complete epoch indexed")
+
+// API exposes the participation of the validators accounted by the indexer.
+type API struct {
+	indexer *Indexer
+}
+
+// NewAPI creates the participation api of the indexer.
+func NewAPI(indexer *Indexer) *API {
+	return &API{indexer: indexer}
+}
+
+// GetParticipation returns the participation of the validators in the given epoch,
+// or in the latest complete epoch if the number is not given.
+func (api *API) GetParticipation(number *uint64) (*Epoch, error) {
+	if number == nil {
+		latest, ok := api.indexer.LatestEpoch()
+		if !ok {
+			return nil, errNoCompleteEpoch
+		}
+		number = &latest
+	}
+	return api.indexer.Epoch(*number)
+}
+
+// ValidatorEpoch is the participation of a validator in an epoch.
+type ValidatorEpoch struct {
+	Epoch uint64 `json:"epoch"`
+	*Stats
+}
+
+// GetValidatorParticipation returns the participation of the validator in the
+// given number of epochs up to the latest complete one, in ascending order. The
+// epochs in which it wasn't a validator are left out.
+func (api *API) GetValidatorParticipation(address common.Address, epochs uint64) ([]*ValidatorEpoch, error) {
+	latest, ok := api.indexer.LatestEpoch()
+	if !ok {
+		return nil, errNoCompleteEpoch
+	}
+	if epochs == 0 {
+		epochs = 1
+	}
+	first := uint64(0)
+	if epochs <= latest {
+		first = latest - epochs + 1
+	}
+	var result []*ValidatorEpoch
+	for number := first; number <= latest; number++ {
+		epoch, err := api.indexer.Epoch(number)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range epoch.Validators {
+			if s.Address == address {
+				result = append(result, &ValidatorEpoch{Epoch: number, Stats: s})
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,351 @@
+// Package participation accounts the participation of the bft validators in the
+// committed blocks. The blocks are indexed by epochs, the proposals, the failed
+// rounds and the committed seals of every validator are counted per epoch, so the
+// validators which are chronically absent can be found and voted out.
+package participation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// DefaultEpochLength is the number of blocks accounted together if the chain
+	// has no epochs.
+	DefaultEpochLength = 4096
+
+	// confirms is the number of blocks after which a section is indexed, the blocks
+	// committed by the event driven protocol need a few descendants.
+	confirms = 16
+
+	// throttling is the time to wait between processing two consecutive sections,
+	// the committed seals of every block are recovered.
+	throttling = 50 * time.Millisecond
+)
+
+var (
+	// errUnknownEpoch is returned if the participation of an epoch is not indexed.
+	errUnknownEpoch = errors.New("epoch participation not indexed")
+
+	epochGauge = metrics.NewRegisteredGauge("bft/participation/epoch", nil)
+)
+
+// Config is the handling of the absent validators by the node.
+type Config struct {
+	AbsentEpochs uint64 `toml:",omitempty"` // Consecutive absent epochs after which the node votes to remove a validator, zero disables the votes
+	AbsentVotes  uint64 `toml:",omitempty"` // Percentage of the blocks of an epoch committed with the seal of a validator up to which it is absent
+}
+
+// Stats is the participation of a validator in the blocks of an epoch.
+type Stats struct {
+	Address  common.Address `json:"address"`
+	Blocks   uint64         `json:"blocks"`   // Blocks of the epoch validated by the validator
+	Proposed uint64         `json:"proposed"` // Blocks proposed by the validator
+	Missed   uint64         `json:"missed"`   // Rounds led by the validator which failed
+	Votes    uint64         `json:"votes"`    // Blocks committed with the seal of the validator
+}
+
+// absent returns whether the validator's seals are in at most the given percentage
+// of the blocks it validated.
+func (s *Stats) absent(percent uint64) bool {
+	return s.Blocks > 0 && s.Votes*100 <= s.Blocks*percent
+}
+
+// Epoch is the participation of the validators in the blocks of an epoch, the
+// epoch n contains the blocks from n*length+1 to (n+1)*length.
+type Epoch struct {
+	Number     uint64   `json:"number"`
+	Blocks     uint64   `json:"blocks"`           // Blocks of the epoch accounted
+	Complete   bool     `json:"complete" rlp:"-"` // Whether all the blocks of the epoch are accounted
+	Validators []*Stats `json:"validators"`       // Participation of the validators, sorted by address
+}
+
+// stats returns the participation of the validator, adding it if it's missing.
+func (e *Epoch) stats(addr common.Address) *Stats {
+	i := sort.Search(len(e.Validators), func(i int) bool {
+		return bytes.Compare(e.Validators[i].Address[:], addr[:]) >= 0
+	})
+	if i < len(e.Validators) && e.Validators[i].Address == addr {
+		return e.Validators[i]
+	}
+	stats := &Stats{Address: addr}
+	e.Validators = append(e.Validators, nil)
+	copy(e.Validators[i+1:], e.Validators[i:])
+	e.Validators[i] = stats
+	return stats
+}
+
+// merge adds the participation accounted in another section to the epoch.
+func (e *Epoch) merge(o *Epoch) {
+	e.Blocks += o.Blocks
+	for _, s := range o.Validators {
+		stats := e.stats(s.Address)
+		stats.Blocks += s.Blocks
+		stats.Proposed += s.Proposed
+		stats.Missed += s.Missed
+		stats.Votes += s.Votes
+	}
+}
+
+// Indexer is the chain indexer of the validator participation. The sections of the
+// indexer are as long as the epochs, the section n holds the last block of the
+// epoch n-1 and the other blocks of the epoch n.
+type Indexer struct {
+	*core.ChainIndexer
+
+	db ethdb.Database
+}
+
+// NewIndexer returns a chain indexer accounting the participation of the validators
+// of the engine in the canonical chain, by the epochs of the hotstuff config.
+func NewIndexer(db ethdb.Database, chain consensus.ChainHeaderReader, engine consensus.BFT, hs *params.HotStuffConfig, config Config) (*Indexer, error) {
+	bftConfig, err := bft.NewConfig(hs)
+	if err != nil {
+		return nil, err
+	}
+	length := bftConfig.Epoch
+	if length == 0 {
+		length = DefaultEpochLength
+	}
+	backend := &processor{
+		db:     db,
+		chain:  chain,
+		engine: engine,
+		length: length,
+		config: config,
+	}
+	table := rawdb.NewTable(db, string(rawdb.BftParticipationIndexPrefix))
+
+	return &Indexer{
+		ChainIndexer: core.NewChainIndexer(db, table, backend, length, confirms, throttling, "participation"),
+		db:           db,
+	}, nil
+}
+
+// Epoch returns the participation of the validators in the given epoch. The last
+// block of the epoch is accounted with the next section, the epoch isn't complete
+// until then.
+func (i *Indexer) Epoch(number uint64) (*Epoch, error) {
+	sections, _, _ := i.Sections()
+	if number >= sections {
+		return nil, fmt.Errorf("%w: %d", errUnknownEpoch, number)
+	}
+	epoch := &Epoch{Number: number, Complete: number+1 < sections}
+	last := number
+	if epoch.Complete {
+		last++
+	}
+	for section := number; section <= last; section++ {
+		epochs, err := readSection(i.db, section)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range epochs {
+			if e.Number == number {
+				epoch.merge(e)
+			}
+		}
+	}
+	return epoch, nil
+}
+
+// LatestEpoch returns the number of the latest complete epoch, or false if no
+// epoch is complete yet.
+func (i *Indexer) LatestEpoch() (uint64, bool) {
+	sections, _, _ := i.Sections()
+	if sections < 2 {
+		return 0, false
+	}
+	return sections - 2, true
+}
+
+// readSection retrieves the participation accounted in a section, by epoch.
+func readSection(db ethdb.KeyValueReader, section uint64) ([]*Epoch, error) {
+	blob := rawdb.ReadBftParticipation(db, section)
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	var epochs []*Epoch
+	if err := rlp.DecodeBytes(blob, &epochs); err != nil {
+		return nil, err
+	}
+	return epochs, nil
+}
+
+// processor implements core.ChainIndexerBackend, accounting the participation of
+// the validators in the blocks of a section.
+type processor struct {
+	db     ethdb.Database
+	chain  consensus.ChainHeaderReader
+	engine consensus.BFT
+	length uint64
+	config Config
+
+	section uint64
+	epochs  []*Epoch // Epochs accounted in the section, in ascending order
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section.
+func (p *processor) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	p.section, p.epochs = section, nil
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the participation in a new
+// header to it's epoch. The blocks whose proposer isn't elected in any round, or
+// which are sealed by another engine, are not accounted.
+func (p *processor) Process(ctx context.Context, header *types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	parent := p.chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	part, err := p.engine.Participation(parent, header)
+	if err != nil {
+		log.Warn("Failed to account validator participation", "number", number, "hash", header.Hash(), "err", err)
+		return nil
+	}
+	if part == nil {
+		return nil
+	}
+	epoch := p.epoch((number - 1) / p.length)
+	epoch.Blocks++
+	for _, addr := range part.Validators {
+		epoch.stats(addr).Blocks++
+	}
+	epoch.stats(part.Proposer).Proposed++
+	for _, addr := range part.Missed {
+		epoch.stats(addr).Missed++
+	}
+	for _, addr := range part.Committers {
+		epoch.stats(addr).Votes++
+	}
+	return nil
+}
+
+// epoch returns the participation of the epoch accounted in the section.
+func (p *processor) epoch(number uint64) *Epoch {
+	if n := len(p.epochs); n > 0 && p.epochs[n-1].Number == number {
+		return p.epochs[n-1]
+	}
+	epoch := &Epoch{Number: number}
+	p.epochs = append(p.epochs, epoch)
+	return epoch
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the participation of the
+// section. The epoch ending in the section is complete, it's exported as metrics
+// and it's absent validators are voted on.
+func (p *processor) Commit() error {
+	blob, err := rlp.EncodeToBytes(p.epochs)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteBftParticipation(p.db, p.section, blob)
+
+	if p.section == 0 {
+		return nil
+	}
+	epoch, err := p.completeEpoch(p.section - 1)
+	if err != nil {
+		return err
+	}
+	epochGauge.Update(int64(epoch.Number))
+	for _, s := range epoch.Validators {
+		prefix := "bft/participation/" + s.Address.Hex()
+		metrics.GetOrRegisterGauge(prefix+"/blocks", nil).Update(int64(s.Blocks))
+		metrics.GetOrRegisterGauge(prefix+"/proposed", nil).Update(int64(s.Proposed))
+		metrics.GetOrRegisterGauge(prefix+"/missed", nil).Update(int64(s.Missed))
+		metrics.GetOrRegisterGauge(prefix+"/votes", nil).Update(int64(s.Votes))
+	}
+	return p.voteAbsent(epoch)
+}
+
+// completeEpoch returns the participation of an epoch whose sections are written.
+func (p *processor) completeEpoch(number uint64) (*Epoch, error) {
+	epoch := &Epoch{Number: number, Complete: true}
+	for section := number; section <= number+1; section++ {
+		epochs, err := readSection(p.db, section)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range epochs {
+			if e.Number == number {
+				epoch.merge(e)
+			}
+		}
+	}
+	return epoch, nil
+}
+
+// voteAbsent casts the removal votes of the validators which were absent in the
+// configured number of epochs up to the given one. The epochs accounted while the
+// node catches up with the chain are not voted on.
+func (p *processor) voteAbsent(epoch *Epoch) error {
+	if p.config.AbsentEpochs == 0 || epoch.Number+1 < p.config.AbsentEpochs {
+		return nil
+	}
+	governance, ok := p.engine.(consensus.Governance)
+	if !ok {
+		return nil
+	}
+	if head := p.chain.CurrentHeader().Number.Uint64(); head/p.length > epoch.Number+2 {
+		return nil
+	}
+	var self common.Address
+	if handler, ok := p.engine.(consensus.Handler); ok {
+		self = handler.Address()
+	}
+	absent := make(map[common.Address]bool)
+	for _, s := range epoch.Validators {
+		if s.Address != self && s.absent(p.config.AbsentVotes) {
+			absent[s.Address] = true
+		}
+	}
+	for number := epoch.Number - p.config.AbsentEpochs + 1; number < epoch.Number && len(absent) > 0; number++ {
+		prev, err := p.completeEpoch(number)
+		if err != nil {
+			return err
+		}
+		// Validators which didn't validate an earlier epoch weren't absent in it
+		still := make(map[common.Address]bool)
+		for _, s := range prev.Validators {
+			if absent[s.Address] && s.absent(p.config.AbsentVotes) {
+				still[s.Address] = true
+			}
+		}
+		absent = still
+	}
+	for addr := range absent {
+		if err := governance.Propose(addr, false); err != nil {
+			log.Warn("Failed to vote out absent validator", "validator", addr, "epoch", epoch.Number, "err", err)
+			return nil
+		}
+		log.Warn("Voting out absent validator", "validator", addr, "epoch", epoch.Number, "epochs", p.config.AbsentEpochs)
+	}
+	return nil
+}
+
+// Prune implements core.ChainIndexerBackend, the participation isn't pruned.
+func (p *processor) Prune(threshold uint64) error {
+	return nil
+}
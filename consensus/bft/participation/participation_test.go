@@ -0,0 +1,122 @@
+package participation
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	valA = common.HexToAddress("0x000000000000000000000000000000000000000a")
+	valB = common.HexToAddress("0x000000000000000000000000000000000000000b")
+	valC = common.HexToAddress("0x000000000000000000000000000000000000000c")
+)
+
+// testChain is a canonical chain of headers.
+type testChain struct {
+	consensus.ChainHeaderReader
+	headers []*types.Header
+}
+
+func newTestChain(n int) *testChain {
+	chain := new(testChain)
+	var parent common.Hash
+	for i := 0; i <= n; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent}
+		chain.headers = append(chain.headers, header)
+		parent = header.Hash()
+	}
+	return chain
+}
+
+func (c *testChain) CurrentHeader() *types.Header {
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number < uint64(len(c.headers)) && c.headers[number].Hash() == hash {
+		return c.headers[number]
+	}
+	return nil
+}
+
+// testEngine elects A, B and C in turn, C never seals and B's rounds fail at odd
+// heights.
+type testEngine struct {
+	consensus.BFT
+	votes map[common.Address]bool
+}
+
+func (e *testEngine) Participation(parent, header *types.Header) (*consensus.Participation, error) {
+	part := &consensus.Participation{
+		Validators: []common.Address{valA, valB, valC},
+		Proposer:   valA,
+		Committers: []common.Address{valA, valB},
+	}
+	if header.Number.Uint64()%2 == 1 {
+		part.Missed = []common.Address{valB}
+	}
+	return part, nil
+}
+
+func (e *testEngine) Propose(candidate common.Address, authorize bool) error {
+	e.votes[candidate] = authorize
+	return nil
+}
+
+// Tests that the participation is accounted by epoch across the sections, and that
+// the validators absent in enough epochs are voted out.
+func TestParticipation(t *testing.T) {
+	var (
+		chain  = newTestChain(11)
+		engine = &testEngine{votes: make(map[common.Address]bool)}
+		p      = &processor{
+			db:     rawdb.NewMemoryDatabase(),
+			chain:  chain,
+			engine: engine,
+			length: 4,
+			config: Config{AbsentEpochs: 2},
+		}
+	)
+	for section := uint64(0); section < 3; section++ {
+		if err := p.Reset(context.Background(), section, common.Hash{}); err != nil {
+			t.Fatalf("section %d: failed to reset: %v", section, err)
+		}
+		for _, header := range chain.headers[section*4 : section*4+4] {
+			if err := p.Process(context.Background(), header); err != nil {
+				t.Fatalf("block %d: failed to process: %v", header.Number, err)
+			}
+		}
+		if err := p.Commit(); err != nil {
+			t.Fatalf("section %d: failed to commit: %v", section, err)
+		}
+		if section == 1 && len(engine.votes) > 0 {
+			t.Fatalf("votes cast after a single absent epoch: %v", engine.votes)
+		}
+	}
+	epoch, err := p.completeEpoch(1)
+	if err != nil {
+		t.Fatalf("failed to read epoch: %v", err)
+	}
+	if epoch.Blocks != 4 || len(epoch.Validators) != 3 {
+		t.Fatalf("epoch mismatch: have %d blocks %d validators, want 4 blocks 3 validators", epoch.Blocks, len(epoch.Validators))
+	}
+	want := []Stats{
+		{Address: valA, Blocks: 4, Proposed: 4, Votes: 4},
+		{Address: valB, Blocks: 4, Missed: 2, Votes: 4},
+		{Address: valC, Blocks: 4},
+	}
+	for i, s := range epoch.Validators {
+		if *s != want[i] {
+			t.Errorf("validator %d: stats mismatch: have %+v, want %+v", i, *s, want[i])
+		}
+	}
+	if authorize, ok := engine.votes[valC]; len(engine.votes) != 1 || !ok || authorize {
+		t.Errorf("absent votes mismatch: have %v, want removal of %v", engine.votes, valC)
+	}
+}
@@ -0,0 +1,209 @@
+package bft
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// SignKind is the kind of the consensus data signed by a validator.
+type SignKind uint8
+
+const (
+	SignMessage   SignKind = iota + 1 // Consensus message sent to the other validators
+	SignVote                          // Committed seal of a proposal
+	SignTimeout                       // Timeout seal of a round
+	SignProposal                      // Proposer seal of a block header
+	SignHandshake                     // Node id of the validator in the `bft` handshake
+)
+
+// handshakePrefix is the name of the `bft` protocol, which prefixes the genesis
+// hash and node id signed in it's handshake.
+const handshakePrefix = "bft"
+
+func (k SignKind) String() string {
+	switch k {
+	case SignMessage:
+		return "message"
+	case SignVote:
+		return "vote"
+	case SignTimeout:
+		return "timeout"
+	case SignProposal:
+		return "proposal"
+	case SignHandshake:
+		return "handshake"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	// ErrInvalidSignRequest is returned if the described view or digest of a sign
+	// request doesn't match the signed payload.
+	ErrInvalidSignRequest = errors.New("invalid sign request")
+)
+
+// SignRequest is the consensus data signed with a key held outside of the node,
+// e.g. by clef. The signature is taken over the keccak256 hash of the payload, as
+// the local signer does, and the other fields describe the payload, so the signer
+// is able to refuse conflicting data of a view it already signed.
+//
+// Proposals are sealed before their round is known, the view of a proposal only
+// carries the height, and the proposal message binds the seal to it's round. The
+// committed seals of votes only cover the header, the signer checks their height
+// against it, but their round isn't part of the signed data.
+type SignRequest struct {
+	Kind    SignKind
+	Code    uint64        // Code of the consensus message, zero for the seals
+	View    *View         // View of the signed data
+	Digest  common.Hash   // Hash of the signed data, equal for the same content
+	Payload []byte        // Signed data
+	Header  *types.Header `rlp:"optional"` // Sealed header, only set for proposals and votes
+}
+
+// NewMessageRequest returns the sign request of the payload of a consensus message
+// without signature.
+func NewMessageRequest(payload []byte) (*SignRequest, error) {
+	msg, err := decodeRawMessage(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &SignRequest{
+		Kind:    SignMessage,
+		Code:    msg.Code,
+		View:    msg.View,
+		Digest:  crypto.Keccak256Hash(payload),
+		Payload: payload,
+	}, nil
+}
+
+// NewVoteRequest returns the sign request of the committed seal of the header, the
+// hash is the committed seal hash of the header.
+func NewVoteRequest(view *View, header *types.Header, hash common.Hash) *SignRequest {
+	return &SignRequest{Kind: SignVote, View: view, Digest: hash, Payload: hash.Bytes(), Header: header}
+}
+
+// NewTimeoutRequest returns the sign request of the timeout seal of the view.
+func NewTimeoutRequest(view *View) *SignRequest {
+	digest := TimeoutDigest(view)
+	return &SignRequest{Kind: SignTimeout, View: view, Digest: digest, Payload: digest.Bytes()}
+}
+
+// NewProposalRequest returns the sign request of the proposer seal of the header.
+func NewProposalRequest(header *types.Header, sigHash common.Hash) *SignRequest {
+	return &SignRequest{
+		Kind:    SignProposal,
+		View:    &View{Round: new(big.Int), Height: new(big.Int).Set(header.Number)},
+		Digest:  sigHash,
+		Payload: sigHash.Bytes(),
+		Header:  header,
+	}
+}
+
+// NewHandshakeRequest returns the sign request of the `bft` handshake data, which
+// binds the validator to a node id. The handshake isn't part of any view, it's
+// view is left zero.
+func NewHandshakeRequest(payload []byte) *SignRequest {
+	return &SignRequest{
+		Kind:    SignHandshake,
+		View:    &View{Round: new(big.Int), Height: new(big.Int)},
+		Digest:  crypto.Keccak256Hash(payload),
+		Payload: payload,
+	}
+}
+
+// Verify checks that the view and digest of the request describe the payload, the
+// signer must verify them before it trusts the request.
+func (r *SignRequest) Verify() error {
+	if r.View == nil || r.View.Height == nil || r.View.Round == nil {
+		return fmt.Errorf("%w: missing view", ErrInvalidSignRequest)
+	}
+	switch r.Kind {
+	case SignMessage:
+		msg, err := decodeRawMessage(r.Payload)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignRequest, err)
+		}
+		if msg.Code != r.Code || msg.View.Cmp(r.View) != 0 {
+			return fmt.Errorf("%w: message view mismatch", ErrInvalidSignRequest)
+		}
+		if crypto.Keccak256Hash(r.Payload) != r.Digest {
+			return fmt.Errorf("%w: message digest mismatch", ErrInvalidSignRequest)
+		}
+		return nil
+
+	case SignVote:
+		if r.Code != 0 || r.Header == nil || r.Header.Number == nil || !bytes.Equal(r.Payload, r.Digest.Bytes()) {
+			return fmt.Errorf("%w: seal digest mismatch", ErrInvalidSignRequest)
+		}
+		if r.Header.Number.Cmp(r.View.Height) != 0 {
+			return fmt.Errorf("%w: vote view mismatch", ErrInvalidSignRequest)
+		}
+		if RLPHash(types.BftFilteredHeader(r.Header, true)) != r.Digest {
+			return fmt.Errorf("%w: vote digest mismatch", ErrInvalidSignRequest)
+		}
+		return nil
+
+	case SignTimeout:
+		if r.Code != 0 || r.Digest != TimeoutDigest(r.View) || !bytes.Equal(r.Payload, r.Digest.Bytes()) {
+			return fmt.Errorf("%w: timeout digest mismatch", ErrInvalidSignRequest)
+		}
+		return nil
+
+	case SignProposal:
+		if r.Code != 0 || r.Header == nil || r.Header.Number == nil || !bytes.Equal(r.Payload, r.Digest.Bytes()) {
+			return fmt.Errorf("%w: seal digest mismatch", ErrInvalidSignRequest)
+		}
+		if r.Header.Number.Cmp(r.View.Height) != 0 || r.View.Round.Sign() != 0 {
+			return fmt.Errorf("%w: proposal view mismatch", ErrInvalidSignRequest)
+		}
+		if RLPHash(types.BftFilteredHeader(r.Header, false)) != r.Digest {
+			return fmt.Errorf("%w: proposal digest mismatch", ErrInvalidSignRequest)
+		}
+		return nil
+
+	case SignHandshake:
+		if r.Code != 0 || r.View.Height.Sign() != 0 || r.View.Round.Sign() != 0 {
+			return fmt.Errorf("%w: handshake view mismatch", ErrInvalidSignRequest)
+		}
+		if len(r.Payload) != len(handshakePrefix)+2*common.HashLength || !bytes.HasPrefix(r.Payload, []byte(handshakePrefix)) {
+			return fmt.Errorf("%w: invalid handshake data", ErrInvalidSignRequest)
+		}
+		if crypto.Keccak256Hash(r.Payload) != r.Digest {
+			return fmt.Errorf("%w: handshake digest mismatch", ErrInvalidSignRequest)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown kind %d", ErrInvalidSignRequest, r.Kind)
+	}
+}
+
+// rawMessage is the rlp layout of Message, which is decoded without the message
+// codes registered by the core.
+type rawMessage struct {
+	Code          uint64
+	View          *View
+	Msg           []byte
+	Address       common.Address
+	Signature     []byte
+	CommittedSeal []byte
+}
+
+func decodeRawMessage(payload []byte) (*rawMessage, error) {
+	var msg rawMessage
+	if err := rlp.DecodeBytes(payload, &msg); err != nil {
+		return nil, err
+	}
+	if msg.View == nil || msg.View.Height == nil || msg.View.Round == nil {
+		return nil, errors.New("message without view")
+	}
+	return &msg, nil
+}
@@ -11,11 +11,23 @@ type Signer interface {
 	// Sign generate signature
 	Sign(data []byte) ([]byte, error)
 
+	// SignHandshake signs the `bft` handshake data, which binds the validator to
+	// the node id of it's end of the connection
+	SignHandshake(data []byte) ([]byte, error)
+
 	// SigHash generate header hash without signature
 	SigHash(header *types.Header) (hash common.Hash)
 
 	// SignHash returns an signature of wrapped proposal hash which used as an vote
-	SignHash(hash common.Hash) ([]byte, error)
+	// of the view, or the timeout of the view if hash is it's timeout digest. Remote
+	// signers only seal timeouts this way, they need the header of a vote
+	SignHash(view *View, hash common.Hash) ([]byte, error)
+
+	// SignVote returns the committed seal of the header, the vote of the view
+	SignVote(view *View, header *types.Header) ([]byte, error)
+
+	// CommittedSealHash returns the hash signed by the committed seals of the header
+	CommittedSealHash(h *types.Header) common.Hash
 
 	// Recover extracts the proposer address from a signed header.
 	Recover(h *types.Header) (common.Address, error)
@@ -29,11 +41,20 @@ type Signer interface {
 	// SealAfterCommit writes the extra-data field of a block header with given committed seals.
 	SealAfterCommit(h *types.Header, committedSeals [][]byte) error
 
+	// SealVRF writes the proposer's vrf proof into the extra-data field of a block header.
+	SealVRF(h *types.Header) error
+
+	// Randomness returns the vrf output of the header which seeds the next proposer selection.
+	Randomness(h *types.Header) common.Hash
+
+	// Committers returns the validators which signed the committed seals of the header
+	Committers(header *types.Header, valSet ValidatorSet) ([]common.Address, error)
+
 	// VerifyHeader verify proposer signature and committed seals
 	VerifyHeader(header *types.Header, valSet ValidatorSet, seal bool) error
 
-	// VerifyQC verify quorum cert
-	VerifyQC(qc *QuorumCert, valSet ValidatorSet) error
+	// VerifyQC verify quorum cert against the header it certifies
+	VerifyQC(qc *QuorumCert, header *types.Header, valSet ValidatorSet) error
 
 	// CheckQCParticipant return nil if `signer` is qc proposer or committer
 	CheckQCParticipant(qc *QuorumCert, signer common.Address) error
@@ -41,7 +62,26 @@ type Signer interface {
 	// CheckSignature extract address from signature and check if the address exist in validator set
 	CheckSignature(valSet ValidatorSet, data []byte, signature []byte) (common.Address, error)
 
-	VerifyHash(valSet ValidatorSet, hash common.Hash, sig []byte) error
+	// VerifyHash checks a single committed seal of the hash, signed by a validator
+	// of the height
+	VerifyHash(valSet ValidatorSet, height uint64, hash common.Hash, sig []byte) error
+
+	VerifyCommittedSeal(valSet ValidatorSet, height uint64, hash common.Hash, committedSeals [][]byte) error
+
+	// AggregateSeals combines the seals of the hash into a single seal, the signers
+	// must hold the quorum of the validator set of the height
+	AggregateSeals(valSet ValidatorSet, height uint64, hash common.Hash, seals [][]byte) ([]byte, error)
+
+	// VerifyAggregatedSeal checks a seal combined by AggregateSeals
+	VerifyAggregatedSeal(valSet ValidatorSet, height uint64, hash common.Hash, seal []byte) error
+}
+
+// BLSKeyReader provides the validators and their registered bls keys of a height,
+// bls signers need the keys to aggregate and verify committed seals.
+type BLSKeyReader interface {
+	// Validators returns the validator set which commits the block of the height
+	Validators(height uint64) ValidatorSet
 
-	VerifyCommittedSeal(valSet ValidatorSet, hash common.Hash, committedSeals [][]byte) error
+	// BLSKeys returns the bls key registration of the validators by address
+	BLSKeys(height uint64) map[common.Address][]byte
 }
@@ -0,0 +1,422 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// BLSKeyLength is the byte length of a bls key registration, the public key
+	// followed by it's proof of possession.
+	BLSKeyLength = bls.PublicKeyLength + bls.SignatureLength
+
+	// blsSealLength is the byte length of a bls committed seal. bls signatures are
+	// not recoverable, so the committer address is prepended to the signature.
+	blsSealLength = common.AddressLength + bls.SignatureLength
+
+	inmemoryBLSKeys = 1024 // Number of decoded bls public keys to keep in memory
+)
+
+// BLSSigner signs the committed seals with a BLS12-381 key, and aggregates the
+// seals of a block into one signature and a participation bitmap. The proposer
+// seal, vrf proof and consensus messages are still signed with the ecdsa key.
+type BLSSigner struct {
+	*SignerImpl
+
+	blsKey *bls.SecretKey
+	reader bft.BLSKeyReader
+	keys   *lru.ARCCache // Public keys of verified registrations
+}
+
+// NewBLSSigner creates a signer of bls committed seals, the keys of the other
+// validators are resolved by the reader.
+func NewBLSSigner(privateKey *ecdsa.PrivateKey, blsKey *bls.SecretKey, reader bft.BLSKeyReader) bft.Signer {
+	keys, _ := lru.NewARC(inmemoryBLSKeys)
+	return &BLSSigner{
+		SignerImpl: NewSigner(privateKey).(*SignerImpl),
+		blsKey:     blsKey,
+		reader:     reader,
+		keys:       keys,
+	}
+}
+
+// DeriveBLSKey returns the bls key of the node key, so validators don't manage
+// another secret.
+func DeriveBLSKey(privateKey *ecdsa.PrivateKey) (*bls.SecretKey, error) {
+	return bls.DeriveKey(crypto.FromECDSA(privateKey))
+}
+
+// BLSKeyRegistration returns the public key and proof of possession of the key,
+// which validators register in the genesis or with their authorization vote.
+func BLSKeyRegistration(key *bls.SecretKey) []byte {
+	return append(key.PublicKey().Bytes(), key.ProvePossession().Bytes()...)
+}
+
+// ParseBLSKey decodes a bls key registration and checks it's proof of possession.
+func ParseBLSKey(registration []byte) (*bls.PublicKey, error) {
+	if len(registration) != BLSKeyLength {
+		return nil, ErrInvalidBLSKey
+	}
+	pub, err := bls.PublicKeyFromBytes(registration[:bls.PublicKeyLength])
+	if err != nil {
+		return nil, ErrInvalidBLSKey
+	}
+	proof, err := bls.SignatureFromBytes(registration[bls.PublicKeyLength:])
+	if err != nil || !pub.VerifyPossession(proof) {
+		return nil, ErrInvalidBLSKey
+	}
+	return pub, nil
+}
+
+// Registration returns the bls key registration of the signer.
+func (s *BLSSigner) Registration() []byte {
+	return BLSKeyRegistration(s.blsKey)
+}
+
+// SignHash returns the committed seal of the hash, the signer address followed by
+// the bls signature.
+func (s *BLSSigner) SignHash(view *bft.View, hash common.Hash) ([]byte, error) {
+	sig := s.blsKey.Sign(s.wrapCommittedSeal(hash))
+	return append(s.address.Bytes(), sig.Bytes()...), nil
+}
+
+// SignVote returns the committed seal of the header, the bls key is always held by
+// the node.
+func (s *BLSSigner) SignVote(view *bft.View, header *types.Header) ([]byte, error) {
+	return s.SignHash(view, s.CommittedSealHash(header))
+}
+
+// CommittedSealHash returns the hash of the proposer seal. the seal covers the
+// whole proposal, and unlike the header hash it can be derived from the quorum
+// cert, which only carries the extra-data.
+func (s *BLSSigner) CommittedSealHash(h *types.Header) common.Hash {
+	extra, err := types.ExtractBftExtra(h)
+	if err != nil {
+		return common.Hash{}
+	}
+	return crypto.Keccak256Hash(extra.Seal)
+}
+
+// SealAfterCommit aggregates the committed seals into the extra-data field of the
+// header. invalid seals are dropped as long as the rest still hold the quorum.
+func (s *BLSSigner) SealAfterCommit(h *types.Header, committedSeals [][]byte) error {
+	if len(committedSeals) == 0 {
+		return errInvalidCommittedSeals
+	}
+	var (
+		height     = h.Number.Uint64()
+		valSet     = s.reader.Validators(height)
+		keys       = s.reader.BLSKeys(height)
+		hash       = s.wrapCommittedSeal(s.CommittedSealHash(h))
+		bitmap     = make([]byte, (valSet.Size()+7)/8)
+		committers []common.Address
+		sigs       []*bls.Signature
+	)
+	for _, seal := range committedSeals {
+		if len(seal) != blsSealLength {
+			continue
+		}
+		addr := common.BytesToAddress(seal[:common.AddressLength])
+		idx, val := valSet.GetByAddress(addr)
+		if val == nil || bitmap[idx/8]&(1<<(idx%8)) != 0 {
+			continue
+		}
+		pub, err := s.publicKey(keys[addr])
+		if err != nil {
+			continue
+		}
+		sig, err := bls.SignatureFromBytes(seal[common.AddressLength:])
+		if err != nil || !pub.Verify(hash, sig) {
+			continue
+		}
+		bitmap[idx/8] |= 1 << (idx % 8)
+		committers = append(committers, addr)
+		sigs = append(sigs, sig)
+	}
+	if err := checkValidatorQuorum(committers, valSet); err != nil {
+		return err
+	}
+	aggregated, err := bls.AggregateSignatures(sigs)
+	if err != nil {
+		return errInvalidCommittedSeals
+	}
+
+	extra, err := types.ExtractBftExtra(h)
+	if err != nil {
+		return err
+	}
+	extra.CommittedSeal = [][]byte{}
+	extra.AggregatedSeal = aggregated.Bytes()
+	extra.Bitmap = bitmap
+
+	payload, err := rlp.EncodeToBytes(&extra)
+	if err != nil {
+		return err
+	}
+	h.Extra = append(h.Extra[:types.BftExtraVanity], payload...)
+	return nil
+}
+
+func (s *BLSSigner) VerifyHeader(header *types.Header, valSet bft.ValidatorSet, seal bool) error {
+	// Verifying the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	if err := s.verifyProposer(header, valSet); err != nil {
+		return err
+	}
+	if seal {
+		extra, err := types.ExtractBftExtra(header)
+		if err != nil {
+			return errInvalidExtraDataFormat
+		}
+		return s.verifyAggregatedSeal(number, extra, valSet)
+	}
+	return nil
+}
+
+// Committers returns the validators marked in the participation bitmap of the header,
+// the aggregated seal is not verified.
+func (s *BLSSigner) Committers(header *types.Header, valSet bft.ValidatorSet) ([]common.Address, error) {
+	extra, err := types.ExtractBftExtra(header)
+	if err != nil {
+		return nil, errInvalidExtraDataFormat
+	}
+	return bitmapCommitters(extra.Bitmap, valSet)
+}
+
+func (s *BLSSigner) VerifyQC(qc *bft.QuorumCert, header *types.Header, valSet bft.ValidatorSet) error {
+	if qc.View.Height.Uint64() == 0 {
+		return nil
+	}
+	if err := checkQCHeader(qc, header); err != nil {
+		return err
+	}
+	return s.VerifyHeader(header, valSet, true)
+}
+
+func (s *BLSSigner) CheckQCParticipant(qc *bft.QuorumCert, signer common.Address) error {
+	if qc.View.Height.Uint64() == 0 {
+		return nil
+	}
+	extra, err := types.ExtractBftExtraPayload(qc.Extra)
+	if err != nil {
+		return err
+	}
+
+	// check proposer signature
+	proposer, err := getSignatureAddress(qc.Hash.Bytes(), extra.Seal)
+	if err != nil {
+		return err
+	}
+	if signer == qc.Proposer && signer == proposer {
+		return nil
+	}
+
+	// check participation bitmap
+	committers, err := bitmapCommitters(extra.Bitmap, s.reader.Validators(qc.View.Height.Uint64()))
+	if err != nil {
+		return err
+	}
+	for _, committer := range committers {
+		if signer == committer {
+			return nil
+		}
+	}
+	return errUnauthorizedAddress
+}
+
+// VerifyHash checks a single committed seal against the bls keys registered at the
+// height.
+func (s *BLSSigner) VerifyHash(valSet bft.ValidatorSet, height uint64, hash common.Hash, sig []byte) error {
+	_, err := s.verifySeal(valSet, s.reader.BLSKeys(height), s.wrapCommittedSeal(hash), sig)
+	return err
+}
+
+func (s *BLSSigner) VerifyCommittedSeal(valSet bft.ValidatorSet, height uint64, hash common.Hash, committedSeals [][]byte) error {
+	var (
+		keys    = s.reader.BLSKeys(height)
+		data    = s.wrapCommittedSeal(hash)
+		signers = make([]common.Address, 0, len(committedSeals))
+	)
+	for _, seal := range committedSeals {
+		addr, err := s.verifySeal(valSet, keys, data, seal)
+		if err != nil {
+			return err
+		}
+		signers = append(signers, addr)
+	}
+	return checkValidatorQuorum(signers, valSet)
+}
+
+// aggregatedSeal is a bls seal combined from the seals of a hash, the bitmap marks
+// the signers in the validator set.
+type aggregatedSeal struct {
+	Bitmap    []byte
+	Signature []byte
+}
+
+// AggregateSeals aggregates the valid seals of the hash into one bls signature and
+// a participation bitmap.
+func (s *BLSSigner) AggregateSeals(valSet bft.ValidatorSet, height uint64, hash common.Hash, seals [][]byte) ([]byte, error) {
+	var (
+		keys       = s.reader.BLSKeys(height)
+		data       = s.wrapCommittedSeal(hash)
+		bitmap     = make([]byte, (valSet.Size()+7)/8)
+		committers []common.Address
+		sigs       []*bls.Signature
+	)
+	for _, seal := range seals {
+		addr, err := s.verifySeal(valSet, keys, data, seal)
+		if err != nil {
+			continue
+		}
+		idx, _ := valSet.GetByAddress(addr)
+		if bitmap[idx/8]&(1<<(idx%8)) != 0 {
+			continue
+		}
+		sig, _ := bls.SignatureFromBytes(seal[common.AddressLength:])
+		bitmap[idx/8] |= 1 << (idx % 8)
+		committers = append(committers, addr)
+		sigs = append(sigs, sig)
+	}
+	if err := checkValidatorQuorum(committers, valSet); err != nil {
+		return nil, err
+	}
+	aggregated, err := bls.AggregateSignatures(sigs)
+	if err != nil {
+		return nil, errInvalidCommittedSeals
+	}
+	return rlp.EncodeToBytes(&aggregatedSeal{Bitmap: bitmap, Signature: aggregated.Bytes()})
+}
+
+func (s *BLSSigner) VerifyAggregatedSeal(valSet bft.ValidatorSet, height uint64, hash common.Hash, seal []byte) error {
+	var aggregated aggregatedSeal
+	if err := rlp.DecodeBytes(seal, &aggregated); err != nil {
+		return errInvalidCommittedSeals
+	}
+	committers, err := bitmapCommitters(aggregated.Bitmap, valSet)
+	if err != nil {
+		return err
+	}
+	if err := checkValidatorQuorum(committers, valSet); err != nil {
+		return err
+	}
+	keys := s.reader.BLSKeys(height)
+	pubs := make([]*bls.PublicKey, 0, len(committers))
+	for _, committer := range committers {
+		pub, err := s.publicKey(keys[committer])
+		if err != nil {
+			return err
+		}
+		pubs = append(pubs, pub)
+	}
+	sig, err := bls.SignatureFromBytes(aggregated.Signature)
+	if err != nil {
+		return errInvalidCommittedSeals
+	}
+	if !bls.FastAggregateVerify(pubs, s.wrapCommittedSeal(hash), sig) {
+		return errInvalidCommittedSeals
+	}
+	return nil
+}
+
+// verifySeal checks a single committed seal and returns it's committer.
+func (s *BLSSigner) verifySeal(valSet bft.ValidatorSet, keys map[common.Address][]byte, data []byte, seal []byte) (common.Address, error) {
+	if len(seal) != blsSealLength {
+		return common.Address{}, errInvalidSignature
+	}
+	addr := common.BytesToAddress(seal[:common.AddressLength])
+	if _, val := valSet.GetByAddress(addr); val == nil {
+		return common.Address{}, errUnauthorizedAddress
+	}
+	pub, err := s.publicKey(keys[addr])
+	if err != nil {
+		return common.Address{}, err
+	}
+	sig, err := bls.SignatureFromBytes(seal[common.AddressLength:])
+	if err != nil || !pub.Verify(data, sig) {
+		return common.Address{}, errInvalidSignature
+	}
+	return addr, nil
+}
+
+// verifyAggregatedSeal checks that the committers of the bitmap hold the quorum of
+// the validator set, and signed the aggregated seal.
+func (s *BLSSigner) verifyAggregatedSeal(height uint64, extra *types.BftExtra, valSet bft.ValidatorSet) error {
+	if len(extra.AggregatedSeal) == 0 {
+		return errEmptyCommittedSeals
+	}
+	committers, err := bitmapCommitters(extra.Bitmap, valSet)
+	if err != nil {
+		return err
+	}
+	if err := checkValidatorQuorum(committers, valSet); err != nil {
+		return err
+	}
+
+	keys := s.reader.BLSKeys(height)
+	pubs := make([]*bls.PublicKey, 0, len(committers))
+	for _, committer := range committers {
+		pub, err := s.publicKey(keys[committer])
+		if err != nil {
+			return err
+		}
+		pubs = append(pubs, pub)
+	}
+	sig, err := bls.SignatureFromBytes(extra.AggregatedSeal)
+	if err != nil {
+		return errInvalidCommittedSeals
+	}
+	hash := s.wrapCommittedSeal(crypto.Keccak256Hash(extra.Seal))
+	if !bls.FastAggregateVerify(pubs, hash, sig) {
+		return errInvalidCommittedSeals
+	}
+	return nil
+}
+
+// publicKey returns the public key of a registration, the proof of possession is
+// only checked once.
+func (s *BLSSigner) publicKey(registration []byte) (*bls.PublicKey, error) {
+	if len(registration) == 0 {
+		return nil, errMissingBLSKey
+	}
+	if pub, ok := s.keys.Get(string(registration)); ok {
+		return pub.(*bls.PublicKey), nil
+	}
+	pub, err := ParseBLSKey(registration)
+	if err != nil {
+		return nil, errMissingBLSKey
+	}
+	s.keys.Add(string(registration), pub)
+	return pub, nil
+}
+
+// bitmapCommitters returns the validators marked in the participation bitmap, the
+// bitmap must have exactly one bit per validator.
+func bitmapCommitters(bitmap []byte, valSet bft.ValidatorSet) ([]common.Address, error) {
+	size := valSet.Size()
+	if len(bitmap) != (size+7)/8 {
+		return nil, errInvalidBitmap
+	}
+	for i := size; i < len(bitmap)*8; i++ {
+		if bitmap[i/8]&(1<<(i%8)) != 0 {
+			return nil, errInvalidBitmap
+		}
+	}
+	var committers []common.Address
+	for i, val := range valSet.List() {
+		if bitmap[i/8]&(1<<(i%8)) != 0 {
+			committers = append(committers, val.Address())
+		}
+	}
+	return committers, nil
+}
@@ -0,0 +1,109 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+)
+
+// testKeyReader returns the bls keys registered before a height, the last one
+// of every validator counts.
+type testKeyReader struct {
+	valSet bft.ValidatorSet
+	keys   map[uint64]map[common.Address][]byte // registrations by the height they take effect
+}
+
+func (r *testKeyReader) Validators(height uint64) bft.ValidatorSet { return r.valSet }
+
+func (r *testKeyReader) BLSKeys(height uint64) map[common.Address][]byte {
+	var (
+		keys = make(map[common.Address][]byte)
+		best = make(map[common.Address]uint64)
+	)
+	for from, regs := range r.keys {
+		for addr, reg := range regs {
+			if from <= height && (keys[addr] == nil || from > best[addr]) {
+				keys[addr], best[addr] = reg, from
+			}
+		}
+	}
+	return keys
+}
+
+func newTestBLSKey(t *testing.T) *bls.SecretKey {
+	t.Helper()
+
+	seed, _ := crypto.GenerateKey()
+	key, err := DeriveBLSKey(seed)
+	if err != nil {
+		t.Fatalf("failed to derive bls key: %v", err)
+	}
+	return key
+}
+
+// Tests that committed seals are verified and aggregated with the bls keys of the
+// height they're signed at, and not with the latest registrations.
+func TestBLSKeysOfHeight(t *testing.T) {
+	var (
+		keys   = make([]*ecdsa.PrivateKey, 4)
+		addrs  = make([]common.Address, len(keys))
+		old    = make([]*bls.SecretKey, len(keys))
+		rotate = newTestBLSKey(t)
+		hash   = common.HexToHash("0x01")
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		old[i] = newTestBLSKey(t)
+	}
+	valSet := validator.NewSet(addrs, bft.RoundRobin)
+	reader := &testKeyReader{valSet: valSet, keys: map[uint64]map[common.Address][]byte{
+		0:  make(map[common.Address][]byte),
+		10: {addrs[0]: BLSKeyRegistration(rotate)},
+	}}
+	for i := range keys {
+		reader.keys[0][addrs[i]] = BLSKeyRegistration(old[i])
+	}
+	seals := make([][]byte, len(keys))
+	for i := range keys {
+		seals[i], _ = NewBLSSigner(keys[i], old[i], reader).SignHash(nil, hash)
+	}
+	// The first validator rotates it's key at height 10
+	rotated, _ := NewBLSSigner(keys[0], rotate, reader).SignHash(nil, hash)
+	verifier := NewBLSSigner(keys[1], old[1], reader)
+
+	if err := verifier.VerifyHash(valSet, 5, hash, seals[0]); err != nil {
+		t.Errorf("seal of the old key at height 5: %v", err)
+	}
+	if err := verifier.VerifyHash(valSet, 15, hash, seals[0]); err == nil {
+		t.Errorf("seal of the old key accepted at height 15")
+	}
+	if err := verifier.VerifyHash(valSet, 15, hash, rotated); err != nil {
+		t.Errorf("seal of the rotated key at height 15: %v", err)
+	}
+	if err := verifier.VerifyHash(valSet, 5, hash, rotated); err == nil {
+		t.Errorf("seal of the rotated key accepted at height 5")
+	}
+	if err := verifier.VerifyCommittedSeal(valSet, 5, hash, seals[:3]); err != nil {
+		t.Errorf("committed seals at height 5: %v", err)
+	}
+	if err := verifier.VerifyCommittedSeal(valSet, 15, hash, seals[:3]); err == nil {
+		t.Errorf("committed seals of the old key accepted at height 15")
+	}
+	// The aggregated seal is only valid at the height of the keys it was made with
+	aggregated, err := verifier.AggregateSeals(valSet, 15, hash, append([][]byte{rotated}, seals[1:3]...))
+	if err != nil {
+		t.Fatalf("failed to aggregate seals at height 15: %v", err)
+	}
+	if err := verifier.VerifyAggregatedSeal(valSet, 15, hash, aggregated); err != nil {
+		t.Errorf("aggregated seal at height 15: %v", err)
+	}
+	if err := verifier.VerifyAggregatedSeal(valSet, 5, hash, aggregated); err == nil {
+		t.Errorf("aggregated seal of the rotated key accepted at height 5")
+	}
+}
@@ -0,0 +1,53 @@
+package signer
+
+import "errors"
+
+var (
+	errInvalidSignature = errors.New("invalid signature")
+
+	// errUnauthorized is returned if a header is signed by a non authorized entity.
+	errUnauthorized = errors.New("unauthorized")
+
+	// errInvalidExtraDataFormat is returned when the extra data format is incorrect
+	errInvalidExtraDataFormat = errors.New("invalid extra data format")
+
+	// errInvalidCommittedSeals is returned if the committed seal is not signed by any of parent validators.
+	errInvalidCommittedSeals = errors.New("invalid committed seals")
+
+	// errEmptyCommittedSeals is returned if the field of committed seals is zero.
+	errEmptyCommittedSeals = errors.New("zero committed seals")
+
+	// errUnauthorizedAddress is returned when given address cannot be found in
+	// current validator set.
+	errUnauthorizedAddress = errors.New("unauthorized address")
+
+	// errInvalidSigner is returned if the msg is unsigned
+	errInvalidSigner = errors.New("message not signed by the sender")
+
+	// errMissingVRFProof is returned if the header of vrf proposer policy carries no vrf proof.
+	errMissingVRFProof = errors.New("missing vrf proof")
+
+	// errInvalidVRFProof is returned if the vrf proof is not generated by the proposer for the header.
+	errInvalidVRFProof = errors.New("invalid vrf proof")
+
+	// errInvalidBitmap is returned if the participation bitmap doesn't fit the validator set.
+	errInvalidBitmap = errors.New("invalid participation bitmap")
+
+	// errMissingBLSKey is returned if a committer has no valid bls key registered.
+	errMissingBLSKey = errors.New("missing bls key")
+
+	// errMismatchQC is returned if a quorum cert doesn't certify the given header.
+	errMismatchQC = errors.New("quorum cert mismatches header")
+
+	// errRemoteVRF is returned if a vrf proof is requested from a signer without
+	// the private key.
+	errRemoteVRF = errors.New("vrf proofs require the local node key")
+
+	// errRemoteVote is returned if a vote is sealed remotely without it's header,
+	// which the signer needs to check the vote.
+	errRemoteVote = errors.New("remote votes require the sealed header")
+
+	// ErrInvalidBLSKey is returned if a bls key registration is malformed or the
+	// proof of possession is invalid.
+	ErrInvalidBLSKey = errors.New("invalid bls key registration")
+)
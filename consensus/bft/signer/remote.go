@@ -0,0 +1,55 @@
+package signer
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// SignerFn signs the consensus data described by the request, the signature is
+// taken over the keccak256 hash of the payload.
+type SignerFn func(req *bft.SignRequest) ([]byte, error)
+
+// NewRemoteSigner creates an ecdsa signer whose key is held outside of the node,
+// every signature is requested through signFn. Vrf proofs need the key and are not
+// supported.
+func NewRemoteSigner(address common.Address, signFn SignerFn) bft.Signer {
+	signatures, _ := lru.NewARC(inmemorySignatures)
+	return &SignerImpl{
+		address:    address,
+		signFn:     signFn,
+		signatures: signatures,
+	}
+}
+
+// NewExternalSigner connects to an external signer, e.g. clef, holding the key of
+// the validator account. The account may be omitted if the signer lists a single
+// account.
+func NewExternalSigner(endpoint string, address common.Address) (bft.Signer, error) {
+	ext, err := external.NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	account := accounts.Account{Address: address}
+	if address == (common.Address{}) {
+		listed := ext.Accounts()
+		if len(listed) != 1 {
+			return nil, fmt.Errorf("external signer lists %d accounts, the validator account is required", len(listed))
+		}
+		account = listed[0]
+	} else if !ext.Contains(account) {
+		return nil, fmt.Errorf("external signer doesn't hold account %v", address)
+	}
+	return NewRemoteSigner(account.Address, func(req *bft.SignRequest) ([]byte, error) {
+		data, err := rlp.EncodeToBytes(req)
+		if err != nil {
+			return nil, err
+		}
+		return ext.SignData(account, accounts.MimetypeHotStuff, data)
+	}), nil
+}
@@ -1,13 +1,16 @@
 package signer
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/vrf"
 	"github.com/ethereum/go-ethereum/rlp"
 	lru "github.com/hashicorp/golang-lru"
 	"golang.org/x/crypto/sha3"
@@ -20,6 +23,7 @@ const (
 type SignerImpl struct {
 	address    common.Address
 	privateKey *ecdsa.PrivateKey
+	signFn     SignerFn      // Signs with the key held outside of the node, the private key is nil if set
 	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
 }
 
@@ -38,13 +42,53 @@ func (s *SignerImpl) Address() common.Address {
 }
 
 func (s *SignerImpl) Sign(data []byte) ([]byte, error) {
+	if s.signFn != nil {
+		req, err := bft.NewMessageRequest(data)
+		if err != nil {
+			return nil, err
+		}
+		return s.signFn(req)
+	}
+	return s.sign(data)
+}
+
+func (s *SignerImpl) SignHandshake(data []byte) ([]byte, error) {
+	if s.signFn != nil {
+		return s.signFn(bft.NewHandshakeRequest(data))
+	}
+	return s.sign(data)
+}
+
+func (s *SignerImpl) SignHash(view *bft.View, hash common.Hash) ([]byte, error) {
+	voteHash := s.wrapCommittedSeal(hash)
+	if s.signFn != nil {
+		// the signer can't check a bare vote hash against the view
+		if hash != bft.TimeoutDigest(view) {
+			return nil, errRemoteVote
+		}
+		return s.signFn(bft.NewTimeoutRequest(view))
+	}
+	return s.sign(voteHash)
+}
+
+func (s *SignerImpl) SignVote(view *bft.View, header *types.Header) ([]byte, error) {
+	hash := s.CommittedSealHash(header)
+	if s.signFn != nil {
+		return s.signFn(bft.NewVoteRequest(view, header, hash))
+	}
+	return s.sign(s.wrapCommittedSeal(hash))
+}
+
+// sign signs the keccak256 hash of the data with the private key of the node.
+func (s *SignerImpl) sign(data []byte) ([]byte, error) {
 	hashData := crypto.Keccak256(data)
 	return crypto.Sign(hashData, s.privateKey)
 }
 
-func (s *SignerImpl) SignHash(hash common.Hash) ([]byte, error) {
-	voteHash := s.wrapCommittedSeal(hash)
-	return s.Sign(voteHash)
+// CommittedSealHash returns the hash of the header without committed seals, the
+// committers sign the proposal before the committed seals are filled.
+func (s *SignerImpl) CommittedSealHash(h *types.Header) common.Hash {
+	return types.BftFilteredHeader(h, true).Hash()
 }
 
 // SigHash returns the hash which is used as input for the Bft
@@ -92,8 +136,16 @@ func (s *SignerImpl) Recover(header *types.Header) (common.Address, error) {
 
 // SignerSeal proposer sign the header hash and fill extra seal with signature.
 func (s *SignerImpl) SealBeforeCommit(h *types.Header) error {
-	sigHash := s.SigHash(h)
-	seal, err := s.Sign(sigHash.Bytes())
+	var (
+		sigHash = s.SigHash(h)
+		seal    []byte
+		err     error
+	)
+	if s.signFn != nil {
+		seal, err = s.signFn(bft.NewProposalRequest(h, sigHash))
+	} else {
+		seal, err = s.sign(sigHash.Bytes())
+	}
 	if err != nil {
 		return errInvalidSignature
 	}
@@ -144,6 +196,47 @@ func (s *SignerImpl) SealAfterCommit(h *types.Header, committedSeals [][]byte) e
 	return nil
 }
 
+// SealVRF proves the proposer's eligibility with the vrf key of the node, and writes
+// the proof into the extra-data field. it should be called before `SealBeforeCommit`
+// because the proof is covered by the proposer seal.
+func (s *SignerImpl) SealVRF(h *types.Header) error {
+	if s.privateKey == nil {
+		return errRemoteVRF
+	}
+	proof, err := vrf.Prove(s.privateKey, vrfInput(h))
+	if err != nil {
+		return err
+	}
+
+	extra, err := types.ExtractBftExtra(h)
+	if err != nil {
+		return err
+	}
+	extra.VRFProof = proof
+
+	payload, err := rlp.EncodeToBytes(&extra)
+	if err != nil {
+		return err
+	}
+	h.Extra = append(h.Extra[:types.BftExtraVanity], payload...)
+	return nil
+}
+
+// Randomness returns the vrf output carried by the header, which seeds the proposer
+// selection of the next height. headers without vrf proof, e.g. the genesis block,
+// fall back to the header hash.
+func (s *SignerImpl) Randomness(h *types.Header) common.Hash {
+	extra, err := types.ExtractBftExtra(h)
+	if err != nil || len(extra.VRFProof) == 0 {
+		return h.Hash()
+	}
+	output, err := vrf.ProofToHash(extra.VRFProof)
+	if err != nil {
+		return h.Hash()
+	}
+	return output
+}
+
 func (s *SignerImpl) VerifyHeader(header *types.Header, valSet bft.ValidatorSet, seal bool) error {
 	// Verifying the genesis block is not supported
 	number := header.Number.Uint64()
@@ -151,19 +244,9 @@ func (s *SignerImpl) VerifyHeader(header *types.Header, valSet bft.ValidatorSet,
 		return nil
 	}
 
-	// resolve the authorization key and check against signers
-	signer, err := s.Recover(header)
-	if err != nil {
+	if err := s.verifyProposer(header, valSet); err != nil {
 		return err
 	}
-	if signer != header.Coinbase {
-		return errInvalidSigner
-	}
-
-	// Signer should be in the validator set of previous block's extraData.
-	if _, v := valSet.GetByAddress(signer); v == nil {
-		return errUnauthorized
-	}
 
 	if seal {
 		extra, err := types.ExtractBftExtra(header)
@@ -177,7 +260,7 @@ func (s *SignerImpl) VerifyHeader(header *types.Header, valSet bft.ValidatorSet,
 		}
 
 		// Check whether the committed seals are generated by parent's validators
-		committers, err := s.GetSignersFromCommittedSeals(header.Hash(), extra.CommittedSeal)
+		committers, err := s.GetSignersFromCommittedSeals(s.CommittedSealHash(header), extra.CommittedSeal)
 		if err != nil {
 			return err
 		}
@@ -187,35 +270,61 @@ func (s *SignerImpl) VerifyHeader(header *types.Header, valSet bft.ValidatorSet,
 	return nil
 }
 
-func (s *SignerImpl) VerifyQC(qc *bft.QuorumCert, valSet bft.ValidatorSet) error {
-	if qc.View.Height.Uint64() == 0 {
-		return nil
-	}
-	extra, err := types.ExtractBftExtraPayload(qc.Extra)
+// Committers returns the signers of the committed seals of the header, the seals
+// are not checked against the validator set.
+func (s *SignerImpl) Committers(header *types.Header, valSet bft.ValidatorSet) ([]common.Address, error) {
+	extra, err := types.ExtractBftExtra(header)
 	if err != nil {
-		return err
+		return nil, errInvalidExtraDataFormat
 	}
+	return s.GetSignersFromCommittedSeals(s.CommittedSealHash(header), extra.CommittedSeal)
+}
 
-	// check proposer signature
-	addr, err := getSignatureAddress(qc.Hash.Bytes(), extra.Seal)
+// verifyProposer checks that the header is sealed by a validator of the set, and
+// the vrf proof of the proposer if required.
+func (s *SignerImpl) verifyProposer(header *types.Header, valSet bft.ValidatorSet) error {
+	// resolve the authorization key and check against signers
+	signer, err := s.Recover(header)
 	if err != nil {
 		return err
 	}
-	if addr != qc.Proposer {
+	if signer != header.Coinbase {
 		return errInvalidSigner
 	}
-	if idx, _ := valSet.GetByAddress(addr); idx < 0 {
-		return errInvalidSigner
+
+	// Signer should be in the validator set of previous block's extraData.
+	if _, v := valSet.GetByAddress(signer); v == nil {
+		return errUnauthorized
 	}
 
-	// check committed seals
-	committers, err := s.GetSignersFromCommittedSeals(qc.Hash, extra.CommittedSeal)
-	if err != nil {
-		return err
+	// The vrf output of this header decides the next proposer, it must be proved by the signer.
+	if valSet.Policy() == bft.VRF {
+		if err := s.verifyVRF(header); err != nil {
+			return err
+		}
 	}
-	if err := checkValidatorQuorum(committers, valSet); err != nil {
+	return nil
+}
+
+func (s *SignerImpl) VerifyQC(qc *bft.QuorumCert, header *types.Header, valSet bft.ValidatorSet) error {
+	if qc.View.Height.Uint64() == 0 {
+		return nil
+	}
+	if err := checkQCHeader(qc, header); err != nil {
 		return err
 	}
+	return s.VerifyHeader(header, valSet, true)
+}
+
+// checkQCHeader checks that the quorum cert certifies the header. the seals are
+// signed over hashes of the header, which can't be derived from the cert alone.
+func checkQCHeader(qc *bft.QuorumCert, header *types.Header) error {
+	if header == nil || header.Number.Cmp(qc.View.Height) != 0 || header.Hash() != qc.Hash {
+		return errMismatchQC
+	}
+	if header.Coinbase != qc.Proposer || !bytes.Equal(header.Extra, qc.Extra) {
+		return errMismatchQC
+	}
 	return nil
 }
 
@@ -265,7 +374,7 @@ func (s *SignerImpl) CheckSignature(valSet bft.ValidatorSet, data []byte, sig []
 	return common.Address{}, errUnauthorizedAddress
 }
 
-func (s *SignerImpl) VerifyHash(valSet bft.ValidatorSet, hash common.Hash, sig []byte) error {
+func (s *SignerImpl) VerifyHash(valSet bft.ValidatorSet, height uint64, hash common.Hash, sig []byte) error {
 	data := s.wrapCommittedSeal(hash)
 	signer, err := getSignatureAddress(data, sig)
 	if err != nil {
@@ -279,7 +388,7 @@ func (s *SignerImpl) VerifyHash(valSet bft.ValidatorSet, hash common.Hash, sig [
 	return nil
 }
 
-func (s *SignerImpl) VerifyCommittedSeal(valSet bft.ValidatorSet, hash common.Hash, committedSeals [][]byte) error {
+func (s *SignerImpl) VerifyCommittedSeal(valSet bft.ValidatorSet, height uint64, hash common.Hash, committedSeals [][]byte) error {
 	signers, err := s.GetSignersFromCommittedSeals(hash, committedSeals)
 	if err != nil {
 		return err
@@ -287,6 +396,41 @@ func (s *SignerImpl) VerifyCommittedSeal(valSet bft.ValidatorSet, hash common.Ha
 	return checkValidatorQuorum(signers, valSet)
 }
 
+// AggregateSeals keeps the valid seals of distinct validators, ecdsa signatures
+// can't be aggregated so the seal is the rlp list of them.
+func (s *SignerImpl) AggregateSeals(valSet bft.ValidatorSet, height uint64, hash common.Hash, seals [][]byte) ([]byte, error) {
+	var (
+		data       = s.wrapCommittedSeal(hash)
+		seen       = make(map[common.Address]bool)
+		committers []common.Address
+		kept       [][]byte
+	)
+	for _, seal := range seals {
+		addr, err := getSignatureAddress(data, seal)
+		if err != nil || seen[addr] {
+			continue
+		}
+		if _, val := valSet.GetByAddress(addr); val == nil {
+			continue
+		}
+		seen[addr] = true
+		committers = append(committers, addr)
+		kept = append(kept, seal)
+	}
+	if err := checkValidatorQuorum(committers, valSet); err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(kept)
+}
+
+func (s *SignerImpl) VerifyAggregatedSeal(valSet bft.ValidatorSet, height uint64, hash common.Hash, seal []byte) error {
+	var seals [][]byte
+	if err := rlp.DecodeBytes(seal, &seals); err != nil {
+		return errInvalidCommittedSeals
+	}
+	return s.VerifyCommittedSeal(valSet, height, hash, seals)
+}
+
 // todo: useless of wrap committed seal. field of `commitSigSalt` used only to approve that participants sign block header hash
 // at the `commit` step in consensus.
 // wrapCommittedSeal returns a committed seal for the given hash
@@ -300,19 +444,10 @@ func (s *SignerImpl) wrapCommittedSeal(hash common.Hash) []byte {
 	return hash.Bytes()
 }
 
+// checkValidatorQuorum checks that the committers are distinct validators which hold
+// the quorum power of the set.
 func checkValidatorQuorum(committers []common.Address, valSet bft.ValidatorSet) error {
-	validators := valSet.Copy()
-	validSeal := 0
-	for _, addr := range committers {
-		if validators.RemoveValidator(addr) {
-			validSeal++
-			continue
-		}
-		return errInvalidCommittedSeals
-	}
-
-	// The length of validSeal should be larger than number of faulty node + 1
-	if validSeal <= validators.Q() {
+	if err := valSet.CheckQuorum(committers); err != nil {
 		return errInvalidCommittedSeals
 	}
 	return nil
@@ -334,14 +469,50 @@ func (s *SignerImpl) GetSignersFromCommittedSeals(hash common.Hash, seals [][]by
 	return addrs, nil
 }
 
+// verifyVRF checks the vrf proof of header with the public key recovered from the
+// proposer seal, the vrf key of validator is the same as it's node key.
+func (s *SignerImpl) verifyVRF(header *types.Header) error {
+	extra, err := types.ExtractBftExtra(header)
+	if err != nil {
+		return errInvalidExtraDataFormat
+	}
+	if len(extra.VRFProof) == 0 {
+		return errMissingVRFProof
+	}
+
+	pubkey, err := getSignaturePubkey(s.SigHash(header).Bytes(), extra.Seal)
+	if err != nil {
+		return err
+	}
+	if _, err := vrf.Verify(pubkey, vrfInput(header), extra.VRFProof); err != nil {
+		return errInvalidVRFProof
+	}
+	return nil
+}
+
+// vrfInput returns the vrf message of header, which is composed of parent hash and
+// block number. the proposer is not able to grind the input because parent block
+// is already committed before proposing.
+func vrfInput(h *types.Header) []byte {
+	input := make([]byte, common.HashLength+8)
+	copy(input, h.ParentHash.Bytes())
+	binary.BigEndian.PutUint64(input[common.HashLength:], h.Number.Uint64())
+	return input
+}
+
 // GetSignatureAddress gets the address address from the signature
 func getSignatureAddress(data []byte, sig []byte) (common.Address, error) {
-	// 1. Keccak data
-	hashData := crypto.Keccak256(data)
-	// 2. Recover public key
-	pubkey, err := crypto.SigToPub(hashData, sig)
+	pubkey, err := getSignaturePubkey(data, sig)
 	if err != nil {
 		return common.Address{}, err
 	}
 	return crypto.PubkeyToAddress(*pubkey), nil
 }
+
+// getSignaturePubkey recovers the public key from the signature
+func getSignaturePubkey(data []byte, sig []byte) (*ecdsa.PublicKey, error) {
+	// 1. Keccak data
+	hashData := crypto.Keccak256(data)
+	// 2. Recover public key
+	return crypto.SigToPub(hashData, sig)
+}
@@ -0,0 +1,83 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/validator"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// newTestVRFHeader returns the header of the height proposed by the key, sealed
+// with it's vrf proof.
+func newTestVRFHeader(t *testing.T, key *ecdsa.PrivateKey, parent common.Hash, number int64) *types.Header {
+	t.Helper()
+
+	extra, err := rlp.EncodeToBytes(&types.BftExtra{})
+	if err != nil {
+		t.Fatalf("failed to encode extra: %v", err)
+	}
+	header := &types.Header{
+		ParentHash: parent,
+		Number:     big.NewInt(number),
+		Coinbase:   crypto.PubkeyToAddress(key.PublicKey),
+		Extra:      append(make([]byte, types.BftExtraVanity), extra...),
+	}
+	proposer := NewSigner(key)
+	if err := proposer.SealVRF(header); err != nil {
+		t.Fatalf("failed to seal vrf proof: %v", err)
+	}
+	if err := proposer.SealBeforeCommit(header); err != nil {
+		t.Fatalf("failed to seal header: %v", err)
+	}
+	return header
+}
+
+// Tests that the vrf proof of a header is verified against the proposer seal, and
+// that every node derives the same randomness from it.
+func TestVRFProof(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	valSet := validator.NewSet(addrs, bft.VRF)
+	parent := common.HexToHash("0x01")
+
+	header := newTestVRFHeader(t, keys[0], parent, 1)
+	verifier := NewSigner(keys[1])
+	if err := verifier.VerifyHeader(header, valSet, false); err != nil {
+		t.Fatalf("failed to verify header: %v", err)
+	}
+	// The output is unique for the proposer and the input, proving again or
+	// verifying on another node gives the same seed
+	again := newTestVRFHeader(t, keys[0], parent, 1)
+	if have, want := verifier.Randomness(again), NewSigner(keys[0]).Randomness(header); have != want {
+		t.Fatalf("randomness mismatch: have %x, want %x", have, want)
+	}
+	if other := newTestVRFHeader(t, keys[0], common.HexToHash("0x02"), 1); verifier.Randomness(other) == verifier.Randomness(header) {
+		t.Fatalf("randomness doesn't depend on the parent")
+	}
+
+	// The proof of another validator doesn't match the proposer seal
+	forged := newTestVRFHeader(t, keys[1], parent, 1)
+	extra, _ := types.ExtractBftExtra(forged)
+	proof := extra.VRFProof
+
+	extra, _ = types.ExtractBftExtra(header)
+	extra.VRFProof, extra.Seal = proof, nil
+	payload, _ := rlp.EncodeToBytes(extra)
+	header.Extra = append(header.Extra[:types.BftExtraVanity], payload...)
+	if err := NewSigner(keys[0]).SealBeforeCommit(header); err != nil {
+		t.Fatalf("failed to seal header: %v", err)
+	}
+	if err := verifier.VerifyHeader(header, valSet, false); err != errInvalidVRFProof {
+		t.Fatalf("forged proof error mismatch: have %v, want %v", err, errInvalidVRFProof)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/signer"
 	"github.com/ethereum/go-ethereum/consensus/bft/validator"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -21,6 +22,12 @@ import (
 
 // Encode generate bft genesis extra
 func Encode(validators []common.Address) (string, error) {
+	return EncodeWithBLSKeys(validators, nil)
+}
+
+// EncodeWithBLSKeys generate bft genesis extra with the bls key registrations of
+// validators, which is required by chains with bls signatures.
+func EncodeWithBLSKeys(validators []common.Address, blsKeys [][]byte) (string, error) {
 	var vanity []byte
 	vanity = append(vanity, bytes.Repeat([]byte{0x00}, types.BftExtraVanity)...)
 
@@ -28,6 +35,7 @@ func Encode(validators []common.Address) (string, error) {
 		Validators:    validators,
 		Seal:          make([]byte, types.BftExtraSeal),
 		CommittedSeal: [][]byte{},
+		BLSKeys:       blsKeys,
 	}
 
 	payload, err := rlp.EncodeToBytes(&ist)
@@ -133,3 +141,26 @@ func NodeKey2PublicInfo(key string) (string, error) {
 func NodeStaticInfoTemp(src string) string {
 	return fmt.Sprintf("enode://%s@127.0.0.1:30300?discport=0", src)
 }
+
+// NodeKey2BLSKey returns the bls key registration of the node key.
+func NodeKey2BLSKey(key string) (string, error) {
+	if !strings.Contains(key, "0x") {
+		key = "0x" + key
+	}
+
+	dec, err := hexutil.Decode(key)
+	if err != nil {
+		return "", err
+	}
+
+	privKey, err := crypto.ToECDSA(dec)
+	if err != nil {
+		return "", err
+	}
+
+	blsKey, err := signer.DeriveBLSKey(privKey)
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(signer.BLSKeyRegistration(blsKey)), nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -127,6 +128,24 @@ func (qc *QuorumCert) String() string {
 	return fmt.Sprintf("{QuorumCert View: %v, Hash: %v, Proposer: %v}", qc.View, qc.Hash.String(), qc.Proposer.Hex())
 }
 
+// RoundState is a snapshot of the consensus state of the node, it's used by the
+// rpc api to inspect a running engine.
+type RoundState struct {
+	View     *View
+	State    string
+	Proposer common.Address
+	Proposal common.Hash              // hash of the proposal of the round, empty if there is none
+	LockedQC *QuorumCert              // quorum cert of the locked block
+	HighQC   *QuorumCert              // highest quorum cert known by the node
+	Backlogs map[common.Address]int   // number of future messages by sender
+	LastSeen map[common.Address]*View // view of the latest message by sender
+
+	Rounds         uint64                   // rounds started since the engine started
+	RoundChanges   uint64                   // rounds started after the previous round of the height
+	ProposerRounds uint64                   // rounds led by the node
+	PhaseTimes     map[string]time.Duration // time spent by the node in each state since the engine started
+}
+
 func (qc *QuorumCert) Copy() *QuorumCert {
 	enc, err := rlp.EncodeToBytes(qc)
 	if err != nil {
@@ -161,6 +180,24 @@ func (qc *QuorumCert) RoundU64() uint64 {
 	return qc.Round().Uint64()
 }
 
+// TimeoutCert proves that validators holding the quorum timed out in a view, it
+// lets every node leave the round for the next one. The seal aggregates the
+// timeout seals of the validators over the timeout digest of the view.
+type TimeoutCert struct {
+	View *View
+	Seal []byte
+}
+
+// TimeoutDigest returns the hash sealed by the timeouts of the view. The digest
+// is domain separated, so a timeout seal can't be taken for a committed seal.
+func TimeoutDigest(view *View) common.Hash {
+	return RLPHash([]interface{}{"timeout", view})
+}
+
+func (tc *TimeoutCert) String() string {
+	return fmt.Sprintf("{TimeoutCert View: %v}", tc.View)
+}
+
 type MsgType interface {
 	String() string
 	Value() uint64
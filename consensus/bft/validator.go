@@ -10,6 +10,9 @@ type Validator interface {
 	// Address returns address
 	Address() common.Address
 
+	// Power returns the voting power
+	Power() uint64
+
 	// String representation of Validator
 	String() string
 }
@@ -61,12 +64,18 @@ type ValidatorSet interface {
 	ParticipantsNumber(list []common.Address) int
 	// CheckQuorum check committers
 	CheckQuorum(committers []common.Address) error
-	// Get the maximum number of faulty nodes
-	F() int
-	// Get the minimum number of quorum nodes
-	Q() int
+	// Get the total voting power, which equals to the size of unweighted sets
+	TotalPower() uint64
+	// Get the maximum voting power of faulty nodes
+	F() uint64
+	// Get the minimum voting power of quorum nodes
+	Q() uint64
 	// Get speaker policy
 	Policy() SelectProposerPolicy
+	// Set the random seed used by vrf speaker policy
+	SetSeed(seed common.Hash)
+	// Get the random seed used by vrf speaker policy
+	Seed() common.Hash
 	// Cmp compare with another validator set, return false if not the same
 	Cmp(src ValidatorSet) bool
 }
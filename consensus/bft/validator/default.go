@@ -2,13 +2,14 @@ package validator
 
 import (
 	"errors"
-	"math"
+	"math/big"
 	"reflect"
 	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 var ErrInvalidParticipant = errors.New("invalid participants")
@@ -21,6 +22,11 @@ func (val *defaultValidator) Address() common.Address {
 	return val.address
 }
 
+// Power returns the voting power, every validator of an unweighted set has the same power.
+func (val *defaultValidator) Power() uint64 {
+	return DefaultPower
+}
+
 func (val *defaultValidator) String() string {
 	return val.Address().String()
 }
@@ -34,6 +40,7 @@ type defaultSet struct {
 	proposer    bft.Validator
 	validatorMu sync.RWMutex
 	selector    bft.ProposalSelector
+	seed        common.Hash // vrf output of the last committed block
 }
 
 func newDefaultSet(addrs []common.Address, policy bft.SelectProposerPolicy) *defaultSet {
@@ -167,9 +174,23 @@ func stickySelector(valSet bft.ValidatorSet, proposer common.Address, round uint
 	return valSet.GetByIndex(pick)
 }
 
-// TODO: implement VRF
+// vrfSelector picks the proposer with the vrf output of the last committed block, so
+// that the proposer of next height can't be predicted before the parent block committed.
+// rounds in the same height fall back to round robin from the random start point, which
+// ensures that every validator has a chance to propose after enough round changes.
+// without seed, e.g. the last proposal is not a block, it falls back to round robin.
 func vrfSelector(valSet bft.ValidatorSet, proposer common.Address, round uint64) bft.Validator {
-	return nil
+	if valSet.Size() == 0 {
+		return nil
+	}
+	seed := valSet.Seed()
+	if seed == (common.Hash{}) {
+		return roundRobinSelector(valSet, proposer, round)
+	}
+	start := new(big.Int).SetBytes(crypto.Keccak256(seed.Bytes()))
+	start.Mod(start, big.NewInt(int64(valSet.Size())))
+	pick := (start.Uint64() + round) % uint64(valSet.Size())
+	return valSet.GetByIndex(pick)
 }
 
 func (valSet *defaultSet) AddValidator(address common.Address) bool {
@@ -208,7 +229,9 @@ func (valSet *defaultSet) Copy() bft.ValidatorSet {
 	for _, v := range valSet.validators {
 		addresses = append(addresses, v.Address())
 	}
-	return NewSet(addresses, valSet.policy)
+	newSet := NewSet(addresses, valSet.policy)
+	newSet.SetSeed(valSet.seed)
+	return newSet
 }
 
 func (valSet *defaultSet) ParticipantsNumber(list []common.Address) int {
@@ -228,7 +251,7 @@ func (valSet *defaultSet) ParticipantsNumber(list []common.Address) int {
 
 func (valSet *defaultSet) CheckQuorum(committers []common.Address) error {
 	validators := valSet.Copy()
-	validSeal := 0
+	validSeal := uint64(0)
 	for _, addr := range committers {
 		if validators.RemoveValidator(addr) {
 			validSeal++
@@ -237,19 +260,42 @@ func (valSet *defaultSet) CheckQuorum(committers []common.Address) error {
 		return ErrInvalidParticipant
 	}
 
-	// The length of validSeal should be larger than number of faulty node + 1
-	if validSeal <= validators.Q() {
+	// The number of distinct committers should reach the quorum of the whole set
+	if validSeal < valSet.Q() {
 		return ErrInvalidParticipant
 	}
 	return nil
 }
 
-func (valSet *defaultSet) F() int { return int(math.Ceil(float64(valSet.Size())/3)) - 1 }
+func (valSet *defaultSet) TotalPower() uint64 { return uint64(valSet.Size()) * DefaultPower }
+
+// F returns the maximum number of faulty validators, which is less than 1/3 of the set.
+func (valSet *defaultSet) F() uint64 {
+	size := uint64(valSet.Size())
+	if size == 0 {
+		return 0
+	}
+	return (size - 1) / 3
+}
 
-func (valSet *defaultSet) Q() int { return int(math.Ceil(float64(2*valSet.Size()) / 3)) }
+// Q returns the minimum number of validators of a quorum, 2/3 of the set rounded up.
+// Sealed blocks are checked against it, it can't change without a fork.
+func (valSet *defaultSet) Q() uint64 { return (2*uint64(valSet.Size()) + 2) / 3 }
 
 func (valSet *defaultSet) Policy() bft.SelectProposerPolicy { return valSet.policy }
 
+func (valSet *defaultSet) SetSeed(seed common.Hash) {
+	valSet.validatorMu.Lock()
+	defer valSet.validatorMu.Unlock()
+	valSet.seed = seed
+}
+
+func (valSet *defaultSet) Seed() common.Hash {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+	return valSet.seed
+}
+
 func (valSet *defaultSet) Cmp(src bft.ValidatorSet) bool {
 	n := valSet.ParticipantsNumber(src.AddressList())
 	if n != valSet.Size() || n != src.Size() {
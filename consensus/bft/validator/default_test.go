@@ -0,0 +1,46 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+)
+
+// Tests that the vrf policy elects the same proposers from the same seed, that
+// all validators propose in turn after round changes, and that the selection
+// falls back to round robin without seed.
+func TestVRFSelector(t *testing.T) {
+	addrs := make([]common.Address, 4)
+	for i := range addrs {
+		addrs[i] = common.BytesToAddress([]byte{byte(i + 1)})
+	}
+	seed := common.HexToHash("0x1234")
+
+	valSet := NewSet(addrs, bft.VRF)
+	valSet.SetSeed(seed)
+	other := valSet.Copy()
+
+	proposed := make(map[common.Address]bool)
+	for round := uint64(0); round < uint64(len(addrs)); round++ {
+		valSet.CalcProposer(common.Address{}, round)
+		other.CalcProposer(addrs[0], round)
+		if have, want := other.GetProposer().Address(), valSet.GetProposer().Address(); have != want {
+			t.Fatalf("round %d: proposer mismatch: have %x, want %x", round, have, want)
+		}
+		proposed[valSet.GetProposer().Address()] = true
+	}
+	if len(proposed) != len(addrs) {
+		t.Fatalf("proposer count mismatch: have %d, want %d", len(proposed), len(addrs))
+	}
+
+	robin := NewSet(addrs, bft.RoundRobin)
+	valSet.SetSeed(common.Hash{})
+	for round := uint64(0); round < uint64(len(addrs)); round++ {
+		valSet.CalcProposer(addrs[1], round)
+		robin.CalcProposer(addrs[1], round)
+		if have, want := valSet.GetProposer().Address(), robin.GetProposer().Address(); have != want {
+			t.Fatalf("round %d: proposer without seed mismatch: have %x, want %x", round, have, want)
+		}
+	}
+}
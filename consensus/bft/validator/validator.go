@@ -15,6 +15,51 @@ func NewSet(addrs []common.Address, policy bft.SelectProposerPolicy) bft.Validat
 	return newDefaultSet(addrs, policy)
 }
 
+// NewWeightedSet creates a validator set where every validator votes with the power
+// of the same index.
+func NewWeightedSet(addrs []common.Address, powers []uint64, policy bft.SelectProposerPolicy) (bft.ValidatorSet, error) {
+	return newWeightedSet(addrs, powers, policy)
+}
+
+// Powers returns the powers of the validators in the order of `AddressList`, or nil
+// if the set is not weighted.
+func Powers(valSet bft.ValidatorSet) []uint64 {
+	if _, ok := valSet.(*weightedSet); !ok {
+		return nil
+	}
+	list := valSet.List()
+	powers := make([]uint64, len(list))
+	for i, val := range list {
+		powers[i] = val.Power()
+	}
+	return powers
+}
+
+// IncrementProposerPriority advances the proposer priorities of a weighted set by
+// the given number of heights, it has no effect on unweighted sets.
+func IncrementProposerPriority(valSet bft.ValidatorSet, times uint64) {
+	if set, ok := valSet.(*weightedSet); ok {
+		set.IncrementProposerPriority(times)
+	}
+}
+
+// ProposerPriorities returns the proposer priorities of a weighted set in the order
+// of `List`, or nil if the set is not weighted.
+func ProposerPriorities(valSet bft.ValidatorSet) []int64 {
+	if set, ok := valSet.(*weightedSet); ok {
+		return set.priorityList()
+	}
+	return nil
+}
+
+// SetProposerPriorities restores the proposer priorities returned by
+// ProposerPriorities for the same validators, it has no effect on unweighted sets.
+func SetProposerPriorities(valSet bft.ValidatorSet, priorities []int64) {
+	if set, ok := valSet.(*weightedSet); ok {
+		set.setPriorityList(priorities)
+	}
+}
+
 func ExtractValidators(extraData []byte) []common.Address {
 	// get the validator addresses
 	addrs := make([]common.Address, (len(extraData) / common.AddressLength))
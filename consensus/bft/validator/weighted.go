@@ -0,0 +1,402 @@
+package validator
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// DefaultPower is the voting power of validators without explicit weight, e.g.
+	// the ones added by votes.
+	DefaultPower uint64 = 1
+
+	// MaxTotalPower is the upper bound of the total voting power, it keeps the
+	// proposer priorities far from overflowing int64.
+	MaxTotalPower = uint64(math.MaxInt64) / 8
+)
+
+var (
+	// ErrInvalidPowers is returned if the powers are not aligned with the validators,
+	// or any power is zero.
+	ErrInvalidPowers = errors.New("invalid validator powers")
+	// ErrTotalPowerOverflow is returned if the total power exceeds MaxTotalPower.
+	ErrTotalPowerOverflow = errors.New("total validator power overflow")
+)
+
+type weightedValidator struct {
+	address common.Address
+	power   uint64
+}
+
+func (val *weightedValidator) Address() common.Address {
+	return val.address
+}
+
+func (val *weightedValidator) Power() uint64 {
+	return val.power
+}
+
+func (val *weightedValidator) String() string {
+	return val.Address().String()
+}
+
+// ----------------------------------------------------------------------------
+
+// weightedSet is a validator set where every validator votes with it's own power.
+// the quorum is reached with more than 2/3 of the total power, and the proposer is
+// elected in proportion to the power with the priority algorithm of tendermint.
+type weightedSet struct {
+	validators bft.Validators
+	priorities map[common.Address]int64
+	totalPower uint64
+	policy     bft.SelectProposerPolicy
+
+	proposer    bft.Validator
+	validatorMu sync.RWMutex
+	seed        common.Hash // vrf output of the last committed block
+}
+
+func newWeightedSet(addrs []common.Address, powers []uint64, policy bft.SelectProposerPolicy) (*weightedSet, error) {
+	if len(addrs) != len(powers) {
+		return nil, ErrInvalidPowers
+	}
+	valSet := &weightedSet{
+		validators: make([]bft.Validator, 0, len(addrs)),
+		priorities: make(map[common.Address]int64, len(addrs)),
+		policy:     policy,
+	}
+	for i, addr := range addrs {
+		if powers[i] == 0 {
+			return nil, ErrInvalidPowers
+		}
+		if _, ok := valSet.priorities[addr]; ok {
+			continue
+		}
+		valSet.validators = append(valSet.validators, &weightedValidator{address: addr, power: powers[i]})
+		valSet.priorities[addr] = 0
+		valSet.totalPower += powers[i]
+		if valSet.totalPower > MaxTotalPower {
+			return nil, ErrTotalPowerOverflow
+		}
+	}
+	sort.Sort(valSet.validators)
+	if len(valSet.validators) > 0 {
+		valSet.proposer = valSet.validators[0]
+	}
+	return valSet, nil
+}
+
+func (valSet *weightedSet) Size() int {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+	return len(valSet.validators)
+}
+
+// List returns a copy of the validators, sorted by address.
+func (valSet *weightedSet) List() []bft.Validator {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+
+	vals := make([]bft.Validator, len(valSet.validators))
+	copy(vals, valSet.validators)
+	return vals
+}
+
+func (valSet *weightedSet) AddressList() []common.Address {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+
+	vals := make([]common.Address, len(valSet.validators))
+	for i, v := range valSet.validators {
+		vals[i] = v.Address()
+	}
+	return vals
+}
+
+func (valSet *weightedSet) GetByIndex(i uint64) bft.Validator {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+	if i < uint64(len(valSet.validators)) {
+		return valSet.validators[i]
+	}
+	return nil
+}
+
+func (valSet *weightedSet) GetByAddress(addr common.Address) (int, bft.Validator) {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+
+	for i, val := range valSet.validators {
+		if addr == val.Address() {
+			return i, val
+		}
+	}
+	return -1, nil
+}
+
+func (valSet *weightedSet) GetProposer() bft.Validator {
+	return valSet.proposer
+}
+
+func (valSet *weightedSet) IsProposer(address common.Address) bool {
+	proposer := valSet.GetProposer()
+	return proposer != nil && proposer.Address() == address
+}
+
+// CalcProposer elects the proposer of the given round. the sticky policy keeps the
+// last proposer in the first round, and the vrf policy picks a random validator in
+// proportion to the power. otherwise the validator with the highest priority after
+// `round+1` increments proposes.
+func (valSet *weightedSet) CalcProposer(lastProposer common.Address, round uint64) {
+	valSet.validatorMu.Lock()
+	defer valSet.validatorMu.Unlock()
+
+	if len(valSet.validators) == 0 {
+		valSet.proposer = nil
+		return
+	}
+	switch valSet.policy {
+	case bft.Sticky:
+		if round == 0 && !emptyAddress(lastProposer) {
+			for _, val := range valSet.validators {
+				if val.Address() == lastProposer {
+					valSet.proposer = val
+					return
+				}
+			}
+		}
+	case bft.VRF:
+		// without seed the proposer is selected by priority like the round robin policy
+		if valSet.seed != (common.Hash{}) {
+			valSet.proposer = valSet.randomProposer(round)
+			return
+		}
+	}
+	priorities := make(map[common.Address]int64, len(valSet.priorities))
+	for addr, priority := range valSet.priorities {
+		priorities[addr] = priority
+	}
+	var proposer bft.Validator
+	for i := uint64(0); i <= round; i++ {
+		proposer = valSet.incrementPriorities(priorities)
+	}
+	valSet.proposer = proposer
+}
+
+func (valSet *weightedSet) CalcProposerByIndex(index uint64) {
+	valSet.validatorMu.Lock()
+	defer valSet.validatorMu.Unlock()
+
+	if index > 1 {
+		index = (index - 1) % uint64(len(valSet.validators))
+	} else {
+		index = 0
+	}
+	valSet.proposer = valSet.validators[index]
+}
+
+// IncrementProposerPriority advances the proposer priorities by the given number
+// of heights, the set of an epoch is advanced once for every block since the
+// start of the epoch.
+func (valSet *weightedSet) IncrementProposerPriority(times uint64) {
+	valSet.validatorMu.Lock()
+	defer valSet.validatorMu.Unlock()
+
+	if len(valSet.validators) == 0 {
+		return
+	}
+	for i := uint64(0); i < times; i++ {
+		valSet.incrementPriorities(valSet.priorities)
+	}
+}
+
+// priorityList returns the proposer priorities in the order of the validators.
+func (valSet *weightedSet) priorityList() []int64 {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+
+	priorities := make([]int64, len(valSet.validators))
+	for i, val := range valSet.validators {
+		priorities[i] = valSet.priorities[val.Address()]
+	}
+	return priorities
+}
+
+// setPriorityList replaces the proposer priorities with the ones returned by
+// priorityList for the same validators.
+func (valSet *weightedSet) setPriorityList(priorities []int64) {
+	valSet.validatorMu.Lock()
+	defer valSet.validatorMu.Unlock()
+
+	for i, val := range valSet.validators {
+		if i < len(priorities) {
+			valSet.priorities[val.Address()] = priorities[i]
+		}
+	}
+}
+
+// incrementPriorities raises the priority of every validator by it's power, and
+// charges the total power to the validator with the highest priority, which is
+// returned as the proposer. ties are broken by the address order.
+func (valSet *weightedSet) incrementPriorities(priorities map[common.Address]int64) bft.Validator {
+	var proposer bft.Validator
+	for _, val := range valSet.validators {
+		addr := val.Address()
+		priorities[addr] += int64(val.Power())
+		if proposer == nil || priorities[addr] > priorities[proposer.Address()] {
+			proposer = val
+		}
+	}
+	priorities[proposer.Address()] -= int64(valSet.totalPower)
+	return proposer
+}
+
+// randomProposer picks the proposer with the vrf output of the last committed block,
+// every validator is chosen with the probability of it's share of the total power.
+func (valSet *weightedSet) randomProposer(round uint64) bft.Validator {
+	var buf [8]byte
+	for i := 0; i < 8; i++ {
+		buf[i] = byte(round >> (8 * (7 - i)))
+	}
+	point := new(big.Int).SetBytes(crypto.Keccak256(valSet.seed.Bytes(), buf[:]))
+	point.Mod(point, new(big.Int).SetUint64(valSet.totalPower))
+
+	target := point.Uint64()
+	for _, val := range valSet.validators {
+		if target < val.Power() {
+			return val
+		}
+		target -= val.Power()
+	}
+	return valSet.validators[len(valSet.validators)-1]
+}
+
+func (valSet *weightedSet) AddValidator(address common.Address) bool {
+	valSet.validatorMu.Lock()
+	defer valSet.validatorMu.Unlock()
+	for _, v := range valSet.validators {
+		if v.Address() == address {
+			return false
+		}
+	}
+	valSet.validators = append(valSet.validators, &weightedValidator{address: address, power: DefaultPower})
+	valSet.priorities[address] = 0
+	valSet.totalPower += DefaultPower
+	sort.Sort(valSet.validators)
+	return true
+}
+
+func (valSet *weightedSet) RemoveValidator(address common.Address) bool {
+	valSet.validatorMu.Lock()
+	defer valSet.validatorMu.Unlock()
+
+	for i, v := range valSet.validators {
+		if v.Address() == address {
+			valSet.validators = append(valSet.validators[:i:i], valSet.validators[i+1:]...)
+			valSet.totalPower -= v.Power()
+			delete(valSet.priorities, address)
+			return true
+		}
+	}
+	return false
+}
+
+func (valSet *weightedSet) Copy() bft.ValidatorSet {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+
+	newSet := &weightedSet{
+		validators: make([]bft.Validator, len(valSet.validators)),
+		priorities: make(map[common.Address]int64, len(valSet.priorities)),
+		totalPower: valSet.totalPower,
+		policy:     valSet.policy,
+		proposer:   valSet.proposer,
+		seed:       valSet.seed,
+	}
+	copy(newSet.validators, valSet.validators)
+	for addr, priority := range valSet.priorities {
+		newSet.priorities[addr] = priority
+	}
+	return newSet
+}
+
+func (valSet *weightedSet) ParticipantsNumber(list []common.Address) int {
+	size := 0
+	for _, v := range list {
+		if index, _ := valSet.GetByAddress(v); index >= 0 {
+			size++
+		}
+	}
+	return size
+}
+
+func (valSet *weightedSet) CheckQuorum(committers []common.Address) error {
+	var (
+		power uint64
+		seen  = make(map[common.Address]bool, len(committers))
+	)
+	for _, addr := range committers {
+		_, val := valSet.GetByAddress(addr)
+		if val == nil || seen[addr] {
+			return ErrInvalidParticipant
+		}
+		seen[addr] = true
+		power += val.Power()
+	}
+	if power < valSet.Q() {
+		return ErrInvalidParticipant
+	}
+	return nil
+}
+
+func (valSet *weightedSet) TotalPower() uint64 {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+	return valSet.totalPower
+}
+
+// F returns the maximum power of faulty validators, which is less than 1/3 of total.
+func (valSet *weightedSet) F() uint64 {
+	total := valSet.TotalPower()
+	if total == 0 {
+		return 0
+	}
+	return (total - 1) / 3
+}
+
+// Q returns the minimum power of a quorum, which is more than 2/3 of total.
+func (valSet *weightedSet) Q() uint64 { return valSet.TotalPower()*2/3 + 1 }
+
+func (valSet *weightedSet) Policy() bft.SelectProposerPolicy { return valSet.policy }
+
+func (valSet *weightedSet) SetSeed(seed common.Hash) {
+	valSet.validatorMu.Lock()
+	defer valSet.validatorMu.Unlock()
+	valSet.seed = seed
+}
+
+func (valSet *weightedSet) Seed() common.Hash {
+	valSet.validatorMu.RLock()
+	defer valSet.validatorMu.RUnlock()
+	return valSet.seed
+}
+
+func (valSet *weightedSet) Cmp(src bft.ValidatorSet) bool {
+	n := valSet.ParticipantsNumber(src.AddressList())
+	if n != valSet.Size() || n != src.Size() {
+		return false
+	}
+	for _, val := range src.List() {
+		if _, v := valSet.GetByAddress(val.Address()); v == nil || v.Power() != val.Power() {
+			return false
+		}
+	}
+	return true
+}
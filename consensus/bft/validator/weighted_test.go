@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+)
+
+func testAddresses(n int) []common.Address {
+	addrs := make([]common.Address, n)
+	for i := range addrs {
+		addrs[i] = common.BytesToAddress([]byte{byte(i + 1)})
+	}
+	return addrs
+}
+
+// Tests that the quorum of weighted sets is more than 2/3 of the voting power, the
+// quorum of unweighted sets keeps 2/3 of the validators rounded up as the chains
+// sealed with it, and that the tolerated faulty power stays below 1/3.
+func TestQuorum(t *testing.T) {
+	for n := 1; n <= 10; n++ {
+		valSet := NewSet(testAddresses(n), bft.RoundRobin)
+		if q := valSet.Q(); q*3 < uint64(2*n) || (q-1)*3 >= uint64(2*n) {
+			t.Errorf("unweighted set of %d: quorum %d is not 2/3 rounded up", n, q)
+		}
+		if f := valSet.F(); f*3 >= uint64(n) || (f+1)*3 < uint64(n) {
+			t.Errorf("unweighted set of %d: faulty power %d is not the largest power below 1/3", n, f)
+		}
+	}
+	for _, powers := range [][]uint64{{1}, {1, 2}, {3, 3}, {1, 1, 1}, {5, 1, 1, 2}, {10, 20, 30}} {
+		valSet, err := NewWeightedSet(testAddresses(len(powers)), powers, bft.RoundRobin)
+		if err != nil {
+			t.Fatalf("failed to create weighted set %v: %v", powers, err)
+		}
+		total := valSet.TotalPower()
+		if q := valSet.Q(); q*3 <= 2*total || (q-1)*3 > 2*total {
+			t.Errorf("weighted set %v: quorum %d is not the smallest power above 2/3", powers, q)
+		}
+		if f := valSet.F(); f*3 >= total || (f+1)*3 < total {
+			t.Errorf("weighted set %v: faulty power %d is not the largest power below 1/3", powers, f)
+		}
+	}
+}
+
+// Tests that the weighted validators propose in proportion to their power, and
+// that the priorities are restored from the exported ones.
+func TestWeightedProposerPriority(t *testing.T) {
+	powers := []uint64{1, 2, 3, 4}
+	valSet, err := NewWeightedSet(testAddresses(len(powers)), powers, bft.RoundRobin)
+	if err != nil {
+		t.Fatalf("failed to create weighted set: %v", err)
+	}
+	proposed := make(map[common.Address]uint64)
+	for i := uint64(0); i < valSet.TotalPower(); i++ {
+		IncrementProposerPriority(valSet, 1)
+		valSet.CalcProposer(common.Address{}, 0)
+		proposed[valSet.GetProposer().Address()]++
+	}
+	for _, val := range valSet.List() {
+		if proposed[val.Address()] != val.Power() {
+			t.Errorf("validator %x: proposals mismatch: have %d, want %d", val.Address(), proposed[val.Address()], val.Power())
+		}
+	}
+	saved := ProposerPriorities(valSet)
+	restored, _ := NewWeightedSet(testAddresses(len(powers)), powers, bft.RoundRobin)
+	SetProposerPriorities(restored, saved)
+	for round := uint64(0); round < 8; round++ {
+		valSet.CalcProposer(common.Address{}, round)
+		restored.CalcProposer(common.Address{}, round)
+		if have, want := restored.GetProposer().Address(), valSet.GetProposer().Address(); have != want {
+			t.Errorf("round %d: restored proposer mismatch: have %x, want %x", round, have, want)
+		}
+	}
+	if ProposerPriorities(NewSet(testAddresses(2), bft.RoundRobin)) != nil {
+		t.Errorf("priorities of an unweighted set")
+	}
+}
+
+// Tests that the validator list of a weighted set can't modify the set.
+func TestWeightedList(t *testing.T) {
+	valSet, err := NewWeightedSet(testAddresses(3), []uint64{1, 2, 3}, bft.RoundRobin)
+	if err != nil {
+		t.Fatalf("failed to create weighted set: %v", err)
+	}
+	list := valSet.List()
+	list[0] = list[2]
+
+	if _, val := valSet.GetByAddress(testAddresses(1)[0]); val == nil || val.Power() != 1 {
+		t.Fatalf("validator list modified through the returned slice: have %v", val)
+	}
+}
@@ -0,0 +1,223 @@
+// Package wal implements the write-ahead log of the consensus messages seen by a
+// node. The log is a directory of numbered files with rlp encoded entries, a new
+// file is started on every open and once the current file exceeds the max size.
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	fileSuffix = ".wal"
+
+	// DefaultMaxSize is the size in bytes after which the log rotates to a new file
+	DefaultMaxSize = 64 * 1024 * 1024
+)
+
+var errClosed = errors.New("wal closed")
+
+// Direction tells apart the messages received by the node from the messages it
+// sent.
+type Direction uint8
+
+const (
+	Inbound Direction = iota
+	Outbound
+)
+
+func (d Direction) String() string {
+	if d == Outbound {
+		return "out"
+	}
+	return "in"
+}
+
+// Entry is a consensus message seen by the node.
+type Entry struct {
+	Time    uint64         // Unix time in nanoseconds the message was seen
+	Dir     Direction      // Whether the message was received or sent
+	Peer    common.Address // Peer the message was received from, empty for sent messages
+	Payload []byte         // Encoded bft.Message
+}
+
+// WAL appends the consensus messages to the current file of the log directory.
+type WAL struct {
+	dir      string
+	maxSize  uint64
+	maxFiles int
+
+	mu     sync.Mutex
+	file   *os.File
+	index  uint64 // Number of the current file
+	size   uint64 // Size of the current file
+	closed bool
+}
+
+// Open creates the log directory if needed and starts a new file after the existing
+// ones, so a torn entry of a crashed node is never followed by new entries. The
+// oldest files are removed if there are more than maxFiles, zero keeps all of them.
+func Open(dir string, maxSize uint64, maxFiles int) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	indexes, err := files(dir)
+	if err != nil {
+		return nil, err
+	}
+	w := &WAL{dir: dir, maxSize: maxSize, maxFiles: maxFiles}
+	if len(indexes) > 0 {
+		w.index = indexes[len(indexes)-1] + 1
+	}
+	if err := w.openFile(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends the entry to the log, the current file is rotated first if the
+// entry doesn't fit.
+func (w *WAL) Write(entry *Entry) error {
+	enc, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errClosed
+	}
+	if w.size > 0 && w.size+uint64(len(enc)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := w.file.Write(enc)
+	w.size += uint64(n)
+	return err
+}
+
+// Close syncs and closes the current file.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// rotate closes the current file and starts the next one, the files beyond the
+// max number of files are removed.
+//
+// Note, this function assumes that the `mu` mutex is held!
+func (w *WAL) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.index++
+	return w.openFile()
+}
+
+// openFile creates the file of the current index and removes the oldest files.
+//
+// Note, this function assumes that the `mu` mutex is held!
+func (w *WAL) openFile() error {
+	file, err := os.OpenFile(filepath.Join(w.dir, fileName(w.index)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.size = file, 0
+
+	if w.maxFiles <= 0 {
+		return nil
+	}
+	indexes, err := files(w.dir)
+	if err != nil {
+		return err
+	}
+	for len(indexes) > w.maxFiles {
+		if err := os.Remove(filepath.Join(w.dir, fileName(indexes[0]))); err != nil {
+			return err
+		}
+		indexes = indexes[1:]
+	}
+	return nil
+}
+
+// Read returns the entries of all files of the log directory in the order they
+// were written. A torn entry at the end of a file, left by a crashed node, is
+// skipped.
+func Read(dir string) ([]*Entry, error) {
+	indexes, err := files(dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, index := range indexes {
+		file, err := os.Open(filepath.Join(dir, fileName(index)))
+		if err != nil {
+			return nil, err
+		}
+		stream := rlp.NewStream(file, 0)
+		for {
+			entry := new(Entry)
+			if err := stream.Decode(entry); err != nil {
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					break
+				}
+				file.Close()
+				return nil, fmt.Errorf("%s: %w", fileName(index), err)
+			}
+			entries = append(entries, entry)
+		}
+		file.Close()
+	}
+	return entries, nil
+}
+
+func fileName(index uint64) string {
+	return fmt.Sprintf("%08d%s", index, fileSuffix)
+}
+
+// files returns the numbers of the log files in the directory in ascending order.
+func files(dir string) ([]uint64, error) {
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []uint64
+	for _, dirent := range dirents {
+		name := dirent.Name()
+		if dirent.IsDir() || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(name, fileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	return indexes, nil
+}
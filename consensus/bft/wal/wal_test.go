@@ -0,0 +1,84 @@
+package wal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the entries are read back in order across rotated files and reopens,
+// that the oldest files are removed, and that a torn entry ends it's file.
+func TestWAL(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := Open(dir, 100, 0)
+	if err != nil {
+		t.Fatalf("failed to open wal: %v", err)
+	}
+	var written []*Entry
+	for i := 0; i < 10; i++ {
+		entry := &Entry{
+			Time:    uint64(i),
+			Dir:     Direction(i % 2),
+			Peer:    common.Address{byte(i)},
+			Payload: bytes.Repeat([]byte{byte(i)}, 30),
+		}
+		if err := w.Write(entry); err != nil {
+			t.Fatalf("failed to write entry %d: %v", i, err)
+		}
+		written = append(written, entry)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close wal: %v", err)
+	}
+	// Every entry exceeds half of the max size, so each one has it's own file
+	indexes, _ := files(dir)
+	if len(indexes) != len(written) {
+		t.Fatalf("file count mismatch: have %d, want %d", len(indexes), len(written))
+	}
+	checkEntries(t, dir, written)
+
+	// A reopened log starts a new file, the torn entry of the last file is skipped
+	last := filepath.Join(dir, fileName(indexes[len(indexes)-1]))
+	data, err := os.ReadFile(last)
+	if err != nil {
+		t.Fatalf("failed to read the last file: %v", err)
+	}
+	if err := os.WriteFile(last, append(data, 0xf8, 0x40, 0x01), 0644); err != nil {
+		t.Fatalf("failed to tear the last file: %v", err)
+	}
+	if w, err = Open(dir, 100, 2); err != nil {
+		t.Fatalf("failed to reopen wal: %v", err)
+	}
+	entry := &Entry{Time: 10, Dir: Outbound, Payload: []byte{0x0a}}
+	if err := w.Write(entry); err != nil {
+		t.Fatalf("failed to write entry: %v", err)
+	}
+	w.Close()
+
+	kept, _ := files(dir)
+	if len(kept) != 2 || kept[0] != indexes[len(indexes)-1] || kept[1] != kept[0]+1 {
+		t.Fatalf("files mismatch: have %v", kept)
+	}
+	checkEntries(t, dir, []*Entry{written[len(written)-1], entry})
+}
+
+func checkEntries(t *testing.T, dir string, want []*Entry) {
+	t.Helper()
+
+	have, err := Read(dir)
+	if err != nil {
+		t.Fatalf("failed to read wal: %v", err)
+	}
+	if len(have) != len(want) {
+		t.Fatalf("entry count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range have {
+		if have[i].Time != want[i].Time || have[i].Dir != want[i].Dir || have[i].Peer != want[i].Peer || !bytes.Equal(have[i].Payload, want[i].Payload) {
+			t.Errorf("entry %d mismatch: have %+v, want %+v", i, have[i], want[i])
+		}
+	}
+}
@@ -365,6 +365,16 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 	return c.verifySeal(snap, header, parents)
 }
 
+// Signers retrieves the authorized signers at the given block in ascending order,
+// the parents are used to rebuild the snapshot if they are not in the database.
+func (c *Clique) Signers(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) ([]common.Address, error) {
+	snap, err := c.snapshot(chain, number, hash, parents)
+	if err != nil {
+		return nil, err
+	}
+	return snap.signers(), nil
+}
+
 // snapshot retrieves the authorization snapshot at a given point in time.
 func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
@@ -2,10 +2,13 @@ package consensus
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -45,6 +48,14 @@ type ChainReader interface {
 
 	// PreExecuteBlock pre-execute block transactions and validate states
 	PreExecuteBlock(block *types.Block) error
+
+	// InsertBlockWithoutSetHead executes the block and stores it beside the chain,
+	// the head is kept
+	InsertBlockWithoutSetHead(block *types.Block) error
+
+	// SetCanonical sets the stored block as the chain head, the chain reorgs to it
+	// if it's on another fork
+	SetCanonical(head *types.Block) (common.Hash, error)
 }
 
 // Engine is an algorithm agnostic consensus engine.
@@ -128,9 +139,52 @@ type BFT interface {
 
 	// ChangeEpoch save validators and start height for next epoch
 	ChangeEpoch(epochStartHeight uint64, list []common.Address) error
+
+	// InitEpoch save validators of the first epoch of a chain migrated to the engine,
+	// it does nothing if the epochs are already initialized
+	InitEpoch(epochStartHeight uint64, list []common.Address) error
+
+	// Health returns the consensus health of the node, nil if the engine is stopped
+	Health() *Health
+
+	// Signers returns the proposer and the committers of a block
+	Signers(header *types.Header) (common.Address, []common.Address, error)
+
+	// Participation returns the participation of the validators in a block, it
+	// returns nil for the blocks sealed by another engine
+	Participation(parent, header *types.Header) (*Participation, error)
+}
+
+// Participation is the part taken by the validators of a height in it's block.
+type Participation struct {
+	Validators []common.Address // Validators of the height
+	Proposer   common.Address   // Validator which proposed the block
+	Missed     []common.Address // Proposers of the rounds which failed before the block was proposed
+	Committers []common.Address // Validators whose committed seals are in the header
+}
+
+// Health is the consensus health of a node running a BFT engine, the counters
+// are accumulated since the engine started.
+type Health struct {
+	Height         uint64                   // Height of the current round
+	Round          uint64                   // Number of the current round
+	State          string                   // State of the node in the current round
+	PhaseTimes     map[string]time.Duration // Time spent in each state
+	Rounds         uint64                   // Rounds started
+	RoundChanges   uint64                   // Rounds started after a failed round of the same height
+	ProposerRounds uint64                   // Rounds led by the node
+	MissedVotes    uint64                   // Blocks committed without the seal of the validator
 }
 
 
+// StateVerifier is implemented by the engines whose headers carry fields derived
+// from the chain state, they can't be checked with the headers alone.
+type StateVerifier interface {
+	// VerifyState checks the fields of the header derived from the state of it's
+	// parent, before the transactions of the block are applied to it.
+	VerifyState(chain ChainHeaderReader, header *types.Header, parent *state.StateDB) error
+}
+
 // Interfaces below are used by BFT
 // Broadcaster defines the interface to enqueue blocks to fetcher and find peer
 type Broadcaster interface {
@@ -150,3 +204,91 @@ type Peer interface {
 	// Send sends the message to this peer
 	Send(msgcode uint64, data interface{}) error
 }
+
+// Pipeliner should be implemented by the engines which certify blocks before they
+// are final. The certified blocks are kept beside the chain until they are final,
+// the next blocks are built on top of them.
+type Pipeliner interface {
+	// CertifiedHead returns the highest certified block above the chain head, or
+	// nil if there is none
+	CertifiedHead() *types.Block
+
+	// SubscribeCertifiedHead subscribes to the blocks certified by the engine
+	SubscribeCertifiedHead(ch chan<- *types.Block) event.Subscription
+}
+
+// Handler should be implemented by the engines which exchange their own messages
+// with the peers, over the `bft` protocol.
+type Handler interface {
+	// Address returns the validator address the engine signs with
+	Address() common.Address
+
+	// SignHandshake signs the keccak256 hash of the `bft` handshake data with the
+	// validator key
+	SignHandshake(data []byte) ([]byte, error)
+
+	// IsValidator returns whether the address validates the block of the height
+	IsValidator(height uint64, address common.Address) bool
+
+	// HandleMsg handles a message of the validator at the address, it returns
+	// whether the message was consumed by the engine
+	HandleMsg(address common.Address, msg p2p.Msg) (bool, error)
+
+	// SetBroadcaster sets the broadcaster the engine sends it's messages with
+	SetBroadcaster(Broadcaster)
+
+	// NewChainHead notifies the engine of a new head block
+	NewChainHead(header *types.Header) error
+}
+
+// Finality should be implemented by the engines with deterministic finality, the
+// final blocks can't be reverted by the chain.
+type Finality interface {
+	// Finalized returns the highest final header up to the head, or nil if the
+	// engine doesn't know of any
+	Finalized(chain ChainHeaderReader, head *types.Header) *types.Header
+}
+
+// EpochProver should be implemented by the engines which change their validators
+// by epochs. The last header of an epoch carries the validators of the next one,
+// it's quorum seals prove them to the nodes which know the current validators.
+type EpochProver interface {
+	// LatestEpoch returns the start height of the latest epoch known to the engine
+	LatestEpoch() uint64
+
+	// EpochProof returns up to limit headers, which change the validators of the
+	// epochs after the one starting at from, in ascending order
+	EpochProof(chain ChainHeaderReader, from uint64, limit int) []*types.Header
+
+	// VerifyEpochProof verifies the epoch change headers in ascending order after
+	// the epoch starting at from, and stores the epochs they prove
+	VerifyEpochProof(from uint64, headers []*types.Header) error
+}
+
+// BuildDeadliner should be implemented by the engines which bound the time spent by
+// the proposer on assembling a block, so it's proposed within the round.
+type BuildDeadliner interface {
+	// BuildDeadline returns the time after which no more transactions are added
+	// to the block of the header, or false if the building isn't bounded
+	BuildDeadline(header *types.Header) (time.Time, bool)
+}
+
+// Governance should be implemented by the engines whose validators are added and
+// removed by the votes of the validators.
+type Governance interface {
+	// Propose casts the vote of the node on the candidate in the blocks it proposes,
+	// until the vote is discarded
+	Propose(candidate common.Address, authorize bool) error
+}
+
+// EpochStore should be implemented by the engines which persist the validators of
+// their epochs, the stored epochs follow the rewinds and the finality of the chain.
+type EpochStore interface {
+	// RewindEpochs drops the epochs which are not defined by the chain up to the
+	// given head
+	RewindEpochs(head uint64) error
+
+	// FreezeEpochs moves the epochs started up to the finalized number to the
+	// ancient store
+	FreezeEpochs(finalized uint64) error
+}
@@ -356,7 +356,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		}
 		if needRewind {
 			log.Error("Truncating ancient chain", "from", bc.CurrentHeader().Number.Uint64(), "to", low)
-			if err := bc.SetHead(low); err != nil {
+			if err := bc.setHead(low); err != nil {
 				return nil, err
 			}
 		}
@@ -374,7 +374,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 			// make sure the headerByNumber (if present) is in our current canonical chain
 			if headerByNumber != nil && headerByNumber.Hash() == header.Hash() {
 				log.Error("Found bad hash, rewinding chain", "number", header.Number, "hash", header.ParentHash)
-				if err := bc.SetHead(header.Number.Uint64() - 1); err != nil {
+				if err := bc.setHead(header.Number.Uint64() - 1); err != nil {
 					return nil, err
 				}
 				log.Error("Chain rewind was successful, resuming normal operation")
@@ -424,7 +424,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
-		bc.SetHead(compat.RewindTo)
+		bc.setHead(compat.RewindTo)
 		rawdb.WriteChainConfig(db, genesisHash, chainConfig)
 	}
 	// Start tx indexer/unindexer if required.
@@ -528,7 +528,21 @@ func (bc *BlockChain) loadLastState() error {
 // SetHead rewinds the local chain to a new head. Depending on whether the node
 // was fast synced or full synced and in which state, the method will try to
 // delete minimal data from disk whilst retaining chain consistency.
+//
+// If the consensus engine has deterministic finality, rewinding below the
+// finalized block is refused.
 func (bc *BlockChain) SetHead(head uint64) error {
+	if _, ok := bc.engine.(consensus.Finality); ok {
+		if finalized := bc.CurrentFinalizedBlock(); finalized != nil && head < finalized.NumberU64() {
+			return fmt.Errorf("%w: head %d, finalized %d", ErrRewindFinalized, head, finalized.NumberU64())
+		}
+	}
+	return bc.setHead(head)
+}
+
+// setHead rewinds the local chain to a new head regardless of the finalized
+// block, it's used to repair the database and to reset the chain.
+func (bc *BlockChain) setHead(head uint64) error {
 	_, err := bc.setHeadBeyondRoot(head, common.Hash{}, false)
 	return err
 }
@@ -719,7 +733,12 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash, repair bo
 		log.Error("SetHead invalidated finalized block")
 		bc.SetFinalized(nil)
 	}
-
+	// Drop the epochs of the consensus engine which were defined by the rewound headers
+	if store, ok := bc.engine.(consensus.EpochStore); ok {
+		if err := store.RewindEpochs(bc.hc.CurrentHeader().Number.Uint64()); err != nil {
+			log.Error("Failed to rewind consensus epochs", "err", err)
+		}
+	}
 	return rootNumber, bc.loadLastState()
 }
 
@@ -761,7 +780,7 @@ func (bc *BlockChain) Reset() error {
 // specified genesis state.
 func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 	// Dump the entire block chain and purge the caches
-	if err := bc.SetHead(0); err != nil {
+	if err := bc.setHead(0); err != nil {
 		return err
 	}
 	if !bc.chainmu.TryLock() {
@@ -853,6 +872,45 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 
 	bc.currentBlock.Store(block)
 	headBlockGauge.Update(int64(block.NumberU64()))
+
+	bc.updateFinalized(block)
+}
+
+// updateFinalized moves the finalized and safe markers to the highest final block
+// of the consensus engine, if it has deterministic finality. The final blocks are
+// safe as well, they can't be reorged out.
+//
+// Note, this function assumes that the `mu` mutex is held!
+func (bc *BlockChain) updateFinalized(head *types.Block) {
+	engine, ok := bc.engine.(consensus.Finality)
+	if !ok {
+		return
+	}
+	header := engine.Finalized(bc, head.Header())
+	if header == nil {
+		return
+	}
+	number := header.Number.Uint64()
+	if current := bc.CurrentFinalizedBlock(); current != nil && current.NumberU64() >= number {
+		return
+	}
+	if rawdb.ReadCanonicalHash(bc.db, number) != header.Hash() {
+		return
+	}
+	block := head
+	if block.Hash() != header.Hash() {
+		if block = bc.GetBlock(header.Hash(), number); block == nil {
+			return
+		}
+	}
+	bc.SetFinalized(block)
+	bc.SetSafe(block)
+
+	if store, ok := bc.engine.(consensus.EpochStore); ok {
+		if err := store.FreezeEpochs(number); err != nil {
+			log.Error("Failed to freeze consensus epochs", "err", err)
+		}
+	}
 }
 
 // stop stops the blockchain service. If any imports are currently in progress
@@ -2075,6 +2133,12 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		}
 	}
 
+	// Ensure the final blocks are never reverted
+	if _, ok := bc.engine.(consensus.Finality); ok && len(oldChain) > 0 {
+		if finalized := bc.CurrentFinalizedBlock(); finalized != nil && commonBlock.NumberU64() < finalized.NumberU64() {
+			return fmt.Errorf("%w: ancestor %d, finalized %d", ErrReorgFinalized, commonBlock.NumberU64(), finalized.NumberU64())
+		}
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Info
@@ -4093,3 +4093,94 @@ func testCreateThenDelete(t *testing.T, config *params.ChainConfig) {
 		}
 	}
 }
+
+// finalityEngine is a fake engine with deterministic finality, the blocks are
+// final once they are buried by depth blocks.
+type finalityEngine struct {
+	consensus.Engine
+	depth uint64
+}
+
+func (e *finalityEngine) Finalized(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	if head.Number.Uint64() < e.depth {
+		return nil
+	}
+	number := head.Number.Uint64() - e.depth
+	for head != nil && head.Number.Uint64() > number {
+		head = chain.GetHeader(head.ParentHash, head.Number.Uint64()-1)
+	}
+	return head
+}
+
+// Tests that the finalized and safe markers follow the final blocks of the engine.
+func TestFinalizedMarkers(t *testing.T) {
+	_, _, chain, err := newCanonical(&finalityEngine{ethash.NewFaker(), 2}, 10, true)
+	if err != nil {
+		t.Fatalf("failed to create canonical chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if finalized := chain.CurrentFinalizedBlock(); finalized == nil || finalized.NumberU64() != 8 {
+		t.Fatalf("finalized block mismatch: have %v, want %d", finalized, 8)
+	}
+	if safe := chain.CurrentSafeBlock(); safe == nil || safe.Hash() != chain.CurrentFinalizedBlock().Hash() {
+		t.Fatalf("safe block mismatch: have %v, want %d", safe, 8)
+	}
+	// Extend the chain, the markers should advance with it
+	blocks := makeBlockChain(chain.Config(), chain.CurrentBlock(), 3, ethash.NewFaker(), chain.db, canonicalSeed)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to extend chain: %v", err)
+	}
+	if finalized := chain.CurrentFinalizedBlock(); finalized.NumberU64() != 11 {
+		t.Fatalf("finalized block mismatch: have %d, want %d", finalized.NumberU64(), 11)
+	}
+	if safe := chain.CurrentSafeBlock(); safe.NumberU64() != 11 {
+		t.Fatalf("safe block mismatch: have %d, want %d", safe.NumberU64(), 11)
+	}
+}
+
+// Tests that the chain can't be rewound below the finalized block.
+func TestSetHeadFinalized(t *testing.T) {
+	_, _, chain, err := newCanonical(&finalityEngine{ethash.NewFaker(), 2}, 10, true)
+	if err != nil {
+		t.Fatalf("failed to create canonical chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if err := chain.SetHead(7); !errors.Is(err, ErrRewindFinalized) {
+		t.Fatalf("rewind below finalized block: have %v, want %v", err, ErrRewindFinalized)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 10 {
+		t.Fatalf("head block mismatch: have %d, want %d", head, 10)
+	}
+	if err := chain.SetHead(8); err != nil {
+		t.Fatalf("failed to rewind to finalized block: %v", err)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 8 {
+		t.Fatalf("head block mismatch: have %d, want %d", head, 8)
+	}
+	if finalized := chain.CurrentFinalizedBlock(); finalized == nil || finalized.NumberU64() != 8 {
+		t.Fatalf("finalized block mismatch: have %v, want %d", finalized, 8)
+	}
+}
+
+// Tests that a heavier fork which reverts final blocks isn't reorged to.
+func TestReorgFinalized(t *testing.T) {
+	genDb, _, chain, err := newCanonical(&finalityEngine{ethash.NewFaker(), 2}, 10, true)
+	if err != nil {
+		t.Fatalf("failed to create canonical chain: %v", err)
+	}
+	defer chain.Stop()
+
+	head := chain.CurrentBlock()
+	fork := makeBlockChain(chain.Config(), chain.GetBlockByNumber(5), 10, ethash.NewFaker(), genDb, forkSeed)
+	if _, err := chain.InsertChain(fork); !errors.Is(err, ErrReorgFinalized) {
+		t.Fatalf("reorg below finalized block: have %v, want %v", err, ErrReorgFinalized)
+	}
+	if current := chain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("head block mismatch: have %d, want %d", current.NumberU64(), head.NumberU64())
+	}
+	if finalized := chain.CurrentFinalizedBlock(); finalized.NumberU64() != 8 {
+		t.Fatalf("finalized block mismatch: have %d, want %d", finalized.NumberU64(), 8)
+	}
+}
@@ -32,6 +32,14 @@ var (
 	// ErrNoGenesis is returned when there is no Genesis Block.
 	ErrNoGenesis = errors.New("genesis not found in chain")
 
+	// ErrRewindFinalized is returned if the chain is asked to rewind below the
+	// finalized block of an engine with deterministic finality.
+	ErrRewindFinalized = errors.New("rewind below finalized block")
+
+	// ErrReorgFinalized is returned if a reorg would revert the finalized block
+	// of an engine with deterministic finality.
+	ErrReorgFinalized = errors.New("reorg below finalized block")
+
 	errSideChainReceipts = errors.New("side blocks can't be accepted as ancient chain data")
 )
 
@@ -221,6 +221,39 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, override
 	return newcfg, stored, nil
 }
 
+// LoadChainConfig loads the stored chain config if it is already present in
+// database, otherwise, return the config in the provided genesis specification.
+func LoadChainConfig(db ethdb.Database, genesis *Genesis) (*params.ChainConfig, error) {
+	// Load the stored chain config from the database. It can be nil
+	// in case the database is empty. Notably, we only care about the
+	// chain config corresponds to the canonical chain.
+	stored := rawdb.ReadCanonicalHash(db, 0)
+	if stored != (common.Hash{}) {
+		storedcfg := rawdb.ReadChainConfig(db, stored)
+		if storedcfg != nil {
+			return storedcfg, nil
+		}
+	}
+	// Load the config from the provided genesis specification
+	if genesis != nil {
+		// Reject invalid genesis spec without valid chain config
+		if genesis.Config == nil {
+			return nil, errGenesisNoConfig
+		}
+		// If the canonical genesis header is present, but the chain
+		// config is missing(initialize the empty leveldb with an
+		// external ancient chain segment), ensure the provided genesis
+		// is matched.
+		if stored != (common.Hash{}) && genesis.ToBlock(nil).Hash() != stored {
+			return nil, &GenesisMismatchError{stored, genesis.ToBlock(nil).Hash()}
+		}
+		return genesis.Config, nil
+	}
+	// There is no stored chain config and no new config provided,
+	// In this case the default chain config(mainnet) will be used
+	return params.MainnetChainConfig, nil
+}
+
 func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 	switch {
 	case g != nil:
@@ -286,7 +319,7 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 		Coinbase:   g.Coinbase,
 		Root:       root,
 	}
-	if g.Config.Bft != nil {
+	if g.Config.HotStuff != nil {
 		head.MixDigest = types.BftDigest
 	}
 	if g.GasLimit == 0 {
@@ -438,6 +471,34 @@ func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
 	}
 }
 
+// DeveloperBftGenesisBlock returns the 'geth --dev --dev.bft' genesis block, a
+// hotstuff chain of the basic protocol with a single validator. A zero period
+// seals blocks on demand.
+func DeveloperBftGenesisBlock(period uint64, gasLimit uint64, faucet, validator common.Address) *Genesis {
+	genesis := DeveloperGenesisBlock(period, faucet)
+
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.HotStuff = &params.HotStuffConfig{
+		Protocol:       "basic",
+		RequestTimeout: period*1000 + 1000,
+		MaxTimeout:     period*1000 + 4000,
+		BlockPeriod:    period,
+		LeaderPolicy:   "roundrobin",
+		OnDemand:       period == 0,
+	}
+	genesis.Config = &config
+	genesis.GasLimit = gasLimit
+	genesis.Mixhash = types.BftDigest
+
+	header := &types.Header{Extra: make([]byte, types.BftExtraVanity)}
+	if err := types.BftHeaderFillWithValidators(header, []common.Address{validator}); err != nil {
+		panic(err)
+	}
+	genesis.ExtraData = header.Extra
+	return genesis
+}
+
 func decodePrealloc(data string) GenesisAlloc {
 	var p []struct{ Addr, Balance *big.Int }
 	if err := rlp.NewStream(strings.NewReader(data), 0).Decode(&p); err != nil {
@@ -17,14 +17,17 @@
 package core
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
@@ -210,6 +213,37 @@ func TestGenesis_Commit(t *testing.T) {
 	}
 }
 
+// Tests that the hotstuff developer genesis is sealed by the single validator, and
+// that a zero period seals blocks on demand.
+func TestDeveloperBftGenesis(t *testing.T) {
+	var (
+		faucet    = common.HexToAddress("0xfa")
+		validator = common.HexToAddress("0x01")
+	)
+	for _, period := range []uint64{0, 5} {
+		genesis := DeveloperBftGenesisBlock(period, 11500000, faucet, validator)
+		block := genesis.MustCommit(rawdb.NewMemoryDatabase())
+
+		extra, err := types.ExtractBftExtra(block.Header())
+		if err != nil {
+			t.Fatalf("period %d: invalid bft extra: %v", period, err)
+		}
+		if len(extra.Validators) != 1 || extra.Validators[0] != validator {
+			t.Errorf("period %d: validators mismatch: have %v, want [%v]", period, extra.Validators, validator)
+		}
+		if block.MixDigest() != types.BftDigest {
+			t.Errorf("period %d: mix digest mismatch: have %x, want %x", period, block.MixDigest(), types.BftDigest)
+		}
+		config, err := bft.NewConfig(genesis.Config.HotStuff)
+		if err != nil {
+			t.Fatalf("period %d: invalid hotstuff config: %v", period, err)
+		}
+		if config.OnDemand != (period == 0) || config.BlockPeriod != period {
+			t.Errorf("period %d: sealing mismatch: on demand %v, block period %d", period, config.OnDemand, config.BlockPeriod)
+		}
+	}
+}
+
 func TestReadWriteGenesisAlloc(t *testing.T) {
 	var (
 		db    = rawdb.NewMemoryDatabase()
@@ -239,3 +273,40 @@ func TestReadWriteGenesisAlloc(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the hotstuff section of a genesis spec is loaded as the chain config
+// before and after the genesis is committed, and that it marks the genesis as bft.
+func TestHotStuffGenesisConfig(t *testing.T) {
+	spec := `{
+		"config": {"chainId": 1337, "hotstuff": {"protocol": "basic", "requestTimeout": 8000, "blockPeriod": 5, "leaderPolicy": "sticky", "epoch": 100}},
+		"difficulty": "0x1",
+		"gasLimit": "0x47b760",
+		"alloc": {}
+	}`
+	var genesis Genesis
+	if err := json.Unmarshal([]byte(spec), &genesis); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	want := &params.HotStuffConfig{Protocol: "basic", RequestTimeout: 8000, BlockPeriod: 5, LeaderPolicy: "sticky", Epoch: 100}
+	if !reflect.DeepEqual(genesis.Config.HotStuff, want) {
+		t.Fatalf("hotstuff config mismatch: have %+v, want %+v", genesis.Config.HotStuff, want)
+	}
+	db := rawdb.NewMemoryDatabase()
+	config, err := LoadChainConfig(db, &genesis)
+	if err != nil {
+		t.Fatalf("failed to load genesis config: %v", err)
+	}
+	if !reflect.DeepEqual(config.HotStuff, want) {
+		t.Errorf("genesis hotstuff config mismatch: have %+v, want %+v", config.HotStuff, want)
+	}
+	block := genesis.MustCommit(db)
+	if block.MixDigest() != types.BftDigest {
+		t.Errorf("mix digest mismatch: have %x, want %x", block.MixDigest(), types.BftDigest)
+	}
+	if config, err = LoadChainConfig(db, nil); err != nil {
+		t.Fatalf("failed to load stored config: %v", err)
+	}
+	if !reflect.DeepEqual(config.HotStuff, want) {
+		t.Errorf("stored hotstuff config mismatch: have %+v, want %+v", config.HotStuff, want)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadBftRoundState retrieves the RLP encoded voting state of the local bft
+// validator.
+func ReadBftRoundState(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(bftRoundStateKey)
+	return data
+}
+
+// WriteBftRoundState stores the RLP encoded voting state of the local bft
+// validator. unlike most writers it returns the error, the validator must not
+// vote if it's state is not persisted.
+func WriteBftRoundState(db ethdb.KeyValueWriter, state []byte) error {
+	return db.Put(bftRoundStateKey, state)
+}
+
+// ReadBftDecidedHash retrieves the hash of the highest block decided by the local
+// bft validator.
+func ReadBftDecidedHash(db ethdb.KeyValueReader) common.Hash {
+	data, _ := db.Get(bftDecidedKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteBftDecidedHash stores the hash of the highest block decided by the local
+// bft validator.
+func WriteBftDecidedHash(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Put(bftDecidedKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store bft decided block's hash", "err", err)
+	}
+}
+
+// ReadBftCertifiedHash retrieves the hash of the highest block certified by the
+// local bft validator.
+func ReadBftCertifiedHash(db ethdb.KeyValueReader) common.Hash {
+	data, _ := db.Get(bftCertifiedKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteBftCertifiedHash stores the hash of the highest block certified by the
+// local bft validator.
+func WriteBftCertifiedHash(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Put(bftCertifiedKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store bft certified block's hash", "err", err)
+	}
+}
+
+// ReadBftEvidence retrieves the RLP encoded equivocation evidence of the given
+// hash.
+func ReadBftEvidence(db ethdb.KeyValueReader, hash common.Hash) []byte {
+	data, _ := db.Get(bftEvidenceKey(hash))
+	return data
+}
+
+// HasBftEvidence checks if the equivocation evidence of the given hash is known.
+func HasBftEvidence(db ethdb.KeyValueReader, hash common.Hash) bool {
+	has, _ := db.Has(bftEvidenceKey(hash))
+	return has
+}
+
+// WriteBftEvidence stores the RLP encoded equivocation evidence of the given hash.
+func WriteBftEvidence(db ethdb.KeyValueWriter, hash common.Hash, evidence []byte) {
+	if err := db.Put(bftEvidenceKey(hash), evidence); err != nil {
+		log.Crit("Failed to store bft evidence", "err", err)
+	}
+}
+
+// DeleteBftEvidence removes the equivocation evidence of the given hash.
+func DeleteBftEvidence(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(bftEvidenceKey(hash)); err != nil {
+		log.Crit("Failed to delete bft evidence", "err", err)
+	}
+}
+
+// ReadAllBftEvidence retrieves all the RLP encoded equivocation evidence stored
+// in the database.
+func ReadAllBftEvidence(db ethdb.Iteratee) [][]byte {
+	it := db.NewIterator(BftEvidencePrefix, nil)
+	defer it.Release()
+
+	var evidence [][]byte
+	for it.Next() {
+		if key := it.Key(); len(key) == len(BftEvidencePrefix)+common.HashLength {
+			evidence = append(evidence, common.CopyBytes(it.Value()))
+		}
+	}
+	return evidence
+}
+
+// ReadBftEpochIndex retrieves the start heights of the stored bft epochs in
+// ascending order.
+func ReadBftEpochIndex(db ethdb.KeyValueReader) []uint64 {
+	data, _ := db.Get(bftEpochIndexKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var heights []uint64
+	if err := rlp.DecodeBytes(data, &heights); err != nil {
+		log.Error("Invalid bft epoch index", "err", err)
+		return nil
+	}
+	return heights
+}
+
+// WriteBftEpochIndex stores the start heights of the bft epochs.
+func WriteBftEpochIndex(db ethdb.KeyValueWriter, heights []uint64) {
+	data, err := rlp.EncodeToBytes(heights)
+	if err != nil {
+		log.Crit("Failed to encode bft epoch index", "err", err)
+	}
+	if err := db.Put(bftEpochIndexKey, data); err != nil {
+		log.Crit("Failed to store bft epoch index", "err", err)
+	}
+}
+
+// ReadBftEpoch retrieves the RLP encoded bft epoch starting at the given height.
+// the epochs moved to the freezer are not in the key-value store.
+func ReadBftEpoch(db ethdb.KeyValueReader, height uint64) []byte {
+	data, _ := db.Get(bftEpochKey(height))
+	return data
+}
+
+// WriteBftEpoch stores the RLP encoded bft epoch starting at the given height.
+func WriteBftEpoch(db ethdb.KeyValueWriter, height uint64, epoch []byte) {
+	if err := db.Put(bftEpochKey(height), epoch); err != nil {
+		log.Crit("Failed to store bft epoch", "err", err)
+	}
+}
+
+// DeleteBftEpoch removes the bft epoch starting at the given height.
+func DeleteBftEpoch(db ethdb.KeyValueWriter, height uint64) {
+	if err := db.Delete(bftEpochKey(height)); err != nil {
+		log.Crit("Failed to delete bft epoch", "err", err)
+	}
+}
+
+// ReadBftParticipation retrieves the RLP encoded participation of the validators
+// in the blocks of the given index section.
+func ReadBftParticipation(db ethdb.KeyValueReader, section uint64) []byte {
+	data, _ := db.Get(bftParticipationKey(section))
+	return data
+}
+
+// WriteBftParticipation stores the RLP encoded participation of the validators in
+// the blocks of the given index section.
+func WriteBftParticipation(db ethdb.KeyValueWriter, section uint64, participation []byte) {
+	if err := db.Put(bftParticipationKey(section), participation); err != nil {
+		log.Crit("Failed to store bft participation", "err", err)
+	}
+}
+
+// ReadBftFrozenEpoch retrieves the RLP encoded bft epoch of the given index from
+// the bft freezer.
+func ReadBftFrozenEpoch(db ethdb.AncientReaderOp, index uint64) []byte {
+	data, _ := db.Ancient(bftFreezerEpochTable, index)
+	return data
+}
+
+// WriteBftFrozenEpochs appends the RLP encoded bft epochs to the bft freezer,
+// the first epoch is stored at the given index.
+func WriteBftFrozenEpochs(db ethdb.AncientWriter, index uint64, epochs [][]byte) error {
+	_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i, epoch := range epochs {
+			if err := op.AppendRaw(bftFreezerEpochTable, index+uint64(i), epoch); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
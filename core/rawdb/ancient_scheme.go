@@ -16,6 +16,8 @@
 
 package rawdb
 
+import "path/filepath"
+
 // The list of table names of chain freezer.
 const (
 	// chainFreezerHeaderTable indicates the name of the freezer header table.
@@ -44,10 +46,29 @@ var chainFreezerNoSnappy = map[string]bool{
 	chainFreezerDifficultyTable: true,
 }
 
+// The list of table names of bft freezer.
+const (
+	// bftFreezerEpochTable indicates the name of the freezer bft epoch table.
+	bftFreezerEpochTable = "epochs"
+)
+
+// bftFreezerNoSnappy configures whether compression is disabled for the bft
+// ancient-tables.
+var bftFreezerNoSnappy = map[string]bool{
+	bftFreezerEpochTable: false,
+}
+
 // The list of identifiers of ancient stores.
 var (
 	chainFreezerName = "chain" // the folder name of chain segment ancient store.
+	bftFreezerName   = "bft"   // the folder name of bft epoch ancient store.
 )
 
 // freezers the collections of all builtin freezers.
-var freezers = []string{chainFreezerName}
+var freezers = []string{chainFreezerName, bftFreezerName}
+
+// NewBftFreezer initializes the freezer for the finalized bft epochs, it's
+// located in the given root ancient directory.
+func NewBftFreezer(ancient string, readonly bool) (*Freezer, error) {
+	return NewFreezer(filepath.Join(ancient, bftFreezerName), "eth/db/bft", readonly, freezerTableSize, bftFreezerNoSnappy)
+}
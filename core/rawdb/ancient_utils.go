@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -82,6 +83,35 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 			info.tail = tail
 			infos = append(infos, info)
 
+		case bftFreezerName:
+			// The bft freezer is only opened by the bft engine, skip it if the
+			// database has no ancient store or the freezer is not initialized.
+			datadir, err := db.AncientDatadir()
+			if err != nil || !common.FileExist(filepath.Join(datadir, bftFreezerName)) {
+				continue
+			}
+			f, err := NewBftFreezer(datadir, true)
+			if err != nil {
+				return nil, err
+			}
+			info := freezerInfo{name: freezer}
+			for table := range bftFreezerNoSnappy {
+				size, err := f.AncientSize(table)
+				if err != nil {
+					f.Close()
+					return nil, err
+				}
+				info.sizes = append(info.sizes, tableSize{name: table, size: common.StorageSize(size)})
+			}
+			ancients, _ := f.Ancients()
+			tail, _ := f.Tail()
+			f.Close()
+			if ancients == 0 {
+				continue
+			}
+			info.head, info.tail = ancients-1, tail
+			infos = append(infos, info)
+
 		default:
 			return nil, fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 		}
@@ -101,6 +131,8 @@ func InspectFreezerTable(ancient string, freezerName string, tableName string, s
 	switch freezerName {
 	case chainFreezerName:
 		path, tables = resolveChainFreezerDir(ancient), chainFreezerNoSnappy
+	case bftFreezerName:
+		path, tables = filepath.Join(ancient, bftFreezerName), bftFreezerNoSnappy
 	default:
 		return fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 	}
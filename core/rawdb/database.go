@@ -364,21 +364,24 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		logged = time.Now()
 
 		// Key-value store statistics
-		headers         stat
-		bodies          stat
-		receipts        stat
-		tds             stat
-		numHashPairings stat
-		hashNumPairings stat
-		tries           stat
-		codes           stat
-		txLookups       stat
-		accountSnaps    stat
-		storageSnaps    stat
-		preimages       stat
-		bloomBits       stat
-		beaconHeaders   stat
-		cliqueSnaps     stat
+		headers          stat
+		bodies           stat
+		receipts         stat
+		tds              stat
+		numHashPairings  stat
+		hashNumPairings  stat
+		tries            stat
+		codes            stat
+		txLookups        stat
+		accountSnaps     stat
+		storageSnaps     stat
+		preimages        stat
+		bloomBits        stat
+		beaconHeaders    stat
+		cliqueSnaps      stat
+		bftEvidence      stat
+		bftEpochs        stat
+		bftParticipation stat
 
 		// Les statistic
 		chtTrieNodes   stat
@@ -435,6 +438,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			beaconHeaders.Add(size)
 		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
+		case bytes.HasPrefix(key, BftEvidencePrefix) && len(key) == len(BftEvidencePrefix)+common.HashLength:
+			bftEvidence.Add(size)
+		case bytes.HasPrefix(key, BftEpochPrefix) && len(key) == len(BftEpochPrefix)+8:
+			bftEpochs.Add(size)
+		case bytes.HasPrefix(key, BftParticipationPrefix) && len(key) == len(BftParticipationPrefix)+8:
+			bftParticipation.Add(size)
+		case bytes.HasPrefix(key, BftParticipationIndexPrefix):
+			bftParticipation.Add(size)
 		case bytes.HasPrefix(key, ChtTablePrefix) ||
 			bytes.HasPrefix(key, ChtIndexTablePrefix) ||
 			bytes.HasPrefix(key, ChtPrefix): // Canonical hash trie
@@ -450,6 +461,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
+				bftRoundStateKey, bftEpochIndexKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -484,6 +496,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
 		{"Key-Value store", "Beacon sync headers", beaconHeaders.Size(), beaconHeaders.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
+		{"Key-Value store", "BFT evidence", bftEvidence.Size(), bftEvidence.Count()},
+		{"Key-Value store", "BFT epochs", bftEpochs.Size(), bftEpochs.Count()},
+		{"Key-Value store", "BFT participation", bftParticipation.Size(), bftParticipation.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Light client", "CHT trie nodes", chtTrieNodes.Size(), chtTrieNodes.Count()},
 		{"Light client", "Bloom trie nodes", bloomTrieNodes.Size(), bloomTrieNodes.Count()},
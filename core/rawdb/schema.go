@@ -84,6 +84,20 @@ var (
 	// transitionStatusKey tracks the eth2 transition status.
 	transitionStatusKey = []byte("eth2-transition")
 
+	// bftRoundStateKey tracks the voting state of the local bft validator.
+	bftRoundStateKey = []byte("BftRoundState")
+
+	// bftEpochIndexKey tracks the start heights of the bft epochs.
+	bftEpochIndexKey = []byte("BftEpochIndex")
+
+	// bftDecidedKey tracks the hash of the highest block decided by the local bft
+	// validator.
+	bftDecidedKey = []byte("BftDecided")
+
+	// bftCertifiedKey tracks the hash of the highest block certified by the local
+	// bft validator.
+	bftCertifiedKey = []byte("BftCertified")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -119,6 +133,12 @@ var (
 
 	CliqueSnapshotPrefix = []byte("clique-")
 
+	BftEvidencePrefix = []byte("bft-evidence-") // BftEvidencePrefix + hash -> equivocation evidence
+	BftEpochPrefix    = []byte("bft-epoch-")    // BftEpochPrefix + start height (uint64 big endian) -> epoch
+
+	BftParticipationPrefix      = []byte("bft-participation-") // BftParticipationPrefix + section (uint64 big endian) -> validator participation
+	BftParticipationIndexPrefix = []byte("bft-pindex-")        // Chain indexer metadata of the validator participation
+
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
 )
@@ -131,6 +151,21 @@ type LegacyTxLookupEntry struct {
 	Index      uint64
 }
 
+// bftEvidenceKey = BftEvidencePrefix + hash
+func bftEvidenceKey(hash common.Hash) []byte {
+	return append(BftEvidencePrefix, hash.Bytes()...)
+}
+
+// bftEpochKey = BftEpochPrefix + start height (uint64 big endian)
+func bftEpochKey(height uint64) []byte {
+	return append(BftEpochPrefix, encodeBlockNumber(height)...)
+}
+
+// bftParticipationKey = BftParticipationPrefix + section (uint64 big endian)
+func bftParticipationKey(section uint64) []byte {
+	return append(BftParticipationPrefix, encodeBlockNumber(section)...)
+}
+
 // encodeBlockNumber encodes a block number as big endian uint64
 func encodeBlockNumber(number uint64) []byte {
 	enc := make([]byte, 8)
@@ -66,6 +66,12 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		allLogs     []*types.Log
 		gp          = new(GasPool).AddGas(block.GasLimit())
 	)
+	// Check the header fields the consensus engine derives from the parent state
+	if verifier, ok := p.engine.(consensus.StateVerifier); ok {
+		if err := verifier.VerifyState(p.bc, header, statedb); err != nil {
+			return nil, nil, 0, err
+		}
+	}
 	// Mutate the block and state according to any hard-fork specs
 	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 		misc.ApplyDAOHardFork(statedb)
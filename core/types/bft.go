@@ -20,35 +20,69 @@ var (
 	ErrInvalidBftHeaderExtra = errors.New("invalid istanbul header extra-data")
 )
 
+// BftVote is a proposal of the block proposer to add or remove a validator at the
+// next epoch boundary.
+type BftVote struct {
+	Candidate common.Address // address of the validator to add or remove
+	Authorize bool           // true to add the candidate, false to remove it
+	BLSKey    []byte         `rlp:"optional"` // bls public key and proof of possession of an authorized candidate, only used by bls signatures
+}
+
 type BftExtra struct {
-	Validators    []common.Address // consensus participants address for next epoch, and in the first block, it contains all genesis validators. keep empty if no epoch change.
-	Seal          []byte           // proposer signature
-	CommittedSeal [][]byte         // consensus participants signatures and it's size should be greater than 2/3 of validators
-	Salt          []byte           // omit empty
+	Validators     []common.Address // consensus participants address for next epoch, and in the first block, it contains all genesis validators. keep empty if no epoch change.
+	Seal           []byte           // proposer signature
+	CommittedSeal  [][]byte         // consensus participants signatures and it's size should be greater than 2/3 of validators
+	Salt           []byte           // omit empty
+	VRFProof       []byte           // proposer's vrf proof over parent hash and height, only used by the VRF proposer policy. omit empty
+	Vote           *BftVote         // proposer's validator vote, tallied until the epoch boundary. omit empty
+	Powers         []uint64         // voting power of `Validators` by index, only used by weighted validator sets. omit empty
+	BLSKeys        [][]byte         // bls public key and proof of possession of `Validators` by index, only used by bls signatures. omit empty
+	AggregatedSeal []byte           // aggregated bls signature of the committers, replaces `CommittedSeal` with bls signatures. omit empty
+	Bitmap         []byte           // committers of `AggregatedSeal` by validator index, bit i is `Bitmap[i/8] & (1 << (i%8))`. omit empty
 }
 
 // EncodeRLP serializes ist into the Ethereum RLP format.
 func (ist *BftExtra) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, []interface{}{
+	fields := []interface{}{
 		ist.Validators,
 		ist.Seal,
 		ist.CommittedSeal,
 		ist.Salt,
-	})
+	}
+	// keep the legacy layout for chains without vrf proposer selection, votes,
+	// weights and bls signatures, optional fields can only be omitted from the tail.
+	tail := []interface{}{ist.VRFProof, ist.Vote, ist.Powers, ist.BLSKeys, ist.AggregatedSeal, ist.Bitmap}
+	present := []bool{len(ist.VRFProof) > 0, ist.Vote != nil, len(ist.Powers) > 0, len(ist.BLSKeys) > 0, len(ist.AggregatedSeal) > 0, len(ist.Bitmap) > 0}
+	last := -1
+	for i, ok := range present {
+		if ok {
+			last = i
+		}
+	}
+	fields = append(fields, tail[:last+1]...)
+	return rlp.Encode(w, fields)
 }
 
 // DecodeRLP implements rlp.Decoder, and load the istanbul fields from a RLP stream.
 func (ist *BftExtra) DecodeRLP(s *rlp.Stream) error {
 	var extra struct {
-		Validators    []common.Address
-		Seal          []byte
-		CommittedSeal [][]byte
-		Salt          []byte
+		Validators     []common.Address
+		Seal           []byte
+		CommittedSeal  [][]byte
+		Salt           []byte
+		VRFProof       []byte   `rlp:"optional"`
+		Vote           *BftVote `rlp:"nil,optional"`
+		Powers         []uint64 `rlp:"optional"`
+		BLSKeys        [][]byte `rlp:"optional"`
+		AggregatedSeal []byte   `rlp:"optional"`
+		Bitmap         []byte   `rlp:"optional"`
 	}
 	if err := s.Decode(&extra); err != nil {
 		return err
 	}
 	ist.Validators, ist.Seal, ist.CommittedSeal, ist.Salt = extra.Validators, extra.Seal, extra.CommittedSeal, extra.Salt
+	ist.VRFProof, ist.Vote, ist.Powers = extra.VRFProof, extra.Vote, extra.Powers
+	ist.BLSKeys, ist.AggregatedSeal, ist.Bitmap = extra.BLSKeys, extra.AggregatedSeal, extra.Bitmap
 	return nil
 }
 
@@ -86,6 +120,7 @@ func BftFilteredHeader(h *Header, keepSeal bool) *Header {
 		extra.Seal = []byte{}
 	}
 	extra.CommittedSeal = [][]byte{}
+	extra.AggregatedSeal, extra.Bitmap = nil, nil
 	//extra.Salt = []byte{}
 
 	payload, err := rlp.EncodeToBytes(&extra)
@@ -124,4 +159,3 @@ func BftHeaderFillWithValidators(header *Header, vals []common.Address) error {
 	header.Extra = append(buf.Bytes(), payload...)
 	return nil
 }
-
@@ -0,0 +1,312 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bls implements BLS signatures over the BLS12-381 curve, following the
+// proof of possession scheme of the IETF BLS signature draft with the minimal
+// signature size variant: signatures live in G1 and public keys in G2.
+//
+// Signatures of many keys over the same message can be aggregated into a single
+// signature, which is verified against the sum of the public keys. Aggregation
+// over the same message is only secure if every key proved the possession of its
+// secret, see ProvePossession.
+package bls
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// SecretKeyLength is the byte length of an encoded secret key.
+	SecretKeyLength = 32
+	// PublicKeyLength is the byte length of an uncompressed G2 public key.
+	PublicKeyLength = 192
+	// SignatureLength is the byte length of an uncompressed G1 signature.
+	SignatureLength = 96
+)
+
+var (
+	// dstSignature is the hash to curve domain of message signatures.
+	dstSignature = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+	// dstPossession is the hash to curve domain of proofs of possession.
+	dstPossession = []byte("BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+
+	// fieldModulus is the modulus of the base field of BLS12-381.
+	fieldModulus, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+)
+
+var (
+	// ErrInvalidSecretKey is returned if a secret key is malformed or zero.
+	ErrInvalidSecretKey = errors.New("invalid bls secret key")
+	// ErrInvalidPublicKey is returned if a public key is malformed, not in the
+	// prime order subgroup or the identity.
+	ErrInvalidPublicKey = errors.New("invalid bls public key")
+	// ErrInvalidSignature is returned if a signature is malformed or not in the
+	// prime order subgroup.
+	ErrInvalidSignature = errors.New("invalid bls signature")
+	// ErrEmptyAggregate is returned when aggregating nothing.
+	ErrEmptyAggregate = errors.New("empty bls aggregate")
+)
+
+// SecretKey is a BLS secret scalar.
+type SecretKey struct {
+	s *big.Int
+}
+
+// PublicKey is a BLS public key, a point of G2.
+type PublicKey struct {
+	p *bls12381.PointG2
+}
+
+// Signature is a BLS signature or an aggregate of signatures, a point of G1.
+type Signature struct {
+	p *bls12381.PointG1
+}
+
+// GenerateKey creates a random secret key with the entropy of the given reader.
+func GenerateKey(rand io.Reader) (*SecretKey, error) {
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(rand, ikm); err != nil {
+		return nil, err
+	}
+	return DeriveKey(ikm)
+}
+
+// DeriveKey deterministically derives a secret key from at least 32 bytes of
+// input key material, with the KeyGen procedure of the IETF draft.
+func DeriveKey(ikm []byte) (*SecretKey, error) {
+	if len(ikm) < 32 {
+		return nil, ErrInvalidSecretKey
+	}
+	order := bls12381.NewG1().Q()
+	salt := []byte("BLS-SIG-KEYGEN-SALT-")
+	for {
+		h := sha256.Sum256(salt)
+		salt = h[:]
+
+		okm := make([]byte, 48)
+		r := hkdf.New(sha256.New, append(append([]byte{}, ikm...), 0x00), salt, []byte{0x00, 0x30})
+		if _, err := io.ReadFull(r, okm); err != nil {
+			return nil, err
+		}
+		s := new(big.Int).Mod(new(big.Int).SetBytes(okm), order)
+		if s.Sign() != 0 {
+			return &SecretKey{s: s}, nil
+		}
+	}
+}
+
+// SecretKeyFromBytes decodes a big endian secret scalar.
+func SecretKeyFromBytes(b []byte) (*SecretKey, error) {
+	if len(b) != SecretKeyLength {
+		return nil, ErrInvalidSecretKey
+	}
+	s := new(big.Int).SetBytes(b)
+	if s.Sign() == 0 || s.Cmp(bls12381.NewG1().Q()) >= 0 {
+		return nil, ErrInvalidSecretKey
+	}
+	return &SecretKey{s: s}, nil
+}
+
+// Bytes returns the big endian encoding of the secret scalar.
+func (sk *SecretKey) Bytes() []byte {
+	b := make([]byte, SecretKeyLength)
+	return sk.s.FillBytes(b)
+}
+
+// PublicKey returns the public key of the secret key.
+func (sk *SecretKey) PublicKey() *PublicKey {
+	g2 := bls12381.NewG2()
+	return &PublicKey{p: g2.MulScalar(g2.New(), g2.One(), sk.s)}
+}
+
+// Sign signs the message.
+func (sk *SecretKey) Sign(msg []byte) *Signature {
+	return sk.sign(msg, dstSignature)
+}
+
+// ProvePossession signs the public key of the secret key, proving that the owner
+// of the public key knows the secret. Without this proof a rogue key could be
+// chosen to forge aggregate signatures of other keys.
+func (sk *SecretKey) ProvePossession() *Signature {
+	return sk.sign(sk.PublicKey().Bytes(), dstPossession)
+}
+
+func (sk *SecretKey) sign(msg, dst []byte) *Signature {
+	g1 := bls12381.NewG1()
+	h := hashToG1(msg, dst)
+	return &Signature{p: g1.MulScalar(g1.New(), h, sk.s)}
+}
+
+// PublicKeyFromBytes decodes an uncompressed public key and checks that it is a
+// valid key of the prime order subgroup.
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	if len(b) != PublicKeyLength {
+		return nil, ErrInvalidPublicKey
+	}
+	g2 := bls12381.NewG2()
+	p, err := g2.FromBytes(b)
+	if err != nil || g2.IsZero(p) || !g2.InCorrectSubgroup(p) {
+		return nil, ErrInvalidPublicKey
+	}
+	return &PublicKey{p: p}, nil
+}
+
+// Bytes returns the uncompressed encoding of the public key.
+func (pk *PublicKey) Bytes() []byte {
+	return bls12381.NewG2().ToBytes(pk.p)
+}
+
+// Verify checks the signature of the message.
+func (pk *PublicKey) Verify(msg []byte, sig *Signature) bool {
+	return verify(pk.p, msg, dstSignature, sig)
+}
+
+// VerifyPossession checks the proof of possession of the public key.
+func (pk *PublicKey) VerifyPossession(proof *Signature) bool {
+	return verify(pk.p, pk.Bytes(), dstPossession, proof)
+}
+
+// AggregatePublicKeys sums the public keys, the result verifies the aggregate of
+// their signatures over the same message.
+func AggregatePublicKeys(keys []*PublicKey) (*PublicKey, error) {
+	if len(keys) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	g2 := bls12381.NewG2()
+	sum := g2.Zero()
+	for _, key := range keys {
+		g2.Add(sum, sum, key.p)
+	}
+	return &PublicKey{p: sum}, nil
+}
+
+// SignatureFromBytes decodes an uncompressed signature and checks that it is in
+// the prime order subgroup.
+func SignatureFromBytes(b []byte) (*Signature, error) {
+	if len(b) != SignatureLength {
+		return nil, ErrInvalidSignature
+	}
+	g1 := bls12381.NewG1()
+	p, err := g1.FromBytes(b)
+	if err != nil || !g1.InCorrectSubgroup(p) {
+		return nil, ErrInvalidSignature
+	}
+	return &Signature{p: p}, nil
+}
+
+// Bytes returns the uncompressed encoding of the signature.
+func (sig *Signature) Bytes() []byte {
+	return bls12381.NewG1().ToBytes(sig.p)
+}
+
+// AggregateSignatures sums the signatures into a single one.
+func AggregateSignatures(sigs []*Signature) (*Signature, error) {
+	if len(sigs) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	g1 := bls12381.NewG1()
+	sum := g1.Zero()
+	for _, sig := range sigs {
+		g1.Add(sum, sum, sig.p)
+	}
+	return &Signature{p: sum}, nil
+}
+
+// FastAggregateVerify checks the aggregate signature of the keys over the same
+// message. The keys must have proved their possession.
+func FastAggregateVerify(keys []*PublicKey, msg []byte, sig *Signature) bool {
+	pk, err := AggregatePublicKeys(keys)
+	if err != nil {
+		return false
+	}
+	return pk.Verify(msg, sig)
+}
+
+// verify checks e(sig, g2) == e(H(msg), pk).
+func verify(pk *bls12381.PointG2, msg, dst []byte, sig *Signature) bool {
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	if sig == nil || g1.IsZero(sig.p) || g2.IsZero(pk) {
+		return false
+	}
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(new(bls12381.PointG1).Set(sig.p), g2.One())
+	engine.AddPairInv(hashToG1(msg, dst), new(bls12381.PointG2).Set(pk))
+	return engine.Check()
+}
+
+// hashToG1 implements hash_to_curve of the BLS12381G1_XMD:SHA-256_SSWU_RO_ suite.
+// The simplified SWU map of bls12381 also clears the cofactor of each point, which
+// is the same as clearing it from the sum.
+func hashToG1(msg, dst []byte) *bls12381.PointG1 {
+	g1 := bls12381.NewG1()
+	uniform := expandMessageXMD(msg, dst, 128)
+
+	sum := g1.Zero()
+	for i := 0; i < 2; i++ {
+		u := new(big.Int).SetBytes(uniform[i*64 : (i+1)*64])
+		u.Mod(u, fieldModulus)
+		p, err := g1.MapToCurve(u.FillBytes(make([]byte, 48)))
+		if err != nil {
+			// The reduced input is always a valid field element
+			panic(err)
+		}
+		g1.Add(sum, sum, p)
+	}
+	return g1.Affine(sum)
+}
+
+// expandMessageXMD implements expand_message_xmd of RFC 9380 with SHA-256.
+func expandMessageXMD(msg, dst []byte, length int) []byte {
+	const hashSize = sha256.Size
+
+	ell := (length + hashSize - 1) / hashSize
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, sha256.BlockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(length >> 8), byte(length), 0x00})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{0x01})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+
+	out := make([]byte, 0, ell*hashSize)
+	out = append(out, bi...)
+	for i := 2; i <= ell; i++ {
+		mixed := make([]byte, hashSize)
+		for j := range mixed {
+			mixed[j] = b0[j] ^ bi[j]
+		}
+		h.Reset()
+		h.Write(mixed)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:length]
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+// Test vectors of RFC 9380, appendix K.1 and J.9.1.
+func TestHashToCurve(t *testing.T) {
+	want := common.FromHex("68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235")
+	if have := expandMessageXMD(nil, []byte("QUUX-V01-CS02-with-expander-SHA256-128"), 32); !bytes.Equal(have, want) {
+		t.Fatalf("expand_message_xmd mismatch: have %x, want %x", have, want)
+	}
+	p := hashToG1(nil, []byte("QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_RO_"))
+	want = common.FromHex("052926add2207b76ca4fa57a8734416c8dc95e24501772c814278700eed6d1e4e8cf62d9c09db0fac349612b759e79a1" +
+		"08ba738453bfed09cb546dbb0783dbb3a5f1f566ed67bb6be0e8c67e2e81a4cc68ee29813bb7994998f3eae0c9c6a265")
+	if have := bls12381.NewG1().ToBytes(p); !bytes.Equal(have, want) {
+		t.Fatalf("hash_to_curve mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	msg := []byte("sample message")
+	sig := sk.Sign(msg)
+	if !sk.PublicKey().Verify(msg, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if sk.PublicKey().Verify([]byte("other message"), sig) {
+		t.Fatal("signature of other message accepted")
+	}
+	other, _ := GenerateKey(rand.Reader)
+	if other.PublicKey().Verify(msg, sig) {
+		t.Fatal("signature of other key accepted")
+	}
+	if !sk.PublicKey().VerifyPossession(sk.ProvePossession()) {
+		t.Fatal("valid proof of possession rejected")
+	}
+	if sk.PublicKey().VerifyPossession(sig) || other.PublicKey().VerifyPossession(sk.ProvePossession()) {
+		t.Fatal("invalid proof of possession accepted")
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	sk, _ := GenerateKey(rand.Reader)
+	dec, err := SecretKeyFromBytes(sk.Bytes())
+	if err != nil || !bytes.Equal(dec.Bytes(), sk.Bytes()) {
+		t.Fatalf("secret key round trip failed: %v", err)
+	}
+	pk, err := PublicKeyFromBytes(sk.PublicKey().Bytes())
+	if err != nil || !bytes.Equal(pk.Bytes(), sk.PublicKey().Bytes()) {
+		t.Fatalf("public key round trip failed: %v", err)
+	}
+	sig, err := SignatureFromBytes(sk.Sign(nil).Bytes())
+	if err != nil || !pk.Verify(nil, sig) {
+		t.Fatalf("signature round trip failed: %v", err)
+	}
+	if _, err := PublicKeyFromBytes(make([]byte, PublicKeyLength)); err != ErrInvalidPublicKey {
+		t.Fatalf("identity public key accepted: %v", err)
+	}
+	if _, err := SecretKeyFromBytes(make([]byte, SecretKeyLength)); err != ErrInvalidSecretKey {
+		t.Fatalf("zero secret key accepted: %v", err)
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	ikm := bytes.Repeat([]byte{0x01}, 32)
+	a, _ := DeriveKey(ikm)
+	b, _ := DeriveKey(ikm)
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatal("key derivation is not deterministic")
+	}
+	if _, err := DeriveKey(ikm[:31]); err != ErrInvalidSecretKey {
+		t.Fatalf("short key material accepted: %v", err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	var (
+		msg  = []byte("block hash")
+		keys []*PublicKey
+		sigs []*Signature
+	)
+	for i := 0; i < 4; i++ {
+		sk, _ := GenerateKey(rand.Reader)
+		keys = append(keys, sk.PublicKey())
+		sigs = append(sigs, sk.Sign(msg))
+	}
+	agg, err := AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatalf("failed to aggregate: %v", err)
+	}
+	if !FastAggregateVerify(keys, msg, agg) {
+		t.Fatal("valid aggregate rejected")
+	}
+	if FastAggregateVerify(keys[:3], msg, agg) {
+		t.Fatal("aggregate accepted with missing key")
+	}
+	partial, _ := AggregateSignatures(sigs[:3])
+	if FastAggregateVerify(keys, msg, partial) {
+		t.Fatal("partial aggregate accepted with all keys")
+	}
+	if _, err := AggregateSignatures(nil); err != ErrEmptyAggregate {
+		t.Fatalf("empty aggregate: have %v, want %v", err, ErrEmptyAggregate)
+	}
+}
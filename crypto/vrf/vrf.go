@@ -0,0 +1,207 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package vrf implements an elliptic curve verifiable random function over the
+// secp256k1 curve, following the ECVRF construction of RFC 9381 with the
+// try-and-increment hash to curve method.
+//
+// The key pair is a plain secp256k1 key, so nodes can prove with the same key
+// they use for p2p and consensus signing, and verifiers only need the public key
+// that is recovered from any ordinary signature of the prover.
+package vrf
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	suite = 0xfe // suite string, private range of RFC 9381 for secp256k1-SHA256-TAI
+
+	pointLength     = 33 // compressed curve point
+	challengeLength = 16 // truncated challenge
+	scalarLength    = 32
+
+	// ProofLength is the byte length of an encoded proof.
+	ProofLength = pointLength + challengeLength + scalarLength
+)
+
+var (
+	// ErrInvalidProof is returned if a proof is malformed or does not match the input.
+	ErrInvalidProof = errors.New("invalid vrf proof")
+	// ErrInvalidKey is returned if the proving or verifying key is unusable.
+	ErrInvalidKey = errors.New("invalid vrf key")
+	// errHashToCurve is returned if no curve point was found for the input.
+	errHashToCurve = errors.New("vrf hash to curve failed")
+)
+
+// Prove computes the proof that `output` is the unique random value of the key
+// for the given input. The output can be retrieved from the proof by ProofToHash.
+func Prove(key *ecdsa.PrivateKey, alpha []byte) ([]byte, error) {
+	if key == nil || key.D == nil || key.D.Sign() <= 0 {
+		return nil, ErrInvalidKey
+	}
+	curve := crypto.S256()
+	n := curve.Params().N
+	pk := crypto.CompressPubkey(&key.PublicKey)
+
+	hx, hy, err := hashToCurve(pk, alpha)
+	if err != nil {
+		return nil, err
+	}
+	sk := math.PaddedBigBytes(key.D, scalarLength)
+	gx, gy := curve.ScalarMult(hx, hy, sk)
+
+	k := nonce(sk, compress(hx, hy))
+	ux, uy := curve.ScalarBaseMult(math.PaddedBigBytes(k, scalarLength))
+	vx, vy := curve.ScalarMult(hx, hy, math.PaddedBigBytes(k, scalarLength))
+
+	c := challenge(compress(hx, hy), compress(gx, gy), compress(ux, uy), compress(vx, vy))
+	s := new(big.Int).Mul(c, key.D)
+	s.Add(s, k)
+	s.Mod(s, n)
+
+	proof := make([]byte, 0, ProofLength)
+	proof = append(proof, compress(gx, gy)...)
+	proof = append(proof, math.PaddedBigBytes(c, challengeLength)...)
+	proof = append(proof, math.PaddedBigBytes(s, scalarLength)...)
+	return proof, nil
+}
+
+// Verify checks that the proof was generated by the owner of the public key for
+// the given input, and returns the random output of the proof.
+func Verify(pub *ecdsa.PublicKey, alpha, proof []byte) (common.Hash, error) {
+	if pub == nil || pub.X == nil || pub.Y == nil {
+		return common.Hash{}, ErrInvalidKey
+	}
+	curve := crypto.S256()
+	n := curve.Params().N
+
+	gx, gy, c, s, err := decodeProof(proof)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hx, hy, err := hashToCurve(crypto.CompressPubkey(pub), alpha)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// U = s*G - c*Y, V = s*H - c*Gamma
+	negC := math.PaddedBigBytes(new(big.Int).Sub(n, c), scalarLength)
+	sb := math.PaddedBigBytes(s, scalarLength)
+
+	ux, uy := curve.ScalarBaseMult(sb)
+	cyx, cyy := curve.ScalarMult(pub.X, pub.Y, negC)
+	vx, vy := curve.ScalarMult(hx, hy, sb)
+	cgx, cgy := curve.ScalarMult(gx, gy, negC)
+	if ux == nil || cyx == nil || vx == nil || cgx == nil {
+		return common.Hash{}, ErrInvalidProof
+	}
+	ux, uy = curve.Add(ux, uy, cyx, cyy)
+	vx, vy = curve.Add(vx, vy, cgx, cgy)
+
+	if challenge(compress(hx, hy), compress(gx, gy), compress(ux, uy), compress(vx, vy)).Cmp(c) != 0 {
+		return common.Hash{}, ErrInvalidProof
+	}
+	return gammaToHash(gx, gy), nil
+}
+
+// ProofToHash returns the random output of the proof without verifying it. It
+// should only be used on proofs that have already been verified.
+func ProofToHash(proof []byte) (common.Hash, error) {
+	gx, gy, _, _, err := decodeProof(proof)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return gammaToHash(gx, gy), nil
+}
+
+func decodeProof(proof []byte) (gx, gy, c, s *big.Int, err error) {
+	if len(proof) != ProofLength {
+		return nil, nil, nil, nil, ErrInvalidProof
+	}
+	gamma, err := crypto.DecompressPubkey(proof[:pointLength])
+	if err != nil {
+		return nil, nil, nil, nil, ErrInvalidProof
+	}
+	c = new(big.Int).SetBytes(proof[pointLength : pointLength+challengeLength])
+	s = new(big.Int).SetBytes(proof[pointLength+challengeLength:])
+	if c.Sign() == 0 || s.Sign() == 0 || s.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, nil, nil, nil, ErrInvalidProof
+	}
+	return gamma.X, gamma.Y, c, s, nil
+}
+
+// hashToCurve maps the public key and input to a curve point by the
+// try-and-increment method, which is fine since both are public.
+func hashToCurve(pk, alpha []byte) (*big.Int, *big.Int, error) {
+	for ctr := 0; ctr < 256; ctr++ {
+		h := sha256.New()
+		h.Write([]byte{suite, 0x01})
+		h.Write(pk)
+		h.Write(alpha)
+		h.Write([]byte{byte(ctr), 0x00})
+
+		candidate := append([]byte{0x02}, h.Sum(nil)...)
+		if point, err := crypto.DecompressPubkey(candidate); err == nil {
+			return point.X, point.Y, nil
+		}
+	}
+	return nil, nil, errHashToCurve
+}
+
+// nonce derives the deterministic proving nonce from the secret key and the
+// hashed input point.
+func nonce(sk, h []byte) *big.Int {
+	n := crypto.S256().Params().N
+	for ctr := byte(0); ; ctr++ {
+		digest := sha256.Sum256(bytes.Join([][]byte{{suite, 0x05, ctr}, sk, h}, nil))
+		k := new(big.Int).SetBytes(digest[:])
+		k.Mod(k, n)
+		if k.Sign() > 0 {
+			return k
+		}
+	}
+}
+
+func challenge(points ...[]byte) *big.Int {
+	h := sha256.New()
+	h.Write([]byte{suite, 0x02})
+	for _, p := range points {
+		h.Write(p)
+	}
+	h.Write([]byte{0x00})
+	return new(big.Int).SetBytes(h.Sum(nil)[:challengeLength])
+}
+
+func gammaToHash(gx, gy *big.Int) common.Hash {
+	h := sha256.New()
+	h.Write([]byte{suite, 0x03})
+	h.Write(compress(gx, gy))
+	h.Write([]byte{0x00})
+	return common.BytesToHash(h.Sum(nil))
+}
+
+func compress(x, y *big.Int) []byte {
+	return crypto.CompressPubkey(&ecdsa.PublicKey{Curve: crypto.S256(), X: x, Y: y})
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vrf
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestProveVerify(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	alpha := []byte("sample input")
+
+	proof, err := Prove(key, alpha)
+	if err != nil {
+		t.Fatalf("failed to prove: %v", err)
+	}
+	if len(proof) != ProofLength {
+		t.Fatalf("proof length mismatch: have %d, want %d", len(proof), ProofLength)
+	}
+	output, err := Verify(&key.PublicKey, alpha, proof)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if hash, _ := ProofToHash(proof); hash != output {
+		t.Fatalf("output mismatch: have %x, want %x", hash, output)
+	}
+
+	// Proving is deterministic, the output is unique for key and input
+	again, _ := Prove(key, alpha)
+	if string(again) != string(proof) {
+		t.Fatalf("proof is not deterministic")
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	alpha := []byte("sample input")
+	proof, _ := Prove(key, alpha)
+
+	if _, err := Verify(&other.PublicKey, alpha, proof); err != ErrInvalidProof {
+		t.Errorf("wrong key: have %v, want %v", err, ErrInvalidProof)
+	}
+	if _, err := Verify(&key.PublicKey, []byte("other input"), proof); err != ErrInvalidProof {
+		t.Errorf("wrong input: have %v, want %v", err, ErrInvalidProof)
+	}
+	for i := range proof {
+		tampered := make([]byte, len(proof))
+		copy(tampered, proof)
+		tampered[i] ^= 0x01
+		if _, err := Verify(&key.PublicKey, alpha, tampered); err == nil {
+			t.Fatalf("tampered byte %d: proof accepted", i)
+		}
+	}
+	if _, err := Verify(&key.PublicKey, alpha, proof[1:]); err != ErrInvalidProof {
+		t.Errorf("short proof: have %v, want %v", err, ErrInvalidProof)
+	}
+}
@@ -30,6 +30,8 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/bft/migration"
+	"github.com/ethereum/go-ethereum/consensus/bft/participation"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
@@ -41,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/protocols/bft"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -85,6 +88,8 @@ type Ethereum struct {
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
 
+	participation *participation.Indexer // Validator participation indexer of bft chains, nil otherwise
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -137,11 +142,28 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	// Transfer mining-related config to the ethash config.
 	ethashConfig := config.Ethash
 	ethashConfig.NotifyFull = config.Miner.NotifyFull
-	cliqueConfig, err := core.LoadCliqueConfig(chainDb, config.Genesis)
+	chainConfig, err := core.LoadChainConfig(chainDb, config.Genesis)
 	if err != nil {
 		return nil, err
 	}
-	engine := ethconfig.CreateConsensusEngine(stack, &ethashConfig, cliqueConfig, config.Miner.Notify, config.Miner.Noverify, chainDb)
+	if err := ethconfig.OverrideHotStuff(chainConfig, config.HotStuff); err != nil {
+		return nil, err
+	}
+	engine, err := ethconfig.CreateConsensusEngine(stack, chainConfig, &ethashConfig, &config.HotStuffWAL, &config.HotStuffSigner, config.Miner.Notify, config.Miner.Noverify, chainDb)
+	if err != nil {
+		return nil, err
+	}
+	// Persist the validated hotstuff overrides, through the genesis spec if one
+	// is supplied since it replaces the stored chain config on startup.
+	if config.HotStuff != nil {
+		if config.Genesis != nil && config.Genesis.Config != nil {
+			genesisConfig := *config.Genesis.Config
+			genesisConfig.HotStuff = chainConfig.HotStuff
+			config.Genesis.Config = &genesisConfig
+		} else if stored := rawdb.ReadCanonicalHash(chainDb, 0); stored != (common.Hash{}) {
+			rawdb.WriteChainConfig(chainDb, stored, chainConfig)
+		}
+	}
 
 	eth := &Ethereum{
 		config:            config,
@@ -207,6 +229,14 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	if engine, ok := eth.engine.(consensus.BFT); ok && chainConfig.HotStuff != nil {
+		eth.participation, err = participation.NewIndexer(chainDb, eth.blockchain, engine, chainConfig.HotStuff, config.HotStuffAbsence)
+		if err != nil {
+			return nil, err
+		}
+		eth.participation.Start(eth.blockchain)
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
@@ -229,6 +259,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		EventMux:       eth.eventMux,
 		Checkpoint:     checkpoint,
 		RequiredBlocks: config.RequiredBlocks,
+		Server:         eth.p2pServer,
 	}); err != nil {
 		return nil, err
 	}
@@ -295,6 +326,12 @@ func (s *Ethereum) APIs() []rpc.API {
 
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
+	if s.participation != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "bft",
+			Service:   participation.NewAPI(s.participation),
+		})
+	}
 
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
@@ -446,6 +483,10 @@ func (s *Ethereum) StartMining(threads int) error {
 			if c, ok := cl.InnerEngine().(*clique.Clique); ok {
 				cli = c
 			}
+		} else if m, ok := s.engine.(*migration.Migration); ok {
+			if c, ok := m.LegacyEngine().(*clique.Clique); ok {
+				cli = c
+			}
 		}
 		if cli != nil {
 			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
@@ -506,6 +547,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.handler.bftEngine != nil {
+		protos = append(protos, bft.MakeProtocols((*bftHandler)(s.handler))...)
+	}
 	return protos
 }
 
@@ -543,6 +587,9 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.participation != nil {
+		s.participation.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Close()
@@ -18,8 +18,8 @@
 package ethconfig
 
 import (
-	"github.com/ethereum/go-ethereum/consensus/bft"
-	bftbackend "github.com/ethereum/go-ethereum/consensus/bft/backend"
+	"errors"
+	"fmt"
 	"math/big"
 	"os"
 	"os/user"
@@ -30,6 +30,11 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	bftbackend "github.com/ethereum/go-ethereum/consensus/bft/backend"
+	"github.com/ethereum/go-ethereum/consensus/bft/migration"
+	"github.com/ethereum/go-ethereum/consensus/bft/participation"
+	snr "github.com/ethereum/go-ethereum/consensus/bft/signer"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -38,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
@@ -180,6 +186,18 @@ type Config struct {
 	// Ethash options
 	Ethash ethash.Config
 
+	// HotStuff overrides applied on top of the hotstuff section of the chain config
+	HotStuff *params.HotStuffConfig `toml:",omitempty"`
+
+	// Write-ahead log of the hotstuff consensus messages
+	HotStuffWAL bft.WALConfig `toml:",omitempty"`
+
+	// External signer of the hotstuff consensus data
+	HotStuffSigner bft.SignerConfig `toml:",omitempty"`
+
+	// Handling of the hotstuff validators absent from the committed blocks
+	HotStuffAbsence participation.Config `toml:",omitempty"`
+
 	// Transaction pool options
 	TxPool txpool.Config
 
@@ -219,44 +237,103 @@ type Config struct {
 	SyncTarget *types.Block
 }
 
+// OverrideHotStuff applies the hotstuff settings of the node on top of the chain
+// config, the chain must be running hotstuff.
+func OverrideHotStuff(chainConfig *params.ChainConfig, override *params.HotStuffConfig) error {
+	if override == nil {
+		return nil
+	}
+	if chainConfig.HotStuff == nil {
+		return errors.New("hotstuff settings given for a chain not running hotstuff")
+	}
+	chainConfig.HotStuff = chainConfig.HotStuff.Override(override)
+	return nil
+}
+
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
-func CreateConsensusEngine(stack *node.Node, ethashConfig *ethash.Config, cliqueConfig *params.CliqueConfig, notify []string, noverify bool, db ethdb.Database) consensus.Engine {
+func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, ethashConfig *ethash.Config, walConfig *bft.WALConfig, signerConfig *bft.SignerConfig, notify []string, noverify bool, db ethdb.Database) (consensus.Engine, error) {
 	// If proof-of-authority is requested, set it up
 	var engine consensus.Engine
-	if cliqueConfig != nil {
-		engine = clique.New(cliqueConfig, db)
-		return beacon.New(engine)
+	if chainConfig.Clique != nil && chainConfig.HotStuffBlock == nil {
+		engine = clique.New(chainConfig.Clique, db)
+		return beacon.New(engine), nil
 	}
-
-	// hardCode for now
-	// ToDo: refactor CreateConsensusEngine function:
-	// 1、add bftConfig
-	config := bft.DefaultBasicConfig
-	nodeKey := stack.Config().NodeKey()
-	return bftbackend.New(config, nodeKey, db)
-
-	//else {
-	//	switch ethashConfig.PowMode {
-	//	case ethash.ModeFake:
-	//		log.Warn("Ethash used in fake mode")
-	//	case ethash.ModeTest:
-	//		log.Warn("Ethash used in test mode")
-	//	case ethash.ModeShared:
-	//		log.Warn("Ethash used in shared mode")
-	//	}
-	//	engine = ethash.New(ethash.Config{
-	//		PowMode:          ethashConfig.PowMode,
-	//		CacheDir:         stack.ResolvePath(ethashConfig.CacheDir),
-	//		CachesInMem:      ethashConfig.CachesInMem,
-	//		CachesOnDisk:     ethashConfig.CachesOnDisk,
-	//		CachesLockMmap:   ethashConfig.CachesLockMmap,
-	//		DatasetDir:       ethashConfig.DatasetDir,
-	//		DatasetsInMem:    ethashConfig.DatasetsInMem,
-	//		DatasetsOnDisk:   ethashConfig.DatasetsOnDisk,
-	//		DatasetsLockMmap: ethashConfig.DatasetsLockMmap,
-	//		NotifyFull:       ethashConfig.NotifyFull,
-	//	}, notify, noverify)
-	//	engine.(*ethash.Ethash).SetThreads(-1) // Disable CPU mining
-	//}
-	//return beacon.New(engine)
+	// If hotstuff is requested, set it up with the validated genesis settings
+	var hotstuff consensus.BFT
+	if chainConfig.HotStuff != nil {
+		config, err := bft.NewConfig(chainConfig.HotStuff)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hotstuff config: %w", err)
+		}
+		log.Info("Using hotstuff consensus", "protocol", config.Protocol, "timeout", config.RequestTimeout, "maxtimeout", config.MaxTimeout,
+			"period", config.BlockPeriod, "ondemand", config.OnDemand, "skipempty", config.SkipEmpty, "maxidle", config.MaxIdle,
+			"deadline", config.BuildDeadline, "refresh", config.RefreshPayload, "policy", config.LeaderPolicy, "epoch", config.Epoch, "signature", config.Signature, "fork", chainConfig.HotStuffBlock)
+		if walConfig != nil && walConfig.Dir != "" {
+			config.WAL = *walConfig
+			config.WAL.Dir = stack.ResolvePath(walConfig.Dir)
+		}
+		if signerConfig != nil && signerConfig.Endpoint != "" {
+			config.Signer = *signerConfig
+			if err := config.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid hotstuff signer: %w", err)
+			}
+			signer, err := snr.NewExternalSigner(signerConfig.Endpoint, signerConfig.Account)
+			if err != nil {
+				return nil, fmt.Errorf("hotstuff signer unavailable: %w", err)
+			}
+			log.Info("Signing consensus data externally", "endpoint", signerConfig.Endpoint, "account", signer.Address())
+			hotstuff = bftbackend.NewWithSigner(config, signer, db)
+		} else {
+			hotstuff = bftbackend.New(config, stack.Config().NodeKey(), db)
+		}
+		if chainConfig.HotStuffBlock == nil {
+			return hotstuff, nil
+		}
+		// The validators of a migrated chain have no registered bls keys
+		if config.Signature == bft.BLSSignature {
+			return nil, errors.New("hotstuff fork requires ecdsa signatures")
+		}
+	}
+	if chainConfig.HotStuffBlock != nil {
+		if hotstuff == nil {
+			return nil, errors.New("hotstuff fork without hotstuff config")
+		}
+		if chainConfig.HotStuffBlock.Sign() <= 0 {
+			return nil, errors.New("hotstuff fork at the genesis block")
+		}
+		// Switch the running chain to hotstuff at the fork block
+		if chainConfig.Clique != nil {
+			engine = clique.New(chainConfig.Clique, db)
+			return migration.New(engine, hotstuff, chainConfig.HotStuffBlock, chainConfig.HotStuff.Validators), nil
+		}
+		if len(chainConfig.HotStuff.Validators) == 0 {
+			return nil, errors.New("hotstuff fork of an ethash chain without validators")
+		}
+	}
+	// Otherwise assume proof-of-work
+	switch ethashConfig.PowMode {
+	case ethash.ModeFake:
+		log.Warn("Ethash used in fake mode")
+	case ethash.ModeTest:
+		log.Warn("Ethash used in test mode")
+	case ethash.ModeShared:
+		log.Warn("Ethash used in shared mode")
+	}
+	engine = ethash.New(ethash.Config{
+		PowMode:          ethashConfig.PowMode,
+		CacheDir:         stack.ResolvePath(ethashConfig.CacheDir),
+		CachesInMem:      ethashConfig.CachesInMem,
+		CachesOnDisk:     ethashConfig.CachesOnDisk,
+		CachesLockMmap:   ethashConfig.CachesLockMmap,
+		DatasetDir:       ethashConfig.DatasetDir,
+		DatasetsInMem:    ethashConfig.DatasetsInMem,
+		DatasetsOnDisk:   ethashConfig.DatasetsOnDisk,
+		DatasetsLockMmap: ethashConfig.DatasetsLockMmap,
+		NotifyFull:       ethashConfig.NotifyFull,
+	}, notify, noverify)
+	engine.(*ethash.Ethash).SetThreads(-1) // Disable CPU mining
+	if hotstuff != nil {
+		return migration.New(engine, hotstuff, chainConfig.HotStuffBlock, chainConfig.HotStuff.Validators), nil
+	}
+	return beacon.New(engine), nil
 }
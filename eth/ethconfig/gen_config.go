@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/participation"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -53,6 +55,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		FilterLogCacheSize                    int
 		Miner                                 miner.Config
 		Ethash                                ethash.Config
+		HotStuff                              *params.HotStuffConfig `toml:",omitempty"`
+		HotStuffWAL                           bft.WALConfig          `toml:",omitempty"`
+		HotStuffSigner                        bft.SignerConfig       `toml:",omitempty"`
+		HotStuffAbsence                       participation.Config   `toml:",omitempty"`
 		TxPool                                txpool.Config
 		GPO                                   gasprice.Config
 		EnablePreimageRecording               bool
@@ -100,6 +106,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.FilterLogCacheSize = c.FilterLogCacheSize
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
+	enc.HotStuff = c.HotStuff
+	enc.HotStuffWAL = c.HotStuffWAL
+	enc.HotStuffSigner = c.HotStuffSigner
+	enc.HotStuffAbsence = c.HotStuffAbsence
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
@@ -151,6 +161,10 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		FilterLogCacheSize                    *int
 		Miner                                 *miner.Config
 		Ethash                                *ethash.Config
+		HotStuff                              *params.HotStuffConfig `toml:",omitempty"`
+		HotStuffWAL                           *bft.WALConfig         `toml:",omitempty"`
+		HotStuffSigner                        *bft.SignerConfig      `toml:",omitempty"`
+		HotStuffAbsence                       *participation.Config  `toml:",omitempty"`
 		TxPool                                *txpool.Config
 		GPO                                   *gasprice.Config
 		EnablePreimageRecording               *bool
@@ -267,6 +281,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
+	if dec.HotStuff != nil {
+		c.HotStuff = dec.HotStuff
+	}
+	if dec.HotStuffWAL != nil {
+		c.HotStuffWAL = *dec.HotStuffWAL
+	}
+	if dec.HotStuffSigner != nil {
+		c.HotStuffSigner = *dec.HotStuffSigner
+	}
+	if dec.HotStuffAbsence != nil {
+		c.HotStuffAbsence = *dec.HotStuffAbsence
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
@@ -87,6 +87,7 @@ type handlerConfig struct {
 	EventMux       *event.TypeMux            // Legacy event mux, deprecate for `feed`
 	Checkpoint     *params.TrustedCheckpoint // Hard coded checkpoint for sync challenges
 	RequiredBlocks map[uint64]common.Hash    // Hard coded map of required block hashes for sync challenges
+	Server         *p2p.Server               // Server to keep the validator connections of the `bft` protocol
 }
 
 type handler struct {
@@ -117,6 +118,13 @@ type handler struct {
 
 	requiredBlocks map[uint64]common.Hash
 
+	// consensus engine running on the `bft` protocol
+	bftEngine    consensus.Handler
+	bftPeers     *bftPeerSet
+	server       *p2p.Server
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+
 	// channels for fetcher, syncer, txsyncLoop
 	quitSync chan struct{}
 
@@ -141,6 +149,8 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		peers:          newPeerSet(),
 		merger:         config.Merger,
 		requiredBlocks: config.RequiredBlocks,
+		bftPeers:       newBftPeerSet(),
+		server:         config.Server,
 		quitSync:       make(chan struct{}),
 	}
 	if config.Sync == downloader.FullSync {
@@ -303,6 +313,12 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	}
 	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotes, fetchTx)
 	h.chainSync = newChainSyncer(h)
+
+	// Consensus messages of the engine are sent over the `bft` protocol
+	if engine, ok := h.chain.Engine().(consensus.Handler); ok {
+		h.bftEngine = engine
+		engine.SetBroadcaster((*bftHandler)(h))
+	}
 	return h, nil
 }
 
@@ -556,11 +572,17 @@ func (h *handler) Start(maxPeers int) {
 	// start sync handlers
 	h.wg.Add(1)
 	go h.chainSync.loop()
+
+	// notify the consensus engine of new heads
+	h.startBft()
 }
 
 func (h *handler) Stop() {
 	h.txsSub.Unsubscribe()        // quits txBroadcastLoop
 	h.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
+	if h.chainHeadSub != nil {
+		h.chainHeadSub.Unsubscribe() // quits bftHeadLoop
+	}
 
 	// Quit chainSync and txsync64.
 	// After this is done, no new peers will be accepted.
@@ -572,6 +594,7 @@ func (h *handler) Stop() {
 	// sessions which are already established but not added to h.peers yet
 	// will exit when they try to register.
 	h.peers.close()
+	h.bftPeers.close()
 	h.peerWG.Wait()
 
 	log.Info("Ethereum protocol stopped")
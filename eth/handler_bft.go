@@ -0,0 +1,198 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/bft"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// bftChainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	bftChainHeadChanSize = 10
+)
+
+// bftHandler implements the bft.Backend interface to deliver the consensus
+// messages to the engine, and the consensus.Broadcaster interface to send the
+// messages of the engine to the validators.
+type bftHandler handler
+
+// Address returns the validator address of the local node.
+func (h *bftHandler) Address() common.Address { return h.bftEngine.Address() }
+
+// RunPeer is invoked when a peer joins on the `bft` protocol.
+func (h *bftHandler) RunPeer(peer *bft.Peer, hand bft.Handler) error {
+	return (*handler)(h).runBftPeer(peer, hand)
+}
+
+// PeerInfo retrieves all known `bft` information about a peer.
+func (h *bftHandler) PeerInfo(id enode.ID) interface{} {
+	if p := h.bftPeers.peer(id.String()); p != nil {
+		return p.info()
+	}
+	return nil
+}
+
+// Handle is invoked from a peer's message handler when it receives a consensus
+// message. Messages the engine fails to handle don't drop the peer, they may be
+// sent before the engine started or for a view the node already left.
+func (h *bftHandler) Handle(peer *bft.Peer, msg p2p.Msg) error {
+	if _, err := h.bftEngine.HandleMsg(peer.Address(), msg); err != nil {
+		peer.Log().Trace("Failed to handle consensus message", "code", msg.Code, "err", err)
+	}
+	return nil
+}
+
+// Enqueue implements consensus.Broadcaster, the blocks committed by the engine
+// are imported through the block fetcher.
+func (h *bftHandler) Enqueue(id string, block *types.Block) {
+	h.blockFetcher.Enqueue(id, block)
+}
+
+// FindPeers implements consensus.Broadcaster, it returns the connected peers of
+// the targeted validators.
+func (h *bftHandler) FindPeers(targets map[common.Address]bool) map[common.Address]consensus.Peer {
+	peers := make(map[common.Address]consensus.Peer)
+	for address := range targets {
+		if p := h.bftPeers.validator(address); p != nil {
+			peers[address] = p.Peer
+		}
+	}
+	return peers
+}
+
+// FindPeer implements consensus.Broadcaster.
+func (h *bftHandler) FindPeer(target common.Address) consensus.Peer {
+	if p := h.bftPeers.validator(target); p != nil {
+		return p.Peer
+	}
+	return nil
+}
+
+// PeerCount implements consensus.Broadcaster.
+func (h *bftHandler) PeerCount() int {
+	return h.bftPeers.len()
+}
+
+// runBftPeer runs the `bft` handshake, proving the validator identities of both
+// ends, and registers the peer for the consensus traffic.
+func (h *handler) runBftPeer(peer *bft.Peer, hand bft.Handler) error {
+	h.peerWG.Add(1)
+	defer h.peerWG.Done()
+
+	var self enode.ID
+	if h.server != nil {
+		self = h.server.Self().ID()
+	}
+	genesis := h.chain.Genesis().Hash()
+	if err := peer.Handshake(h.networkID, genesis, self, h.bftEngine.Address(), h.bftEngine.SignHandshake); err != nil {
+		peer.Log().Debug("Consensus handshake failed", "err", err)
+		return err
+	}
+	if err := h.bftPeers.register(peer); err != nil {
+		peer.Log().Debug("Consensus peer registration failed", "address", peer.Address(), "err", err)
+		return err
+	}
+	defer h.bftPeers.unregister(peer.ID())
+
+	peer.Log().Debug("Consensus peer connected", "address", peer.Address())
+	h.keepValidators(h.chain.CurrentHeader())
+
+	return hand(peer)
+}
+
+// keepValidators keeps the connections to the validators of the block above the
+// head, they are marked trusted and redialed once dropped. Peers which stopped
+// validating fall back to regular connections, they are only removed from the
+// static nodes if they were added here.
+func (h *handler) keepValidators(head *types.Header) {
+	height := head.Number.Uint64() + 1
+	for _, p := range h.bftPeers.all() {
+		validator := h.bftEngine.IsValidator(height, p.Address())
+		if validator == (atomic.LoadUint32(&p.validator) == 1) {
+			continue
+		}
+		if validator {
+			atomic.StoreUint32(&p.validator, 1)
+			p.Log().Debug("Keeping validator connection", "address", p.Address())
+			if h.server != nil {
+				h.server.AddTrustedPeer(p.Node())
+				if !h.isStaticNode(p.Node()) && h.bftPeers.addStatic(p.Node().ID()) {
+					h.server.AddPeer(p.Node())
+				}
+			}
+		} else {
+			atomic.StoreUint32(&p.validator, 0)
+			p.Log().Debug("Releasing validator connection", "address", p.Address())
+			if h.server != nil {
+				h.server.RemoveTrustedPeer(p.Node())
+				if h.bftPeers.removeStatic(p.Node().ID()) {
+					h.server.RemoveStaticPeer(p.Node())
+				}
+			}
+		}
+	}
+}
+
+// isStaticNode reports whether the node is configured as a static node by the
+// user.
+func (h *handler) isStaticNode(node *enode.Node) bool {
+	for _, n := range h.server.StaticNodes {
+		if n.ID() == node.ID() {
+			return true
+		}
+	}
+	return false
+}
+
+// bftHeadLoop notifies the consensus engine of the new head blocks, and keeps the
+// connections to the validators of the next block. The heads are announced to the
+// peers, the blocks committed by the engine may bypass the block fetcher.
+func (h *handler) bftHeadLoop() {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case ev := <-h.chainHeadCh:
+			h.bftEngine.NewChainHead(ev.Block.Header())
+			h.keepValidators(ev.Block.Header())
+			h.BroadcastBlock(ev.Block, false)
+
+		case <-h.chainHeadSub.Err():
+			return
+		}
+	}
+}
+
+// startBft starts the consensus head loop if the engine runs on the `bft`
+// protocol.
+func (h *handler) startBft() {
+	if h.bftEngine == nil {
+		return
+	}
+	h.wg.Add(1)
+	h.chainHeadCh = make(chan core.ChainHeadEvent, bftChainHeadChanSize)
+	h.chainHeadSub = h.chain.SubscribeChainHeadEvent(h.chainHeadCh)
+	go h.bftHeadLoop()
+}
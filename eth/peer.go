@@ -18,7 +18,10 @@ package eth
 
 import (
 	"math/big"
+	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/eth/protocols/bft"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 )
@@ -65,3 +68,26 @@ func (p *snapPeer) info() *snapPeerInfo {
 		Version: p.Version(),
 	}
 }
+
+// bftPeerInfo represents a short summary of the `bft` sub-protocol metadata known
+// about a connected peer.
+type bftPeerInfo struct {
+	Version   uint           `json:"version"`   // Consensus protocol version negotiated
+	Address   common.Address `json:"address"`   // Validator address proven in the handshake
+	Validator bool           `json:"validator"` // Whether the peer validates the next block
+}
+
+// bftPeer is a wrapper around bft.Peer to maintain a few extra metadata.
+type bftPeer struct {
+	*bft.Peer
+	validator uint32 // Flag whether the peer validates the next block, it's kept connected
+}
+
+// info gathers and returns some `bft` protocol metadata known about a peer.
+func (p *bftPeer) info() *bftPeerInfo {
+	return &bftPeerInfo{
+		Version:   p.Version(),
+		Address:   p.Address(),
+		Validator: atomic.LoadUint32(&p.validator) == 1,
+	}
+}
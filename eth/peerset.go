@@ -22,9 +22,11 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/eth/protocols/bft"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 )
 
 var (
@@ -257,3 +259,131 @@ func (ps *peerSet) close() {
 	}
 	ps.closed = true
 }
+
+// bftPeerSet represents the collection of active peers participating in the `bft`
+// protocol, by node id and by validator address.
+type bftPeerSet struct {
+	peers      map[string]*bftPeer
+	validators map[common.Address]*bftPeer
+	static     map[enode.ID]struct{} // Validator nodes added to the static nodes, kept across reconnects
+
+	lock   sync.RWMutex
+	closed bool
+}
+
+// newBftPeerSet creates a new peer set to track the active `bft` participants.
+func newBftPeerSet() *bftPeerSet {
+	return &bftPeerSet{
+		peers:      make(map[string]*bftPeer),
+		validators: make(map[common.Address]*bftPeer),
+		static:     make(map[enode.ID]struct{}),
+	}
+}
+
+// register injects a new `bft` peer into the working set, or returns an error if
+// the peer or another peer running the same validator is already known.
+func (ps *bftPeerSet) register(peer *bft.Peer) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.closed {
+		return errPeerSetClosed
+	}
+	if _, ok := ps.peers[peer.ID()]; ok {
+		return errPeerAlreadyRegistered
+	}
+	if _, ok := ps.validators[peer.Address()]; ok {
+		return errPeerAlreadyRegistered
+	}
+	p := &bftPeer{Peer: peer}
+	ps.peers[peer.ID()] = p
+	ps.validators[peer.Address()] = p
+	return nil
+}
+
+// unregister removes a remote peer from the active set.
+func (ps *bftPeerSet) unregister(id string) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	peer, ok := ps.peers[id]
+	if !ok {
+		return errPeerNotRegistered
+	}
+	delete(ps.peers, id)
+	delete(ps.validators, peer.Address())
+	return nil
+}
+
+// peer retrieves the registered peer with the given id.
+func (ps *bftPeerSet) peer(id string) *bftPeer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return ps.peers[id]
+}
+
+// validator retrieves the registered peer running the validator.
+func (ps *bftPeerSet) validator(address common.Address) *bftPeer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return ps.validators[address]
+}
+
+// all retrieves the registered peers.
+func (ps *bftPeerSet) all() []*bftPeer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*bftPeer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		list = append(list, p)
+	}
+	return list
+}
+
+// addStatic marks the node as added to the static nodes, it reports false if it
+// was already added.
+func (ps *bftPeerSet) addStatic(id enode.ID) bool {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.static[id]; ok {
+		return false
+	}
+	ps.static[id] = struct{}{}
+	return true
+}
+
+// removeStatic unmarks the node added to the static nodes, it reports false if it
+// wasn't added.
+func (ps *bftPeerSet) removeStatic(id enode.ID) bool {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.static[id]; !ok {
+		return false
+	}
+	delete(ps.static, id)
+	return true
+}
+
+// len returns if the current number of `bft` peers in the set.
+func (ps *bftPeerSet) len() int {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return len(ps.peers)
+}
+
+// close disconnects all peers.
+func (ps *bftPeerSet) close() {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	for _, p := range ps.peers {
+		p.Disconnect(p2p.DiscQuitting)
+	}
+	ps.closed = true
+}
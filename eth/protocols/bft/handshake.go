@@ -0,0 +1,126 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// handshakeTimeout is the maximum allowed time for the `bft` handshake to
+	// complete before dropping the connection as malicious.
+	handshakeTimeout = 5 * time.Second
+)
+
+// SignFn signs the keccak256 hash of the data with the validator key.
+type SignFn func(data []byte) ([]byte, error)
+
+// Handshake executes the bft protocol handshake, negotiating version number,
+// network ID and genesis block, and exchanging the validator addresses. The
+// address of the peer is proven by it's signature over the node id of the peer,
+// which the devp2p transport already authenticated.
+func (p *Peer) Handshake(network uint64, genesis common.Hash, self enode.ID, address common.Address, sign SignFn) error {
+	sig, err := sign(handshakeData(genesis, self))
+	if err != nil {
+		return err
+	}
+	// Send out own handshake in a new thread
+	errc := make(chan error, 2)
+
+	var status StatusPacket // safe to read after two values have been received from errc
+
+	go func() {
+		errc <- p2p.Send(p.rw, StatusMsg, &StatusPacket{
+			ProtocolVersion: uint32(p.version),
+			NetworkID:       network,
+			Genesis:         genesis,
+			Address:         address,
+			Signature:       sig,
+		})
+	}()
+	go func() {
+		errc <- p.readStatus(network, &status, genesis)
+	}()
+	timeout := time.NewTimer(handshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return err
+			}
+		case <-timeout.C:
+			return p2p.DiscReadTimeout
+		}
+	}
+	if status.Address == address {
+		return fmt.Errorf("%w: %x", errSameValidator, address)
+	}
+	p.address = status.Address
+	return nil
+}
+
+// readStatus reads the remote handshake message, and checks the signature of the
+// validator.
+func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.Hash) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != StatusMsg {
+		return fmt.Errorf("%w: first msg has code %x (!= %x)", errNoStatusMsg, msg.Code, StatusMsg)
+	}
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+	// Decode the handshake and make sure everything matches
+	if err := msg.Decode(&status); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	if status.NetworkID != network {
+		return fmt.Errorf("%w: %d (!= %d)", errNetworkIDMismatch, status.NetworkID, network)
+	}
+	if uint(status.ProtocolVersion) != p.version {
+		return fmt.Errorf("%w: %d (!= %d)", errProtocolVersionMismatch, status.ProtocolVersion, p.version)
+	}
+	if status.Genesis != genesis {
+		return fmt.Errorf("%w: %x (!= %x)", errGenesisMismatch, status.Genesis, genesis)
+	}
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(handshakeData(genesis, p.Peer.ID())), status.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidSignature, err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != status.Address {
+		return fmt.Errorf("%w: signed by %x (!= %x)", errInvalidSignature, signer, status.Address)
+	}
+	return nil
+}
+
+// handshakeData returns the data a validator signs in the handshake, it binds the
+// validator to the node id of it's end of the connection.
+func handshakeData(genesis common.Hash, node enode.ID) []byte {
+	data := make([]byte, 0, len(ProtocolName)+common.HashLength+len(node))
+	data = append(data, ProtocolName...)
+	data = append(data, genesis.Bytes()...)
+	return append(data, node.Bytes()...)
+}
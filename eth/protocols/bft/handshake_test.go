@@ -0,0 +1,197 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	cbft "github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/bft/signer"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// signFn returns a handshake signer of the key.
+func signFn(key *ecdsa.PrivateKey) SignFn {
+	return func(data []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(data), key)
+	}
+}
+
+// Tests that two validators prove their addresses to each other.
+func TestHandshake(t *testing.T) {
+	t.Parallel()
+
+	var (
+		genesis = common.Hash{1}
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		id1     = enode.ID{1}
+		id2     = enode.ID{2}
+	)
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	// each end knows the other by it's node id
+	peer1 := NewPeer(BFT1, p2p.NewPeer(id2, "peer2", nil), app)
+	defer peer1.Close()
+	peer2 := NewPeer(BFT1, p2p.NewPeer(id1, "peer1", nil), net)
+	defer peer2.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- peer2.Handshake(1, genesis, id2, crypto.PubkeyToAddress(key2.PublicKey), signFn(key2))
+	}()
+	if err := peer1.Handshake(1, genesis, id1, crypto.PubkeyToAddress(key1.PublicKey), signFn(key1)); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("remote handshake failed: %v", err)
+	}
+	if have, want := peer1.Address(), crypto.PubkeyToAddress(key2.PublicKey); have != want {
+		t.Errorf("remote address mismatch: have %x, want %x", have, want)
+	}
+	if have, want := peer2.Address(), crypto.PubkeyToAddress(key1.PublicKey); have != want {
+		t.Errorf("remote address mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that validators signing with a remote signer, e.g. clef, prove their
+// addresses in the handshake. The remote signer checks the request like clef does.
+func TestHandshakeRemoteSigner(t *testing.T) {
+	t.Parallel()
+
+	remote := func(key *ecdsa.PrivateKey) SignFn {
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		return signer.NewRemoteSigner(address, func(req *cbft.SignRequest) ([]byte, error) {
+			if err := req.Verify(); err != nil {
+				return nil, err
+			}
+			if req.Kind != cbft.SignHandshake {
+				return nil, fmt.Errorf("unexpected sign request kind %v", req.Kind)
+			}
+			return crypto.Sign(crypto.Keccak256(req.Payload), key)
+		}).SignHandshake
+	}
+	var (
+		genesis = common.Hash{1}
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		id1     = enode.ID{1}
+		id2     = enode.ID{2}
+	)
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	peer1 := NewPeer(BFT1, p2p.NewPeer(id2, "peer2", nil), app)
+	defer peer1.Close()
+	peer2 := NewPeer(BFT1, p2p.NewPeer(id1, "peer1", nil), net)
+	defer peer2.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- peer2.Handshake(1, genesis, id2, crypto.PubkeyToAddress(key2.PublicKey), remote(key2))
+	}()
+	if err := peer1.Handshake(1, genesis, id1, crypto.PubkeyToAddress(key1.PublicKey), remote(key1)); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("remote handshake failed: %v", err)
+	}
+	if have, want := peer1.Address(), crypto.PubkeyToAddress(key2.PublicKey); have != want {
+		t.Errorf("remote address mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that handshake failures are detected and reported correctly.
+func TestHandshakeFailures(t *testing.T) {
+	t.Parallel()
+
+	var (
+		genesis  = common.Hash{1}
+		key, _   = crypto.GenerateKey()
+		other, _ = crypto.GenerateKey()
+		remote   = enode.ID{2}
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	sign := func(key *ecdsa.PrivateKey, genesis common.Hash, node enode.ID) []byte {
+		sig, _ := signFn(key)(handshakeData(genesis, node))
+		return sig
+	}
+	tests := []struct {
+		code uint64
+		data interface{}
+		want error
+	}{
+		{
+			code: ConsensusMsg, data: []byte{},
+			want: errNoStatusMsg,
+		},
+		{
+			code: StatusMsg, data: StatusPacket{10, 1, genesis, address, sign(key, genesis, remote)},
+			want: errProtocolVersionMismatch,
+		},
+		{
+			code: StatusMsg, data: StatusPacket{BFT1, 999, genesis, address, sign(key, genesis, remote)},
+			want: errNetworkIDMismatch,
+		},
+		{
+			code: StatusMsg, data: StatusPacket{BFT1, 1, common.Hash{3}, address, sign(key, common.Hash{3}, remote)},
+			want: errGenesisMismatch,
+		},
+		{
+			// the validator signed the handshake of another node
+			code: StatusMsg, data: StatusPacket{BFT1, 1, genesis, address, sign(key, genesis, enode.ID{3})},
+			want: errInvalidSignature,
+		},
+		{
+			// the address of another validator is claimed
+			code: StatusMsg, data: StatusPacket{BFT1, 1, genesis, address, sign(other, genesis, remote)},
+			want: errInvalidSignature,
+		},
+		{
+			code: StatusMsg, data: StatusPacket{BFT1, 1, genesis, address, []byte{1, 2, 3}},
+			want: errInvalidSignature,
+		},
+	}
+	for i, test := range tests {
+		// Create the two peers to shake with each other
+		app, net := p2p.MsgPipe()
+		defer app.Close()
+		defer net.Close()
+
+		peer := NewPeer(BFT1, p2p.NewPeer(remote, "peer", nil), net)
+		defer peer.Close()
+
+		// Send the junk test with one peer, check the handshake failure
+		go p2p.Send(app, test.code, test.data)
+
+		err := peer.Handshake(1, genesis, enode.ID{1}, crypto.PubkeyToAddress(other.PublicKey), signFn(other))
+		if err == nil {
+			t.Errorf("test %d: protocol returned nil error, want %q", i, test.want)
+		} else if !errors.Is(err, test.want) {
+			t.Errorf("test %d: wrong error: got %q, want %q", i, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	// maxQueuedMsgs is the maximum number of messages of a priority to queue up
+	// before dropping the older ones. Consensus messages go stale with the round,
+	// so there's no point in queueing many of them for a slow peer.
+	maxQueuedMsgs = 1024
+)
+
+// queuedMsg is a message waiting for it's turn in the send queue of the peer.
+type queuedMsg struct {
+	code uint64
+	data interface{}
+}
+
+// Peer is a collection of relevant information we have about a `bft` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                   // The embedded P2P package peer
+	rw        p2p.MsgReadWriter // Input/output streams for bft
+	version   uint              // Protocol version negotiated
+
+	address common.Address // Validator address proven in the handshake
+
+	queues [numPriorities][]*queuedMsg // Messages waiting to be sent, by priority
+	queued chan struct{}               // Notification channel of the send loop
+	term   chan struct{}               // Termination channel to stop the send loop
+	lock   sync.Mutex                  // Mutex protecting the send queues
+
+	logger log.Logger // Contextual logger with the peer id injected
+}
+
+// NewPeer create a wrapper for a network connection and negotiated  protocol
+// version.
+func NewPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	id := p.ID().String()
+	peer := &Peer{
+		id:      id,
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		queued:  make(chan struct{}, 1),
+		term:    make(chan struct{}),
+		logger:  log.New("peer", id[:8]),
+	}
+	go peer.sendLoop()
+
+	return peer
+}
+
+// Close signals the send goroutine to terminate. Only ever call this if
+// you created the peer yourself via NewPeer. Otherwise let whoever created it
+// clean it up!
+func (p *Peer) Close() {
+	close(p.term)
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negotiated `bft` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Address retrieves the validator address of the peer, it's known once the
+// handshake completed.
+func (p *Peer) Address() common.Address {
+	return p.address
+}
+
+// Log overrides the P2P logger with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// Send queues a message for the peer, the messages of higher priority are sent
+// first. It implements consensus.Peer, the engine never waits for the network.
+func (p *Peer) Send(msgcode uint64, data interface{}) error {
+	priority, ok := msgPriorities[msgcode]
+	if !ok {
+		return fmt.Errorf("%w: %v", errInvalidMsgCode, msgcode)
+	}
+	select {
+	case <-p.term:
+		return errPeerClosed
+	default:
+	}
+	p.lock.Lock()
+	queue := append(p.queues[priority], &queuedMsg{code: msgcode, data: data})
+	if len(queue) > maxQueuedMsgs {
+		// Fancy copy and resize to ensure buffer doesn't grow indefinitely
+		queue = queue[:copy(queue, queue[len(queue)-maxQueuedMsgs:])]
+	}
+	p.queues[priority] = queue
+	p.lock.Unlock()
+
+	select {
+	case p.queued <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// nextMsg pops the oldest message of the highest priority from the send queues.
+func (p *Peer) nextMsg() *queuedMsg {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for priority := numPriorities - 1; priority >= 0; priority-- {
+		if queue := p.queues[priority]; len(queue) > 0 {
+			msg := queue[0]
+			p.queues[priority] = queue[1:]
+			return msg
+		}
+	}
+	return nil
+}
+
+// sendLoop is a write loop that sends the queued messages to the remote peer. The
+// goal is to have an async writer that does not lock up the consensus engine.
+func (p *Peer) sendLoop() {
+	for {
+		msg := p.nextMsg()
+		if msg == nil {
+			select {
+			case <-p.queued:
+				continue
+			case <-p.term:
+				return
+			}
+		}
+		if err := p2p.Send(p.rw, msg.code, msg.data); err != nil {
+			p.logger.Debug("Failed to send consensus message", "code", msg.code, "err", err)
+			return
+		}
+	}
+}
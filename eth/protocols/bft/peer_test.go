@@ -0,0 +1,60 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+	"testing"
+)
+
+// Tests that the queued consensus messages are sent before the evidence, and that
+// the oldest messages are dropped once a queue is full.
+func TestSendPriority(t *testing.T) {
+	// the send loop is not started, the queues are drained by the test
+	peer := &Peer{queued: make(chan struct{}, 1), term: make(chan struct{})}
+
+	peer.Send(EvidenceMsg, []byte{1})
+	peer.Send(ConsensusMsg, []byte{2})
+	peer.Send(EvidenceMsg, []byte{3})
+	peer.Send(ConsensusMsg, []byte{4})
+	if err := peer.Send(StatusMsg, []byte{5}); !errors.Is(err, errInvalidMsgCode) {
+		t.Fatalf("status message error mismatch: have %v, want %v", err, errInvalidMsgCode)
+	}
+	for i, want := range []byte{2, 4, 1, 3} {
+		msg := peer.nextMsg()
+		if msg == nil {
+			t.Fatalf("message %d missing", i)
+		}
+		if data := msg.data.([]byte); data[0] != want {
+			t.Errorf("message %d mismatch: have %d, want %d", i, data[0], want)
+		}
+	}
+	if msg := peer.nextMsg(); msg != nil {
+		t.Fatalf("unexpected message %v", msg)
+	}
+
+	for i := 0; i < maxQueuedMsgs+10; i++ {
+		peer.Send(ConsensusMsg, i)
+	}
+	if msg := peer.nextMsg(); msg.data.(int) != 10 {
+		t.Fatalf("oldest message mismatch: have %d, want 10", msg.data)
+	}
+	peer.Close()
+	if err := peer.Send(ConsensusMsg, []byte{}); !errors.Is(err, errPeerClosed) {
+		t.Fatalf("closed peer error mismatch: have %v, want %v", err, errPeerClosed)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	BFT1 = 1
+)
+
+// ProtocolName is the official short name of the `bft` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "bft"
+
+// ProtocolVersions are the supported versions of the `bft` protocol (first
+// is primary).
+var ProtocolVersions = []uint{BFT1}
+
+// protocolLengths are the number of implemented message corresponding to
+// different protocol versions.
+var protocolLengths = map[uint]uint64{BFT1: 5}
+
+// maxMessageSize is the maximum cap on the size of a protocol message, proposals
+// carry full blocks.
+const maxMessageSize = 10 * 1024 * 1024
+
+const (
+	StatusMsg       = 0x00
+	ConsensusMsg    = 0x01
+	EvidenceMsg     = 0x02
+	GetCertifiedMsg = 0x03
+	CertifiedMsg    = 0x04
+)
+
+var (
+	errNoStatusMsg             = errors.New("no status message")
+	errMsgTooLarge             = errors.New("message too long")
+	errDecode                  = errors.New("invalid message")
+	errInvalidMsgCode          = errors.New("invalid message code")
+	errProtocolVersionMismatch = errors.New("protocol version mismatch")
+	errNetworkIDMismatch       = errors.New("network ID mismatch")
+	errGenesisMismatch         = errors.New("genesis mismatch")
+	errInvalidSignature        = errors.New("invalid validator signature")
+	errSameValidator           = errors.New("peer runs the same validator")
+	errPeerClosed              = errors.New("peer closed")
+)
+
+// StatusPacket is the network packet for the status message. The signature of
+// the validator over the genesis and the node id of the sender proves that the
+// validator runs the node at the other end of the connection.
+type StatusPacket struct {
+	ProtocolVersion uint32
+	NetworkID       uint64
+	Genesis         common.Hash
+	Address         common.Address
+	Signature       []byte
+}
+
+// msgPriorities are the send priorities of the message codes, the higher ones are
+// sent to the peer first.
+var msgPriorities = map[uint64]int{
+	ConsensusMsg:    1,
+	EvidenceMsg:     0,
+	GetCertifiedMsg: 0,
+	CertifiedMsg:    0,
+}
+
+// numPriorities is the number of send priorities.
+const numPriorities = 2
@@ -0,0 +1,76 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bftclient provides an RPC client for the consensus introspection APIs
+// of the HotStuff engine.
+package bftclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bft/backend"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client is a wrapper around rpc.Client that implements the bft namespace.
+type Client struct {
+	c *rpc.Client
+}
+
+// New creates a client that uses the given RPC client.
+func New(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// RoundState returns the consensus state of the node in the current view.
+func (ec *Client) RoundState(ctx context.Context) (*backend.RPCRoundState, error) {
+	var result *backend.RPCRoundState
+	err := ec.c.CallContext(ctx, &result, "bft_getRoundState")
+	return result, err
+}
+
+// Validators returns the validators and their voting power at the given block. The
+// current head is used if the block number is nil.
+func (ec *Client) Validators(ctx context.Context, number *big.Int) (*backend.Validators, error) {
+	var result *backend.Validators
+	err := ec.c.CallContext(ctx, &result, "bft_getValidators", toBlockNumArg(number))
+	return result, err
+}
+
+// Epochs returns the epochs of the validator set in ascending order of their start
+// heights.
+func (ec *Client) Epochs(ctx context.Context) ([]*backend.RPCEpoch, error) {
+	var result []*backend.RPCEpoch
+	err := ec.c.CallContext(ctx, &result, "bft_getEpochs")
+	return result, err
+}
+
+// Signers returns the proposer and the committers of the given block. The current
+// head is used if the block number is nil.
+func (ec *Client) Signers(ctx context.Context, number *big.Int) (*backend.Signers, error) {
+	var result *backend.Signers
+	err := ec.c.CallContext(ctx, &result, "bft_getSigners", toBlockNumArg(number))
+	return result, err
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
@@ -580,6 +580,8 @@ type blockStats struct {
 	TxHash     common.Hash    `json:"transactionsRoot"`
 	Root       common.Hash    `json:"stateRoot"`
 	Uncles     uncleStats     `json:"uncles"`
+
+	Signers []common.Address `json:"signers,omitempty"` // Committers of a bft block
 }
 
 // txStats is the information to report about individual transactions.
@@ -616,6 +618,35 @@ func (s *Service) reportBlock(conn *connWrapper, block *types.Block) error {
 	return conn.WriteJSON(report)
 }
 
+// assembleConsensusStats retrieves the consensus health of the node, nil if the
+// engine is not a running bft engine.
+func (s *Service) assembleConsensusStats() *consensusStats {
+	engine, ok := s.engine.(consensus.BFT)
+	if !ok {
+		return nil
+	}
+	health := engine.Health()
+	if health == nil {
+		return nil
+	}
+	stats := &consensusStats{
+		Height:       health.Height,
+		Round:        health.Round,
+		State:        health.State,
+		PhaseTimes:   make(map[string]uint64, len(health.PhaseTimes)),
+		Rounds:       health.Rounds,
+		RoundChanges: health.RoundChanges,
+		MissedVotes:  health.MissedVotes,
+	}
+	for state, elapsed := range health.PhaseTimes {
+		stats.PhaseTimes[state] = uint64(elapsed.Milliseconds())
+	}
+	if health.Rounds > 0 {
+		stats.ProposerShare = float64(health.ProposerRounds) / float64(health.Rounds)
+	}
+	return stats
+}
+
 // assembleBlockStats retrieves any required metadata to report a single block
 // and assembles the block stats. If block is nil, the current head is processed.
 func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
@@ -652,9 +683,16 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		txs = []txStats{}
 	}
 
-	// Assemble and return the block stats
-	author, _ := s.engine.Author(header)
-
+	// Assemble and return the block stats, with the committers of bft blocks
+	var (
+		author  common.Address
+		signers []common.Address
+	)
+	if engine, ok := s.engine.(consensus.BFT); ok && header.Number.Sign() > 0 {
+		author, signers, _ = engine.Signers(header)
+	} else {
+		author, _ = s.engine.Author(header)
+	}
 	return &blockStats{
 		Number:     header.Number,
 		Hash:       header.Hash(),
@@ -669,6 +707,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		TxHash:     header.TxHash,
 		Root:       header.Root,
 		Uncles:     uncles,
+		Signers:    signers,
 	}
 }
 
@@ -763,6 +802,21 @@ type nodeStats struct {
 	Peers    int  `json:"peers"`
 	GasPrice int  `json:"gasPrice"`
 	Uptime   int  `json:"uptime"`
+
+	Consensus *consensusStats `json:"consensus,omitempty"`
+}
+
+// consensusStats is the information to report about the consensus health of a
+// node running a bft engine.
+type consensusStats struct {
+	Height        uint64            `json:"height"`
+	Round         uint64            `json:"round"`
+	State         string            `json:"state"`
+	PhaseTimes    map[string]uint64 `json:"phaseTimes"` // Milliseconds spent in each state
+	Rounds        uint64            `json:"rounds"`
+	RoundChanges  uint64            `json:"roundChanges"`
+	ProposerShare float64           `json:"proposerShare"` // Share of the rounds led by the node
+	MissedVotes   uint64            `json:"missedVotes"`
 }
 
 // reportStats retrieves various stats about the node at the networking and
@@ -799,13 +853,14 @@ func (s *Service) reportStats(conn *connWrapper) error {
 	stats := map[string]interface{}{
 		"id": s.node,
 		"stats": &nodeStats{
-			Active:   true,
-			Mining:   mining,
-			Hashrate: hashrate,
-			Peers:    s.server.PeerCount(),
-			GasPrice: gasprice,
-			Syncing:  syncing,
-			Uptime:   100,
+			Active:    true,
+			Mining:    mining,
+			Hashrate:  hashrate,
+			Peers:     s.server.PeerCount(),
+			GasPrice:  gasprice,
+			Syncing:   syncing,
+			Uptime:    100,
+			Consensus: s.assembleConsensusStats(),
 		},
 	}
 	report := map[string][]interface{}{
@@ -111,6 +111,17 @@ func New(stack *node.Node, config *ethconfig.Config) (*LightEthereum, error) {
 	log.Info(strings.Repeat("-", 153))
 	log.Info("")
 
+	if err := ethconfig.OverrideHotStuff(chainConfig, config.HotStuff); err != nil {
+		return nil, err
+	}
+	engine, err := ethconfig.CreateConsensusEngine(stack, chainConfig, &config.Ethash, nil, nil, nil, false, chainDb)
+	if err != nil {
+		return nil, err
+	}
+	// Persist the validated hotstuff overrides, the genesis is already set up
+	if config.HotStuff != nil {
+		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
+	}
 	peers := newServerPeerSet()
 	merger := consensus.NewMerger(chainDb)
 	leth := &LightEthereum{
@@ -128,7 +139,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*LightEthereum, error) {
 		reqDist:         newRequestDistributor(peers, &mclock.System{}),
 		accountManager:  stack.AccountManager(),
 		merger:          merger,
-		engine:          ethconfig.CreateConsensusEngine(stack, &config.Ethash, chainConfig.Clique, nil, false, chainDb),
+		engine:          engine,
 		bloomRequests:   make(chan chan *bloombits.Retrieval),
 		bloomIndexer:    core.NewBloomIndexer(chainDb, params.BloomBitsBlocksClient, params.HelperTrieConfirmations),
 		p2pServer:       stack.Server(),
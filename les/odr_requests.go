@@ -68,6 +68,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*BloomRequest)(r)
 	case *light.TxStatusRequest:
 		return (*TxStatusRequest)(r)
+	case *light.EpochProofRequest:
+		return (*EpochProofRequest)(r)
 	default:
 		return nil
 	}
@@ -513,6 +515,50 @@ func (r *TxStatusRequest) Validate(db ethdb.Database, msg *Msg) error {
 	return nil
 }
 
+// EpochProofRequest is the ODR request type for the headers which change the
+// validators of the epochs after a known one
+type EpochProofRequest light.EpochProofRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *EpochProofRequest) GetCost(peer *serverPeer) uint64 {
+	return peer.getRequestCost(GetEpochProofsMsg, MaxEpochProofsFetch)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *EpochProofRequest) CanSend(peer *serverPeer) bool {
+	return peer.version >= lpv5
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *EpochProofRequest) Request(reqID uint64, peer *serverPeer) error {
+	peer.Log().Debug("Requesting epoch proofs", "from", r.From)
+	return peer.requestEpochProofs(reqID, r.From, MaxEpochProofsFetch)
+}
+
+// Validate processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *EpochProofRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating epoch proofs", "from", r.From)
+
+	if msg.MsgType != MsgEpochProofs {
+		return errInvalidMessageType
+	}
+	headers := msg.Obj.([]*types.Header)
+	if len(headers) > MaxEpochProofsFetch {
+		return errInvalidEntryCount
+	}
+	// The engine stores the epochs proven before a failed header, the next request
+	// continues from the last one. The epochs may be proven meanwhile by the reply
+	// to another request, they are verified again.
+	if err := r.Engine.VerifyEpochProof(r.From, headers); err != nil {
+		return err
+	}
+	r.Headers = headers
+	return nil
+}
+
 // readTraceDB stores the keys of database reads. We use this to check that received node
 // sets contain only the trie nodes necessary to make proofs pass.
 type readTraceDB struct {
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -435,3 +437,62 @@ func randomHash() common.Hash {
 	}
 	return hash
 }
+
+// testEpochProver is an epoch prover which accepts the proofs of the epochs after
+// the latest one, the latest epoch is moved by the tests.
+type testEpochProver struct {
+	latest uint64
+	from   uint64
+}
+
+func (p *testEpochProver) LatestEpoch() uint64 { return p.latest }
+
+func (p *testEpochProver) EpochProof(chain consensus.ChainHeaderReader, from uint64, limit int) []*types.Header {
+	return nil
+}
+
+func (p *testEpochProver) VerifyEpochProof(from uint64, headers []*types.Header) error {
+	p.from = from
+	for _, header := range headers {
+		if header.Number.Uint64() < from {
+			return errors.New("epoch before the requested one")
+		}
+		from = header.Number.Uint64() + 1
+	}
+	return nil
+}
+
+// Tests that the epoch proof replies are validated against the epoch they were
+// requested from, and that bad replies are rejected.
+func TestEpochProofRequestValidate(t *testing.T) {
+	var (
+		prover  = &testEpochProver{latest: 8}
+		req     = &EpochProofRequest{From: prover.latest, Engine: prover}
+		headers = []*types.Header{{Number: big.NewInt(15)}, {Number: big.NewInt(23)}}
+	)
+	// Another request moved the latest epoch meanwhile
+	prover.latest = 16
+	if err := req.Validate(nil, &Msg{MsgType: MsgEpochProofs, Obj: headers}); err != nil {
+		t.Fatalf("failed to validate epoch proof: %v", err)
+	}
+	if prover.from != 8 {
+		t.Fatalf("proof verified from the wrong epoch: have %d, want %d", prover.from, 8)
+	}
+	if len(req.Headers) != len(headers) {
+		t.Fatalf("proof headers mismatch: have %d, want %d", len(req.Headers), len(headers))
+	}
+	// Bad replies are rejected
+	req = &EpochProofRequest{From: prover.latest, Engine: prover}
+	if err := req.Validate(nil, &Msg{MsgType: MsgEpochProofs, Obj: headers}); err == nil {
+		t.Fatal("epoch proof before the requested epoch accepted")
+	}
+	if err := req.Validate(nil, &Msg{MsgType: MsgBlockHeaders, Obj: headers}); err != errInvalidMessageType {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidMessageType)
+	}
+	if err := req.Validate(nil, &Msg{MsgType: MsgEpochProofs, Obj: make([]*types.Header, MaxEpochProofsFetch+1)}); err != errInvalidEntryCount {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidEntryCount)
+	}
+	if req.Headers != nil {
+		t.Fatalf("headers of a bad proof stored: %d", len(req.Headers))
+	}
+}
@@ -481,6 +481,13 @@ func (p *serverPeer) requestTxStatus(reqID uint64, txHashes []common.Hash) error
 	return p.sendRequest(GetTxStatusMsg, reqID, txHashes, len(txHashes))
 }
 
+// requestEpochProofs fetches the headers which change the validators of the epochs
+// after the one starting at the given height.
+func (p *serverPeer) requestEpochProofs(reqID uint64, from uint64, amount int) error {
+	p.Log().Debug("Fetching epoch proofs", "from", from, "count", amount)
+	return p.sendRequest(GetEpochProofsMsg, reqID, &GetEpochProofsData{From: from, Amount: uint64(amount)}, amount)
+}
+
 // sendTxs creates a reply with a batch of transactions to be added to the remote transaction pool.
 func (p *serverPeer) sendTxs(reqID uint64, amount int, txs rlp.RawValue) error {
 	p.Log().Debug("Sending batch of transactions", "amount", amount, "size", len(txs))
@@ -924,6 +931,12 @@ func (p *clientPeer) replyTxStatus(reqID uint64, stats []light.TxStatus) *reply
 	return &reply{p.rw, TxStatusMsg, reqID, data}
 }
 
+// replyEpochProofs creates a reply with a batch of epoch change headers, corresponding to the ones requested.
+func (p *clientPeer) replyEpochProofs(reqID uint64, headers []*types.Header) *reply {
+	data, _ := rlp.EncodeToBytes(headers)
+	return &reply{p.rw, EpochProofsMsg, reqID, data}
+}
+
 // sendAnnounce announces the availability of a number of blocks through
 // a hash notification.
 func (p *clientPeer) sendAnnounce(request announceData) error {
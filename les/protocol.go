@@ -36,17 +36,18 @@ const (
 	lpv2 = 2
 	lpv3 = 3
 	lpv4 = 4
+	lpv5 = 5
 )
 
 // Supported versions of the les protocol (first is primary)
 var (
-	ClientProtocolVersions    = []uint{lpv2, lpv3, lpv4}
-	ServerProtocolVersions    = []uint{lpv2, lpv3, lpv4}
+	ClientProtocolVersions    = []uint{lpv2, lpv3, lpv4, lpv5}
+	ServerProtocolVersions    = []uint{lpv2, lpv3, lpv4, lpv5}
 	AdvertiseProtocolVersions = []uint{lpv2} // clients are searching for the first advertised protocol in the list
 )
 
 // ProtocolLengths is the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = map[uint]uint64{lpv2: 22, lpv3: 24, lpv4: 24}
+var ProtocolLengths = map[uint]uint64{lpv2: 22, lpv3: 24, lpv4: 24, lpv5: 26}
 
 const (
 	NetworkId          = 1
@@ -82,6 +83,9 @@ const (
 	// Protocol messages introduced in LPV3
 	StopMsg   = 0x16
 	ResumeMsg = 0x17
+	// Protocol messages introduced in LPV5
+	GetEpochProofsMsg = 0x18
+	EpochProofsMsg    = 0x19
 )
 
 // GetBlockHeadersData represents a block header query (the request ID is not included)
@@ -134,6 +138,19 @@ type SendTxPacket struct {
 	Txs   []*types.Transaction
 }
 
+// GetEpochProofsData represents a query of the headers which change the validators
+// of the epochs after a known one (the request ID is not included)
+type GetEpochProofsData struct {
+	From   uint64 // Start height of the known epoch
+	Amount uint64 // Maximum number of headers to retrieve
+}
+
+// GetEpochProofsPacket represents an epoch proof request
+type GetEpochProofsPacket struct {
+	ReqID uint64
+	Query GetEpochProofsData
+}
+
 // GetTxStatusPacket represents a transaction status query
 type GetTxStatusPacket struct {
 	ReqID  uint64
@@ -51,6 +51,7 @@ const (
 	MaxHelperTrieProofsFetch = 64  // Amount of helper tries to be fetched per retrieval request
 	MaxTxSend                = 64  // Amount of transactions to be send per request
 	MaxTxStatus              = 256 // Amount of transactions to queried per request
+	MaxEpochProofsFetch      = 64  // Amount of epoch change headers to be fetched per request
 )
 
 var (
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -146,6 +147,16 @@ var Les3 = map[uint64]RequestType{
 		ServingTimeMeter: miscServingTimeTxStatusTimer,
 		Handle:           handleGetTxStatus,
 	},
+	GetEpochProofsMsg: {
+		Name:             "epoch proof request",
+		MaxCount:         MaxEpochProofsFetch,
+		InPacketsMeter:   miscInEpochProofPacketsMeter,
+		InTrafficMeter:   miscInEpochProofTrafficMeter,
+		OutPacketsMeter:  miscOutEpochProofPacketsMeter,
+		OutTrafficMeter:  miscOutEpochProofTrafficMeter,
+		ServingTimeMeter: miscServingTimeEpochProofTimer,
+		Handle:           handleGetEpochProofs,
+	},
 }
 
 // handleGetBlockHeaders handles a block header request
@@ -552,6 +563,25 @@ func handleGetTxStatus(msg Decoder) (serveRequestFn, uint64, uint64, error) {
 	}, r.ReqID, uint64(len(r.Hashes)), nil
 }
 
+// handleGetEpochProofs handles an epoch proof request, chains without epochs are
+// served with an empty proof
+func handleGetEpochProofs(msg Decoder) (serveRequestFn, uint64, uint64, error) {
+	var r GetEpochProofsPacket
+	if err := msg.Decode(&r); err != nil {
+		return nil, 0, 0, err
+	}
+	return func(backend serverBackend, p *clientPeer, waitOrStop func() bool) *reply {
+		var (
+			bc      = backend.BlockChain()
+			headers []*types.Header
+		)
+		if prover, ok := bc.Engine().(consensus.EpochProver); ok {
+			headers = prover.EpochProof(bc, r.Query.From, int(r.Query.Amount))
+		}
+		return p.replyEpochProofs(r.ReqID, headers)
+	}, r.ReqID, r.Query.Amount, nil
+}
+
 // txStatus returns the status of a specified transaction.
 func txStatus(b serverBackend, hash common.Hash) light.TxStatus {
 	var stat light.TxStatus
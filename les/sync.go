@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/les/downloader"
 	"github.com/ethereum/go-ethereum/light"
@@ -192,6 +193,16 @@ func (h *clientHandler) synchronise(peer *serverPeer) {
 		}
 	}
 
+	// Prove the validators of the recent epochs of a BFT chain, the headers after a
+	// checkpoint can't be verified by walking the epochs from the genesis. Without
+	// the proofs the headers are still verified epoch by epoch.
+	if prover, ok := h.backend.engine.(consensus.EpochProver); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		if err := light.SyncEpochs(ctx, h.backend.odr, prover); err != nil {
+			log.Debug("Epoch proof syncing failed", "reason", err)
+		}
+	}
 	if h.syncStart != nil {
 		h.syncStart(h.backend.blockchain.CurrentHeader())
 	}
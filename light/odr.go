@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -196,3 +197,15 @@ type TxStatusRequest struct {
 
 // StoreResult stores the retrieved data in local database
 func (req *TxStatusRequest) StoreResult(db ethdb.Database) {}
+
+// EpochProofRequest is the ODR request type for retrieving the headers which change
+// the validators of the epochs after the one starting at From
+type EpochProofRequest struct {
+	From    uint64
+	Engine  consensus.EpochProver
+	Headers []*types.Header
+}
+
+// StoreResult stores the retrieved data in local database, the epochs are already
+// stored by the engine while verifying the headers.
+func (req *EpochProofRequest) StoreResult(db ethdb.Database) {}
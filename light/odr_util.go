@@ -23,6 +23,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -292,3 +293,18 @@ func GetTransaction(ctx context.Context, odr OdrBackend, txHash common.Hash) (*t
 	}
 	return body.Transactions[pos.Index], pos.BlockHash, pos.BlockIndex, pos.Index, nil
 }
+
+// SyncEpochs retrieves the epoch proofs after the latest epoch known to the engine,
+// until the epoch of the server head is reached. The engine verifies every epoch
+// with the validators of the previous one.
+func SyncEpochs(ctx context.Context, odr OdrBackend, engine consensus.EpochProver) error {
+	for {
+		r := &EpochProofRequest{From: engine.LatestEpoch(), Engine: engine}
+		if err := odr.Retrieve(ctx, r); err != nil {
+			return err
+		}
+		if len(r.Headers) == 0 {
+			return nil
+		}
+	}
+}
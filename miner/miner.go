@@ -96,15 +96,11 @@ func New(eth Backend, config *Config, chainConfig *params.ChainConfig, mux *even
 		stopCh:  make(chan struct{}),
 		worker:  newWorker(config, chainConfig, engine, eth, mux, isLocalBlock, true),
 	}
-	protocol := bft.BftProtocol(chainConfig.Bft.Protocol)
-	switch protocol {
-	// Currently the logic doesn't go here
-	// ToDo: modify the chainConfig at the launch time
-	case bft.BFT_PROTOCOL_BASIC:
-		miner.worker = newWorker(config, chainConfig, engine, eth, mux, isLocalBlock, true)
-		miner.EnablePreseal()
-	default:
-		miner.worker = newWorker(config, chainConfig, engine, eth, mux, isLocalBlock, true)
+	if chainConfig.HotStuff != nil {
+		switch bft.BftProtocol(chainConfig.HotStuff.Protocol) {
+		case bft.BFT_PROTOCOL_BASIC, bft.BFT_PROTOCOL_EVENT_DRIVEN:
+			miner.EnablePreseal()
+		}
 	}
 
 	miner.wg.Add(1)
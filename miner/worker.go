@@ -208,6 +208,8 @@ type worker struct {
 	chainHeadSub event.Subscription
 	chainSideCh  chan core.ChainSideEvent
 	chainSideSub event.Subscription
+	certifiedCh  chan *types.Block // Blocks certified by a pipelining engine, nil for the other engines
+	certifiedSub event.Subscription
 
 	// Channels
 	newWorkCh          chan *newWorkReq
@@ -300,6 +302,10 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	// Subscribe events for blockchain
 	worker.chainHeadSub = eth.BlockChain().SubscribeChainHeadEvent(worker.chainHeadCh)
 	worker.chainSideSub = eth.BlockChain().SubscribeChainSideEvent(worker.chainSideCh)
+	if pipeliner, ok := engine.(consensus.Pipeliner); ok {
+		worker.certifiedCh = make(chan *types.Block, chainHeadChanSize)
+		worker.certifiedSub = pipeliner.SubscribeCertifiedHead(worker.certifiedCh)
+	}
 
 	// Sanitize recommit interval if the user-specified one is too short.
 	recommit := worker.config.Recommit
@@ -416,6 +422,21 @@ func (w *worker) stop() {
 	atomic.StoreInt32(&w.running, 0)
 }
 
+// sealsOnDemand returns whether the consensus engine only seals blocks with
+// transactions, e.g. 0 period clique or on-demand hotstuff.
+func (w *worker) sealsOnDemand() bool {
+	if w.chainConfig.Clique != nil && w.chainConfig.Clique.Period == 0 {
+		return true
+	}
+	return w.chainConfig.HotStuff != nil && w.chainConfig.HotStuff.OnDemand
+}
+
+// skipsEmpty returns whether the consensus engine holds back empty blocks until an
+// idle interval passed, e.g. hotstuff skipping empty blocks.
+func (w *worker) skipsEmpty() bool {
+	return w.chainConfig.HotStuff != nil && w.chainConfig.HotStuff.SkipEmpty
+}
+
 // isRunning returns an indicator whether worker is running or not.
 func (w *worker) isRunning() bool {
 	return atomic.LoadInt32(&w.running) == 1
@@ -497,14 +518,23 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 			commit(false, commitInterruptNewHead)
 
 		case head := <-w.chainHeadCh:
+			// the blocks are built on the certified block above the head
+			if w.certifiedHead() != nil {
+				continue
+			}
 			clearPending(head.Block.NumberU64())
 			timestamp = time.Now().Unix()
 			commit(false, commitInterruptNewHead)
 
+		case head := <-w.certifiedCh:
+			clearPending(head.NumberU64())
+			timestamp = time.Now().Unix()
+			commit(false, commitInterruptNewHead)
+
 		case <-timer.C:
 			// If sealing is running resubmit a new work cycle periodically to pull in
 			// higher priced transactions. Disable this overhead for pending blocks.
-			if w.isRunning() && (w.chainConfig.Clique == nil || w.chainConfig.Clique.Period > 0) {
+			if w.isRunning() && !w.sealsOnDemand() {
 				// Short circuit if no new transaction arrives.
 				if atomic.LoadInt32(&w.newTxs) == 0 {
 					timer.Reset(recommit)
@@ -557,6 +587,9 @@ func (w *worker) mainLoop() {
 	defer w.txsSub.Unsubscribe()
 	defer w.chainHeadSub.Unsubscribe()
 	defer w.chainSideSub.Unsubscribe()
+	if w.certifiedSub != nil {
+		defer w.certifiedSub.Unsubscribe()
+	}
 	defer func() {
 		if w.current != nil {
 			w.current.discard()
@@ -641,10 +674,12 @@ func (w *worker) mainLoop() {
 					w.updateSnapshot(w.current)
 				}
 			} else {
-				// Special case, if the consensus engine is 0 period clique(dev mode),
-				// submit sealing work here since all empty submission will be rejected
-				// by clique. Of course the advance sealing(empty submission) is disabled.
-				if w.chainConfig.Clique != nil && w.chainConfig.Clique.Period == 0 {
+				// Special case, if the consensus engine is 0 period clique or on-demand
+				// hotstuff (dev mode), submit sealing work here since all empty submission
+				// will be rejected by the engine. Of course the advance sealing(empty
+				// submission) is disabled. Engines skipping empty blocks replace the held
+				// back empty block the same way.
+				if w.sealsOnDemand() || (w.skipsEmpty() && (w.current == nil || w.current.tcount == 0)) {
 					w.commitWork(nil, true, time.Now().Unix())
 				}
 			}
@@ -976,6 +1011,15 @@ type generateParams struct {
 	noTxs      bool           // Flag whether an empty block without any transaction is expected
 }
 
+// certifiedHead returns the certified block above the chain head if the engine
+// pipelines the blocks, nil otherwise.
+func (w *worker) certifiedHead() *types.Block {
+	if pipeliner, ok := w.engine.(consensus.Pipeliner); ok {
+		return pipeliner.CertifiedHead()
+	}
+	return nil
+}
+
 // prepareWork constructs the sealing task according to the given parameters,
 // either based on the last chain head or specified parent. In this function
 // the pending transactions are not filled yet, only the empty task returned.
@@ -983,8 +1027,12 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
-	// Find the parent block for sealing task
+	// Find the parent block for sealing task, the certified block above the head
+	// of pipelining engines
 	parent := w.chain.CurrentBlock()
+	if head := w.certifiedHead(); head != nil {
+		parent = head
+	}
 	if genParams.parentHash != (common.Hash{}) {
 		parent = w.chain.GetBlockByHash(genParams.parentHash)
 	}
@@ -1140,6 +1188,19 @@ func (w *worker) commitWork(interrupt *int32, noempty bool, timestamp int64) {
 	if !noempty && atomic.LoadUint32(&w.noempty) == 0 {
 		w.commit(work.copy(), nil, false, start)
 	}
+	// Stop filling transactions at the building deadline of the consensus engine,
+	// the block is then sealed as it is.
+	if deadliner, ok := w.engine.(consensus.BuildDeadliner); ok {
+		if deadline, ok := deadliner.BuildDeadline(work.header); ok {
+			if interrupt == nil {
+				interrupt = new(int32)
+			}
+			timer := time.AfterFunc(time.Until(deadline), func() {
+				atomic.CompareAndSwapInt32(interrupt, commitInterruptNone, commitInterruptTimeout)
+			})
+			defer timer.Stop()
+		}
+	}
 	// Fill pending transactions from the txpool into the block.
 	err = w.fillTransactions(interrupt, work)
 	switch {
@@ -1161,6 +1222,11 @@ func (w *worker) commitWork(interrupt *int32, noempty bool, timestamp int64) {
 			inc:   true,
 		}
 
+	case errors.Is(err, errBlockInterruptedByTimeout):
+		// The building deadline of the consensus engine passed, submit the
+		// partially filled block without adjusting the resubmit interval.
+		log.Debug("Block building reached the deadline", "number", work.header.Number, "txs", work.tcount)
+
 	case errors.Is(err, errBlockInterruptedByNewHead):
 		// If the block building is interrupted by newhead event, discard it
 		// totally. Committing the interrupted block introduces unnecessary
@@ -357,6 +357,12 @@ func (srv *Server) RemovePeer(node *enode.Node) {
 	}
 }
 
+// RemoveStaticPeer removes a node from the static node set, the connection to the
+// node is kept if it's currently connected as a peer.
+func (srv *Server) RemoveStaticPeer(node *enode.Node) {
+	srv.dialsched.removeStatic(node)
+}
+
 // AddTrustedPeer adds the given node to a reserved trusted list which allows the
 // node to always connect, even if the slot are full.
 func (srv *Server) AddTrustedPeer(node *enode.Node) {
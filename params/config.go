@@ -243,16 +243,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil, nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil, nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -335,16 +335,92 @@ type ChainConfig struct {
 	EWASMBlock    *big.Int `json:"ewasmBlock,omitempty"`    // EWASM switch block (nil = no fork, 0 = already activated)
 	CatalystBlock *big.Int `json:"catalystBlock,omitempty"` // Catalyst switch block (nil = no fork, 0 = already on catalyst)
 
+	// HotStuffBlock switches a running clique or ethash chain to the HotStuff engine,
+	// the engine of the chain is selected by the genesis if it's not set.
+	HotStuffBlock *big.Int `json:"hotStuffBlock,omitempty"` // HotStuff switch block (nil = no fork)
+
 	// Various consensus engines
 	Ethash   *EthashConfig   `json:"ethash,omitempty"`
 	Clique   *CliqueConfig   `json:"clique,omitempty"`
-	HotStuff *HotStuffConfig `json:"hotstuff"`
+	HotStuff *HotStuffConfig `json:"hotstuff,omitempty"`
 }
 
-// EthashConfig is the consensus engine configs for proof-of-work based sealing.
-// todo:
+// HotStuffConfig is the consensus engine configs for HotStuff based BFT sealing.
+// Zero values fall back to the defaults of the selected protocol.
 type HotStuffConfig struct {
-	Protocol string `json:"protocol"`
+	Protocol       string `json:"protocol"`                 // Protocol variant, "basic" or "event_driven"
+	RequestTimeout uint64 `json:"requestTimeout,omitempty"` // Round timeout in milliseconds
+	MaxTimeout     uint64 `json:"maxTimeout,omitempty"`     // Cap of the exponential round timeout backoff in milliseconds
+	BlockPeriod    uint64 `json:"blockPeriod,omitempty"`    // Minimum block interval, seconds for basic and milliseconds for event_driven
+	LeaderPolicy   string `json:"leaderPolicy,omitempty"`   // Proposer selection policy, "roundrobin", "sticky" or "vrf"
+	Epoch          uint64 `json:"epoch,omitempty"`          // Number of blocks after which to checkpoint and reset the pending votes
+	Signature      string `json:"signature,omitempty"`      // Committed seal scheme, "ecdsa" or "bls"
+	OnDemand       bool   `json:"onDemand,omitempty"`       // Seal blocks only with pending transactions, without block period (basic only)
+	SkipEmpty      bool   `json:"skipEmpty,omitempty"`      // Hold back empty blocks until the max idle interval passed since the parent
+	MaxIdle        uint64 `json:"maxIdle,omitempty"`        // Max interval between blocks in milliseconds when empty blocks are skipped
+	BuildDeadline  uint64 `json:"buildDeadline,omitempty"`  // Milliseconds after the start of a height at which the proposer stops adding transactions
+	RefreshPayload bool   `json:"refreshPayload,omitempty"` // Let the proposer swap in a fresher block until it proposes
+
+	DropEquivocators bool `json:"dropEquivocators,omitempty"` // Vote out validators with equivocation evidence
+
+	Validators []common.Address `json:"validators,omitempty"` // Validators of the HotStuff fork block, the last clique signers if empty
+
+	ValidatorContract *common.Address `json:"validatorContract,omitempty"` // Contract read for the validators at every epoch boundary instead of the votes
+}
+
+// Override returns a copy of the config with every non-zero field of o applied
+// on top of it.
+func (c *HotStuffConfig) Override(o *HotStuffConfig) *HotStuffConfig {
+	cpy := *c
+	if o == nil {
+		return &cpy
+	}
+	if o.Protocol != "" {
+		cpy.Protocol = o.Protocol
+	}
+	if o.RequestTimeout != 0 {
+		cpy.RequestTimeout = o.RequestTimeout
+	}
+	if o.MaxTimeout != 0 {
+		cpy.MaxTimeout = o.MaxTimeout
+	}
+	if o.BlockPeriod != 0 {
+		cpy.BlockPeriod = o.BlockPeriod
+	}
+	if o.LeaderPolicy != "" {
+		cpy.LeaderPolicy = o.LeaderPolicy
+	}
+	if o.Epoch != 0 {
+		cpy.Epoch = o.Epoch
+	}
+	if o.Signature != "" {
+		cpy.Signature = o.Signature
+	}
+	if o.DropEquivocators {
+		cpy.DropEquivocators = true
+	}
+	if o.OnDemand {
+		cpy.OnDemand = true
+	}
+	if o.SkipEmpty {
+		cpy.SkipEmpty = true
+	}
+	if o.MaxIdle != 0 {
+		cpy.MaxIdle = o.MaxIdle
+	}
+	if o.BuildDeadline != 0 {
+		cpy.BuildDeadline = o.BuildDeadline
+	}
+	if o.RefreshPayload {
+		cpy.RefreshPayload = true
+	}
+	if len(o.Validators) > 0 {
+		cpy.Validators = o.Validators
+	}
+	if o.ValidatorContract != nil {
+		cpy.ValidatorContract = o.ValidatorContract
+	}
+	return &cpy
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -375,10 +451,14 @@ func (c *CliqueConfig) String() string {
 func (c *ChainConfig) String() string {
 	var engine interface{}
 	switch {
+	case c.HotStuffBlock != nil:
+		engine = fmt.Sprintf("%v -> %v@%v", c.legacyEngine(), c.HotStuff, c.HotStuffBlock)
 	case c.Ethash != nil:
 		engine = c.Ethash
 	case c.Clique != nil:
 		engine = c.Clique
+	case c.HotStuff != nil:
+		engine = c.HotStuff
 	default:
 		engine = "unknown"
 	}
@@ -401,6 +481,15 @@ func (c *ChainConfig) String() string {
 	)
 }
 
+// legacyEngine returns the config of the engine which seals the blocks before the
+// HotStuff fork.
+func (c *ChainConfig) legacyEngine() interface{} {
+	if c.Clique != nil {
+		return c.Clique
+	}
+	return c.Ethash
+}
+
 // IsHomestead returns whether num is either equal to the homestead block or greater.
 func (c *ChainConfig) IsHomestead(num *big.Int) bool {
 	return isForked(c.HomesteadBlock, num)
@@ -473,6 +562,11 @@ func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 	return isForked(c.EWASMBlock, num)
 }
 
+// IsHotStuff returns whether num is either equal to the HotStuff fork block or greater.
+func (c *ChainConfig) IsHotStuff(num *big.Int) bool {
+	return isForked(c.HotStuffBlock, num)
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -585,6 +679,9 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
 		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
 	}
+	if isForkIncompatible(c.HotStuffBlock, newcfg.HotStuffBlock, head) {
+		return newCompatError("HotStuff fork block", c.HotStuffBlock, newcfg.HotStuffBlock)
+	}
 	return nil
 }
 
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -96,3 +98,35 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestHotStuffOverride(t *testing.T) {
+	base := &HotStuffConfig{Protocol: "basic", RequestTimeout: 6000, BlockPeriod: 3, LeaderPolicy: "roundrobin", Epoch: 30000}
+
+	// Zero fields of the override keep the base settings
+	if have := base.Override(&HotStuffConfig{}); !reflect.DeepEqual(have, base) {
+		t.Errorf("empty override mismatch: have %+v, want %+v", have, base)
+	}
+	if have := base.Override(nil); !reflect.DeepEqual(have, base) || have == base {
+		t.Errorf("nil override mismatch: have %+v, want copy of %+v", have, base)
+	}
+	override := &HotStuffConfig{Protocol: "event_driven", RequestTimeout: 4000, BlockPeriod: 2000, LeaderPolicy: "vrf", Epoch: 100}
+	if have := base.Override(override); !reflect.DeepEqual(have, override) {
+		t.Errorf("override mismatch: have %+v, want %+v", have, override)
+	}
+	if base.Protocol != "basic" || base.Epoch != 30000 {
+		t.Errorf("override modified the base config: %+v", base)
+	}
+	// A fork may enable or move the validator contract
+	contract := common.HexToAddress("0x1000")
+	if have := base.Override(&HotStuffConfig{ValidatorContract: &contract}); have.ValidatorContract == nil || *have.ValidatorContract != contract {
+		t.Errorf("validator contract override mismatch: have %v, want %v", have.ValidatorContract, contract)
+	}
+	moved := common.HexToAddress("0x2000")
+	enabled := &HotStuffConfig{Protocol: "basic", ValidatorContract: &contract}
+	if have := enabled.Override(&HotStuffConfig{ValidatorContract: &moved}); *have.ValidatorContract != moved {
+		t.Errorf("validator contract move mismatch: have %v, want %v", have.ValidatorContract, moved)
+	}
+	if have := enabled.Override(&HotStuffConfig{}); *have.ValidatorContract != contract {
+		t.Errorf("validator contract dropped: have %v, want %v", have.ValidatorContract, contract)
+	}
+}
@@ -186,6 +186,10 @@ var (
 		accounts.MimetypeClique,
 		0x02,
 	}
+	ApplicationHotStuff = SigFormat{
+		accounts.MimetypeHotStuff,
+		0x03,
+	}
 	TextPlain = SigFormat{
 		accounts.MimetypeTextPlain,
 		0x45,
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -167,6 +168,39 @@ func (api *SignerAPI) determineSignatureFormat(ctx context.Context, contentType
 		// Clique uses V on the form 0 or 1
 		useEthereumV = false
 		req = &SignDataRequest{ContentType: mediaType, Rawdata: cliqueRlp, Messages: messages, Hash: sighash}
+	case apitypes.ApplicationHotStuff.Mime:
+		// HotStuff consensus data, described by it's view so rules can refuse
+		// conflicting data of a view
+		stringData, ok := data.(string)
+		if !ok {
+			return nil, useEthereumV, fmt.Errorf("input for %v must be an hex-encoded string", apitypes.ApplicationHotStuff.Mime)
+		}
+		hotstuffData, err := hexutil.Decode(stringData)
+		if err != nil {
+			return nil, useEthereumV, err
+		}
+		request := new(bft.SignRequest)
+		if err := rlp.DecodeBytes(hotstuffData, request); err != nil {
+			return nil, useEthereumV, err
+		}
+		if err := request.Verify(); err != nil {
+			return nil, useEthereumV, err
+		}
+		messages := []*apitypes.NameValueType{
+			{
+				Name:  "HotStuff " + request.Kind.String(),
+				Typ:   "hotstuff",
+				Value: fmt.Sprintf("hotstuff %v of %v [%#x]", request.Kind, request.View, request.Digest),
+			},
+			{Name: "kind", Typ: "string", Value: request.Kind.String()},
+			{Name: "code", Typ: "uint64", Value: request.Code},
+			{Name: "height", Typ: "uint256", Value: request.View.Height.String()},
+			{Name: "round", Typ: "uint256", Value: request.View.Round.String()},
+			{Name: "digest", Typ: "bytes32", Value: request.Digest.Hex()},
+		}
+		// HotStuff uses V on the form 0 or 1
+		useEthereumV = false
+		req = &SignDataRequest{ContentType: mediaType, Rawdata: request.Payload, Messages: messages, Hash: crypto.Keccak256(request.Payload)}
 	default: // also case TextPlain.Mime:
 		// Calculates an Ethereum ECDSA signature for:
 		// hash = keccak256("\x19Ethereum Signed Message:\n${message length}${message}")
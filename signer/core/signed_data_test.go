@@ -20,7 +20,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 	"os"
 	"path"
 	"strings"
@@ -30,7 +32,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
@@ -232,6 +237,76 @@ func TestSignData(t *testing.T) {
 	}
 }
 
+// Tests that hotstuff consensus data is signed over the payload of the request
+// with a 0/1 V, and that requests whose view doesn't match the payload are refused.
+func TestSignHotStuffData(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control.approveCh <- "1"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0])
+
+	view := &bft.View{Round: big.NewInt(1), Height: big.NewInt(10)}
+	header := hotstuffHeader(10)
+	digest := types.BftFilteredHeader(header, true).Hash()
+	vote, err := rlp.EncodeToBytes(bft.NewVoteRequest(view, header, digest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	control.approveCh <- "Y"
+	control.inputCh <- "a_long_password"
+	signature, err := api.SignData(context.Background(), apitypes.ApplicationHotStuff.Mime, a, hexutil.Encode(vote))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signature) != 65 || signature[64] > 1 {
+		t.Fatalf("Expected 65 byte signature with 0/1 V, got %x", signature)
+	}
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(digest.Bytes()), signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := crypto.PubkeyToAddress(*pubkey); addr != a.Address() {
+		t.Errorf("Signer mismatch: have %v, want %v", addr, a.Address())
+	}
+	// A vote claiming to be a timeout of the view, a vote labelled with another
+	// height and a vote without the header it seals
+	timeout := bft.NewVoteRequest(view, header, digest)
+	timeout.Kind = bft.SignTimeout
+
+	other := hotstuffHeader(11)
+	relabelled := bft.NewVoteRequest(view, other, types.BftFilteredHeader(other, true).Hash())
+
+	bare := bft.NewVoteRequest(view, header, digest)
+	bare.Header = nil
+
+	for _, req := range []*bft.SignRequest{timeout, relabelled, bare} {
+		forged, err := rlp.EncodeToBytes(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.SignData(context.Background(), apitypes.ApplicationHotStuff.Mime, a, hexutil.Encode(forged)); !errors.Is(err, bft.ErrInvalidSignRequest) {
+			t.Errorf("Expected ErrInvalidSignRequest, got %v", err)
+		}
+	}
+}
+
+// hotstuffHeader returns an unsealed hotstuff header of the height.
+func hotstuffHeader(number int64) *types.Header {
+	extra, _ := rlp.EncodeToBytes(&types.BftExtra{
+		Seal:          make([]byte, types.BftExtraSeal),
+		CommittedSeal: [][]byte{},
+	})
+	return &types.Header{
+		Number:     big.NewInt(number),
+		Difficulty: common.Big1,
+		Extra:      append(make([]byte, types.BftExtraVanity), extra...),
+	}
+}
+
 func TestDomainChainId(t *testing.T) {
 	withoutChainID := apitypes.TypedData{
 		Types: apitypes.Types{